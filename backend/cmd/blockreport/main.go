@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/services"
+)
+
+// ตรวจ block ที่มีอยู่แล้วกับ schema ก่อนเปิดใช้การตรวจ แล้วพิมพ์รายงานเป็น JSON
+// exit code 1 ถ้ามี block ที่ไม่ผ่าน
+func main() {
+	loadEnv()
+	config.ConnectionDatabase()
+
+	report, err := services.BuildBlockSchemaReport(config.GetDB(), services.BlockSchemas)
+	if err != nil {
+		log.Fatalf("Failed to build block schema report: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Checked %d portfolio blocks and %d template blocks, %d invalid",
+		report.PortfolioBlocksChecked, report.TemplateBlocksChecked, report.InvalidCount)
+	if report.InvalidCount > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadEnv() {
+	if os.Getenv("GIN_MODE") == "release" {
+		return
+	}
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sut68/team14/backend/services"
+)
+
+// คัดลอกไฟล์อัปโหลดทั้งหมดจาก backend หนึ่งไปอีก backend ด้วยชื่อเดิม (ไม่ลบต้นทาง)
+// ค่าตั้งของแต่ละ backend อ่านจาก environment เดียวกับ server (UPLOAD_DIR, S3_*, AZURE_STORAGE_*)
+// เช่น go run ./cmd/storagemigrate -from local -to s3 แล้วตั้ง STORAGE_BACKEND=s3
+// URL แบบ /uploads/<name> ที่บันทึกไว้ยังใช้ได้เพราะ server redirect ไปที่ backend ใหม่
+// exit code 1 ถ้ามีไฟล์ที่คัดลอกไม่สำเร็จ
+func main() {
+	from := flag.String("from", services.StorageBackendLocal, "source backend (local, s3, azure)")
+	to := flag.String("to", "", "destination backend (local, s3, azure)")
+	overwrite := flag.Bool("overwrite", false, "copy files that already exist in the destination")
+	dryRun := flag.Bool("dry-run", false, "list files that would be copied without copying")
+	flag.Parse()
+
+	loadEnv()
+	if *to == "" || *to == *from {
+		log.Fatal("-to must be set to a backend different from -from")
+	}
+
+	src, err := services.NewStorageBackend(*from)
+	if err != nil {
+		log.Fatalf("Failed to open source storage: %v", err)
+	}
+	dst, err := services.NewStorageBackend(*to)
+	if err != nil {
+		log.Fatalf("Failed to open destination storage: %v", err)
+	}
+
+	result, err := services.MigrateStorage(src, dst, services.MigrateStorageOptions{Overwrite: *overwrite, DryRun: *dryRun})
+	if err != nil {
+		log.Fatalf("Failed to migrate storage: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Copied %d files, skipped %d, failed %d", len(result.Copied), len(result.Skipped), len(result.Failed))
+	if len(result.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadEnv() {
+	if os.Getenv("GIN_MODE") == "release" {
+		return
+	}
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+}
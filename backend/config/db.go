@@ -100,6 +100,17 @@ func SetupDatabase() {
 		&entity.Event{},
 		&entity.Selection{},
 		&entity.PasswordReset{},
+		&entity.ImportJob{},
+		&entity.SelectionRoundLimit{},
+		&entity.PortfolioChange{},
+		&entity.PortfolioRestorePoint{},
+		&entity.PortfolioShareLink{},
+		&entity.PortfolioCurriculum{},
+		&entity.TemplateVersion{},
+		&entity.TemplateRating{},
+		&entity.CurriculumRecommendedTemplate{},
+		&entity.CurriculumRequiredSection{},
+		&entity.UploadedFile{},
 	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -43,6 +43,8 @@ func (ac *ActivityController) CreateActivity(c *gin.Context) {
 		return
 	}
 
+	fillActivityImageRenditions(activity.ActivityDetail)
+
 	// Create activity
 	if err := db.Create(&activity).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -170,6 +172,7 @@ func (ac *ActivityController) UpdateActivity(c *gin.Context) {
 
 				// Assign new images
 				detail.Images = input.ActivityDetail.Images
+				fillActivityImageRenditions(&detail)
 
 				// Save detail
 				db.Save(&detail)
@@ -180,6 +183,7 @@ func (ac *ActivityController) UpdateActivity(c *gin.Context) {
 			// but GORM association update can be tricky.
 			// Let's use GORM's association mode or just update explicitly.
 			activity.ActivityDetail = input.ActivityDetail
+			fillActivityImageRenditions(activity.ActivityDetail)
 		}
 	}
 
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// respondBlockValidation ตอบ 400 พร้อมรายละเอียดถ้า error มาจากการตรวจ schema ของ block
+func respondBlockValidation(c *gin.Context, err error) bool {
+	var validationErr *services.BlockValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "validation": validationErr})
+	return true
+}
+
+// GetBlockTypes - GET /portfolio/block-types
+// ชนิด block ทั้งหมดพร้อม JSON Schema ให้ frontend ใช้ตรวจก่อนบันทึก
+func GetBlockTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": services.BlockSchemas.Types()})
+}
+
+// BlockSchemaController รายงาน block เดิมที่ไม่ผ่าน schema (สำหรับ admin)
+type BlockSchemaController struct {
+	DB *gorm.DB
+}
+
+// GetInvalidBlockReport - GET /api/admin/block-schemas/report
+func (ctl *BlockSchemaController) GetInvalidBlockReport(c *gin.Context) {
+	report, err := services.BuildBlockSchemaReport(ctl.DB, services.BlockSchemas)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
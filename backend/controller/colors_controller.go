@@ -1,15 +1,19 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
-	"github.com/sut68/team14/backend/entity"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
 )
 
+// GetAllColors ธีมสีของระบบ (ธีมส่วนตัวของนักศึกษาดูได้ที่ /colors/mine)
 func GetAllColors(c *gin.Context) {
 	var colors []entity.Colors
-	if err := config.GetDB().Find(&colors).Error; err != nil {
+	if err := config.GetDB().Where("user_id IS NULL").Find(&colors).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -17,17 +21,155 @@ func GetAllColors(c *gin.Context) {
 		"status": "success",
 		"data":   colors,
 	})
-}	
+}
 
 func GetColorByID(c *gin.Context) {
-    var color entity.Colors
-    id := c.Param("id")
-    if err := config.GetDB().First(&color, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Color theme not found"})
-        return
-    }
-    c.JSON(http.StatusOK, gin.H{
-        "status": "success",
-        "data":   color,
-    })
-}
\ No newline at end of file
+	var color entity.Colors
+	id := c.Param("id")
+	if err := config.GetDB().First(&color, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Color theme not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   color,
+	})
+}
+
+type colorThemeInput struct {
+	ColorsName      string `json:"colors_name"`
+	PrimaryColor    string `json:"primary_color"`
+	SecondaryColor  string `json:"secondary_color"`
+	BackgroundColor string `json:"background_color"`
+	HexValue        string `json:"hex_value"`
+}
+
+func (in colorThemeInput) apply(color *entity.Colors) error {
+	color.ColorsName = in.ColorsName
+	color.PrimaryColor = in.PrimaryColor
+	color.SecondaryColor = in.SecondaryColor
+	color.BackgroundColor = in.BackgroundColor
+	color.HexValue = in.HexValue
+	if err := color.Validate(); err != nil {
+		return err
+	}
+	return color.ValidateContrast()
+}
+
+// GetMyColors ธีมสีส่วนตัวของผู้ใช้
+func GetMyColors(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+	colors := []entity.Colors{}
+	if err := config.GetDB().Where("user_id = ?", userID).Order("id ASC").Find(&colors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": colors})
+}
+
+// CreateColorTheme สร้างธีมสีส่วนตัว ต้องผ่าน Colors.Validate และ contrast ตาม WCAG AA
+func CreateColorTheme(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+	var input colorThemeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	color := entity.Colors{UserID: &userID}
+	if err := input.apply(&color); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := config.GetDB().Omit("Portfolio").Create(&color).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "data": color})
+}
+
+// UpdateColorTheme แก้ธีมสีส่วนตัว (แก้ธีมของระบบหรือของคนอื่นไม่ได้)
+func UpdateColorTheme(c *gin.Context) {
+	color, ok := loadOwnColorTheme(c)
+	if !ok {
+		return
+	}
+	var input colorThemeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.apply(color); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := config.GetDB().Omit("Portfolio").Save(color).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": color})
+}
+
+// DeleteColorTheme ลบธีมสีส่วนตัวที่ไม่มี portfolio ใช้อยู่
+func DeleteColorTheme(c *gin.Context) {
+	color, ok := loadOwnColorTheme(c)
+	if !ok {
+		return
+	}
+	db := config.GetDB()
+	var used int64
+	if err := db.Model(&entity.Portfolio{}).Where("colors_id = ?", color.ID).Count(&used).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if used > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Color theme is used by a portfolio", "portfolio_count": used})
+		return
+	}
+	if err := db.Delete(color).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Color theme deleted"})
+}
+
+func loadOwnColorTheme(c *gin.Context) (*entity.Colors, bool) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return nil, false
+	}
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid color theme id"})
+		return nil, false
+	}
+	var color entity.Colors
+	if err := config.GetDB().Where("user_id = ?", userID).First(&color, id).Error; err != nil {
+		handleDBError(c, err, "Color theme not found")
+		return nil, false
+	}
+	return &color, true
+}
+
+// checkPortfolioColors ธีมที่ portfolio เลือกต้องเป็นธีมของระบบหรือธีมของเจ้าของ portfolio
+func checkPortfolioColors(db *gorm.DB, colorsID, ownerID uint) error {
+	var color entity.Colors
+	if err := db.First(&color, colorsID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("selected color theme does not exist")
+		}
+		return err
+	}
+	if color.UserID != nil && *color.UserID != ownerID {
+		return errors.New("selected color theme belongs to another user")
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -8,6 +9,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/middlewares"
+	"github.com/sut68/team14/backend/services"
 	"gorm.io/gorm"
 )
 
@@ -104,6 +107,12 @@ func (cc *CurriculumController) RegisterRoutes(r *gin.Engine, protected *gin.Rou
 		admin.PUT("/curricula/:id", cc.UpdateCurriculum)
 		admin.DELETE("/curricula/:id", cc.DeleteCurriculum)
 		admin.GET("/curricula/summary", cc.GetCurriculumSummary)
+		admin.POST("/curricula/import", middlewares.RequireAdmin(), cc.ImportCurricula) // CSV / XLSX
+		admin.GET("/curricula/export", middlewares.RequireAdmin(), cc.ExportCurricula)  // ?format=csv|xlsx
+
+		// template ที่แนะนำ และ section ที่ portfolio ต้องมี
+		admin.PUT("/curricula/:id/recommended-templates", middlewares.RequireAdmin(), cc.UpdateRecommendedTemplates)
+		admin.PUT("/curricula/:id/required-sections", middlewares.RequireAdmin(), cc.UpdateRequiredSections)
 	}
 }
 
@@ -158,6 +167,13 @@ func (cc *CurriculumController) GetCurriculumByID(c *gin.Context) {
 		Preload("RequiredDocuments.DocumentType").
 		Preload("Skills.Skill").
 		Preload("CourseGroups.CourseGroup.CourseGroupSkills.Skill").
+		Preload("RecommendedTemplates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Preload("RecommendedTemplates.Templates").
+		Preload("RequiredSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
 		First(&curriculum, id).Error; err != nil {
 
 		c.JSON(http.StatusNotFound, gin.H{"error": "curriculum not found"})
@@ -234,7 +250,7 @@ func (cc *CurriculumController) CreateCurriculum(c *gin.Context) {
 	calculatedStatus := getCalculatedStatus(payload.ApplicationPeriod)
 
 	cur := entity.Curriculum{
-		Code:              payload.Code,
+		Code:              normalizeCurriculumCode(payload.Code),
 		Name:              payload.Name,
 		Description:       payload.Description,
 		Link:              payload.Link,
@@ -270,7 +286,7 @@ func (cc *CurriculumController) UpdateCurriculum(c *gin.Context) {
 	}
 
 	// อัปเดตฟิลด์อื่นๆ
-	cur.Code = payload.Code
+	cur.Code = normalizeCurriculumCode(payload.Code)
 	cur.Name = payload.Name
 	cur.Description = payload.Description
 	cur.Link = payload.Link
@@ -304,6 +320,75 @@ func (cc *CurriculumController) DeleteCurriculum(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": true})
 }
 
+// UpdateRecommendedTemplates - PUT /admin/curricula/:id/recommended-templates
+// body: {"template_ids": [3, 1]} แทนที่รายการเดิมทั้งหมด (ลำดับตามที่ส่งมา)
+func (cc *CurriculumController) UpdateRecommendedTemplates(c *gin.Context) {
+	curriculumID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid curriculum id"})
+		return
+	}
+	var payload struct {
+		TemplateIDs []uint `json:"template_ids"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !curriculumExists(c, cc.db, curriculumID) {
+		return
+	}
+
+	err = cc.db.Transaction(func(tx *gorm.DB) error {
+		return services.ReplaceCurriculumRecommendedTemplates(tx, curriculumID, payload.TemplateIDs)
+	})
+	if err != nil {
+		handleDBError(c, err, "template not found")
+		return
+	}
+
+	var templates []entity.CurriculumRecommendedTemplate
+	cc.db.Preload("Templates").Where("curriculum_id = ?", curriculumID).Order("order_index ASC").Find(&templates)
+	c.JSON(http.StatusOK, gin.H{"data": templates})
+}
+
+// UpdateRequiredSections - PUT /admin/curricula/:id/required-sections
+// body: {"sections": [{"section_port_key": "projects", "title": "Project experience", "note": "..."}]}
+// แทนที่รายการเดิมทั้งหมด portfolio ที่ไม่มี section ตาม key จะได้คำเตือนตอนส่ง
+func (cc *CurriculumController) UpdateRequiredSections(c *gin.Context) {
+	curriculumID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid curriculum id"})
+		return
+	}
+	var payload struct {
+		Sections []entity.CurriculumRequiredSection `json:"sections"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !curriculumExists(c, cc.db, curriculumID) {
+		return
+	}
+
+	err = cc.db.Transaction(func(tx *gorm.DB) error {
+		return services.ReplaceCurriculumRequiredSections(tx, curriculumID, payload.Sections)
+	})
+	if errors.Is(err, services.ErrRequiredSectionInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sections []entity.CurriculumRequiredSection
+	cc.db.Where("curriculum_id = ?", curriculumID).Order("order_index ASC").Find(&sections)
+	c.JSON(http.StatusOK, gin.H{"data": sections})
+}
+
 // -------------------- SUMMARY (Admin Report) --------------------
 
 type ProgramStat struct {
@@ -398,40 +483,28 @@ type StatResult struct {
 }
 
 // GET /admin/curricula/stats
+// รองรับตัวกรอง ?academic_year=&round=&faculty_id=&program_id=&from=YYYY-MM-DD&to=YYYY-MM-DD
 func (cc *CurriculumController) GetSelectionStats(c *gin.Context) {
-	var facultyStats []StatResult
-	var programStats []StatResult
-
-	// 1. สถิติแยกตามสำนักวิชา (Faculty)
-	// นับจำนวน Selection Group ตามชื่อสำนักวิชา
-	if err := cc.db.Table("selections").
-		Joins("JOIN curriculums ON selections.curriculum_id = curriculums.id").
-		Joins("JOIN faculties ON curriculums.faculty_id = faculties.id").
-		Select("faculties.name as name, count(selections.id) as value").
-		Group("faculties.name").
-		Order("value desc"). // เรียงจากมากไปน้อย
-		Scan(&facultyStats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	filter, err := parseSelectionStatsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 2. สถิติแยกตามสาขาวิชา (Program) โดยมีชื่อสำนักวิชากำกับ (GroupName)
-	// เพื่อเอาไว้ Filter ตอน Drill-down
-	if err := cc.db.Table("selections").
-		Joins("JOIN curriculums ON selections.curriculum_id = curriculums.id").
-		Joins("JOIN programs ON curriculums.program_id = programs.id").
-		Joins("JOIN faculties ON curriculums.faculty_id = faculties.id").
-		Select("programs.name as name, count(selections.id) as value, faculties.name as group_name").
-		Group("programs.name, faculties.name").
-		Order("value desc").
-		Scan(&programStats).Error; err != nil {
+	stats, err := cc.buildSelectionStats(filter)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"faculty_stats": facultyStats,
-		"program_stats": programStats,
+		"faculty_stats":         stats.FacultyStats,
+		"program_stats":         stats.ProgramStats,
+		"daily_stats":           stats.DailyStats,
+		"school_type_stats":     stats.SchoolTypeStats,
+		"education_level_stats": stats.EducationLevelStats,
+		"conversion":            stats.Conversion,
+		"faculty_conversion":    stats.FacultyConversion,
 	})
 }
 
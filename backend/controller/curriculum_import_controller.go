@@ -0,0 +1,554 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// คอลัมน์ของไฟล์ import/export หลักสูตร (ใช้รูปแบบเดียวกันทั้งสองทาง)
+//
+//	required_documents : "ชื่อเอกสาร" หรือ "ชื่อเอกสาร|optional" คั่นด้วย ";"
+//	skills             : ชื่อทักษะ (TH หรือ EN) คั่นด้วย ";"
+//	course_groups      : "ชื่อกลุ่มวิชา|เปอร์เซ็นต์หน่วยกิต" คั่นด้วย ";"
+var curriculumSheetHeader = []string{
+	"code",
+	"name",
+	"description",
+	"link",
+	"gpax_min",
+	"portfolio_max_pages",
+	"quota",
+	"round_name",
+	"academic_year",
+	"application_period",
+	"faculty",
+	"program",
+	"required_documents",
+	"skills",
+	"course_groups",
+}
+
+var curriculumSheetRequiredColumns = []string{"code", "name", "faculty", "program"}
+
+const optionalDocumentMarker = "optional"
+
+// curriculumImportRow ข้อมูลหนึ่งแถวที่ผ่านการตรวจสอบแล้ว พร้อมบันทึก
+type curriculumImportRow struct {
+	Row          int
+	Curriculum   entity.Curriculum
+	Documents    []entity.CurriculumRequiredDocument
+	Skills       []entity.CurriculumSkill
+	CourseGroups []entity.CurriculumCourseGroup
+}
+
+// curriculumLookup โหลดข้อมูลอ้างอิงทั้งหมดครั้งเดียว เพื่อไม่ต้อง query ทุกแถว
+type curriculumLookup struct {
+	faculties     []entity.Faculty
+	programs      []entity.Program
+	documentTypes map[string]entity.DocumentType
+	skills        map[string]entity.Skill
+	courseGroups  map[string]entity.CourseGroup
+}
+
+func (cc *CurriculumController) loadCurriculumLookup() (*curriculumLookup, error) {
+	lookup := &curriculumLookup{
+		documentTypes: map[string]entity.DocumentType{},
+		skills:        map[string]entity.Skill{},
+		courseGroups:  map[string]entity.CourseGroup{},
+	}
+
+	if err := cc.db.Find(&lookup.faculties).Error; err != nil {
+		return nil, err
+	}
+	if err := cc.db.Find(&lookup.programs).Error; err != nil {
+		return nil, err
+	}
+
+	var docTypes []entity.DocumentType
+	if err := cc.db.Find(&docTypes).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range docTypes {
+		lookup.documentTypes[normalizeLookupKey(d.Name)] = d
+	}
+
+	var skills []entity.Skill
+	if err := cc.db.Find(&skills).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range skills {
+		if s.SkillNameTH != "" {
+			lookup.skills[normalizeLookupKey(s.SkillNameTH)] = s
+		}
+		if s.SkillNameEN != "" {
+			lookup.skills[normalizeLookupKey(s.SkillNameEN)] = s
+		}
+	}
+
+	var groups []entity.CourseGroup
+	if err := cc.db.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		lookup.courseGroups[normalizeLookupKey(g.Name)] = g
+		if g.NameEN != "" {
+			lookup.courseGroups[normalizeLookupKey(g.NameEN)] = g
+		}
+	}
+
+	return lookup, nil
+}
+
+// findFaculty ค้นหาสำนักวิชาจากชื่อ หรือรหัสย่อ (ShortName)
+func (l *curriculumLookup) findFaculty(value string) *entity.Faculty {
+	key := normalizeLookupKey(value)
+	for i := range l.faculties {
+		f := &l.faculties[i]
+		if normalizeLookupKey(f.Name) == key || normalizeLookupKey(f.ShortName) == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// findProgram ค้นหาสาขาวิชาจากชื่อ หรือรหัสย่อ ภายในสำนักวิชาที่ระบุ
+func (l *curriculumLookup) findProgram(value string, facultyID uint) *entity.Program {
+	key := normalizeLookupKey(value)
+	for i := range l.programs {
+		p := &l.programs[i]
+		if p.FacultyID != facultyID {
+			continue
+		}
+		if normalizeLookupKey(p.Name) == key || normalizeLookupKey(p.ShortName) == key {
+			return p
+		}
+	}
+	return nil
+}
+
+func normalizeLookupKey(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// POST /admin/curricula/import (multipart: file=.csv|.xlsx, dry_run=true เพื่อตรวจสอบอย่างเดียว)
+func (cc *CurriculumController) ImportCurricula(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	format, err := services.SpreadsheetFormatFromName(header.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := services.ReadSpreadsheet(file, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file has no data rows"})
+		return
+	}
+
+	index := services.HeaderIndex(rows[0])
+	for _, col := range curriculumSheetRequiredColumns {
+		if _, ok := index[col]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("missing required column: %s", col)})
+			return
+		}
+	}
+
+	lookup, err := cc.loadCurriculumLookup()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := getAuthUserID(c)
+
+	var (
+		parsed    []curriculumImportRow
+		rowErrors []services.SpreadsheetRowError
+		seenCodes = map[string]int{}
+	)
+
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		if services.IsEmptyRow(row) {
+			continue
+		}
+
+		item, errs := parseCurriculumRow(row, rowNum, index, lookup)
+		if code := item.Curriculum.Code; code != "" {
+			if first, dup := seenCodes[code]; dup {
+				errs = append(errs, services.SpreadsheetRowError{
+					Row:     rowNum,
+					Column:  "code",
+					Message: fmt.Sprintf("duplicate code %s (already used in row %d)", item.Curriculum.Code, first),
+				})
+			} else {
+				seenCodes[code] = rowNum
+			}
+		}
+
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		// ผู้ import เป็นเจ้าของเฉพาะหลักสูตรที่สร้างใหม่ (saveImportedCurriculum คงเจ้าของเดิมไว้)
+		item.Curriculum.UserID = userID
+		parsed = append(parsed, item)
+	}
+
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "validation failed, nothing was imported",
+			"errors": rowErrors,
+		})
+		return
+	}
+
+	// นับว่าแถวไหนเป็นการสร้างใหม่ / อัปเดต (ใช้ code เป็น key)
+	var created, updated int
+	for _, item := range parsed {
+		var count int64
+		cc.db.Model(&entity.Curriculum{}).Where("UPPER(code) = ?", item.Curriculum.Code).Count(&count)
+		if count > 0 {
+			updated++
+		} else {
+			created++
+		}
+	}
+
+	summary := gin.H{
+		"total":   len(parsed),
+		"created": created,
+		"updated": updated,
+	}
+
+	if strings.EqualFold(c.PostForm("dry_run"), "true") || strings.EqualFold(c.Query("dry_run"), "true") {
+		summary["dry_run"] = true
+		c.JSON(http.StatusOK, gin.H{"data": summary})
+		return
+	}
+
+	if err := cc.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range parsed {
+			if err := saveImportedCurriculum(tx, item); err != nil {
+				return fmt.Errorf("row %d: %v", item.Row, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// parseCurriculumRow แปลงหนึ่งแถวเป็น Curriculum + ความสัมพันธ์ และเก็บ error ทุกคอลัมน์ที่ผิด
+func parseCurriculumRow(row []string, rowNum int, index map[string]int, lookup *curriculumLookup) (curriculumImportRow, []services.SpreadsheetRowError) {
+	var errs []services.SpreadsheetRowError
+	addErr := func(column, message string) {
+		errs = append(errs, services.SpreadsheetRowError{Row: rowNum, Column: column, Message: message})
+	}
+
+	cell := func(column string) string {
+		return services.CellValue(row, index, column)
+	}
+
+	cur := entity.Curriculum{
+		Code:              normalizeCurriculumCode(cell("code")),
+		Name:              cell("name"),
+		Description:       cell("description"),
+		Link:              cell("link"),
+		RoundName:         cell("round_name"),
+		AcademicYear:      cell("academic_year"),
+		ApplicationPeriod: cell("application_period"),
+	}
+
+	if v := cell("gpax_min"); v != "" {
+		gpax, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			addErr("gpax_min", "gpax_min must be a number")
+		}
+		cur.GPAXMin = float32(gpax)
+	}
+	if v := cell("portfolio_max_pages"); v != "" {
+		pages, err := strconv.Atoi(v)
+		if err != nil {
+			addErr("portfolio_max_pages", "portfolio_max_pages must be an integer")
+		}
+		cur.PortfolioMaxPages = pages
+	}
+	if v := cell("quota"); v != "" {
+		quota, err := strconv.Atoi(v)
+		if err != nil {
+			addErr("quota", "quota must be an integer")
+		}
+		cur.Quota = quota
+	}
+
+	// สำนักวิชา / สาขาวิชา (ค้นจากชื่อหรือรหัสย่อ)
+	facultyValue := cell("faculty")
+	if facultyValue == "" {
+		addErr("faculty", "faculty is required")
+	} else if faculty := lookup.findFaculty(facultyValue); faculty == nil {
+		addErr("faculty", fmt.Sprintf("faculty %q not found", facultyValue))
+	} else {
+		cur.FacultyID = faculty.ID
+
+		programValue := cell("program")
+		if programValue == "" {
+			addErr("program", "program is required")
+		} else if program := lookup.findProgram(programValue, faculty.ID); program == nil {
+			addErr("program", fmt.Sprintf("program %q not found in faculty %s", programValue, faculty.Name))
+		} else {
+			cur.ProgramID = program.ID
+		}
+	}
+
+	cur.Status = getCalculatedStatus(cur.ApplicationPeriod)
+
+	// ใช้ valid tag ของ entity เหมือนกับตอนทดสอบ
+	if _, err := govalidator.ValidateStruct(cur); err != nil {
+		errList, ok := err.(govalidator.Errors)
+		if !ok {
+			errList = govalidator.Errors{err}
+		}
+		for _, e := range errList.Errors() {
+			column := ""
+			if fe, ok := e.(govalidator.Error); ok {
+				column = fe.Name
+				// FK ถูกแจ้งไปแล้วจากการ lookup ด้านบน
+				if column == "faculty_id" || column == "program_id" {
+					continue
+				}
+			}
+			addErr(column, e.Error())
+		}
+	}
+
+	item := curriculumImportRow{Row: rowNum, Curriculum: cur}
+
+	for _, entry := range services.SplitList(cell("required_documents")) {
+		name, flag, _ := strings.Cut(entry, "|")
+		doc, ok := lookup.documentTypes[normalizeLookupKey(name)]
+		if !ok {
+			addErr("required_documents", fmt.Sprintf("document type %q not found", strings.TrimSpace(name)))
+			continue
+		}
+		item.Documents = append(item.Documents, entity.CurriculumRequiredDocument{
+			DocumentTypeID: doc.ID,
+			IsOptional:     strings.EqualFold(strings.TrimSpace(flag), optionalDocumentMarker),
+		})
+	}
+
+	for _, name := range services.SplitList(cell("skills")) {
+		skill, ok := lookup.skills[normalizeLookupKey(name)]
+		if !ok {
+			addErr("skills", fmt.Sprintf("skill %q not found", name))
+			continue
+		}
+		item.Skills = append(item.Skills, entity.CurriculumSkill{
+			SkillID: skill.ID,
+			Name:    skill.SkillNameTH,
+		})
+	}
+
+	for _, entry := range services.SplitList(cell("course_groups")) {
+		name, percentStr, _ := strings.Cut(entry, "|")
+		group, ok := lookup.courseGroups[normalizeLookupKey(name)]
+		if !ok {
+			addErr("course_groups", fmt.Sprintf("course group %q not found", strings.TrimSpace(name)))
+			continue
+		}
+		percent := 0
+		if p := strings.TrimSpace(percentStr); p != "" {
+			v, err := strconv.Atoi(strings.TrimSuffix(p, "%"))
+			if err != nil || v < 0 || v > 100 {
+				addErr("course_groups", fmt.Sprintf("credit percentage of %q must be between 0 and 100", group.Name))
+				continue
+			}
+			percent = v
+		}
+		item.CourseGroups = append(item.CourseGroups, entity.CurriculumCourseGroup{
+			CourseGroupID:    group.ID,
+			CreditPercentage: percent,
+		})
+	}
+
+	return item, errs
+}
+
+// normalizeCurriculumCode ทำให้รหัสหลักสูตรเป็นรูปแบบเดียวกัน (ตัดช่องว่าง, ตัวพิมพ์ใหญ่)
+// ใช้ทั้งตอนตรวจ code ซ้ำในไฟล์และตอนค้นหาหลักสูตรเดิม เพื่อให้ abc1 กับ ABC1 เป็นหลักสูตรเดียวกัน
+func normalizeCurriculumCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// saveImportedCurriculum สร้างใหม่หรืออัปเดตตาม code แล้วแทนที่ความสัมพันธ์ทั้งหมด
+func saveImportedCurriculum(tx *gorm.DB, item curriculumImportRow) error {
+	cur := item.Curriculum
+
+	var existing entity.Curriculum
+	// หลักสูตรเดิมที่บันทึกก่อนมีการ normalize อาจเป็นตัวพิมพ์เล็ก จึงเทียบแบบไม่สนตัวพิมพ์
+	err := tx.Where("UPPER(code) = ?", cur.Code).Order("id").First(&existing).Error
+	switch {
+	case err == nil:
+		cur.ID = existing.ID
+		cur.CreatedAt = existing.CreatedAt
+		cur.StartDate = existing.StartDate
+		cur.EndDate = existing.EndDate
+		cur.AnnouncementDate = existing.AnnouncementDate
+		// การ import ไม่เปลี่ยนเจ้าของหลักสูตรเดิม
+		cur.UserID = existing.UserID
+		if err := tx.Save(&cur).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("curriculum_id = ?", cur.ID).Delete(&entity.CurriculumRequiredDocument{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("curriculum_id = ?", cur.ID).Delete(&entity.CurriculumSkill{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("curriculum_id = ?", cur.ID).Delete(&entity.CurriculumCourseGroup{}).Error; err != nil {
+			return err
+		}
+	case err == gorm.ErrRecordNotFound:
+		if err := tx.Create(&cur).Error; err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	for i := range item.Documents {
+		item.Documents[i].CurriculumID = cur.ID
+	}
+	for i := range item.Skills {
+		item.Skills[i].CurriculumID = cur.ID
+	}
+	for i := range item.CourseGroups {
+		item.CourseGroups[i].CurriculumID = cur.ID
+	}
+
+	if len(item.Documents) > 0 {
+		if err := tx.Create(&item.Documents).Error; err != nil {
+			return err
+		}
+	}
+	if len(item.Skills) > 0 {
+		if err := tx.Create(&item.Skills).Error; err != nil {
+			return err
+		}
+	}
+	if len(item.CourseGroups) > 0 {
+		if err := tx.Create(&item.CourseGroups).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /admin/curricula/export?format=csv|xlsx
+func (cc *CurriculumController) ExportCurricula(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", services.SpreadsheetFormatCSV))
+	if format != services.SpreadsheetFormatCSV && format != services.SpreadsheetFormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrUnsupportedSpreadsheet.Error()})
+		return
+	}
+
+	var curricula []entity.Curriculum
+	if err := cc.db.
+		Preload("Faculty").
+		Preload("Program").
+		Preload("RequiredDocuments.DocumentType").
+		Preload("Skills.Skill").
+		Preload("CourseGroups.CourseGroup").
+		Order("code asc").
+		Find(&curricula).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows := make([][]string, 0, len(curricula))
+	for _, cur := range curricula {
+		rows = append(rows, curriculumToRow(cur))
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="curricula.%s"`, format))
+	c.Header("Content-Type", services.SpreadsheetContentType(format))
+	c.Status(http.StatusOK)
+	if err := services.WriteSpreadsheet(c.Writer, format, "curricula", curriculumSheetHeader, rows); err != nil {
+		c.Error(err)
+	}
+}
+
+func curriculumToRow(cur entity.Curriculum) []string {
+	faculty, program := "", ""
+	if cur.Faculty != nil {
+		faculty = cur.Faculty.Name
+	}
+	if cur.Program != nil {
+		program = cur.Program.Name
+	}
+
+	var docs []string
+	for _, d := range cur.RequiredDocuments {
+		if d.DocumentType == nil {
+			continue
+		}
+		entry := d.DocumentType.Name
+		if d.IsOptional {
+			entry += "|" + optionalDocumentMarker
+		}
+		docs = append(docs, entry)
+	}
+
+	var skills []string
+	for _, s := range cur.Skills {
+		if s.Skill != nil {
+			skills = append(skills, s.Skill.SkillNameTH)
+		}
+	}
+
+	var groups []string
+	for _, g := range cur.CourseGroups {
+		if g.CourseGroup != nil {
+			groups = append(groups, fmt.Sprintf("%s|%d", g.CourseGroup.Name, g.CreditPercentage))
+		}
+	}
+
+	return []string{
+		cur.Code,
+		cur.Name,
+		cur.Description,
+		cur.Link,
+		strconv.FormatFloat(float64(cur.GPAXMin), 'f', 2, 32),
+		strconv.Itoa(cur.PortfolioMaxPages),
+		strconv.Itoa(cur.Quota),
+		cur.RoundName,
+		cur.AcademicYear,
+		cur.ApplicationPeriod,
+		faculty,
+		program,
+		strings.Join(docs, ";"),
+		strings.Join(skills, ";"),
+		strings.Join(groups, ";"),
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/middlewares"
+	"github.com/sut68/team14/backend/services"
 	"gorm.io/gorm"
 )
 
@@ -42,6 +44,9 @@ func (ec *EducationAdminController) RegisterRoutes(protected *gin.RouterGroup) {
 		admin.POST("/schools", ec.CreateSchool)
 		admin.PUT("/schools/:id", ec.UpdateSchool)
 		admin.DELETE("/schools/:id", ec.DeleteSchool)
+		admin.POST("/schools/import", middlewares.RequireAdmin(), ec.ImportSchools)
+
+		admin.GET("/import-jobs/:id", middlewares.RequireAdmin(), ec.GetImportJob)
 	}
 }
 
@@ -394,7 +399,7 @@ func (ec *EducationAdminController) CreateSchool(ctx *gin.Context) {
 	}
 
 	item := entity.School{
-		Code:           strings.TrimSpace(payload.Code),
+		Code:           services.NormalizeSchoolCode(payload.Code),
 		Name:           name,
 		SchoolTypeID:   payload.SchoolTypeID,
 		IsProjectBased: isProject,
@@ -439,7 +444,7 @@ func (ec *EducationAdminController) UpdateSchool(ctx *gin.Context) {
 		return
 	}
 
-	item.Code = strings.TrimSpace(payload.Code)
+	item.Code = services.NormalizeSchoolCode(payload.Code)
 	item.Name = name
 	item.SchoolTypeID = payload.SchoolTypeID
 	if payload.IsProjectBased != nil {
@@ -462,6 +467,67 @@ func (ec *EducationAdminController) DeleteSchool(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
+// ImportSchools รับไฟล์ CSV/XLSX (code, name, school_type, is_project_based) แล้ว upsert ตาม School.Code
+// งานจะรันเบื้องหลัง ให้ติดตามผลผ่าน GET /admin/education/import-jobs/:id
+func (ec *EducationAdminController) ImportSchools(ctx *gin.Context) {
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	format, err := services.SpreadsheetFormatFromName(header.Filename)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := services.ReadSpreadsheet(file, format)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, rowErrors, err := services.ParseSchoolRows(rows)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := getAuthUserID(ctx)
+	job, err := services.StartSchoolImport(ec.db, userID, header.Filename, items, rowErrors)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"data": job, "progress": job.Progress()})
+}
+
+func (ec *EducationAdminController) GetImportJob(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	var job entity.ImportJob
+	if err := ec.db.First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "import job not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// job ดูได้เฉพาะผู้สร้างหรือแอดมิน (account_type_id ถูกตั้งโดย RequireAccountTypes)
+	userID, _ := getAuthUserID(ctx)
+	if job.UserID != userID && ctx.GetUint("account_type_id") != entity.UserTypeAdmin {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "import job not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": job, "progress": job.Progress()})
+}
+
 // Helpers
 
 func normalizeOptionalID(id *uint) *uint {
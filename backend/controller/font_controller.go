@@ -1,10 +1,15 @@
 package controller
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"github.com/sut68/team14/backend/entity"
+	"os"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
 )
 
 func GetAllFonts(c *gin.Context) {
@@ -42,4 +47,142 @@ func GetFontByID(c *gin.Context) {
 		"status": "success",
 		"data":   font,
 	})
-}
\ No newline at end of file
+}
+// UploadFont admin อัปโหลดไฟล์ฟอนต์ (multipart: file, font_name, font_family, font_category, font_variant)
+// ไฟล์ถูกเก็บใน local storage และ FontURL ชี้ไปที่ไฟล์นั้นแทน URL ภายนอก
+func UploadFont(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > services.MaxFontFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Font file must not exceed 10MB"})
+		return
+	}
+
+	font := entity.Font{
+		FontName:     c.PostForm("font_name"),
+		FontFamily:   c.PostForm("font_family"),
+		FontCategory: c.PostForm("font_category"),
+		FontVariant:  c.PostForm("font_variant"),
+		IsActive:     c.DefaultPostForm("is_active", "true") != "false",
+	}
+	// ตรวจข้อมูลก่อนเขียนไฟล์ (FontURL ยังว่างอยู่)
+	if err := font.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	url, storedName, err := services.SaveFontFile(file, header.Filename)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFontFile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload font: " + err.Error()})
+		return
+	}
+	font.FontURL = url
+	font.FontFile = storedName
+
+	if err := font.Validate(); err != nil {
+		services.Storage.DeleteFile(storedName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := config.GetDB().Omit("Portfolio").Create(&font).Error; err != nil {
+		services.Storage.DeleteFile(storedName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "data": font})
+}
+
+// UpdateFont admin แก้ชื่อ/หมวดหรือเปิดปิดการใช้งานฟอนต์ (ไฟล์และ URL แก้ไม่ได้)
+func UpdateFont(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid font id"})
+		return
+	}
+	var input struct {
+		FontName     *string `json:"font_name"`
+		FontFamily   *string `json:"font_family"`
+		FontCategory *string `json:"font_category"`
+		FontVariant  *string `json:"font_variant"`
+		IsActive     *bool   `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := config.GetDB()
+	var font entity.Font
+	if err := db.First(&font, id).Error; err != nil {
+		handleDBError(c, err, "Font not found")
+		return
+	}
+	if input.FontName != nil {
+		font.FontName = *input.FontName
+	}
+	if input.FontFamily != nil {
+		font.FontFamily = *input.FontFamily
+	}
+	if input.FontCategory != nil {
+		font.FontCategory = *input.FontCategory
+	}
+	if input.FontVariant != nil {
+		font.FontVariant = *input.FontVariant
+	}
+	if input.IsActive != nil {
+		font.IsActive = *input.IsActive
+	}
+	if err := font.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Omit("Portfolio").Save(&font).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": font})
+}
+
+// DeleteFont admin ลบฟอนต์ที่ไม่มี portfolio ใช้ พร้อมไฟล์ที่อัปโหลดไว้
+// ฟอนต์ที่ถูกใช้อยู่ให้ปิดการใช้งานด้วย UpdateFont แทน
+func DeleteFont(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid font id"})
+		return
+	}
+	db := config.GetDB()
+	var font entity.Font
+	if err := db.First(&font, id).Error; err != nil {
+		handleDBError(c, err, "Font not found")
+		return
+	}
+	var used int64
+	if err := db.Model(&entity.Portfolio{}).Where("font_id = ?", font.ID).Count(&used).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if used > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Font is used by a portfolio, deactivate it instead", "portfolio_count": used})
+		return
+	}
+	if err := db.Unscoped().Delete(&font).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if font.FontFile != "" && services.Storage != nil {
+		if err := services.Storage.DeleteFile(font.FontFile); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️ font %d: failed to remove %s: %v", font.ID, font.FontFile, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Font deleted"})
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// สิทธิ์ที่ต้องการต่อ portfolio
+type portfolioAccess int
+
+const (
+	portfolioRead portfolioAccess = iota
+	portfolioWrite
+)
+
+var (
+	errPortfolioNotFound  = errors.New("Portfolio not found")
+	errPortfolioForbidden = errors.New("permission denied")
+)
+
+// portfolioIDOfSection หา portfolio ที่เป็นเจ้าของ section
+func portfolioIDOfSection(db *gorm.DB, sectionID uint) (uint, error) {
+	var section entity.PortfolioSection
+	if err := db.Select("id", "portfolio_id").First(&section, sectionID).Error; err != nil {
+		return 0, err
+	}
+	return section.PortfolioID, nil
+}
+
+// portfolioIDOfBlock หา portfolio ที่เป็นเจ้าของ block (block → section → portfolio)
+func portfolioIDOfBlock(db *gorm.DB, blockID uint) (uint, error) {
+	var block entity.PortfolioBlock
+	if err := db.Select("id", "portfolio_section_id").First(&block, blockID).Error; err != nil {
+		return 0, err
+	}
+	return portfolioIDOfSection(db, block.PortfolioSectionID)
+}
+
+// checkPortfolioAccess ตรวจสิทธิ์ของ userID ต่อ portfolio
+//   - เจ้าของ: อ่าน/แก้ไขได้
+//   - อาจารย์/แอดมิน: อ่านได้เฉพาะ portfolio ที่ถูกส่งให้ตรวจ (มี PortfolioSubmission) แก้ไขไม่ได้ (403)
+//   - ผู้ใช้อื่น: ถือว่าไม่พบ (404) เพื่อไม่เปิดเผยว่ามี portfolio นี้อยู่
+func checkPortfolioAccess(db *gorm.DB, userID, portfolioID uint, access portfolioAccess) (*entity.Portfolio, error) {
+	var portfolio entity.Portfolio
+	if err := db.Select("id", "user_id", "status").First(&portfolio, portfolioID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errPortfolioNotFound
+		}
+		return nil, err
+	}
+	if portfolio.UserID == userID {
+		return &portfolio, nil
+	}
+
+	var user entity.User
+	if err := db.Select("id", "account_type_id").First(&user, userID).Error; err != nil {
+		return nil, errPortfolioNotFound
+	}
+	if user.AccountTypeID != entity.UserTypeTeacher && user.AccountTypeID != entity.UserTypeAdmin {
+		return nil, errPortfolioNotFound
+	}
+
+	var submissions int64
+	if err := db.Model(&entity.PortfolioSubmission{}).Where("portfolio_id = ?", portfolioID).Count(&submissions).Error; err != nil {
+		return nil, err
+	}
+	if submissions == 0 {
+		return nil, errPortfolioNotFound
+	}
+	if access == portfolioWrite {
+		return nil, errPortfolioForbidden
+	}
+	return &portfolio, nil
+}
+
+// authorizePortfolio ตรวจสิทธิ์ผู้ใช้ที่ login ต่อ portfolio และตอบ error ให้เองถ้าไม่ผ่าน
+func authorizePortfolio(c *gin.Context, db *gorm.DB, portfolioID uint, access portfolioAccess) (*entity.Portfolio, bool) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	portfolio, err := checkPortfolioAccess(db, userID, portfolioID, access)
+	switch {
+	case errors.Is(err, errPortfolioNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	case errors.Is(err, errPortfolioForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return portfolio, true
+}
+
+// authorizePortfolioSection ตรวจสิทธิ์ผ่าน section (ไม่พบ section = 404) คืน portfolio ID ของ section
+func authorizePortfolioSection(c *gin.Context, db *gorm.DB, sectionID uint, access portfolioAccess) (uint, bool) {
+	portfolioID, err := portfolioIDOfSection(db, sectionID)
+	if err != nil {
+		handleDBError(c, err, "Section not found")
+		return 0, false
+	}
+	_, ok := authorizePortfolio(c, db, portfolioID, access)
+	return portfolioID, ok
+}
+
+// authorizePortfolioBlock ตรวจสิทธิ์ผ่าน block (ไม่พบ block = 404) คืน portfolio ID ของ block
+func authorizePortfolioBlock(c *gin.Context, db *gorm.DB, blockID uint, access portfolioAccess) (uint, bool) {
+	portfolioID, err := portfolioIDOfBlock(db, blockID)
+	if err != nil {
+		handleDBError(c, err, "Block not found")
+		return 0, false
+	}
+	_, ok := authorizePortfolio(c, db, portfolioID, access)
+	return portfolioID, ok
+}
@@ -0,0 +1,476 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ชนิดของ operation ที่ PATCH /portfolio/:id/batch รองรับ
+const (
+	batchCreateSection   = "create_section"
+	batchUpdateSection   = "update_section"
+	batchDeleteSection   = "delete_section"
+	batchReorderSections = "reorder_sections"
+	batchCreateBlock     = "create_block"
+	batchUpdateBlock     = "update_block"
+	batchDeleteBlock     = "delete_block"
+	batchReorderBlocks   = "reorder_blocks"
+)
+
+// portfolioBatchOp หนึ่ง operation ใน batch
+// section/block ที่สร้างใหม่ระบุ temp_id ได้ เพื่อให้ operation ถัดไปอ้างถึงผ่าน section_ref
+type portfolioBatchOp struct {
+	Op         string                 `json:"op"`
+	ID         uint                   `json:"id"`
+	TempID     string                 `json:"temp_id"`
+	SectionID  uint                   `json:"section_id"`
+	SectionRef string                 `json:"section_ref"`
+	Data       map[string]interface{} `json:"data"`
+	Order      []uint                 `json:"order"`
+}
+
+// batchOpError operation ที่ไม่ถูกต้อง (ตอบกลับเป็น 400 พร้อม index)
+type batchOpError struct {
+	Index int
+	Err   error
+}
+
+func (e *batchOpError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Err.Error())
+}
+
+func (e *batchOpError) Unwrap() error { return e.Err }
+
+var errRevisionConflict = errors.New("portfolio was modified by another session")
+
+// portfolioETag ETag ของ portfolio คือเลข revision
+func portfolioETag(revision uint) string {
+	return fmt.Sprintf(`"%d"`, revision)
+}
+
+// parseIfMatchRevision อ่าน revision จาก If-Match (รองรับ "3", W/"3" และ 3)
+func parseIfMatchRevision(header string) (uint, bool) {
+	value := strings.TrimSpace(header)
+	value = strings.TrimPrefix(value, "W/")
+	value = strings.Trim(value, `"`)
+	rev, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(rev), true
+}
+
+// bumpPortfolioRevision เพิ่ม revision หลังการแก้ไขแบบทีละรายการ (endpoint เดิม)
+func bumpPortfolioRevision(db *gorm.DB, portfolioID uint) {
+	db.Model(&entity.Portfolio{}).Where("id = ?", portfolioID).
+		UpdateColumn("revision", gorm.Expr("revision + 1"))
+}
+
+// toJSONColumn แปลงค่าจาก JSON body (map/slice/string) ให้บันทึกลงคอลัมน์ jsonb ได้
+func toJSONColumn(value interface{}) datatypes.JSON {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if json.Valid([]byte(v)) {
+			return datatypes.JSON(v)
+		}
+	}
+	raw, _ := json.Marshal(value)
+	return datatypes.JSON(raw)
+}
+
+// pickUpdates เลือกเฉพาะฟิลด์ที่อนุญาต ฟิลด์ JSON จะถูกแปลงเป็น datatypes.JSON
+func pickUpdates(data map[string]interface{}, allowed []string, jsonFields ...string) map[string]interface{} {
+	updates := map[string]interface{}{}
+	for _, field := range allowed {
+		if val, ok := data[field]; ok {
+			updates[field] = val
+		}
+	}
+	for _, field := range jsonFields {
+		if val, ok := updates[field]; ok {
+			updates[field] = toJSONColumn(val)
+		}
+	}
+	return updates
+}
+
+// validateBlockUpdates ตรวจ content/style ใหม่ของ block (รวมกับค่าเดิมที่ไม่ได้แก้) กับ schema
+// content ของ block แบบอ้างอิงจะเหลือเฉพาะ data_id
+func validateBlockUpdates(tx *gorm.DB, blockID uint, updates map[string]interface{}) error {
+	content, hasContent := updates["content"].(datatypes.JSON)
+	style, hasStyle := updates["block_style"].(datatypes.JSON)
+	if !hasContent && !hasStyle {
+		return nil
+	}
+	var block entity.PortfolioBlock
+	if err := tx.Select("id", "block_port_type", "content", "block_style").First(&block, blockID).Error; err != nil {
+		return err
+	}
+	if hasContent {
+		content = services.NormalizeReferenceContent(block.BlockPortType, content)
+		updates["content"] = content
+	} else {
+		content = block.Content
+	}
+	if !hasStyle {
+		style = block.BlockStyle
+	}
+	return services.BlockSchemas.ValidateBlock(block.BlockPortType, content, style)
+}
+
+func loadPortfolioWithBlocks(db *gorm.DB, portfolioID uint) (entity.Portfolio, error) {
+	var portfolio entity.Portfolio
+	err := db.
+		Preload("PortfolioSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("section_order ASC")
+		}).
+		Preload("PortfolioSections.PortfolioBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("block_order ASC")
+		}).
+		First(&portfolio, portfolioID).Error
+	return portfolio, err
+}
+
+// PatchPortfolioBatch - PATCH /portfolio/:id/batch
+// ใช้ operation หลายรายการกับ section/block ใน transaction เดียว
+// ต้องส่ง revision ปัจจุบันมาใน If-Match (หรือ base_revision) ถ้าไม่ตรงจะตอบ 409 พร้อมข้อมูลล่าสุด
+func PatchPortfolioBatch(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	var payload struct {
+		BaseRevision *uint              `json:"base_revision"`
+		Operations   []portfolioBatchOp `json:"operations"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(payload.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operations is required"})
+		return
+	}
+
+	var baseRevision uint
+	if rev, ok := parseIfMatchRevision(c.GetHeader("If-Match")); ok {
+		baseRevision = rev
+	} else if payload.BaseRevision != nil {
+		baseRevision = *payload.BaseRevision
+	} else {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the portfolio revision is required"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	userID, _ := getAuthUserID(c)
+	roomSession := c.GetHeader(roomSessionHeader)
+	created := map[string]uint{}
+	var changes *services.PortfolioChangeSet
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// จอง revision ก่อน ถ้ามีคนแก้ไปก่อนแล้วจะไม่มีแถวถูกอัปเดต
+		result := tx.Model(&entity.Portfolio{}).
+			Where("id = ? AND revision = ?", portfolioID, baseRevision).
+			UpdateColumn("revision", gorm.Expr("revision + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRevisionConflict
+		}
+
+		// ทั้ง batch เป็นการแก้ไขชุดเดียวในประวัติ (undo ครั้งเดียวย้อนทั้ง batch)
+		var err error
+		changes, err = services.NewPortfolioChangeSet(tx, portfolioID, userID, baseRevision+1)
+		if err != nil {
+			return err
+		}
+		for i, op := range payload.Operations {
+			if err := applyPortfolioBatchOp(tx, changes, roomSession, op, created); err != nil {
+				return &batchOpError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+
+	var opErr *batchOpError
+	var lockErr *blockLockedError
+	var validationErr *services.BlockValidationError
+	switch {
+	case errors.Is(err, errRevisionConflict):
+		current, loadErr := loadPortfolioWithBlocks(db, portfolioID)
+		if loadErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": loadErr.Error()})
+			return
+		}
+		c.Header("ETag", portfolioETag(current.Revision))
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    err.Error(),
+			"revision": current.Revision,
+			"data":     current,
+		})
+		return
+	case errors.As(err, &lockErr) && errors.As(err, &opErr):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error(), "operation_index": opErr.Index, "lock": lockErr.Lock})
+		return
+	case errors.As(err, &validationErr) && errors.As(err, &opErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": opErr.Error(), "operation_index": opErr.Index, "validation": validationErr})
+		return
+	case errors.As(err, &opErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": opErr.Error(), "operation_index": opErr.Index})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	broadcastPortfolioChanges(changes)
+
+	portfolio, err := loadPortfolioWithBlocks(db, portfolioID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", portfolioETag(portfolio.Revision))
+	c.JSON(http.StatusOK, gin.H{
+		"data":     portfolio,
+		"revision": portfolio.Revision,
+		"created":  created,
+	})
+}
+
+// applyPortfolioBatchOp ทำ operation หนึ่งรายการ section/block ต้องอยู่ใน portfolio นี้เท่านั้น
+// ทุกการเปลี่ยนแปลงถูกบันทึกลง changes เพื่อใช้ undo / restore, block ที่ session อื่น lock อยู่แก้/ลบไม่ได้
+func applyPortfolioBatchOp(tx *gorm.DB, changes *services.PortfolioChangeSet, roomSession string, op portfolioBatchOp, created map[string]uint) error {
+	portfolioID := changes.PortfolioID
+	sectionID := op.SectionID
+	if op.SectionRef != "" {
+		id, ok := created[op.SectionRef]
+		if !ok {
+			return fmt.Errorf("unknown section_ref %q", op.SectionRef)
+		}
+		sectionID = id
+	}
+
+	switch op.Op {
+	case batchCreateSection:
+		section := entity.PortfolioSection{PortfolioID: portfolioID, IsEnabled: true}
+		if v, ok := op.Data["section_title"].(string); ok {
+			section.SectionTitle = v
+		}
+		if v, ok := op.Data["section_port_key"].(string); ok {
+			section.SectionPortKey = v
+		}
+		if section.SectionPortKey == "" {
+			section.SectionPortKey = section.SectionTitle
+		}
+		if v, ok := op.Data["is_enabled"].(bool); ok {
+			section.IsEnabled = v
+		}
+		if v, ok := op.Data["section_order"].(float64); ok {
+			section.SectionOrder = int(v)
+		}
+		if v, ok := op.Data["section_style"]; ok {
+			section.SectionStyle = toJSONColumn(v)
+		}
+		if strings.TrimSpace(section.SectionTitle) == "" {
+			return errors.New("section_title is required")
+		}
+		err := changes.Track(op.Op, entity.PortfolioChangeSection, 0, func() (uint, error) {
+			err := tx.Omit("Portfolio").Create(&section).Error
+			return section.ID, err
+		})
+		if err != nil {
+			return err
+		}
+		if op.TempID != "" {
+			created[op.TempID] = section.ID
+		}
+		return nil
+
+	case batchUpdateSection:
+		if err := ensureSectionInPortfolio(tx, portfolioID, op.ID); err != nil {
+			return err
+		}
+		updates := pickUpdates(op.Data, []string{"section_title", "is_enabled", "section_order", "section_style"}, "section_style")
+		if len(updates) == 0 {
+			return nil
+		}
+		return changes.Track(op.Op, entity.PortfolioChangeSection, op.ID, func() (uint, error) {
+			return op.ID, tx.Model(&entity.PortfolioSection{}).Where("id = ?", op.ID).Updates(updates).Error
+		})
+
+	case batchDeleteSection:
+		if err := ensureSectionInPortfolio(tx, portfolioID, op.ID); err != nil {
+			return err
+		}
+		var blockIDs []uint
+		if err := tx.Model(&entity.PortfolioBlock{}).Where("portfolio_section_id = ?", op.ID).Pluck("id", &blockIDs).Error; err != nil {
+			return err
+		}
+		for _, blockID := range blockIDs {
+			blockID := blockID
+			if err := checkBlockLock(portfolioID, blockID, roomSession); err != nil {
+				return err
+			}
+			err := changes.Track(batchDeleteBlock, entity.PortfolioChangeBlock, blockID, func() (uint, error) {
+				return blockID, tx.Delete(&entity.PortfolioBlock{}, blockID).Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return changes.Track(op.Op, entity.PortfolioChangeSection, op.ID, func() (uint, error) {
+			return op.ID, tx.Delete(&entity.PortfolioSection{}, op.ID).Error
+		})
+
+	case batchReorderSections:
+		for i, id := range op.Order {
+			if err := ensureSectionInPortfolio(tx, portfolioID, id); err != nil {
+				return err
+			}
+			id, order := id, i+1
+			err := changes.Track(op.Op, entity.PortfolioChangeSection, id, func() (uint, error) {
+				return id, tx.Model(&entity.PortfolioSection{}).Where("id = ?", id).Update("section_order", order).Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case batchCreateBlock:
+		if err := ensureSectionInPortfolio(tx, portfolioID, sectionID); err != nil {
+			return err
+		}
+		block := entity.PortfolioBlock{PortfolioSectionID: sectionID, BlockPortType: "text"}
+		if v, ok := op.Data["block_port_type"].(string); ok && v != "" {
+			block.BlockPortType = v
+		}
+		if v, ok := op.Data["block_order"].(float64); ok {
+			block.BlockOrder = int(v)
+		}
+		if v, ok := op.Data["content"]; ok {
+			block.Content = services.NormalizeReferenceContent(block.BlockPortType, toJSONColumn(v))
+		}
+		if v, ok := op.Data["block_style"]; ok {
+			block.BlockStyle = toJSONColumn(v)
+		}
+		if err := services.BlockSchemas.ValidateBlock(block.BlockPortType, block.Content, block.BlockStyle); err != nil {
+			return err
+		}
+		err := changes.Track(op.Op, entity.PortfolioChangeBlock, 0, func() (uint, error) {
+			err := tx.Omit("PortfolioSection").Create(&block).Error
+			return block.ID, err
+		})
+		if err != nil {
+			return err
+		}
+		if op.TempID != "" {
+			created[op.TempID] = block.ID
+		}
+		return nil
+
+	case batchUpdateBlock:
+		if err := ensureBlockInPortfolio(tx, portfolioID, op.ID); err != nil {
+			return err
+		}
+		if err := checkBlockLock(portfolioID, op.ID, roomSession); err != nil {
+			return err
+		}
+		updates := pickUpdates(op.Data, []string{"content", "block_order", "block_style"}, "content", "block_style")
+		if err := validateBlockUpdates(tx, op.ID, updates); err != nil {
+			return err
+		}
+		// ย้าย block ไป section อื่น (ต้องอยู่ใน portfolio เดียวกัน)
+		if sectionID != 0 {
+			if err := ensureSectionInPortfolio(tx, portfolioID, sectionID); err != nil {
+				return err
+			}
+			updates["portfolio_section_id"] = sectionID
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return changes.Track(op.Op, entity.PortfolioChangeBlock, op.ID, func() (uint, error) {
+			return op.ID, tx.Model(&entity.PortfolioBlock{}).Where("id = ?", op.ID).Updates(updates).Error
+		})
+
+	case batchDeleteBlock:
+		if err := ensureBlockInPortfolio(tx, portfolioID, op.ID); err != nil {
+			return err
+		}
+		if err := checkBlockLock(portfolioID, op.ID, roomSession); err != nil {
+			return err
+		}
+		return changes.Track(op.Op, entity.PortfolioChangeBlock, op.ID, func() (uint, error) {
+			return op.ID, tx.Delete(&entity.PortfolioBlock{}, op.ID).Error
+		})
+
+	case batchReorderBlocks:
+		if err := ensureSectionInPortfolio(tx, portfolioID, sectionID); err != nil {
+			return err
+		}
+		for i, id := range op.Order {
+			id, order := id, i+1
+			err := changes.Track(op.Op, entity.PortfolioChangeBlock, id, func() (uint, error) {
+				result := tx.Model(&entity.PortfolioBlock{}).
+					Where("id = ? AND portfolio_section_id = ?", id, sectionID).
+					Update("block_order", order)
+				if result.Error != nil {
+					return id, result.Error
+				}
+				if result.RowsAffected == 0 {
+					return id, fmt.Errorf("block %d is not in section %d", id, sectionID)
+				}
+				return id, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported op %q", op.Op)
+}
+
+func ensureSectionInPortfolio(tx *gorm.DB, portfolioID, sectionID uint) error {
+	if sectionID == 0 {
+		return errors.New("section id is required")
+	}
+	owner, err := portfolioIDOfSection(tx, sectionID)
+	if err != nil || owner != portfolioID {
+		return fmt.Errorf("section %d not found in this portfolio", sectionID)
+	}
+	return nil
+}
+
+func ensureBlockInPortfolio(tx *gorm.DB, portfolioID, blockID uint) error {
+	if blockID == 0 {
+		return errors.New("block id is required")
+	}
+	owner, err := portfolioIDOfBlock(tx, blockID)
+	if err != nil || owner != portfolioID {
+		return fmt.Errorf("block %d not found in this portfolio", blockID)
+	}
+	return nil
+}
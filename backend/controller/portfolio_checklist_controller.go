@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/services"
+)
+
+// GetPortfolioChecklist - GET /portfolio/:id/checklist?curriculum_id=3
+// ตรวจความครบถ้วนก่อนส่ง: section/block ว่าง, รูปไม่มี alt text, ข้อมูลส่วนตัว/การศึกษา,
+// เอกสารที่หลักสูตรบังคับ และจำนวนหน้าเทียบกับ PortfolioMaxPages พร้อมคะแนน 0-100
+func GetPortfolioChecklist(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+	var curriculumID uint
+	if raw := c.Query("curriculum_id"); raw != "" {
+		if curriculumID, err = parseUintParam(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid curriculum id"})
+			return
+		}
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioRead); !ok {
+		return
+	}
+	if curriculumID != 0 && !curriculumExists(c, db, curriculumID) {
+		return
+	}
+
+	checklist, err := services.BuildPortfolioChecklist(db, portfolioID, curriculumID)
+	if err != nil {
+		handleDBError(c, err, "Portfolio not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": checklist})
+}
+
+// GetPortfolioAccessibility - GET /portfolio/:id/accessibility
+// ตรวจ contrast ของสีตาม WCAG AA, ขนาดตัวอักษรที่เล็กเกินไป, รูปที่ไม่มี alt text และลำดับหัวข้อ
+func GetPortfolioAccessibility(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioRead); !ok {
+		return
+	}
+
+	audit, err := services.AuditPortfolioAccessibility(db, portfolioID)
+	if err != nil {
+		handleDBError(c, err, "Portfolio not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": audit})
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -241,6 +243,13 @@ func UseTemplate(c *gin.Context) {
 		return
 	}
 
+	// version ของ template ที่ใช้สร้าง (ใช้เทียบตอน rebase ภายหลัง)
+	version, err := services.SaveTemplateVersion(config.GetDB(), template.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Find valid user
 	// var user entity.User
 	// if err := config.GetDB().First(&user).Error; err != nil {
@@ -287,10 +296,11 @@ func UseTemplate(c *gin.Context) {
 	}
 
 	portfolio := entity.Portfolio{
-		PortfolioName: portfolioName,
-		Status:        status,
-		TemplateID:    &template.ID,
-		UserID:        userID,
+		PortfolioName:   portfolioName,
+		Status:          status,
+		TemplateID:      &template.ID,
+		TemplateVersion: version.Version,
+		UserID:          userID,
 	}
 
 	// Ensure Color
@@ -331,11 +341,12 @@ func UseTemplate(c *gin.Context) {
 		ts := link.TemplatesSection
 
 		ps := entity.PortfolioSection{
-			SectionTitle:   ts.SectionName,
-			SectionPortKey: ts.SectionName,
-			IsEnabled:      true,
-			SectionOrder:   int(link.OrderIndex),
-			PortfolioID:    portfolio.ID,
+			SectionTitle:      ts.SectionName,
+			SectionPortKey:    ts.SectionName,
+			IsEnabled:         true,
+			SectionOrder:      int(link.OrderIndex),
+			TemplateSectionID: &ts.ID,
+			PortfolioID:       portfolio.ID,
 		}
 
 		if err := config.GetDB().Create(&ps).Error; err != nil {
@@ -349,6 +360,8 @@ func UseTemplate(c *gin.Context) {
 				BlockOrder:         sb.OrderIndex,
 				PortfolioSectionID: ps.ID,
 				Content:            sb.TemplatesBlock.DefaultContent,
+				BlockStyle:         sb.TemplatesBlock.DefaultStyle,
+				TemplateBlockID:    &sb.TemplatesBlock.ID,
 			}
 			config.GetDB().Create(&pb)
 		}
@@ -395,7 +408,7 @@ func GetMyPortfolio(c *gin.Context) {
 
 	config.GetDB().Model(&entity.Portfolio{}).Where("user_id = ?", userID).Count(&total)
 
-	query := config.GetDB().Where("user_id = ?", userID).Preload("Colors").Preload("Font")
+	query := config.GetDB().Where("user_id = ?", userID).Preload("Colors").Preload("Font").Preload("Curricula")
 
 	// Only preload sections and blocks if needed (for list view, often not needed)
 	if includeBlocks {
@@ -416,6 +429,14 @@ func GetMyPortfolio(c *gin.Context) {
 		return
 	}
 
+	// block ที่อ้างถึงกิจกรรม/ผลงาน/โปรไฟล์ แสดงข้อมูลล่าสุดจากต้นทาง
+	if includeBlocks {
+		if err := services.HydratePortfolioBlocks(config.GetDB(), portfolios); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":       portfolios,
 		"page":       page,
@@ -433,7 +454,16 @@ func CreatePortfolioSection(c *gin.Context) {
 		return
 	}
 
-	if err := config.GetDB().Create(&section).Error; err != nil {
+	// 🔐 เพิ่ม section ได้เฉพาะใน portfolio ของตัวเอง
+	if _, ok := authorizePortfolio(c, config.GetDB(), section.PortfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	err := trackPortfolioChange(c, section.PortfolioID, batchCreateSection, entity.PortfolioChangeSection, 0, func(tx *gorm.DB) (uint, error) {
+		err := tx.Create(&section).Error
+		return section.ID, err
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -443,7 +473,11 @@ func CreatePortfolioSection(c *gin.Context) {
 
 // UpdatePortfolioSection updates a portfolio section
 func UpdatePortfolioSection(c *gin.Context) {
-	id := c.Param("id")
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
 	var input map[string]interface{}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -451,6 +485,11 @@ func UpdatePortfolioSection(c *gin.Context) {
 		return
 	}
 
+	portfolioID, ok := authorizePortfolioSection(c, config.GetDB(), id, portfolioWrite)
+	if !ok {
+		return
+	}
+
 	var section entity.PortfolioSection
 	if err := config.GetDB().First(&section, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
@@ -466,10 +505,16 @@ func UpdatePortfolioSection(c *gin.Context) {
 			updates[field] = val
 		}
 	}
+	if style, ok := updates["section_style"]; ok {
+		updates["section_style"] = toJSONColumn(style)
+	}
 
 	// ✅ Update only fields present in the request
 	if len(updates) > 0 {
-		if err := config.GetDB().Model(&section).Updates(updates).Error; err != nil {
+		err := trackPortfolioChange(c, portfolioID, batchUpdateSection, entity.PortfolioChangeSection, section.ID, func(tx *gorm.DB) (uint, error) {
+			return section.ID, tx.Model(&section).Updates(updates).Error
+		})
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -484,7 +529,16 @@ func UpdatePortfolioSection(c *gin.Context) {
 
 // ✅ DeletePortfolioSection - ลบ Section
 func DeletePortfolioSection(c *gin.Context) {
-	id := c.Param("id")
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+
+	portfolioID, ok := authorizePortfolioSection(c, config.GetDB(), id, portfolioWrite)
+	if !ok {
+		return
+	}
 
 	// Check if exists
 	var section entity.PortfolioSection
@@ -494,7 +548,11 @@ func DeletePortfolioSection(c *gin.Context) {
 	}
 
 	// Delete (Unscoped to remove permanently if needed, or Soft Delete if using gorm.Model)
-	if err := config.GetDB().Delete(&section).Error; err != nil {
+	// บันทึกลงประวัติเพื่อให้ undo / restore กู้ section คืนได้
+	err = trackPortfolioChange(c, portfolioID, batchDeleteSection, entity.PortfolioChangeSection, section.ID, func(tx *gorm.DB) (uint, error) {
+		return section.ID, tx.Delete(&section).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -506,23 +564,42 @@ func DeletePortfolioSection(c *gin.Context) {
 func CreatePortfolioBlock(c *gin.Context) {
 	var payload struct {
 		PortfolioSectionID uint           `json:"portfolio_section_id"`
+		BlockPortType      string         `json:"block_port_type"`
 		BlockOrder         int            `json:"block_order"`
 		Content            datatypes.JSON `json:"content"`
+		BlockStyle         datatypes.JSON `json:"block_style"`
 	}
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if payload.BlockPortType == "" {
+		payload.BlockPortType = "text" // ชนิดจริงอยู่ใน content.type (ถ้ามี)
+	}
+	if err := services.BlockSchemas.ValidateBlock(payload.BlockPortType, payload.Content, payload.BlockStyle); err != nil {
+		respondBlockValidation(c, err)
+		return
+	}
+
+	portfolioID, ok := authorizePortfolioSection(c, config.GetDB(), payload.PortfolioSectionID, portfolioWrite)
+	if !ok {
+		return
+	}
 
 	block := entity.PortfolioBlock{
 		PortfolioSectionID: payload.PortfolioSectionID,
 		BlockOrder:         payload.BlockOrder,
-		Content:            payload.Content,
-		BlockPortType:      "text", // Default to text for now
+		Content:            services.NormalizeReferenceContent(payload.BlockPortType, payload.Content),
+		BlockStyle:         payload.BlockStyle,
+		BlockPortType:      payload.BlockPortType,
 	}
 
-	if err := config.GetDB().Create(&block).Error; err != nil {
+	err := trackPortfolioChange(c, portfolioID, batchCreateBlock, entity.PortfolioChangeBlock, 0, func(tx *gorm.DB) (uint, error) {
+		err := tx.Create(&block).Error
+		return block.ID, err
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -532,10 +609,15 @@ func CreatePortfolioBlock(c *gin.Context) {
 
 // ✅ UpdatePortfolioBlock - แก้ไข Block
 func UpdatePortfolioBlock(c *gin.Context) {
-	id := c.Param("id")
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block id"})
+		return
+	}
 
 	var payload struct {
 		Content    datatypes.JSON `json:"content"`
+		BlockStyle datatypes.JSON `json:"block_style"`
 		BlockOrder int            `json:"block_order"`
 	}
 
@@ -544,6 +626,11 @@ func UpdatePortfolioBlock(c *gin.Context) {
 		return
 	}
 
+	portfolioID, ok := authorizePortfolioBlock(c, config.GetDB(), id, portfolioWrite)
+	if !ok || rejectLockedBlock(c, portfolioID, id) {
+		return
+	}
+
 	var block entity.PortfolioBlock
 	if err := config.GetDB().First(&block, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Block not found"})
@@ -552,13 +639,26 @@ func UpdatePortfolioBlock(c *gin.Context) {
 
 	// Update fields
 	if payload.Content != nil {
-		block.Content = payload.Content
+		block.Content = services.NormalizeReferenceContent(block.BlockPortType, payload.Content)
+	}
+	if payload.BlockStyle != nil {
+		block.BlockStyle = payload.BlockStyle
 	}
 	if payload.BlockOrder > 0 {
 		block.BlockOrder = payload.BlockOrder
 	}
+	// ตรวจ schema เฉพาะเมื่อแก้ content/style เพื่อให้ย้ายลำดับ block เก่าที่ข้อมูลไม่ผ่านได้
+	if payload.Content != nil || payload.BlockStyle != nil {
+		if err := services.BlockSchemas.ValidateBlock(block.BlockPortType, block.Content, block.BlockStyle); err != nil {
+			respondBlockValidation(c, err)
+			return
+		}
+	}
 
-	if err := config.GetDB().Save(&block).Error; err != nil {
+	err = trackPortfolioChange(c, portfolioID, batchUpdateBlock, entity.PortfolioChangeBlock, block.ID, func(tx *gorm.DB) (uint, error) {
+		return block.ID, tx.Save(&block).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -568,9 +668,21 @@ func UpdatePortfolioBlock(c *gin.Context) {
 
 // ✅ DeletePortfolioBlock - ลบ Block
 func DeletePortfolioBlock(c *gin.Context) {
-	id := c.Param("id")
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block id"})
+		return
+	}
 
-	if err := config.GetDB().Delete(&entity.PortfolioBlock{}, id).Error; err != nil {
+	portfolioID, ok := authorizePortfolioBlock(c, config.GetDB(), id, portfolioWrite)
+	if !ok || rejectLockedBlock(c, portfolioID, id) {
+		return
+	}
+
+	err = trackPortfolioChange(c, portfolioID, batchDeleteBlock, entity.PortfolioChangeBlock, id, func(tx *gorm.DB) (uint, error) {
+		return id, tx.Delete(&entity.PortfolioBlock{}, id).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -578,9 +690,19 @@ func DeletePortfolioBlock(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Block deleted successfully"})
 }
 
+// portfolioEditableFields คอลัมน์ที่เจ้าของแก้ได้ผ่าน PATCH /portfolio/:id
+var portfolioEditableFields = []string{
+	"portfolio_name", "decription", "status", "portfolio_style",
+	"cover_image", "content_description", "colors_id", "font_id",
+}
+
 // ✅ UpdatePortfolio - อัปเดตข้อมูล Portfolio (เช่น CoverImage, Name, Description)
 func UpdatePortfolio(c *gin.Context) {
-	id := c.Param("id")
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
 	var payload map[string]interface{}
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
@@ -588,12 +710,43 @@ func UpdatePortfolio(c *gin.Context) {
 		return
 	}
 
+	if _, ok := authorizePortfolio(c, config.GetDB(), id, portfolioWrite); !ok {
+		return
+	}
+
+	// แก้ได้เฉพาะคอลัมน์ที่กำหนด (เจ้าของ, template, revision ฯลฯ เปลี่ยนผ่าน endpoint นี้ไม่ได้)
+	payload = pickUpdates(payload, portfolioEditableFields, "portfolio_style")
+	if len(payload) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no editable fields in payload"})
+		return
+	}
+
 	var portfolio entity.Portfolio
 	if err := config.GetDB().First(&portfolio, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
 		return
 	}
 
+	// ธีมสีต้องเป็นของระบบหรือของเจ้าของ portfolio และฟอนต์ต้องเปิดใช้งานอยู่
+	if id, ok, err := payloadUint(payload, "colors_id"); err != nil || ok {
+		if err == nil {
+			err = checkPortfolioColors(config.GetDB(), id, portfolio.UserID)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if id, ok, err := payloadUint(payload, "font_id"); err != nil || ok {
+		if err == nil {
+			_, err = entity.ValidateFontSelection(id, config.GetDB())
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// ✅ Logic: จัดการสถานะ Active ให้มีเพียงอันเดียวเสมอ
 	if val, ok := payload["status"]; ok {
 		if val == "active" {
@@ -623,16 +776,41 @@ func UpdatePortfolio(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	bumpPortfolioRevision(config.GetDB(), portfolio.ID)
 
 	c.JSON(http.StatusOK, gin.H{"data": portfolio})
 }
 
+// payloadUint อ่าน ID จาก payload แบบ map (ตัวเลขจาก JSON เป็น float64)
+func payloadUint(payload map[string]interface{}, keys ...string) (uint, bool, error) {
+	for _, key := range keys {
+		val, exists := payload[key]
+		if !exists {
+			continue
+		}
+		n, isNumber := val.(float64)
+		if !isNumber || n < 1 || n != float64(uint(n)) {
+			return 0, true, fmt.Errorf("%s must be a positive integer", key)
+		}
+		return uint(n), true, nil
+	}
+	return 0, false, nil
+}
+
 // DeletePortfolio - ลบ Portfolio พร้อม Sections และ Blocks ของมัน
 func DeletePortfolio(c *gin.Context) {
-	id := c.Param("id")
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
 
 	db := config.GetDB()
 
+	if _, ok := authorizePortfolio(c, db, id, portfolioWrite); !ok {
+		return
+	}
+
 	// Check if portfolio exists
 	var portfolio entity.Portfolio
 	if err := db.First(&portfolio, id).Error; err != nil {
@@ -656,6 +834,8 @@ func DeletePortfolio(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	services.DeletePortfolioHistory(db, portfolio.ID)
+	db.Unscoped().Where("portfolio_id = ?", portfolio.ID).Delete(&entity.PortfolioCurriculum{})
 	fmt.Println("✅ Portfolio deleted:", portfolio.ID)
 
 	// ✅ Logic: หลังจากลบแล้ว ตรวจสอบว่ามีอันที่ Active อยู่ไหม
@@ -678,6 +858,8 @@ func DeletePortfolio(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Portfolio deleted successfully"})
 }
 
+// GetPortfolioByStatusActive - GET /portfolio?curriculum_id=3
+// portfolio ที่ใช้ยื่นหลักสูตรที่ระบุ ถ้ายังไม่ได้กำหนดไว้ (หรือไม่ระบุหลักสูตร) ใช้ portfolio หลักที่ status = active
 func GetPortfolioByStatusActive(c *gin.Context) {
 	uid, exists := c.Get("user_id")
 	if !exists {
@@ -685,22 +867,39 @@ func GetPortfolioByStatusActive(c *gin.Context) {
 		return
 	}
 	userID := uid.(uint)
+
+	var curriculumID uint
+	if raw := c.Query("curriculum_id"); raw != "" {
+		id, err := parseUintParam(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid curriculum id"})
+			return
+		}
+		curriculumID = id
+	}
+
+	db := config.GetDB()
+	portfolioID, designated, err := services.ActivePortfolioIDForCurriculum(db, userID, curriculumID)
+	if errors.Is(err, services.ErrNoPortfolioForCurriculum) {
+		c.JSON(http.StatusOK, gin.H{"data": nil, "message": "No active portfolio found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var portfolio entity.Portfolio
-	if err := config.GetDB().
+	if err := db.
 		Preload("PortfolioSections.PortfolioBlocks").
 		Preload("Colors").
 		Preload("Font").
-		Where("user_id = ? AND status = ?", userID, "active").
-		First(&portfolio).Error; err != nil {
-		// Handle "record not found" - no active portfolio exists
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusOK, gin.H{"data": nil, "message": "No active portfolio found"})
-			return
-		}
+		Preload("Curricula").
+		First(&portfolio, portfolioID).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": portfolio})
+	c.JSON(http.StatusOK, gin.H{"data": portfolio, "designated": designated})
 }
 
 
@@ -721,6 +920,11 @@ func GetPortfolioById(c *gin.Context) {
 		return
 	}
 
+	// 🔐 เจ้าของ หรืออาจารย์/แอดมินที่ได้รับ portfolio นี้มาตรวจเท่านั้น
+	if _, ok := authorizePortfolio(c, config.GetDB(), uint(portfolioID), portfolioRead); !ok {
+		return
+	}
+
 	includeBlocks := c.DefaultQuery("include_blocks", "true") == "true"
 
 	var portfolio entity.Portfolio
@@ -751,5 +955,15 @@ func GetPortfolioById(c *gin.Context) {
 		return
 	}
 
+	if includeBlocks {
+		hydrated := []entity.Portfolio{portfolio}
+		if err := services.HydratePortfolioBlocks(config.GetDB(), hydrated); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		portfolio = hydrated[0]
+	}
+
+	c.Header("ETag", portfolioETag(portfolio.Revision))
 	c.JSON(http.StatusOK, gin.H{"data": portfolio})
 }
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// ExportPortfolioPDF - GET /portfolio/:id/export.pdf
+// render portfolio เป็น PDF ฝั่ง server จำนวนหน้าส่งกลับใน header X-Page-Count
+// สิทธิ์เหมือน GetPortfolioById (เจ้าของ หรืออาจารย์/แอดมินที่ได้รับ portfolio มาตรวจ)
+func ExportPortfolioPDF(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioRead); !ok {
+		return
+	}
+
+	result, portfolio, err := services.ExportPortfolioPDF(db, portfolioID)
+	if err != nil {
+		handleDBError(c, err, "Portfolio not found")
+		return
+	}
+
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`%s; filename="portfolio-%d.pdf"`, disposition, portfolio.ID))
+	c.Header("X-Page-Count", strconv.Itoa(result.PageCount))
+	c.Data(http.StatusOK, "application/pdf", result.Data)
+}
+
+// checkPortfolioPageLimit render portfolio แล้วตรวจจำนวนหน้ากับ PortfolioMaxPages ของหลักสูตร
+// ถ้าไม่ระบุหลักสูตร จะใช้ค่าที่น้อยที่สุดของหลักสูตรที่นักเรียนเลือกไว้ (submission ยังไม่ผูกกับหลักสูตร)
+// คืน maxPages = 0 เมื่อไม่มีข้อจำกัด
+func checkPortfolioPageLimit(db *gorm.DB, portfolioID, userID, curriculumID uint) (pageCount int, maxPages int, err error) {
+	query := db.Model(&entity.Curriculum{}).Where("portfolio_max_pages > 0")
+	if curriculumID != 0 {
+		query = query.Where("id = ?", curriculumID)
+	} else {
+		query = query.Where("id IN (?)", db.Model(&entity.Selection{}).Select("curriculum_id").Where("user_id = ?", userID))
+	}
+
+	var limit struct{ MaxPages int }
+	if err := query.Select("MIN(portfolio_max_pages) as max_pages").Scan(&limit).Error; err != nil {
+		return 0, 0, err
+	}
+	if limit.MaxPages == 0 {
+		return 0, 0, nil
+	}
+
+	result, _, err := services.ExportPortfolioPDF(db, portfolioID)
+	if err != nil {
+		return 0, limit.MaxPages, err
+	}
+	return result.PageCount, limit.MaxPages, nil
+}
@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// trackPortfolioChange ทำ mutate ใน transaction พร้อมเพิ่ม revision และบันทึกลงประวัติของ portfolio
+func trackPortfolioChange(c *gin.Context, portfolioID uint, action, entityType string, entityID uint, mutate func(tx *gorm.DB) (uint, error)) error {
+	userID, _ := getAuthUserID(c)
+	var changes *services.PortfolioChangeSet
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = services.BeginPortfolioChangeSet(tx, portfolioID, userID)
+		if err != nil {
+			return err
+		}
+		return changes.Track(action, entityType, entityID, func() (uint, error) {
+			return mutate(tx)
+		})
+	})
+	if err == nil {
+		broadcastPortfolioChanges(changes)
+	}
+	return err
+}
+
+// revertPortfolio ทำการย้อน (undo / restore / rebase) ใน transaction แล้วตอบ portfolio ล่าสุดกลับไป
+func revertPortfolio(c *gin.Context, portfolioID uint, revert func(changes *services.PortfolioChangeSet) (int, error)) {
+	db := config.GetDB()
+	userID, _ := getAuthUserID(c)
+
+	var applied int
+	var changes *services.PortfolioChangeSet
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = services.BeginPortfolioChangeSet(tx, portfolioID, userID)
+		if err != nil {
+			return err
+		}
+		applied, err = revert(changes)
+		return err
+	})
+	switch {
+	case errors.Is(err, services.ErrNothingToUndo):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrPortfolioChangeExpired):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrTemplateRebaseOutdated):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrPortfolioHasNoTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	broadcastPortfolioChanges(changes)
+
+	portfolio, err := loadPortfolioWithBlocks(db, portfolioID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", portfolioETag(portfolio.Revision))
+	c.JSON(http.StatusOK, gin.H{
+		"data":     portfolio,
+		"revision": portfolio.Revision,
+		"applied":  applied,
+	})
+}
+
+// GetPortfolioHistory - GET /portfolio/:id/history?limit=50&before_id=
+// ประวัติการแก้ไข section/block เรียงจากล่าสุด
+func GetPortfolioHistory(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := db.Where("portfolio_id = ?", portfolioID)
+	if beforeID, err := parseUintParam(c.Query("before_id")); err == nil {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var changes []entity.PortfolioChange
+	if err := query.Order("id DESC").Limit(limit).Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": changes})
+}
+
+// UndoPortfolioChange - POST /portfolio/:id/undo
+// ย้อนการแก้ไขชุดล่าสุด (หนึ่ง request หรือหนึ่ง batch) ที่ยังไม่ถูก undo
+func UndoPortfolioChange(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+	if _, ok := authorizePortfolio(c, config.GetDB(), portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	revertPortfolio(c, portfolioID, func(changes *services.PortfolioChangeSet) (int, error) {
+		return changes.UndoLastChange()
+	})
+}
+
+// RestorePortfolio - POST /portfolio/:id/restore
+// body: {"restore_point_id": 3} หรือ {"change_id": 120} (คืนสถานะทันทีหลัง change นั้น)
+// การย้อนถูกบันทึกในประวัติด้วย จึง undo การ restore ได้
+func RestorePortfolio(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	var payload struct {
+		RestorePointID uint `json:"restore_point_id"`
+		ChangeID       uint `json:"change_id"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (payload.RestorePointID == 0) == (payload.ChangeID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify either restore_point_id or change_id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	if payload.ChangeID != 0 {
+		revertPortfolio(c, portfolioID, func(changes *services.PortfolioChangeSet) (int, error) {
+			return changes.RollbackToChange(payload.ChangeID)
+		})
+		return
+	}
+
+	var point entity.PortfolioRestorePoint
+	if err := db.Where("portfolio_id = ?", portfolioID).First(&point, payload.RestorePointID).Error; err != nil {
+		handleDBError(c, err, "Restore point not found")
+		return
+	}
+	revertPortfolio(c, portfolioID, func(changes *services.PortfolioChangeSet) (int, error) {
+		return changes.RestoreSnapshot(point.Snapshot)
+	})
+}
+
+// ListPortfolioRestorePoints - GET /portfolio/:id/restore-points
+func ListPortfolioRestorePoints(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	var points []entity.PortfolioRestorePoint
+	if err := db.Where("portfolio_id = ?", portfolioID).Order("id DESC").Find(&points).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": points})
+}
+
+// CreatePortfolioRestorePoint - POST /portfolio/:id/restore-points
+// body: {"name": "ก่อนส่งรอบ 1"}
+func CreatePortfolioRestorePoint(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" || len([]rune(payload.Name)) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be between 1 and 100 characters"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+	userID, _ := getAuthUserID(c)
+
+	retention := services.DefaultPortfolioHistoryRetention()
+	point, err := services.CreatePortfolioRestorePoint(db, portfolioID, userID, payload.Name, retention)
+	if errors.Is(err, services.ErrRestorePointLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "max_restore_points": retention.MaxRestorePoints})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": point})
+}
+
+// DeletePortfolioRestorePoint - DELETE /portfolio/:id/restore-points/:pointId
+func DeletePortfolioRestorePoint(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+	pointID, err := parseUintParam(c.Param("pointId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid restore point id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	result := db.Unscoped().Where("portfolio_id = ? AND id = ?", portfolioID, pointID).Delete(&entity.PortfolioRestorePoint{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Restore point not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Restore point deleted successfully"})
+}
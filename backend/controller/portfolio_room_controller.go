@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/middlewares"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+const (
+	roomWriteTimeout   = 10 * time.Second
+	roomMaxMessageSize = 16 * 1024
+
+	// session ของห้องแก้ไขที่ส่งมากับ request HTTP เพื่อแก้ block ที่ตัวเอง lock ไว้
+	roomSessionHeader = "X-Room-Session"
+
+	// subprotocol ของห้องแก้ไข: browser ส่ง ["portfolio-room", <JWT>] ใน Sec-WebSocket-Protocol
+	roomSubprotocol = "portfolio-room"
+)
+
+// roomUpgrader แยกจาก upgrader ของ /ws: รับเฉพาะ Origin ที่อยู่ใน CORS allow-list
+// (request ที่ไม่มี Origin มาจาก client ที่ไม่ใช่ browser) และตอบ subprotocol ห้องแก้ไขเท่านั้น ไม่ echo token กลับ
+var roomUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	Subprotocols:    []string{roomSubprotocol},
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || middlewares.IsAllowedOrigin(origin)
+	},
+}
+
+// roomToken อ่าน JWT จาก Sec-WebSocket-Protocol (ตัวถัดจาก portfolio-room) หรือ Authorization header
+// ไม่รับจาก query string เพราะ URL ถูกเก็บใน log / history
+func roomToken(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == roomSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+}
+
+// blockLockedError block ถูก lock ในห้องแก้ไขโดย session อื่น (ตอบ 423)
+type blockLockedError struct {
+	Lock services.PortfolioBlockLock
+}
+
+func (e *blockLockedError) Error() string {
+	return fmt.Sprintf("block %d is being edited in another session", e.Lock.BlockID)
+}
+
+// checkBlockLock คืน error ถ้า block ถูก lock โดย session อื่นที่ไม่ใช่ roomSession
+func checkBlockLock(portfolioID, blockID uint, roomSession string) error {
+	if lock, locked := services.PortfolioRooms.BlockLockedByOther(portfolioID, blockID, roomSession); locked {
+		return &blockLockedError{Lock: lock}
+	}
+	return nil
+}
+
+// rejectLockedBlock ตอบ 423 ถ้า block ถูก lock โดย session อื่น
+func rejectLockedBlock(c *gin.Context, portfolioID, blockID uint) bool {
+	var lockErr *blockLockedError
+	if err := checkBlockLock(portfolioID, blockID, c.GetHeader(roomSessionHeader)); errors.As(err, &lockErr) {
+		c.JSON(http.StatusLocked, gin.H{"error": lockErr.Error(), "lock": lockErr.Lock})
+		return true
+	}
+	return false
+}
+
+// broadcastPortfolioChanges แจ้งห้องแก้ไขหลัง commit ให้ทุก session โหลด revision ใหม่
+func broadcastPortfolioChanges(changes *services.PortfolioChangeSet) {
+	if changes == nil {
+		return
+	}
+	services.PortfolioRooms.BroadcastChanges(changes.PortfolioID, changes.Revision, changes.UserID, changes.Changes)
+}
+
+func roomDisplayName(db *gorm.DB, userID uint) string {
+	var user entity.User
+	if err := db.Select("id", "first_name_th", "last_name_th", "email").First(&user, userID).Error; err != nil {
+		return ""
+	}
+	if name := strings.TrimSpace(user.FirstNameTH + " " + user.LastNameTH); name != "" {
+		return name
+	}
+	return user.Email
+}
+
+// PortfolioRoomHandler - GET /ws/portfolio/:id (Sec-WebSocket-Protocol: portfolio-room, <JWT>)
+// ห้องแก้ไข portfolio แบบ real-time: presence (cursor/selection), lock block ที่กำลังแก้ และแจ้งการแก้ไข
+// browser ตั้ง header ให้ WebSocket ไม่ได้ จึงรับ token ผ่าน subprotocol (หรือ Authorization header)
+// ผู้ที่อ่านได้ (เช่น อาจารย์ที่ได้รับ portfolio มาตรวจ) เข้าห้องได้ แต่ lock ได้เฉพาะผู้ที่แก้ไขได้
+func PortfolioRoomHandler(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	if !roomUpgrader.CheckOrigin(c.Request) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+		return
+	}
+	token := roomToken(c.Request)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "token is required"})
+		return
+	}
+	claims, err := services.NewJWTWrapper().ValidateToken(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := config.GetDB()
+	if _, err := checkPortfolioAccess(db, claims.UserID, portfolioID, portfolioRead); err != nil {
+		if errors.Is(err, errPortfolioNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	_, writeErr := checkPortfolioAccess(db, claims.UserID, portfolioID, portfolioWrite)
+
+	conn, err := roomUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		fmt.Println("Error upgrading:", err)
+		return
+	}
+	conn.SetReadLimit(roomMaxMessageSize)
+
+	hub := services.PortfolioRooms
+	session := hub.Join(portfolioID, claims.UserID, roomDisplayName(db, claims.UserID), writeErr == nil)
+
+	// เขียนข้อความจาก Outbox จนกว่าจะออกจากห้อง (gorilla อนุญาตผู้เขียนทีละคน)
+	go func() {
+		defer conn.Close()
+		for msg := range session.Outbox() {
+			conn.SetWriteDeadline(time.Now().Add(roomWriteTimeout))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var msg services.PortfolioRoomMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			break
+		}
+		// lock ได้เฉพาะ block ใน portfolio ของห้องนี้
+		if msg.Type == services.RoomMsgLock {
+			if err := ensureBlockInPortfolio(db, portfolioID, msg.BlockID); err != nil {
+				hub.Notify(session, services.PortfolioRoomMessage{Type: services.RoomMsgError, BlockID: msg.BlockID, Error: err.Error()})
+				continue
+			}
+		}
+		hub.Handle(session, msg)
+	}
+	hub.Leave(session)
+}
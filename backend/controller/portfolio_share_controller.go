@@ -0,0 +1,358 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultShareLinkDays = 30
+	maxShareLinkDays     = 365
+
+	// รหัสผ่านของลิงก์ส่งผ่าน header นี้เท่านั้น ไม่รับทาง query เพราะจะติดไปกับ access log และ Referer
+	sharePasswordHeader = "X-Share-Password"
+	minSharePasswordLen = 8
+
+	// ใส่รหัสผ่านผิดติดกันครบ maxSharePasswordAttempts ครั้ง ล็อกลิงก์ไว้ sharePasswordLockout
+	maxSharePasswordAttempts = 5
+	sharePasswordLockout     = 15 * time.Minute
+)
+
+func newShareToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CreatePortfolioShareLink - POST /portfolio/:id/shares
+// body: {"label": "ครูแนะแนว", "expires_in_days": 7, "password": "optional"}
+func CreatePortfolioShareLink(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	var payload struct {
+		Label         string `json:"label"`
+		ExpiresInDays *int   `json:"expires_in_days"`
+		Password      string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := defaultShareLinkDays
+	if payload.ExpiresInDays != nil {
+		days = *payload.ExpiresInDays
+	}
+	if days < 1 || days > maxShareLinkDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and 365"})
+		return
+	}
+	payload.Label = strings.TrimSpace(payload.Label)
+	if len([]rune(payload.Label)) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Label must not exceed 100 characters"})
+		return
+	}
+	if payload.Password != "" && len(payload.Password) < minSharePasswordLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+	userID, _ := getAuthUserID(c)
+
+	token, err := newShareToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hash, err := config.HashPassword(payload.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	link := entity.PortfolioShareLink{
+		PortfolioID:  portfolioID,
+		CreatedByID:  userID,
+		Token:        token,
+		Label:        payload.Label,
+		PasswordHash: hash,
+		HasPassword:  hash != "",
+		ExpiresAt:    &expiresAt,
+	}
+	if err := db.Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": link, "path": "/p/" + link.Token})
+}
+
+// ListPortfolioShareLinks - GET /portfolio/:id/shares
+func ListPortfolioShareLinks(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	var links []entity.PortfolioShareLink
+	if err := db.Where("portfolio_id = ?", portfolioID).Order("id DESC").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": links})
+}
+
+// RevokePortfolioShareLink - DELETE /portfolio/:id/shares/:shareId
+// เพิกถอนลิงก์ (เก็บแถวไว้เพื่อดูจำนวนครั้งที่ถูกเปิด)
+func RevokePortfolioShareLink(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+	shareID, err := parseUintParam(c.Param("shareId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	var link entity.PortfolioShareLink
+	if err := db.Where("portfolio_id = ?", portfolioID).First(&link, shareID).Error; err != nil {
+		handleDBError(c, err, "Share link not found")
+		return
+	}
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := db.Model(&link).Update("revoked_at", &now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": link})
+}
+
+// ข้อมูลที่เปิดเผยผ่านลิงก์สาธารณะ ไม่รวมข้อมูลส่วนตัว (เลขบัตร, เบอร์โทร, อีเมล, วันเกิด)
+type publicPortfolioOwner struct {
+	FirstNameTH     string `json:"first_name_th"`
+	LastNameTH      string `json:"last_name_th"`
+	FirstNameEN     string `json:"first_name_en"`
+	LastNameEN      string `json:"last_name_en"`
+	ProfileImageURL string `json:"profile_image_url"`
+}
+
+type publicPortfolioBlock struct {
+	ID            uint           `json:"id"`
+	BlockPortType string         `json:"block_port_type"`
+	BlockOrder    int            `json:"block_order"`
+	BlockStyle    datatypes.JSON `json:"block_style"`
+	Content       datatypes.JSON `json:"content"`
+
+	Source *entity.PortfolioBlockSource `json:"source,omitempty"`
+}
+
+type publicPortfolioSection struct {
+	ID              uint                   `json:"id"`
+	SectionPortKey  string                 `json:"section_port_key"`
+	SectionTitle    string                 `json:"section_title"`
+	SectionOrder    int                    `json:"section_order"`
+	SectionStyle    datatypes.JSON         `json:"section_style"`
+	PortfolioBlocks []publicPortfolioBlock `json:"portfolio_blocks"`
+}
+
+type publicPortfolio struct {
+	ID                 uint                     `json:"id"`
+	PortfolioName      string                   `json:"portfolio_name"`
+	Decription         string                   `json:"decription"`
+	ContentDescription string                   `json:"content_description"`
+	CoverImage         string                   `json:"cover_image"`
+	PortfolioStyle     datatypes.JSON           `json:"portfolio_style"`
+	Colors             entity.Colors            `json:"colors"`
+	Font               entity.Font              `json:"font"`
+	UpdatedAt          time.Time                `json:"updated_at"`
+	Owner              publicPortfolioOwner     `json:"owner"`
+	PortfolioSections  []publicPortfolioSection `json:"portfolio_sections"`
+}
+
+func toPublicPortfolio(p entity.Portfolio) publicPortfolio {
+	view := publicPortfolio{
+		ID:                 p.ID,
+		PortfolioName:      p.PortfolioName,
+		Decription:         p.Decription,
+		ContentDescription: p.ContentDescription,
+		CoverImage:         p.CoverImage,
+		PortfolioStyle:     p.PortfolioStyle,
+		Colors:             p.Colors,
+		Font:               p.Font,
+		UpdatedAt:          p.UpdatedAt,
+		Owner: publicPortfolioOwner{
+			FirstNameTH:     p.User.FirstNameTH,
+			LastNameTH:      p.User.LastNameTH,
+			FirstNameEN:     p.User.FirstNameEN,
+			LastNameEN:      p.User.LastNameEN,
+			ProfileImageURL: p.User.ProfileImageURL,
+		},
+		PortfolioSections: []publicPortfolioSection{},
+	}
+	for _, s := range p.PortfolioSections {
+		if !s.IsEnabled {
+			continue
+		}
+		section := publicPortfolioSection{
+			ID:              s.ID,
+			SectionPortKey:  s.SectionPortKey,
+			SectionTitle:    s.SectionTitle,
+			SectionOrder:    s.SectionOrder,
+			SectionStyle:    s.SectionStyle,
+			PortfolioBlocks: []publicPortfolioBlock{},
+		}
+		for _, b := range s.PortfolioBlocks {
+			section.PortfolioBlocks = append(section.PortfolioBlocks, publicPortfolioBlock{
+				ID:            b.ID,
+				BlockPortType: b.BlockPortType,
+				BlockOrder:    b.BlockOrder,
+				BlockStyle:    b.BlockStyle,
+				Content:       b.Content,
+				Source:        b.Source,
+			})
+		}
+		view.PortfolioSections = append(view.PortfolioSections, section)
+	}
+	return view
+}
+
+// checkSharePassword ตรวจรหัสผ่านจาก X-Share-Password และนับครั้งที่ผิดต่อลิงก์
+// ผิดครบ maxSharePasswordAttempts ครั้งจะตอบ 429 จนพ้นช่วงล็อก, ใส่ถูกแล้วรีเซ็ตตัวนับ
+func checkSharePassword(c *gin.Context, db *gorm.DB, link *entity.PortfolioShareLink) bool {
+	now := time.Now()
+	if link.LockedUntil != nil && now.Before(*link.LockedUntil) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password attempts, please try again later", "password_required": true})
+		return false
+	}
+
+	password := c.GetHeader(sharePasswordHeader)
+	if password == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password required", "password_required": true})
+		return false
+	}
+
+	if !config.CheckPasswordHash(password, link.PasswordHash) {
+		// เพิ่มตัวนับใน DB ด้วย expression เพื่อให้ request ที่ยิงพร้อมกันนับครบทุกครั้ง
+		db.Model(&entity.PortfolioShareLink{}).Where("id = ?", link.ID).
+			UpdateColumn("failed_attempts", gorm.Expr("failed_attempts + 1"))
+		var attempts int
+		db.Model(&entity.PortfolioShareLink{}).Where("id = ?", link.ID).Pluck("failed_attempts", &attempts)
+		if attempts >= maxSharePasswordAttempts {
+			lockedUntil := now.Add(sharePasswordLockout)
+			db.Model(&entity.PortfolioShareLink{}).Where("id = ?", link.ID).UpdateColumns(map[string]interface{}{
+				"failed_attempts": 0,
+				"locked_until":    lockedUntil,
+			})
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password attempts, please try again later", "password_required": true})
+			return false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password", "password_required": true})
+		return false
+	}
+
+	if link.FailedAttempts > 0 || link.LockedUntil != nil {
+		db.Model(&entity.PortfolioShareLink{}).Where("id = ?", link.ID).UpdateColumns(map[string]interface{}{
+			"failed_attempts": 0,
+			"locked_until":    nil,
+		})
+	}
+	return true
+}
+
+// GetSharedPortfolio - GET /p/:token (ไม่ต้อง login)
+// ลิงก์ที่หมดอายุหรือถูกเพิกถอนตอบ 410, ลิงก์ที่มีรหัสผ่านต้องส่งรหัสใน X-Share-Password
+// (ใส่ผิดติดกันหลายครั้งลิงก์จะถูกล็อกชั่วคราวและตอบ 429)
+func GetSharedPortfolio(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+
+	db := config.GetDB()
+	var link entity.PortfolioShareLink
+	if err := db.Where("token = ?", c.Param("token")).First(&link).Error; err != nil {
+		handleDBError(c, err, "Share link not found")
+		return
+	}
+	if !link.IsActive(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has expired or been revoked"})
+		return
+	}
+
+	if link.HasPassword && !checkSharePassword(c, db, &link) {
+		return
+	}
+
+	var portfolio entity.Portfolio
+	err := db.
+		Preload("Colors").
+		Preload("Font").
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "first_name_th", "last_name_th", "first_name_en", "last_name_en", "profile_image_url")
+		}).
+		Preload("PortfolioSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("section_order ASC")
+		}).
+		Preload("PortfolioSections.PortfolioBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("block_order ASC")
+		}).
+		First(&portfolio, link.PortfolioID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// block แบบอ้างอิงเก็บแค่ data_id จึงต้องเติมข้อมูลต้นทาง (แบบไม่มีข้อมูลติดต่อของเจ้าของ)
+	hydrated := []entity.Portfolio{portfolio}
+	if err := services.HydratePublicPortfolioBlocks(db, hydrated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	portfolio = hydrated[0]
+
+	now := time.Now()
+	db.Model(&entity.PortfolioShareLink{}).Where("id = ?", link.ID).UpdateColumns(map[string]interface{}{
+		"view_count":     gorm.Expr("view_count + 1"),
+		"last_viewed_at": now,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": toPublicPortfolio(portfolio)})
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// ClonePortfolio - POST /portfolio/:id/clone
+// body: {"portfolio_name": "สำหรับวิศวะคอม", "curriculum_id": 3} (curriculum_id ไม่บังคับ)
+// สร้าง variant ใหม่จาก portfolio เดิม ถ้าระบุหลักสูตรจะกำหนดให้ variant นี้เป็นตัวที่ใช้ยื่นหลักสูตรนั้นด้วย
+func ClonePortfolio(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	var payload struct {
+		PortfolioName string `json:"portfolio_name"`
+		CurriculumID  uint   `json:"curriculum_id"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	payload.PortfolioName = strings.TrimSpace(payload.PortfolioName)
+	if len([]rune(payload.PortfolioName)) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Portfolio name must be between 1 and 100 characters"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+	if payload.CurriculumID != 0 && !curriculumExists(c, db, payload.CurriculumID) {
+		return
+	}
+	userID, _ := getAuthUserID(c)
+
+	var clone *entity.Portfolio
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if clone, err = services.ClonePortfolio(tx, portfolioID, userID, payload.PortfolioName); err != nil {
+			return err
+		}
+		if payload.CurriculumID != 0 {
+			_, err = services.DesignatePortfolioForCurriculum(tx, userID, clone.ID, payload.CurriculumID)
+		}
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := loadPortfolioWithBlocks(db, clone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": result})
+}
+
+// DesignatePortfolioCurriculum - PUT /portfolio/:id/curricula/:curriculumId
+// ใช้ portfolio นี้ยื่นหลักสูตรนี้ (portfolio เดิมที่เคยกำหนดไว้กับหลักสูตรนี้จะถูกแทนที่)
+func DesignatePortfolioCurriculum(c *gin.Context) {
+	portfolioID, curriculumID, ok := parsePortfolioCurriculumParams(c)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+	if !curriculumExists(c, db, curriculumID) {
+		return
+	}
+	userID, _ := getAuthUserID(c)
+
+	var designation *entity.PortfolioCurriculum
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		designation, err = services.DesignatePortfolioForCurriculum(tx, userID, portfolioID, curriculumID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": designation})
+}
+
+// RemovePortfolioCurriculum - DELETE /portfolio/:id/curricula/:curriculumId
+func RemovePortfolioCurriculum(c *gin.Context) {
+	portfolioID, curriculumID, ok := parsePortfolioCurriculumParams(c)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+
+	result := db.Unscoped().
+		Where("portfolio_id = ? AND curriculum_id = ?", portfolioID, curriculumID).
+		Delete(&entity.PortfolioCurriculum{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio is not designated for this curriculum"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Curriculum designation removed"})
+}
+
+func parsePortfolioCurriculumParams(c *gin.Context) (uint, uint, bool) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return 0, 0, false
+	}
+	curriculumID, err := parseUintParam(c.Param("curriculumId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid curriculum id"})
+		return 0, 0, false
+	}
+	return portfolioID, curriculumID, true
+}
+
+func curriculumExists(c *gin.Context, db *gorm.DB, curriculumID uint) bool {
+	var count int64
+	if err := db.Model(&entity.Curriculum{}).Where("id = ?", curriculumID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Curriculum not found"})
+		return false
+	}
+	return true
+}
+
+// resolveCurriculumPortfolio หา portfolio ที่ใช้กับหลักสูตร (ตอบ 404 ถ้าไม่มี)
+func resolveCurriculumPortfolio(c *gin.Context, db *gorm.DB, userID, curriculumID uint) (uint, bool, bool) {
+	portfolioID, designated, err := services.ActivePortfolioIDForCurriculum(db, userID, curriculumID)
+	if errors.Is(err, services.ErrNoPortfolioForCurriculum) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return 0, false, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false, false
+	}
+	return portfolioID, designated, true
+}
@@ -2,12 +2,14 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
 	"gorm.io/gorm"
 )
 
@@ -19,7 +21,8 @@ type PortfolioSubmissionController struct {
 
 func (c *PortfolioSubmissionController) Create(ctx *gin.Context) {
     var body struct {
-        PortfolioID uint `json:"portfolio_id"`
+        PortfolioID  uint `json:"portfolio_id"`
+        CurriculumID uint `json:"curriculum_id"` // optional: หลักสูตรที่ใช้ตรวจจำนวนหน้า
     }
 
     if err := ctx.ShouldBindJSON(&body); err != nil {
@@ -30,7 +33,47 @@ func (c *PortfolioSubmissionController) Create(ctx *gin.Context) {
     userIDAny, _ := ctx.Get("user_id")
     userID := userIDAny.(uint)
 
-    err := c.DB.Transaction(func(tx *gorm.DB) error {
+    // ไม่ระบุ portfolio แต่ระบุหลักสูตร: ใช้ variant ที่กำหนดไว้กับหลักสูตรนั้น
+    if body.PortfolioID == 0 && body.CurriculumID != 0 {
+        portfolioID, _, ok := resolveCurriculumPortfolio(ctx, c.DB, userID, body.CurriculumID)
+        if !ok {
+            return
+        }
+        body.PortfolioID = portfolioID
+    }
+
+    // 🔐 ส่งได้เฉพาะ portfolio ของตัวเอง
+    if _, ok := authorizePortfolio(ctx, c.DB, body.PortfolioID, portfolioWrite); !ok {
+        return
+    }
+
+    // render PDF เพื่อตรวจว่าจำนวนหน้าไม่เกิน PortfolioMaxPages ของหลักสูตร
+    pageCount, maxPages, err := checkPortfolioPageLimit(c.DB, body.PortfolioID, userID, body.CurriculumID)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        ctx.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+        return
+    }
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if maxPages > 0 && pageCount > maxPages {
+        ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+            "error":      fmt.Sprintf("portfolio มี %d หน้า เกินกว่าที่หลักสูตรกำหนด (%d หน้า)", pageCount, maxPages),
+            "page_count": pageCount,
+            "max_pages":  maxPages,
+        })
+        return
+    }
+
+    // section ที่หลักสูตรกำหนดแต่ portfolio ยังไม่มี: แจ้งเตือนแต่ยังส่งได้
+    warnings, err := services.CheckRequiredSections(c.DB, body.PortfolioID, body.CurriculumID)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    err = c.DB.Transaction(func(tx *gorm.DB) error {
         // หา current submission ล่าสุดของ portfolio นี้
         var current entity.PortfolioSubmission
         err := tx.
@@ -67,7 +110,8 @@ func (c *PortfolioSubmissionController) Create(ctx *gin.Context) {
 
     // ส่ง response ว่าอัพเดทสำเร็จ (ไม่มี submission object ใหม่ให้ return)
     ctx.JSON(201, gin.H{
-        "message": "Portfolio submission updated successfully",
+        "message":  "Portfolio submission updated successfully",
+        "warnings": warnings,
     })
 }
 
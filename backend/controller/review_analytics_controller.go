@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// ReviewAnalyticsController สถิติภาระงานการตรวจแฟ้มผลงาน (PortfolioSubmission / Scorecard / Feedback)
+type ReviewAnalyticsController struct {
+	DB *gorm.DB
+}
+
+type statusCount struct {
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
+type curriculumQueueCount struct {
+	CurriculumID   uint   `json:"curriculum_id"`
+	CurriculumName string `json:"curriculum_name"`
+	Count          int    `json:"count"`
+}
+
+type reviewTiming struct {
+	ReviewedCount         int     `json:"reviewed_count"`
+	MedianHoursToReview   float64 `json:"median_hours_to_review"`
+	PendingCount          int     `json:"pending_count"`
+	MedianHoursWaiting    float64 `json:"median_hours_waiting"`
+	LongestHoursWaiting   float64 `json:"longest_hours_waiting"`
+	OldestPendingSubmitID uint    `json:"oldest_pending_submission_id,omitempty"`
+}
+
+type reviewerStat struct {
+	UserID            uint                   `json:"user_id"`
+	Name              string                 `json:"name"`
+	Scorecards        int                    `json:"scorecards"`
+	Feedbacks         int                    `json:"feedbacks"`
+	AverageScore      float64                `json:"average_score"`
+	LastReviewedAt    *time.Time             `json:"last_reviewed_at"`
+	DaysSinceReview   int                    `json:"days_since_review"`
+	ScoreDistribution []services.ScoreBucket `json:"score_distribution"`
+
+	percentages []float64
+}
+
+// GET /api/admin/analytics/reviews?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (c *ReviewAnalyticsController) GetReviewAnalytics(ctx *gin.Context) {
+	from, to, err := parseDateRangeQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inRange := func(q *gorm.DB, column string) *gorm.DB {
+		if from != nil {
+			q = q.Where(column+" >= ?", *from)
+		}
+		if to != nil {
+			q = q.Where(column+" < ?", *to)
+		}
+		return q
+	}
+
+	// 1. ขนาดคิวแยกตามสถานะ
+	var byStatus []statusCount
+	if err := inRange(c.DB.Model(&entity.PortfolioSubmission{}), "submission_at").
+		Select("status, COUNT(*) as count").
+		Group("status").
+		Order("count desc").
+		Scan(&byStatus).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 2. คิวที่รอตรวจแยกตามหลักสูตร
+	// submission นับให้หลักสูตรที่เลือกไว้ เฉพาะเมื่อ portfolio ที่ส่งเป็น portfolio ของหลักสูตรนั้น
+	// (กติกาเดียวกับสถิติ conversion) นักเรียนที่เลือกหลายหลักสูตรจึงไม่ถูกนับซ้ำทุกหลักสูตร
+	var byCurriculum []curriculumQueueCount
+	if err := inRange(c.DB.Table("portfolio_submissions"), "portfolio_submissions.submission_at").
+		Joins("JOIN selections ON selections.user_id = portfolio_submissions.user_id AND selections.deleted_at IS NULL"+
+			" AND portfolio_submissions.portfolio_id = "+selectionPortfolioIDExpr).
+		Joins("JOIN curriculums ON curriculums.id = selections.curriculum_id").
+		Where("portfolio_submissions.deleted_at IS NULL").
+		Where("portfolio_submissions.status IN ?", services.PendingSubmissionStatuses).
+		Select("curriculums.id as curriculum_id, curriculums.name as curriculum_name, COUNT(DISTINCT portfolio_submissions.id) as count").
+		Group("curriculums.id, curriculums.name").
+		Order("count desc").
+		Scan(&byCurriculum).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 3. เวลาที่ใช้ในการตรวจ / เวลาที่รออยู่ในคิว
+	var submissions []entity.PortfolioSubmission
+	if err := inRange(c.DB.Select("id", "status", "submission_at", "reviewed_at", "approved_at"), "submission_at").
+		Find(&submissions).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	var reviewDurations, waitDurations []time.Duration
+	timing := reviewTiming{}
+	var longestWait time.Duration
+
+	for _, s := range submissions {
+		if isPendingSubmission(s.Status) {
+			wait := now.Sub(s.Submission_at)
+			waitDurations = append(waitDurations, wait)
+			if wait > longestWait {
+				longestWait = wait
+				timing.OldestPendingSubmitID = s.ID
+			}
+			continue
+		}
+
+		reviewedAt := s.ReviewedAt
+		if reviewedAt == nil {
+			reviewedAt = s.ApprovedAt
+		}
+		if reviewedAt != nil && reviewedAt.After(s.Submission_at) {
+			reviewDurations = append(reviewDurations, reviewedAt.Sub(s.Submission_at))
+		}
+	}
+
+	timing.ReviewedCount = len(reviewDurations)
+	timing.MedianHoursToReview = services.RoundHours(services.MedianDuration(reviewDurations))
+	timing.PendingCount = len(waitDurations)
+	timing.MedianHoursWaiting = services.RoundHours(services.MedianDuration(waitDurations))
+	timing.LongestHoursWaiting = services.RoundHours(longestWait)
+
+	// 4. สถิติรายผู้ตรวจ + การกระจายคะแนน
+	var scorecards []entity.Scorecard
+	if err := inRange(c.DB.Select("id", "user_id", "total_score", "max_score", "created_at"), "created_at").
+		Find(&scorecards).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var feedbackCounts []struct {
+		UserID uint
+		Count  int
+	}
+	if err := inRange(c.DB.Model(&entity.Feedback{}), "created_at").
+		Select("user_id, COUNT(*) as count").
+		Group("user_id").
+		Scan(&feedbackCounts).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviewers := map[uint]*reviewerStat{}
+	getReviewer := func(userID uint) *reviewerStat {
+		r, ok := reviewers[userID]
+		if !ok {
+			r = &reviewerStat{UserID: userID}
+			reviewers[userID] = r
+		}
+		return r
+	}
+
+	var allPercentages []float64
+	for _, sc := range scorecards {
+		r := getReviewer(sc.UserID)
+		r.Scorecards++
+		percent := services.ScorePercent(sc.Total_Score, sc.Max_Score)
+		r.percentages = append(r.percentages, percent)
+		allPercentages = append(allPercentages, percent)
+
+		createdAt := sc.CreatedAt
+		if r.LastReviewedAt == nil || createdAt.After(*r.LastReviewedAt) {
+			r.LastReviewedAt = &createdAt
+		}
+	}
+	for _, fc := range feedbackCounts {
+		getReviewer(fc.UserID).Feedbacks = fc.Count
+	}
+
+	reviewerIDs := make([]uint, 0, len(reviewers))
+	for id := range reviewers {
+		reviewerIDs = append(reviewerIDs, id)
+	}
+	var users []entity.User
+	if len(reviewerIDs) > 0 {
+		c.DB.Select("id", "first_name_th", "last_name_th", "first_name_en", "last_name_en", "email").
+			Where("id IN ?", reviewerIDs).Find(&users)
+	}
+	for _, u := range users {
+		getReviewer(u.ID).Name = reviewerDisplayName(u)
+	}
+
+	reviewerStats := make([]reviewerStat, 0, len(reviewers))
+	for _, r := range reviewers {
+		if len(r.percentages) > 0 {
+			var sum float64
+			for _, p := range r.percentages {
+				sum += p
+			}
+			r.AverageScore = float64(int(sum/float64(len(r.percentages))*100+0.5)) / 100
+		}
+		if r.LastReviewedAt != nil {
+			r.DaysSinceReview = int(now.Sub(*r.LastReviewedAt).Hours() / 24)
+		} else {
+			r.DaysSinceReview = -1
+		}
+		r.ScoreDistribution = services.ScoreHistogram(r.percentages, 10)
+		reviewerStats = append(reviewerStats, *r)
+	}
+
+	// ผู้ตรวจที่ไม่ได้ตรวจนานที่สุดขึ้นก่อน (คนที่ "ตามหลัง")
+	sort.Slice(reviewerStats, func(i, j int) bool {
+		a, b := reviewerStats[i], reviewerStats[j]
+		if a.DaysSinceReview != b.DaysSinceReview {
+			if a.DaysSinceReview < 0 || b.DaysSinceReview < 0 {
+				return a.DaysSinceReview < 0
+			}
+			return a.DaysSinceReview > b.DaysSinceReview
+		}
+		return a.Scorecards < b.Scorecards
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"queue_by_status":     byStatus,
+			"queue_by_curriculum": byCurriculum,
+			"timing":              timing,
+			"reviewers":           reviewerStats,
+			"score_distribution":  services.ScoreHistogram(allPercentages, 10),
+		},
+	})
+}
+
+func isPendingSubmission(status string) bool {
+	for _, s := range services.PendingSubmissionStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+func reviewerDisplayName(u entity.User) string {
+	if name := strings.TrimSpace(u.FirstNameTH + " " + u.LastNameTH); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(u.FirstNameEN + " " + u.LastNameEN); name != "" {
+		return name
+	}
+	return u.Email
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 // Get section blocks by section ID
 func GetSectionBlocksBySectionID(c *gin.Context) {
@@ -18,4 +19,61 @@ func GetSectionBlocksBySectionID(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, sectionBlocks)
-}
\ No newline at end of file
+}
+
+// PATCH /section-blocks/:id
+// แก้การจัดวาง (order_index, layout_type, position, flex_settings, grid_settings, custom_style)
+func UpdateSectionBlock(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section block id"})
+		return
+	}
+	var input sectionBlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var sb entity.SectionBlock
+	if err := config.GetDB().First(&sb, id).Error; err != nil {
+		handleDBError(c, err, "Section block not found")
+		return
+	}
+
+	section, ok := mutateTemplateSection(c, sb.TemplatesSectionID, func(tx *gorm.DB) error {
+		input.apply(&sb)
+		return tx.Model(&entity.SectionBlock{}).Where("id = ?", sb.ID).Updates(map[string]interface{}{
+			"order_index":   sb.OrderIndex,
+			"layout_type":   sb.LayoutType,
+			"position":      sb.Position,
+			"flex_settings": sb.FlexSettings,
+			"grid_settings": sb.GridSettings,
+			"custom_style":  sb.CustomStyle,
+		}).Error
+	})
+	if ok {
+		c.JSON(http.StatusOK, section)
+	}
+}
+
+// DELETE /section-blocks/:id
+// เอา block ออกจาก section (ตัว template block ยังอยู่)
+func DeleteSectionBlock(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section block id"})
+		return
+	}
+	var sb entity.SectionBlock
+	if err := config.GetDB().First(&sb, id).Error; err != nil {
+		handleDBError(c, err, "Section block not found")
+		return
+	}
+
+	section, ok := mutateTemplateSection(c, sb.TemplatesSectionID, func(tx *gorm.DB) error {
+		return tx.Delete(&entity.SectionBlock{}, sb.ID).Error
+	})
+	if ok {
+		c.JSON(http.StatusOK, section)
+	}
+}
@@ -1,11 +1,16 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
 )
 
 type SelectionController struct{}
@@ -15,9 +20,16 @@ func NewSelectionController() *SelectionController {
 }
 
 // 1. ฟังก์ชันกดเลือก / ยกเลิกเลือก (Select / Unselect)
+// การเลือกใหม่จะถูกต่อท้ายลำดับของรอบนั้น และถูกจำกัดจำนวนตาม SelectionRoundLimit
+// ผู้ใช้มาจาก token เท่านั้น ไม่รับ user_id จาก client
 func (sc *SelectionController) ToggleSelection(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
 	var payload struct {
-		UserID       uint `json:"user_id"`
 		CurriculumID uint `json:"curriculum_id"`
 	}
 	if err := c.ShouldBindJSON(&payload); err != nil {
@@ -26,36 +38,106 @@ func (sc *SelectionController) ToggleSelection(c *gin.Context) {
 	}
 
 	db := config.GetDB()
+
+	var curriculum entity.Curriculum
+	if err := db.Select("id", "academic_year", "round_name").First(&curriculum, payload.CurriculumID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "curriculum not found"})
+		return
+	}
+
 	var selection entity.Selection
 
 	// เช็คว่าเคยเลือกหรือยัง
-	result := db.Where("user_id = ? AND curriculum_id = ?", payload.UserID, payload.CurriculumID).First(&selection)
+	result := db.Where("user_id = ? AND curriculum_id = ?", userID, payload.CurriculumID).First(&selection)
 
 	if result.RowsAffected > 0 {
-		// ถ้ามีแล้ว -> ลบออก (Unselect)
-		db.Delete(&selection)
+		// ถ้ามีแล้ว -> ลบออก (Unselect) แล้วเรียงลำดับที่เหลือในรอบใหม่
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&selection).Error; err != nil {
+				return err
+			}
+			remaining, err := selectionsInRound(tx, userID, curriculum.AcademicYear, curriculum.RoundName)
+			if err != nil {
+				return err
+			}
+			return saveSelectionOrder(tx, remaining)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"message": "removed", "selected": false})
-	} else {
-		// ถ้ายังไม่มี -> เพิ่มใหม่ (Select)
-		newSelection := entity.Selection{
-			UserID:       payload.UserID,
+		return
+	}
+
+	// ถ้ายังไม่มี -> เพิ่มใหม่ (Select) ถ้ายังไม่เกินจำนวนที่รอบนี้อนุญาต
+	var newSelection entity.Selection
+	var maxChoices int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		existing, err := selectionsInRound(tx, userID, curriculum.AcademicYear, curriculum.RoundName)
+		if err != nil {
+			return err
+		}
+		maxChoices, err = roundMaxChoices(tx, curriculum.AcademicYear, curriculum.RoundName)
+		if err != nil {
+			return err
+		}
+		if maxChoices > 0 && len(existing) >= maxChoices {
+			return errSelectionLimitReached
+		}
+
+		newSelection = entity.Selection{
+			UserID:       userID,
 			CurriculumID: payload.CurriculumID,
 			IsNotified:   false, // เริ่มต้นยังไม่เปิดแจ้งเตือน
+			Priority:     len(existing) + 1,
 		}
-		db.Create(&newSelection)
-		c.JSON(http.StatusCreated, gin.H{"message": "added", "selected": true})
+		return tx.Create(&newSelection).Error
+	})
+	if errors.Is(err, errSelectionLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       fmt.Sprintf("เลือกได้สูงสุด %d หลักสูตรในรอบนี้", maxChoices),
+			"max_choices": maxChoices,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusCreated, gin.H{"message": "added", "selected": true, "priority": newSelection.Priority})
 }
 
-// 2. ฟังก์ชันดึงรายการที่เลือกทั้งหมด (Get My Selections)
+// selectionView ข้อมูลที่เลือกพร้อมสถานะของแต่ละตัวเลือก
+type selectionView struct {
+	entity.Selection
+	ApplicationStatus string `json:"application_status"` // open / opening / closed
+	Eligible          bool   `json:"eligible"`
+	IneligibleReason  string `json:"ineligible_reason,omitempty"`
+	Submitted         bool   `json:"submitted"`
+}
+
+// selectionRoundSummary จำนวนที่เลือกไปแล้วในแต่ละรอบ (max_choices = 0 คือไม่จำกัด)
+type selectionRoundSummary struct {
+	AcademicYear string `json:"academic_year"`
+	RoundName    string `json:"round_name"`
+	Count        int    `json:"count"`
+	MaxChoices   int    `json:"max_choices"`
+}
+
+// 2. ฟังก์ชันดึงรายการที่เลือกทั้งหมด (Get My Selections) เรียงตามรอบและลำดับความสำคัญ
 func (sc *SelectionController) GetMySelections(c *gin.Context) {
-	userId := c.Query("user_id")
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
 
 	db := config.GetDB()
 	var selections []entity.Selection
 
 	// Preload Curriculum เพื่อเอาข้อมูลวิชาไปแสดง
-	if err := db.Preload("Curriculum").Preload("Curriculum.Program").Preload("Curriculum.Faculty").Where("user_id = ?", userId).Find(&selections).Error; err != nil {
+	if err := db.Preload("Curriculum").Preload("Curriculum.Program").Preload("Curriculum.Faculty").Where("user_id = ?", userID).Order("priority asc, id asc").Find(&selections).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -67,7 +149,149 @@ func (sc *SelectionController) GetMySelections(c *gin.Context) {
 			calculateCurriculumStatus(selections[i].Curriculum)
 		}
 	}
-	c.JSON(http.StatusOK, gin.H{"data": selections})
+
+	// เกรดเฉลี่ยสะสมของนักเรียน (ใช้เช็คคุณสมบัติ GPAX ขั้นต่ำ)
+	var score entity.AcademicScore
+	hasScore := db.Where("user_id = ?", userID).Limit(1).Find(&score).RowsAffected > 0
+
+	// "ส่งแล้ว" รายหลักสูตร: ส่ง portfolio ของหลักสูตรนั้นแล้ว (กติกาเดียวกับสถิติ conversion)
+	var submittedIDs []uint
+	if err := db.Table("selections").
+		Where("selections.user_id = ? AND selections.deleted_at IS NULL", userID).
+		Where(submittedSelectionCondition).
+		Pluck("selections.curriculum_id", &submittedIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	submitted := make(map[uint]bool, len(submittedIDs))
+	for _, id := range submittedIDs {
+		submitted[id] = true
+	}
+
+	limits, err := loadRoundLimits(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	views := make([]selectionView, 0, len(selections))
+	rounds := []*selectionRoundSummary{}
+	roundIndex := map[string]*selectionRoundSummary{}
+	for _, s := range selections {
+		view := selectionView{Selection: s, Eligible: true, Submitted: submitted[s.CurriculumID]}
+
+		if s.Curriculum != nil {
+			view.ApplicationStatus = s.Curriculum.Status
+			if s.Curriculum.GPAXMin > 0 {
+				if !hasScore {
+					view.Eligible = false
+					view.IneligibleReason = "ยังไม่มีข้อมูล GPAX"
+				} else if score.GPAX < float64(s.Curriculum.GPAXMin) {
+					view.Eligible = false
+					view.IneligibleReason = fmt.Sprintf("GPAX ต่ำกว่าเกณฑ์ขั้นต่ำ %.2f", s.Curriculum.GPAXMin)
+				}
+			}
+
+			key := roundKey(s.Curriculum.AcademicYear, s.Curriculum.RoundName)
+			summary, ok := roundIndex[key]
+			if !ok {
+				summary = &selectionRoundSummary{
+					AcademicYear: s.Curriculum.AcademicYear,
+					RoundName:    s.Curriculum.RoundName,
+					MaxChoices:   limits[key],
+				}
+				roundIndex[key] = summary
+				rounds = append(rounds, summary)
+			}
+			summary.Count++
+		}
+		views = append(views, view)
+	}
+
+	// จัดกลุ่มตามรอบ แล้วเรียงตามลำดับความสำคัญภายในรอบ
+	sort.SliceStable(views, func(i, j int) bool {
+		a, b := views[i].Curriculum, views[j].Curriculum
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		if a.AcademicYear != b.AcademicYear {
+			return a.AcademicYear > b.AcademicYear
+		}
+		if a.RoundName != b.RoundName {
+			return a.RoundName < b.RoundName
+		}
+		return views[i].Priority < views[j].Priority
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": views, "rounds": rounds})
+}
+
+// PUT /selections/priority (ต้อง Login)
+// จัดลำดับความสำคัญของหลักสูตรในรอบเดียวกันของผู้ใช้ที่ login ใหม่ curriculum_ids เรียงจากอันดับ 1 ลงไป
+func (sc *SelectionController) UpdatePriorities(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	var payload struct {
+		CurriculumIDs []uint `json:"curriculum_ids"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(payload.CurriculumIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "curriculum_ids is required"})
+		return
+	}
+
+	db := config.GetDB()
+
+	// ใช้รอบของหลักสูตรแรกเป็นตัวกำหนดรอบที่จะจัดลำดับ
+	var curriculum entity.Curriculum
+	if err := db.Select("id", "academic_year", "round_name").First(&curriculum, payload.CurriculumIDs[0]).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "curriculum not found"})
+		return
+	}
+
+	var ordered []entity.Selection
+	err = db.Transaction(func(tx *gorm.DB) error {
+		current, err := selectionsInRound(tx, userID, curriculum.AcademicYear, curriculum.RoundName)
+		if err != nil {
+			return err
+		}
+
+		currentIDs := make([]uint, len(current))
+		byCurriculum := make(map[uint]entity.Selection, len(current))
+		for i, s := range current {
+			currentIDs[i] = s.CurriculumID
+			byCurriculum[s.CurriculumID] = s
+		}
+
+		newOrder, err := services.ReorderSelections(currentIDs, payload.CurriculumIDs)
+		if err != nil {
+			return &selectionOrderError{err}
+		}
+
+		ordered = make([]entity.Selection, len(newOrder))
+		for i, id := range newOrder {
+			ordered[i] = byCurriculum[id]
+		}
+		return saveSelectionOrder(tx, ordered)
+	})
+	var orderErr *selectionOrderError
+	if errors.As(err, &orderErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": orderErr.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": ordered})
 }
 
 // 3. ฟังก์ชันเปิด/ปิด การแจ้งเตือน (Toggle Notification)
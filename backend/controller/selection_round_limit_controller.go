@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+var errSelectionLimitReached = errors.New("selection limit reached")
+
+// selectionOrderError ข้อมูลลำดับที่ส่งมาไม่ถูกต้อง (ตอบกลับเป็น 400)
+type selectionOrderError struct{ err error }
+
+func (e *selectionOrderError) Error() string { return e.err.Error() }
+
+func roundKey(academicYear, roundName string) string {
+	return academicYear + "|" + roundName
+}
+
+// selectionsInRound ดึงรายการที่ผู้ใช้เลือกไว้ในรอบเดียวกัน (ปีการศึกษา + ชื่อรอบ) เรียงตามลำดับความสำคัญ
+func selectionsInRound(db *gorm.DB, userID uint, academicYear, roundName string) ([]entity.Selection, error) {
+	var selections []entity.Selection
+	err := db.Joins("JOIN curriculums ON curriculums.id = selections.curriculum_id AND curriculums.deleted_at IS NULL").
+		Where("selections.user_id = ? AND curriculums.academic_year = ? AND curriculums.round_name = ?", userID, academicYear, roundName).
+		Order("selections.priority asc, selections.id asc").
+		Find(&selections).Error
+	return selections, err
+}
+
+// saveSelectionOrder บันทึก priority ใหม่ตามลำดับใน slice (เริ่มที่ 1)
+func saveSelectionOrder(tx *gorm.DB, selections []entity.Selection) error {
+	for i := range selections {
+		priority := i + 1
+		if selections[i].Priority == priority {
+			continue
+		}
+		if err := tx.Model(&entity.Selection{}).Where("id = ?", selections[i].ID).Update("priority", priority).Error; err != nil {
+			return err
+		}
+		selections[i].Priority = priority
+	}
+	return nil
+}
+
+// roundMaxChoices จำนวนที่เลือกได้สูงสุดของรอบ (0 = ไม่จำกัด)
+func roundMaxChoices(db *gorm.DB, academicYear, roundName string) (int, error) {
+	var limit entity.SelectionRoundLimit
+	result := db.Where("academic_year = ? AND round_name = ?", academicYear, roundName).Limit(1).Find(&limit)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return limit.MaxChoices, nil
+}
+
+func loadRoundLimits(db *gorm.DB) (map[string]int, error) {
+	var limits []entity.SelectionRoundLimit
+	if err := db.Find(&limits).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]int, len(limits))
+	for _, l := range limits {
+		result[roundKey(l.AcademicYear, l.RoundName)] = l.MaxChoices
+	}
+	return result, nil
+}
+
+// GET /admin/selection-limits
+func (sc *SelectionController) ListRoundLimits(c *gin.Context) {
+	var limits []entity.SelectionRoundLimit
+	if err := config.GetDB().Order("academic_year desc, round_name asc").Find(&limits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": limits})
+}
+
+// PUT /admin/selection-limits
+// สร้างหรือแก้ไขจำนวนที่เลือกได้สูงสุดของรอบ (อ้างอิงด้วย academic_year + round_name)
+func (sc *SelectionController) UpsertRoundLimit(c *gin.Context) {
+	var payload entity.SelectionRoundLimit
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	payload.AcademicYear = strings.TrimSpace(payload.AcademicYear)
+	payload.RoundName = strings.TrimSpace(payload.RoundName)
+
+	if _, err := govalidator.ValidateStruct(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := config.GetDB()
+	var limit entity.SelectionRoundLimit
+	result := db.Where("academic_year = ? AND round_name = ?", payload.AcademicYear, payload.RoundName).Limit(1).Find(&limit)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if result.RowsAffected == 0 {
+		limit = entity.SelectionRoundLimit{AcademicYear: payload.AcademicYear, RoundName: payload.RoundName}
+		status = http.StatusCreated
+	}
+	limit.MaxChoices = payload.MaxChoices
+
+	if err := db.Save(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, gin.H{"data": limit})
+}
+
+// DELETE /admin/selection-limits/:id
+// ลบการตั้งค่า (รอบนั้นจะกลับไปเป็นไม่จำกัดจำนวน)
+func (sc *SelectionController) DeleteRoundLimit(c *gin.Context) {
+	result := config.GetDB().Unscoped().Delete(&entity.SelectionRoundLimit{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "selection limit not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
@@ -0,0 +1,344 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// -------------------- SELECTION STATISTICS (Admin) --------------------
+
+// selectionStatsFilter ตัวกรองที่ใช้ร่วมกันทุกกราฟ (รับจาก query string)
+//
+//	?academic_year=2568&round=Portfolio 1&faculty_id=1&program_id=2&from=2025-01-01&to=2025-01-31
+type selectionStatsFilter struct {
+	AcademicYear string
+	Round        string
+	FacultyID    int
+	ProgramID    int
+	From         *time.Time
+	To           *time.Time
+}
+
+func parseSelectionStatsFilter(c *gin.Context) (selectionStatsFilter, error) {
+	filter := selectionStatsFilter{
+		AcademicYear: strings.TrimSpace(c.Query("academic_year")),
+		Round:        strings.TrimSpace(c.Query("round")),
+		FacultyID:    parseIntWithDefault(c.Query("faculty_id"), 0),
+		ProgramID:    parseIntWithDefault(c.Query("program_id"), 0),
+	}
+
+	from, to, err := parseDateRangeQuery(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.From, filter.To = from, to
+
+	return filter, nil
+}
+
+// parseDateRangeQuery อ่าน ?from=YYYY-MM-DD&to=YYYY-MM-DD (to คืนค่าเป็นต้นวันถัดไป เพื่อให้รวมทั้งวัน)
+func parseDateRangeQuery(c *gin.Context) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+
+	if v := strings.TrimSpace(c.Query("from")); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		from = &t
+	}
+	if v := strings.TrimSpace(c.Query("to")); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+
+	return from, to, nil
+}
+
+// selectionStatsQuery query ตั้งต้น: selections ที่ยังไม่ถูกยกเลิก + join หลักสูตร + ตัวกรอง
+func (cc *CurriculumController) selectionStatsQuery(filter selectionStatsFilter) *gorm.DB {
+	q := cc.db.Table("selections").
+		Joins("JOIN curriculums ON selections.curriculum_id = curriculums.id").
+		Where("selections.deleted_at IS NULL")
+
+	if filter.AcademicYear != "" {
+		q = q.Where("curriculums.academic_year = ?", filter.AcademicYear)
+	}
+	if filter.Round != "" {
+		q = q.Where("curriculums.round_name = ?", filter.Round)
+	}
+	if filter.FacultyID > 0 {
+		q = q.Where("curriculums.faculty_id = ?", filter.FacultyID)
+	}
+	if filter.ProgramID > 0 {
+		q = q.Where("curriculums.program_id = ?", filter.ProgramID)
+	}
+	if filter.From != nil {
+		q = q.Where("selections.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("selections.created_at < ?", *filter.To)
+	}
+	return q
+}
+
+// DailyStatResult จำนวนการเลือกใหม่ต่อวัน
+type DailyStatResult struct {
+	Date  string `json:"date"`
+	Value int    `json:"value"`
+}
+
+// ConversionStatResult อัตราการเปลี่ยนจาก "เลือกหลักสูตร" ไปเป็น "ส่งแฟ้มผลงาน"
+type ConversionStatResult struct {
+	Name           string  `json:"name"`
+	SelectedUsers  int     `json:"selected_users"`
+	SubmittedUsers int     `json:"submitted_users"`
+	ConversionRate float64 `json:"conversion_rate"`
+}
+
+// SelectionStats ผลลัพธ์ของทุกกราฟในหน้า dashboard
+type SelectionStats struct {
+	FacultyStats        []StatResult           `json:"faculty_stats"`
+	ProgramStats        []StatResult           `json:"program_stats"`
+	DailyStats          []DailyStatResult      `json:"daily_stats"`
+	SchoolTypeStats     []StatResult           `json:"school_type_stats"`
+	EducationLevelStats []StatResult           `json:"education_level_stats"`
+	Conversion          ConversionStatResult   `json:"conversion"`
+	FacultyConversion   []ConversionStatResult `json:"faculty_conversion"`
+}
+
+const unspecifiedStatLabel = "ไม่ระบุ"
+
+// selectionPortfolioIDExpr portfolio ที่ใช้ส่งสำหรับการเลือกหลักสูตรหนึ่ง (selections)
+// ใช้กติกาเดียวกับ services.ActivePortfolioIDForCurriculum: portfolio ที่กำหนดไว้กับหลักสูตร
+// ถ้ายังไม่ได้กำหนดใช้ portfolio หลัก (status active) ของผู้ใช้
+const selectionPortfolioIDExpr = `COALESCE(
+	(SELECT portfolio_curriculums.portfolio_id FROM portfolio_curriculums
+		WHERE portfolio_curriculums.deleted_at IS NULL
+			AND portfolio_curriculums.user_id = selections.user_id
+			AND portfolio_curriculums.curriculum_id = selections.curriculum_id),
+	(SELECT MIN(portfolios.id) FROM portfolios
+		WHERE portfolios.deleted_at IS NULL
+			AND portfolios.user_id = selections.user_id
+			AND portfolios.status = 'active'))`
+
+// submittedSelectionCondition การเลือกหลักสูตรที่ส่ง portfolio ของหลักสูตรนั้นแล้ว
+const submittedSelectionCondition = `EXISTS (
+	SELECT 1 FROM portfolio_submissions
+	WHERE portfolio_submissions.deleted_at IS NULL
+		AND portfolio_submissions.user_id = selections.user_id
+		AND portfolio_submissions.portfolio_id = ` + selectionPortfolioIDExpr + `)`
+
+func (cc *CurriculumController) buildSelectionStats(filter selectionStatsFilter) (*SelectionStats, error) {
+	stats := &SelectionStats{}
+
+	// 1. สถิติแยกตามสำนักวิชา (Faculty)
+	if err := cc.selectionStatsQuery(filter).
+		Joins("JOIN faculties ON curriculums.faculty_id = faculties.id").
+		Select("faculties.name as name, count(selections.id) as value").
+		Group("faculties.name").
+		Order("value desc").
+		Scan(&stats.FacultyStats).Error; err != nil {
+		return nil, err
+	}
+
+	// 2. สถิติแยกตามสาขาวิชา (Program) โดยมีชื่อสำนักวิชากำกับ (GroupName) ไว้ใช้ Drill-down
+	if err := cc.selectionStatsQuery(filter).
+		Joins("JOIN programs ON curriculums.program_id = programs.id").
+		Joins("JOIN faculties ON curriculums.faculty_id = faculties.id").
+		Select("programs.name as name, count(selections.id) as value, faculties.name as group_name").
+		Group("programs.name, faculties.name").
+		Order("value desc").
+		Scan(&stats.ProgramStats).Error; err != nil {
+		return nil, err
+	}
+
+	// 3. จำนวนการเลือกใหม่รายวัน
+	if err := cc.selectionStatsQuery(filter).
+		Select("CAST(DATE(selections.created_at) AS TEXT) as date, count(selections.id) as value").
+		Group("DATE(selections.created_at)").
+		Order("DATE(selections.created_at) asc").
+		Scan(&stats.DailyStats).Error; err != nil {
+		return nil, err
+	}
+
+	// 4. แยกตามประเภทโรงเรียนของผู้สมัคร
+	schoolTypeName := fmt.Sprintf("COALESCE(school_types.name, '%s')", unspecifiedStatLabel)
+	if err := cc.selectionStatsQuery(filter).
+		Joins("LEFT JOIN educations ON educations.user_id = selections.user_id AND educations.deleted_at IS NULL").
+		Joins("LEFT JOIN school_types ON school_types.id = educations.school_type_id").
+		Select(schoolTypeName + " as name, count(selections.id) as value").
+		Group(schoolTypeName).
+		Order("value desc").
+		Scan(&stats.SchoolTypeStats).Error; err != nil {
+		return nil, err
+	}
+
+	// 5. แยกตามระดับการศึกษาของผู้สมัคร
+	levelName := fmt.Sprintf("COALESCE(education_levels.name, '%s')", unspecifiedStatLabel)
+	if err := cc.selectionStatsQuery(filter).
+		Joins("LEFT JOIN educations ON educations.user_id = selections.user_id AND educations.deleted_at IS NULL").
+		Joins("LEFT JOIN education_levels ON education_levels.id = educations.education_level_id").
+		Select(levelName + " as name, count(selections.id) as value").
+		Group(levelName).
+		Order("value desc").
+		Scan(&stats.EducationLevelStats).Error; err != nil {
+		return nil, err
+	}
+
+	// 6. Conversion: ผู้ใช้ที่เลือกหลักสูตร -> ผู้ใช้ที่ส่งแฟ้มผลงานของหลักสูตรที่เลือกแล้ว
+	// (รายสำนักวิชานับเฉพาะการส่งที่ผูกกับหลักสูตรของสำนักวิชานั้น)
+	conversionSelect := "COUNT(DISTINCT selections.user_id) as selected_users, " +
+		"COUNT(DISTINCT CASE WHEN " + submittedSelectionCondition + " THEN selections.user_id END) as submitted_users"
+
+	if err := cc.selectionStatsQuery(filter).
+		Select("'all' as name, " + conversionSelect).
+		Scan(&stats.Conversion).Error; err != nil {
+		return nil, err
+	}
+	stats.Conversion.ConversionRate = conversionRate(stats.Conversion)
+
+	if err := cc.selectionStatsQuery(filter).
+		Joins("JOIN faculties ON curriculums.faculty_id = faculties.id").
+		Select("faculties.name as name, " + conversionSelect).
+		Group("faculties.name").
+		Order("selected_users desc").
+		Scan(&stats.FacultyConversion).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats.FacultyConversion {
+		stats.FacultyConversion[i].ConversionRate = conversionRate(stats.FacultyConversion[i])
+	}
+
+	return stats, nil
+}
+
+func conversionRate(r ConversionStatResult) float64 {
+	if r.SelectedUsers == 0 {
+		return 0
+	}
+	// ปัดเป็นทศนิยม 2 ตำแหน่ง (หน่วยเป็นเปอร์เซ็นต์)
+	rate := float64(r.SubmittedUsers) * 100 / float64(r.SelectedUsers)
+	return float64(int(rate*100+0.5)) / 100
+}
+
+// -------------------- EXPORT --------------------
+
+// ชื่อกราฟที่ export ได้ (ใช้เป็นชื่อ sheet ด้วย)
+var selectionStatsCharts = []string{"faculty", "program", "daily", "school_type", "education_level", "conversion"}
+
+func selectionStatsSheet(stats *SelectionStats, chart string) (services.SpreadsheetSheet, bool) {
+	statRows := func(items []StatResult) [][]string {
+		rows := make([][]string, 0, len(items))
+		for _, s := range items {
+			rows = append(rows, []string{s.Name, strconv.Itoa(s.Value)})
+		}
+		return rows
+	}
+
+	switch chart {
+	case "faculty":
+		return services.SpreadsheetSheet{Name: chart, Header: []string{"faculty", "selections"}, Rows: statRows(stats.FacultyStats)}, true
+	case "program":
+		rows := make([][]string, 0, len(stats.ProgramStats))
+		for _, s := range stats.ProgramStats {
+			rows = append(rows, []string{s.GroupName, s.Name, strconv.Itoa(s.Value)})
+		}
+		return services.SpreadsheetSheet{Name: chart, Header: []string{"faculty", "program", "selections"}, Rows: rows}, true
+	case "daily":
+		rows := make([][]string, 0, len(stats.DailyStats))
+		for _, s := range stats.DailyStats {
+			rows = append(rows, []string{s.Date, strconv.Itoa(s.Value)})
+		}
+		return services.SpreadsheetSheet{Name: chart, Header: []string{"date", "new_selections"}, Rows: rows}, true
+	case "school_type":
+		return services.SpreadsheetSheet{Name: chart, Header: []string{"school_type", "selections"}, Rows: statRows(stats.SchoolTypeStats)}, true
+	case "education_level":
+		return services.SpreadsheetSheet{Name: chart, Header: []string{"education_level", "selections"}, Rows: statRows(stats.EducationLevelStats)}, true
+	case "conversion":
+		items := append([]ConversionStatResult{stats.Conversion}, stats.FacultyConversion...)
+		rows := make([][]string, 0, len(items))
+		for _, s := range items {
+			rows = append(rows, []string{
+				s.Name,
+				strconv.Itoa(s.SelectedUsers),
+				strconv.Itoa(s.SubmittedUsers),
+				strconv.FormatFloat(s.ConversionRate, 'f', 2, 64),
+			})
+		}
+		return services.SpreadsheetSheet{Name: chart, Header: []string{"faculty", "selected_users", "submitted_users", "conversion_rate"}, Rows: rows}, true
+	}
+	return services.SpreadsheetSheet{}, false
+}
+
+// GET /admin/curricula/stats/export?chart=daily&format=csv|xlsx (+ ตัวกรองเดียวกับ /stats)
+// XLSX ที่ไม่ระบุ chart จะได้ทุกกราฟแยกเป็นคนละ sheet
+func (cc *CurriculumController) ExportSelectionStats(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", services.SpreadsheetFormatCSV))
+	if format != services.SpreadsheetFormatCSV && format != services.SpreadsheetFormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrUnsupportedSpreadsheet.Error()})
+		return
+	}
+
+	chart := strings.ToLower(strings.TrimSpace(c.Query("chart")))
+	if chart == "" && format == services.SpreadsheetFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chart is required for csv export: " + strings.Join(selectionStatsCharts, ", ")})
+		return
+	}
+
+	filter, err := parseSelectionStatsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := cc.buildSelectionStats(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	charts := selectionStatsCharts
+	if chart != "" {
+		charts = []string{chart}
+	}
+
+	sheets := make([]services.SpreadsheetSheet, 0, len(charts))
+	for _, name := range charts {
+		sheet, ok := selectionStatsSheet(stats, name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown chart, must be one of: " + strings.Join(selectionStatsCharts, ", ")})
+			return
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	fileName := "selection-stats"
+	if chart != "" {
+		fileName += "-" + chart
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName, format))
+	c.Header("Content-Type", services.SpreadsheetContentType(format))
+	c.Status(http.StatusOK)
+
+	if format == services.SpreadsheetFormatCSV {
+		err = services.WriteSpreadsheet(c.Writer, format, sheets[0].Name, sheets[0].Header, sheets[0].Rows)
+	} else {
+		err = services.WriteXLSXSheets(c.Writer, sheets)
+	}
+	if err != nil {
+		c.Error(err)
+	}
+}
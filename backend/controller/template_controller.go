@@ -1,14 +1,42 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
 	"gorm.io/gorm"
 )
 
+// validateTemplateBlock ตรวจ default content/style ของ template block กับ schema ของชนิด block
+func validateTemplateBlock(block entity.TemplatesBlock) error {
+	if err := services.BlockSchemas.ValidateBlock(block.BlockType, block.DefaultContent, block.DefaultStyle); err != nil {
+		return fmt.Errorf("template block %q: %w", block.BlockName, err)
+	}
+	return nil
+}
+
+// validateTemplateSections ตรวจ block ทุกตัวใน section ที่จะนำมาประกอบเป็น template
+func validateTemplateSections(db *gorm.DB, sectionIDs []uint) error {
+	var blocks []entity.TemplatesBlock
+	err := db.
+		Joins("JOIN section_blocks ON section_blocks.templates_block_id = templates_blocks.id AND section_blocks.deleted_at IS NULL").
+		Where("section_blocks.templates_section_id IN ?", sectionIDs).
+		Find(&blocks).Error
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if err := validateTemplateBlock(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GET /templates ทั้งหมด
 func GetTemplates(c *gin.Context) {
 	var templates []entity.Templates
@@ -87,6 +115,12 @@ func CreateTemplate(c *gin.Context) {
 	}
 
 	db := config.GetDB()
+	if err := validateTemplateSections(db, input.SectionIDs); err != nil {
+		if !respondBlockValidation(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
 
 	// สร้าง template
 	if err := db.Create(&template).Error; err != nil {
@@ -109,6 +143,12 @@ func CreateTemplate(c *gin.Context) {
 		}
 	}
 
+	// บันทึก version ของ template (portfolio ที่สร้างจาก template นี้ใช้ rebase ได้)
+	if _, err := services.SaveTemplateVersion(db, template.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save template version"})
+		return
+	}
+
 	// โหลด template พร้อม relations
 	if err := db.
 		Preload("TemplateSectionLinks", func(db *gorm.DB) *gorm.DB {
@@ -154,6 +194,12 @@ func UpdateTemplate(c *gin.Context) {
 	}
 
 	db := config.GetDB()
+	if err := validateTemplateSections(db, input.SectionIDs); err != nil {
+		if !respondBlockValidation(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
 
 	// อัพเดทข้อมูล template
 	template.TemplateName = input.TemplateName
@@ -192,6 +238,12 @@ func UpdateTemplate(c *gin.Context) {
 		}
 	}
 
+	// บันทึก version ของ template (portfolio ที่สร้างจาก template นี้ใช้ rebase ได้)
+	if _, err := services.SaveTemplateVersion(db, template.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save template version"})
+		return
+	}
+
 	// โหลด template พร้อม relations
 	if err := db.
 		Preload("TemplateSectionLinks", func(db *gorm.DB) *gorm.DB {
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+// GetTemplateGallery - GET /templates/gallery
+// Query params: ?page=1&limit=12&category_id=2&q=minimal&sort=popular|rating|newest|name
+// ส่งเฉพาะข้อมูลย่อของ template (ไม่มี section/block) พร้อมจำนวนการใช้งานและคะแนน
+func GetTemplateGallery(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "12"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 12
+	}
+
+	query := services.TemplateGalleryQuery{
+		Search: c.Query("q"),
+		Sort:   c.DefaultQuery("sort", services.TemplateSortPopular),
+		Page:   page,
+		Limit:  limit,
+	}
+	switch query.Sort {
+	case services.TemplateSortPopular, services.TemplateSortRating, services.TemplateSortNewest, services.TemplateSortName:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of popular, rating, newest, name"})
+		return
+	}
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := parseUintParam(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category id"})
+			return
+		}
+		query.CategoryID = id
+	}
+
+	templates, total, err := services.ListTemplateGallery(config.GetDB(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":       templates,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetTemplateRatings - GET /templates/:id/ratings
+// สรุปคะแนน (ค่าเฉลี่ย จำนวน และการกระจายของดาว)
+func GetTemplateRatings(c *gin.Context) {
+	templateID, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+	stats, err := services.GetTemplateRatingStats(config.GetDB(), templateID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+// GetMyTemplateRating - GET /templates/:id/rating
+// คะแนนที่ผู้ใช้ที่ login อยู่ให้ไว้ (data เป็น null ถ้ายังไม่เคยให้)
+func GetMyTemplateRating(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+	templateID, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+	var ratings []entity.TemplateRating
+	if err := config.GetDB().Where("templates_id = ? AND user_id = ?", templateID, userID).Limit(1).Find(&ratings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(ratings) == 0 {
+		c.JSON(http.StatusOK, gin.H{"data": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": ratings[0]})
+}
+
+// RateTemplate - PUT /templates/:id/rating
+// body: {"stars": 4, "comment": "..."} ให้ใหม่หรือแก้คะแนนเดิมของตัวเอง
+func RateTemplate(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+	templateID, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Stars   int    `json:"stars"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := (entity.TemplateRating{Stars: input.Stars, Comment: input.Comment}).Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rating, err := services.RateTemplate(config.GetDB(), templateID, userID, input.Stars, input.Comment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	stats, err := services.GetTemplateRatingStats(config.GetDB(), templateID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rating, "stats": stats})
+}
+
+// DeleteTemplateRating - DELETE /templates/:id/rating
+func DeleteTemplateRating(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+	templateID, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+	deleted, err := services.DeleteTemplateRating(config.GetDB(), templateID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rating deleted successfully"})
+}
+
+// templateIDParam อ่าน :id และตรวจว่ามี template อยู่จริง
+func templateIDParam(c *gin.Context) (uint, bool) {
+	templateID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return 0, false
+	}
+	if err := config.GetDB().Select("id").First(&entity.Templates{}, templateID).Error; err != nil {
+		handleDBError(c, err, "Template not found")
+		return 0, false
+	}
+	return templateID, true
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+const maxTemplatePackageUpload = 20 << 20
+
+// ExportTemplatePackage - GET /templates/:id/export
+// ดาวน์โหลด template ทั้งชุดเป็นไฟล์ zip (manifest.json + assets/)
+func ExportTemplatePackage(c *gin.Context) {
+	templateID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := services.ExportTemplatePackage(config.GetDB(), templateID, &buf); err != nil {
+		handleDBError(c, err, "Template not found")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="template-%d.zip"`, templateID))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// ImportTemplatePackage - POST /templates/import
+// รับไฟล์ zip ผ่าน multipart field "file" (หรือ body ตรงๆ แบบ application/zip)
+// ระบุ category_template_id เพื่อใส่ลง category ที่มีอยู่ ไม่ระบุจะใช้ชื่อ category ใน manifest
+func ImportTemplatePackage(c *gin.Context) {
+	// จำกัดขนาด body ก่อนอ่าน form เพราะ DefaultPostForm จะ parse multipart ทั้งก้อนทันที
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTemplatePackageUpload)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		if err := c.Request.ParseMultipartForm(maxTemplatePackageUpload); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Template package must not exceed 20MB"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read template package: " + err.Error()})
+			return
+		}
+	}
+
+	var categoryID uint
+	if raw := c.DefaultPostForm("category_template_id", c.Query("category_template_id")); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category template id"})
+			return
+		}
+		categoryID = uint(id)
+	}
+
+	var data []byte
+	var err error
+	if file, _, ferr := c.Request.FormFile("file"); ferr == nil {
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read template package: " + err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Template package file is required"})
+		return
+	}
+
+	db := config.GetDB()
+	if categoryID != 0 {
+		var count int64
+		if err := db.Model(&entity.CategoryTemplate{}).Where("id = ?", categoryID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category template not found"})
+			return
+		}
+	}
+
+	template, result, err := services.ImportTemplatePackage(db, data, categoryID)
+	if err != nil {
+		if respondBlockValidation(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTemplatePackage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": template, "summary": result})
+}
@@ -1,10 +1,15 @@
 package controller
 
 import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
-	"github.com/gin-gonic/gin"
-	"net/http"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -18,8 +23,28 @@ func CreateSection(c *gin.Context) {
         return
     }
 
-    if err := config.GetDB().Create(&section).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    // block ที่สร้างมาพร้อม section ต้องผ่าน schema ของชนิด block
+    for _, sb := range section.SectionBlocks {
+        if sb.TemplatesBlock == nil {
+            continue
+        }
+        if err := validateTemplateBlock(*sb.TemplatesBlock); err != nil {
+            respondBlockValidation(c, err)
+            return
+        }
+    }
+
+    // สร้างแล้วตรวจว่า layout render ได้ก่อน commit
+    err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&section).Error; err != nil {
+            return err
+        }
+        return services.CheckSectionLayout(tx, section.ID)
+    })
+    if err != nil {
+        if !respondTemplateLayoutError(c, err) {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
         return
     }
 
@@ -74,3 +99,280 @@ func GetSectionByID(c *gin.Context) {
     c.JSON(http.StatusOK, section)
 }
 
+// respondTemplateLayoutError ตอบ 400 เมื่อ layout render ไม่ได้ และ 409 เมื่อ section/block ถูกใช้ร่วมกันหลาย template
+func respondTemplateLayoutError(c *gin.Context, err error) bool {
+	var layoutErr *services.TemplateLayoutError
+	var inUseErr *services.TemplateInUseError
+	switch {
+	case errors.As(err, &layoutErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": layoutErr.Error(), "layout": layoutErr})
+		return true
+	case errors.As(err, &inUseErr):
+		c.JSON(http.StatusConflict, gin.H{"error": inUseErr.Error(), "usage": inUseErr})
+		return true
+	}
+	return respondBlockValidation(c, err)
+}
+
+// sharedTemplateLimit จำนวน template ที่ยอมให้แก้ section/block ร่วมกันได้
+// ?force=true ยืนยันว่าจะแก้ทุก template ที่ใช้อยู่
+func sharedTemplateLimit(c *gin.Context) int {
+	if c.Query("force") == "true" {
+		return math.MaxInt32
+	}
+	return 1
+}
+
+// mutateTemplateSection แก้ section ใน transaction ตรวจ layout และบันทึก version ใหม่ของ template ที่ใช้ section นี้
+func mutateTemplateSection(c *gin.Context, sectionID uint, mutate func(tx *gorm.DB) error) (*entity.TemplatesSection, bool) {
+	db := config.GetDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&entity.TemplatesSection{}, sectionID).Error; err != nil {
+			return err
+		}
+		templateIDs, err := services.CheckSectionShared(tx, sectionID, sharedTemplateLimit(c))
+		if err != nil {
+			return err
+		}
+		if err := mutate(tx); err != nil {
+			return err
+		}
+		if err := services.CheckSectionLayout(tx, sectionID); err != nil {
+			return err
+		}
+		return services.RefreshTemplateVersions(tx, templateIDs)
+	})
+	if err != nil {
+		if !respondTemplateLayoutError(c, err) {
+			handleDBError(c, err, "Section not found")
+		}
+		return nil, false
+	}
+
+	section, err := services.LoadTemplateSection(db, sectionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return section, true
+}
+
+// PUT /sections/:id
+// body: {"section_name": "...", "layout_type": "..."}
+func UpdateSection(c *gin.Context) {
+	sectionID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+	var input struct {
+		SectionName string `json:"section_name" binding:"required"`
+		LayoutType  string `json:"layout_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	section, ok := mutateTemplateSection(c, sectionID, func(tx *gorm.DB) error {
+		return tx.Model(&entity.TemplatesSection{}).Where("id = ?", sectionID).
+			Updates(map[string]interface{}{"section_name": input.SectionName, "layout_type": input.LayoutType}).Error
+	})
+	if ok {
+		c.JSON(http.StatusOK, section)
+	}
+}
+
+// DELETE /sections/:id
+// ลบได้เฉพาะ section ที่ไม่มี template ใช้อยู่ (เอาออกจาก template ก่อนผ่าน PUT /templates/:id)
+func DeleteSection(c *gin.Context) {
+	sectionID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&entity.TemplatesSection{}, sectionID).Error; err != nil {
+			return err
+		}
+		if _, err := services.CheckSectionShared(tx, sectionID, 0); err != nil {
+			return err
+		}
+		if err := tx.Where("templates_section_id = ?", sectionID).Delete(&entity.SectionBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.TemplatesSection{}, sectionID).Error
+	})
+	if err != nil {
+		if !respondTemplateLayoutError(c, err) {
+			handleDBError(c, err, "Section not found")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Section deleted successfully"})
+}
+
+// GET /sections/:id/usage
+// template ที่ใช้ section นี้ (แก้ section แล้วกระทบทุก template ในรายการ)
+func GetSectionUsage(c *gin.Context) {
+	sectionID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+	db := config.GetDB()
+	if err := db.First(&entity.TemplatesSection{}, sectionID).Error; err != nil {
+		handleDBError(c, err, "Section not found")
+		return
+	}
+	templateIDs, err := services.SectionTemplateIDs(db, sectionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"section_id": sectionID, "template_ids": templateIDs}})
+}
+
+// GET /sections/:id/validate
+// ตรวจ layout ของ section ที่บันทึกไว้ (ข้อมูลเดิมที่สร้างก่อนมีการตรวจ)
+func ValidateSection(c *gin.Context) {
+	sectionID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+	section, err := services.LoadTemplateSection(config.GetDB(), sectionID)
+	if err != nil {
+		handleDBError(c, err, "Section not found")
+		return
+	}
+
+	problems := []string{}
+	var layoutErr *services.TemplateLayoutError
+	if err := services.ValidateSectionLayout(section); errors.As(err, &layoutErr) {
+		problems = layoutErr.Problems
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"section_id": sectionID, "valid": len(problems) == 0, "problems": problems}})
+}
+
+// sectionBlockInput ค่าการจัดวาง block ใน section (field ที่ไม่ส่งมาไม่ถูกแก้)
+type sectionBlockInput struct {
+	TemplatesBlockID uint            `json:"templates_block_id"`
+	OrderIndex       *int            `json:"order_index"`
+	LayoutType       *string         `json:"layout_type"`
+	Position         *datatypes.JSON `json:"position"`
+	FlexSettings     *datatypes.JSON `json:"flex_settings"`
+	GridSettings     *datatypes.JSON `json:"grid_settings"`
+	CustomStyle      *datatypes.JSON `json:"custom_style"`
+}
+
+func (in sectionBlockInput) apply(sb *entity.SectionBlock) {
+	if in.OrderIndex != nil {
+		sb.OrderIndex = *in.OrderIndex
+	}
+	if in.LayoutType != nil {
+		sb.LayoutType = services.NormalizeBlockLayout(*in.LayoutType)
+	}
+	if in.Position != nil {
+		sb.Position = *in.Position
+	}
+	if in.FlexSettings != nil {
+		sb.FlexSettings = *in.FlexSettings
+	}
+	if in.GridSettings != nil {
+		sb.GridSettings = *in.GridSettings
+	}
+	if in.CustomStyle != nil {
+		sb.CustomStyle = *in.CustomStyle
+	}
+}
+
+// POST /sections/:id/section-blocks
+// body: {"templates_block_id": 3, "order_index": 2, "layout_type": "grid", "position": {...}}
+// ไม่ระบุ order_index จะต่อท้าย
+func AddSectionBlock(c *gin.Context) {
+	sectionID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+	var input sectionBlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.TemplatesBlockID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "templates_block_id is required"})
+		return
+	}
+	var block entity.TemplatesBlock
+	if err := config.GetDB().First(&block, input.TemplatesBlockID).Error; err != nil {
+		handleDBError(c, err, "Template block not found")
+		return
+	}
+
+	section, ok := mutateTemplateSection(c, sectionID, func(tx *gorm.DB) error {
+		sb := entity.SectionBlock{TemplatesSectionID: sectionID, TemplatesBlockID: block.ID, LayoutType: services.BlockLayoutGrid}
+		if input.OrderIndex == nil {
+			var last struct{ Max int }
+			if err := tx.Model(&entity.SectionBlock{}).Select("COALESCE(MAX(order_index), -1) AS max").
+				Where("templates_section_id = ?", sectionID).Scan(&last).Error; err != nil {
+				return err
+			}
+			sb.OrderIndex = last.Max + 1
+		}
+		input.apply(&sb)
+		return tx.Omit("TemplatesBlock", "TemplatesSection").Create(&sb).Error
+	})
+	if ok {
+		c.JSON(http.StatusCreated, section)
+	}
+}
+
+// PUT /sections/:id/section-blocks/order
+// body: {"section_block_ids": [5, 3, 4]} ต้องครบทุก block ใน section
+func ReorderSectionBlocks(c *gin.Context) {
+	sectionID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section id"})
+		return
+	}
+	var input struct {
+		SectionBlockIDs []uint `json:"section_block_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	section, ok := mutateTemplateSection(c, sectionID, func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&entity.SectionBlock{}).Where("templates_section_id = ?", sectionID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		inSection := map[uint]bool{}
+		for _, id := range existing {
+			inSection[id] = true
+		}
+		seen := map[uint]bool{}
+		for _, id := range input.SectionBlockIDs {
+			if !inSection[id] || seen[id] {
+				return &services.TemplateLayoutError{SectionID: sectionID, Problems: []string{"section_block_ids must list every block of the section exactly once"}}
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(existing) {
+			return &services.TemplateLayoutError{SectionID: sectionID, Problems: []string{"section_block_ids must list every block of the section exactly once"}}
+		}
+		for i, id := range input.SectionBlockIDs {
+			if err := tx.Model(&entity.SectionBlock{}).Where("id = ?", id).UpdateColumn("order_index", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if ok {
+		c.JSON(http.StatusOK, section)
+	}
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetTemplateVersions - GET /templates/:id/versions
+// รายการ version ของ template (ไม่รวม snapshot)
+func GetTemplateVersions(c *gin.Context) {
+	templateID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return
+	}
+
+	var versions []entity.TemplateVersion
+	if err := config.GetDB().Omit("snapshot").Where("templates_id = ?", templateID).
+		Order("version DESC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
+
+// GetTemplateVersion - GET /templates/:id/versions/:version
+func GetTemplateVersion(c *gin.Context) {
+	templateID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return
+	}
+	version, err := parseUintParam(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	snapshot, err := services.LoadTemplateVersion(config.GetDB(), templateID, version)
+	if errors.Is(err, services.ErrTemplateVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": snapshot})
+}
+
+// PreviewTemplateRebase - GET /portfolio/:id/template-rebase
+// แสดงสิ่งที่จะเปลี่ยนถ้า rebase portfolio ไปยัง template version ล่าสุด (ยังไม่แก้ portfolio)
+func PreviewTemplateRebase(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+	if !saveRebaseTemplateVersion(c, db, portfolioID) {
+		return
+	}
+
+	plan, err := services.PlanTemplateRebase(db, portfolioID)
+	if err != nil {
+		handleDBError(c, err, "Template not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plan})
+}
+
+// ApplyTemplateRebase - POST /portfolio/:id/template-rebase
+// body: {"to_version": 3} (ไม่บังคับ) ถ้า template ถูกแก้หลัง preview จะตอบ 409 ให้ preview ใหม่
+// content ของ block ไม่ถูกแตะ และย้อนกลับได้ด้วย undo
+func ApplyTemplateRebase(c *gin.Context) {
+	portfolioID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio id"})
+		return
+	}
+
+	var payload struct {
+		ToVersion uint `json:"to_version"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	db := config.GetDB()
+	if _, ok := authorizePortfolio(c, db, portfolioID, portfolioWrite); !ok {
+		return
+	}
+	if !saveRebaseTemplateVersion(c, db, portfolioID) {
+		return
+	}
+
+	revertPortfolio(c, portfolioID, func(changes *services.PortfolioChangeSet) (int, error) {
+		_, applied, err := changes.RebaseOntoTemplate(payload.ToVersion)
+		return applied, err
+	})
+}
+
+// saveRebaseTemplateVersion บันทึก version ของ template ที่ portfolio ใช้ก่อนวางแผน rebase
+// ใช้ SaveTemplateVersion (transaction แยก + retry) เพื่อไม่ให้การสร้าง version ไปอยู่ใน transaction ของ portfolio
+func saveRebaseTemplateVersion(c *gin.Context, db *gorm.DB, portfolioID uint) bool {
+	var portfolio entity.Portfolio
+	if err := db.Select("id", "template_id").First(&portfolio, portfolioID).Error; err != nil {
+		handleDBError(c, err, "Portfolio not found")
+		return false
+	}
+	if portfolio.TemplateID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrPortfolioHasNoTemplate.Error()})
+		return false
+	}
+	if _, err := services.SaveTemplateVersion(db, *portfolio.TemplateID); err != nil {
+		handleDBError(c, err, "Template not found")
+		return false
+	}
+	return true
+}
@@ -1,9 +1,14 @@
 package controller
 
 import (
+	"net/http"
+
 	"github.com/sut68/team14/backend/config"
 	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // GET /template-blocks
@@ -16,4 +21,121 @@ func GetTemplateBlocks(c *gin.Context) {
     c.JSON(200, blocks)
 }
 
+// GET /template-blocks/:id
+func GetTemplateBlockByID(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template block id"})
+		return
+	}
+	var block entity.TemplatesBlock
+	if err := config.GetDB().First(&block, id).Error; err != nil {
+		handleDBError(c, err, "Template block not found")
+		return
+	}
+	c.JSON(http.StatusOK, block)
+}
+
+type templateBlockInput struct {
+	BlockName      string         `json:"block_name" binding:"required"`
+	BlockType      string         `json:"block_type" binding:"required"`
+	OrderIndex     uint           `json:"order_index"`
+	DefaultContent datatypes.JSON `json:"default_content"`
+	DefaultStyle   datatypes.JSON `json:"default_style"`
+}
+
+// POST /template-blocks
+func CreateTemplateBlock(c *gin.Context) {
+	var input templateBlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	block := entity.TemplatesBlock{
+		BlockName:      input.BlockName,
+		BlockType:      input.BlockType,
+		OrderIndex:     input.OrderIndex,
+		DefaultContent: input.DefaultContent,
+		DefaultStyle:   input.DefaultStyle,
+	}
+	if err := validateTemplateBlock(block); err != nil {
+		respondBlockValidation(c, err)
+		return
+	}
+	if err := config.GetDB().Create(&block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, block)
+}
+
+// PUT /template-blocks/:id
+// แก้ค่าเริ่มต้นของ block ถ้า block อยู่ใน section ของหลาย template ต้องส่ง ?force=true
+func UpdateTemplateBlock(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template block id"})
+		return
+	}
+	var input templateBlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	var block entity.TemplatesBlock
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&block, id).Error; err != nil {
+			return err
+		}
+		templateIDs, err := services.CheckBlockShared(tx, id, sharedTemplateLimit(c))
+		if err != nil {
+			return err
+		}
+		block.BlockName = input.BlockName
+		block.BlockType = input.BlockType
+		block.OrderIndex = input.OrderIndex
+		block.DefaultContent = input.DefaultContent
+		block.DefaultStyle = input.DefaultStyle
+		if err := validateTemplateBlock(block); err != nil {
+			return err
+		}
+		if err := tx.Save(&block).Error; err != nil {
+			return err
+		}
+		return services.RefreshTemplateVersions(tx, templateIDs)
+	})
+	if err != nil {
+		if !respondTemplateLayoutError(c, err) {
+			handleDBError(c, err, "Template block not found")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, block)
+}
+
+// DELETE /template-blocks/:id
+// ลบได้เฉพาะ block ที่ไม่อยู่ใน section ใด
+func DeleteTemplateBlock(c *gin.Context) {
+	id, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template block id"})
+		return
+	}
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&entity.TemplatesBlock{}, id).Error; err != nil {
+			return err
+		}
+		if _, err := services.CheckBlockShared(tx, id, 0); err != nil {
+			return err
+		}
+		return tx.Delete(&entity.TemplatesBlock{}, id).Error
+	})
+	if err != nil {
+		if !respondTemplateLayoutError(c, err) {
+			handleDBError(c, err, "Template block not found")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Template block deleted successfully"})
+}
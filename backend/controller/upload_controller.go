@@ -1,11 +1,19 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
 	"github.com/sut68/team14/backend/services"
 )
 
@@ -59,12 +67,6 @@ func (u *UploadController) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Check file size (5MB limit)
-	if header.Size > MaxFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size must not exceed 5MB"})
-		return
-	}
-
 	// Check file extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	isImage := AllowedImageExtensions[ext]
@@ -75,6 +77,24 @@ func (u *UploadController) UploadFile(c *gin.Context) {
 		return
 	}
 
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	// รูปภาพถูกย่อและ encode ใหม่ก่อนเก็บ จึงรับไฟล์ใหญ่กว่าเอกสารได้
+	if isImage {
+		u.uploadImage(c, userID, file, header)
+		return
+	}
+
+	// Check file size (5MB limit)
+	if header.Size > MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size must not exceed 5MB"})
+		return
+	}
+
 	// Get or detect content type
 	contentType := header.Header.Get("Content-Type")
 	if !AllowedMimeTypes[contentType] {
@@ -107,21 +127,70 @@ func (u *UploadController) UploadFile(c *gin.Context) {
 	}
 
 	// Upload to Local Storage
-	if services.LocalStorage == nil {
+	if services.Storage == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not initialized"})
+		return
+	}
+
+	data, err := services.ReadUpload(file, MaxFileSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size must not exceed 5MB"})
+		return
+	}
+	stored, err := services.StoreUpload(config.GetDB(), userID, header.Filename, contentType, data, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":          stored.File.URL,
+		"sha256":       stored.File.SHA256,
+		"deduplicated": stored.Deduplicated,
+	})
+}
+
+// uploadImage หมุนรูปตาม EXIF, ตัด metadata (รวม GPS) และสร้างภาพ thumbnail/medium/full
+// url ใน response เป็นภาพ full เหมือนเดิม ส่วน renditions ใช้กรอก thumbnail_url/medium_url ของรูปกิจกรรม/ผลงาน
+func (u *UploadController) uploadImage(c *gin.Context, userID uint, file io.Reader, header *multipart.FileHeader) {
+	if header.Size > services.MaxImageUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image size must not exceed 20MB"})
+		return
+	}
+	if services.Storage == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not initialized"})
 		return
 	}
 
-	savedPath, err := services.LocalStorage.UploadFile(file, header.Filename, contentType)
+	data, err := services.ReadUpload(file, services.MaxImageUploadSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image size must not exceed 20MB"})
+		return
+	}
+	stored, err := services.StoreUpload(config.GetDB(), userID, header.Filename, header.Header.Get("Content-Type"), data, true)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidImage) || errors.Is(err, services.ErrImageTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"url": savedPath})
+	processed := stored.Image
+	c.JSON(http.StatusOK, gin.H{
+		"url":          processed.URL,
+		"format":       processed.Format,
+		"width":        processed.Width,
+		"height":       processed.Height,
+		"renditions":   processed.Renditions,
+		"sha256":       stored.File.SHA256,
+		"deduplicated": stored.Deduplicated,
+	})
 }
 
-// DeleteFile deletes a file from storage by filename
+// DeleteFile ลบไฟล์ที่ผู้ใช้อัปโหลดเอง (admin ลบไฟล์เก่าที่ไม่มีเจ้าของได้)
+// ไฟล์ที่ยังถูกใช้อยู่ในข้อมูลอื่นลบไม่ได้ (409)
 func (u *UploadController) DeleteFile(c *gin.Context) {
 	filename := c.Param("filename")
 	if filename == "" {
@@ -129,17 +198,127 @@ func (u *UploadController) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	if services.LocalStorage == nil {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	if services.Storage == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not initialized"})
 		return
 	}
 
-	err := services.LocalStorage.DeleteFile(filename)
-	if err != nil {
-		// File might not exist, which is okay for profile image replacement
-		c.JSON(http.StatusOK, gin.H{"message": "File deleted or not found"})
+	db := config.GetDB()
+	var user entity.User
+	if err := db.Select("id", "account_type_id").First(&user, userID).Error; err != nil {
+		handleDBError(c, err, "User not found")
 		return
 	}
 
+	removed, err := services.DeleteUpload(db, filename, userID, user.AccountTypeID == entity.UserTypeAdmin)
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrUploadForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrUploadInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !removed {
+		c.JSON(http.StatusOK, gin.H{"message": "File removed from your uploads"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
 }
+
+// uploadRedirectExpiry อายุของ signed URL ที่ ServeUpload redirect ไป
+const uploadRedirectExpiry = time.Hour
+
+// ServeUpload ใช้แทน static /uploads เมื่อเก็บไฟล์ไว้ใน S3/Azure โดย redirect ไปที่ signed URL
+// URL แบบ /uploads/<name> ที่บันทึกไว้ก่อนย้าย backend จึงยังเปิดได้
+func (u *UploadController) ServeUpload(c *gin.Context) {
+	if services.Storage == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not initialized"})
+		return
+	}
+	url, err := services.Storage.SignedURL(c.Param("filename"), uploadRedirectExpiry)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	// redirect หมดอายุพร้อม signed URL จึงห้าม cache นานแบบไฟล์ static
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int((uploadRedirectExpiry-time.Minute).Seconds())))
+	c.Redirect(http.StatusFound, url)
+}
+
+// CollectUploadGarbage ให้ admin สั่งเก็บกวาดไฟล์ที่ไม่มีใครใช้ทันที (?dry_run=true ดูผลอย่างเดียว)
+// grace period เป็นชั่วโมงกำหนดด้วย ?grace_hours (ค่าเริ่มต้นเท่ากับ scheduler)
+func (u *UploadController) CollectUploadGarbage(c *gin.Context) {
+	if services.Storage == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not initialized"})
+		return
+	}
+
+	opts := services.UploadGCOptions{
+		GracePeriod:    services.DefaultUploadGCGracePeriod,
+		AdoptUntracked: c.Query("adopt_untracked") == "true",
+		DryRun:         c.Query("dry_run") == "true",
+	}
+	if raw := c.Query("grace_hours"); raw != "" {
+		hours, err := strconv.Atoi(raw)
+		if err != nil || hours < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "grace_hours must be a non-negative integer"})
+			return
+		}
+		opts.GracePeriod = time.Duration(hours) * time.Hour
+	}
+
+	result, err := services.CollectUnreferencedUploads(config.GetDB(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// fillActivityImageRenditions เติม thumbnail_url/medium_url ของรูปที่อัปโหลดผ่าน pipeline เมื่อ client ไม่ได้ส่งมา
+func fillActivityImageRenditions(detail *entity.ActivityDetail) {
+	if detail == nil {
+		return
+	}
+	for i := range detail.Images {
+		img := &detail.Images[i]
+		thumbnail, medium := services.ImageRenditionURLs(img.ImageURL)
+		if img.ThumbnailURL == "" {
+			img.ThumbnailURL = thumbnail
+		}
+		if img.MediumURL == "" {
+			img.MediumURL = medium
+		}
+	}
+}
+
+// fillWorkingImageRenditions เหมือน fillActivityImageRenditions สำหรับรูปของผลงาน
+func fillWorkingImageRenditions(detail *entity.WorkingDetail) {
+	if detail == nil {
+		return
+	}
+	for i := range detail.Images {
+		img := &detail.Images[i]
+		thumbnail, medium := services.ImageRenditionURLs(img.WorkingImageURL)
+		if img.ThumbnailURL == "" {
+			img.ThumbnailURL = thumbnail
+		}
+		if img.MediumURL == "" {
+			img.MediumURL = medium
+		}
+	}
+}
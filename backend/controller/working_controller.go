@@ -43,6 +43,8 @@ func (wc *WorkingController) CreateWorking(c *gin.Context) {
 		return
 	}
 
+	fillWorkingImageRenditions(working.WorkingDetail)
+
 	// Create the working (and associated detail if struct is set up right)
 	if err := db.Create(&working).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -135,6 +137,7 @@ func (wc *WorkingController) UpdateWorking(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	fillWorkingImageRenditions(working.WorkingDetail)
 
 	if err := db.Save(&working).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -7,6 +7,9 @@ import (
 type ActivityImage struct {
 	gorm.Model
 	ImageURL   string `json:"image_url" valid:"required~Image URL is required,matches(^https?://[^ ]+$|^/uploads/[^ ]+$|^uploads/[^ ]+$)~Image URL must be a valid URL"`
+	// ภาพย่อที่สร้างตอนอัปโหลด (ImageURL คือภาพขนาดเต็ม) ว่างได้สำหรับรูปเก่าหรือรูปจากภายนอก
+	ThumbnailURL string `json:"thumbnail_url" valid:"optional,matches(^https?://[^ ]+$|^/uploads/[^ ]+$|^uploads/[^ ]+$)~Thumbnail URL must be a valid URL"`
+	MediumURL    string `json:"medium_url" valid:"optional,matches(^https?://[^ ]+$|^/uploads/[^ ]+$|^uploads/[^ ]+$)~Medium URL must be a valid URL"`
 
 	ActivityDetailID uint            `json:"activity_detail_id"`
 	ActivityDetail   *ActivityDetail `gorm:"foreignKey:ActivityDetailID" json:"activity_detail"`
@@ -2,7 +2,10 @@ package entity
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
@@ -16,6 +19,9 @@ type Colors struct {
 	BackgroundColor string `json:"background_color" valid:"required~Background color is required"`
 	HexValue        string `json:"hex_value"`
 
+	// UserID เจ้าของธีมส่วนตัว (nil = ธีมของระบบที่ทุกคนเลือกได้)
+	UserID *uint `json:"user_id,omitempty" gorm:"index"`
+
 	//FK
 	Portfolio []Portfolio `gorm:"foreignKey:ColorsID" json:"portfolio"`
 }
@@ -96,3 +102,105 @@ func ValidateColorTheme(primary, secondary, background string) error {
 
 	return nil
 }
+
+// WCAG 2.1 AA: primary ใช้เป็นสีหัวข้อและข้อความเน้น, secondary ใช้กับเส้น/ปุ่ม/ตัวอักษรขนาดใหญ่
+const (
+	MinTextContrast = 4.5
+	MinUIContrast   = 3.0
+)
+
+// RGBA สีที่แปลงจาก hex โดย A อยู่ในช่วง 0-1
+type RGBA struct {
+	R, G, B uint8
+	A       float64
+}
+
+// ParseHexColor แปลง #RGB, #RRGGBB หรือ #RRGGBBAA เป็น RGBA
+func ParseHexColor(color string) (RGBA, error) {
+	color = strings.TrimSpace(color)
+	if err := validateHexColorFormat(color); err != nil {
+		return RGBA{}, err
+	}
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	channel := func(s string) uint8 {
+		v, _ := strconv.ParseUint(s, 16, 8)
+		return uint8(v)
+	}
+	c := RGBA{R: channel(hex[0:2]), G: channel(hex[2:4]), B: channel(hex[4:6]), A: 1}
+	if len(hex) == 8 {
+		c.A = float64(channel(hex[6:8])) / 255
+	}
+	return c, nil
+}
+
+// Over วางสีนี้ (ที่อาจโปร่งแสง) ทับสีพื้น bg ได้สีทึบที่ตาเห็นจริง
+func (c RGBA) Over(bg RGBA) RGBA {
+	mix := func(fg, back uint8) uint8 {
+		return uint8(math.Round(float64(fg)*c.A + float64(back)*(1-c.A)))
+	}
+	return RGBA{R: mix(c.R, bg.R), G: mix(c.G, bg.G), B: mix(c.B, bg.B), A: 1}
+}
+
+// Hex สีในรูป #RRGGBB (ไม่รวม alpha)
+func (c RGBA) Hex() string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// Luminance relative luminance ตาม WCAG (ไม่คิด alpha)
+func (c RGBA) Luminance() float64 {
+	channel := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// ContrastRatioRGBA อัตราส่วน contrast ตาม WCAG ระหว่างสองสี (1-21)
+func ContrastRatioRGBA(a, b RGBA) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// ContrastRatio อัตราส่วน contrast ตาม WCAG ระหว่างสองสี hex (1-21) ไม่คิด alpha
+func ContrastRatio(a, b string) (float64, error) {
+	ca, err := ParseHexColor(a)
+	if err != nil {
+		return 0, err
+	}
+	cb, err := ParseHexColor(b)
+	if err != nil {
+		return 0, err
+	}
+	return ContrastRatioRGBA(ca, cb), nil
+}
+
+// ValidateContrast ตรวจว่าสีหลักและสีรองอ่านออกบนสีพื้นหลัง (เรียกหลัง Validate)
+func (c *Colors) ValidateContrast() error {
+	checks := []struct {
+		name  string
+		color string
+		min   float64
+	}{
+		{"Primary color", c.PrimaryColor, MinTextContrast},
+		{"Secondary color", c.SecondaryColor, MinUIContrast},
+	}
+	for _, check := range checks {
+		ratio, err := ContrastRatio(check.color, c.BackgroundColor)
+		if err != nil {
+			return err
+		}
+		if ratio < check.min {
+			return fmt.Errorf("%s contrast against background is %.2f:1, must be at least %.1f:1", check.name, math.Floor(ratio*100)/100, check.min)
+		}
+	}
+	return nil
+}
@@ -40,6 +40,9 @@ type Curriculum struct {
 	RequiredDocuments []CurriculumRequiredDocument `json:"required_documents"`
 	Skills            []CurriculumSkill            `json:"skills"`
 	CourseGroups      []CurriculumCourseGroup      `json:"course_groups"`
+
+	RecommendedTemplates []CurriculumRecommendedTemplate `json:"recommended_templates"`
+	RequiredSections     []CurriculumRequiredSection     `json:"required_sections"`
 }
 
 func (c *Curriculum) validateDates() error {
@@ -0,0 +1,16 @@
+package entity
+
+import "gorm.io/gorm"
+
+// CurriculumRecommendedTemplate template ที่หลักสูตรแนะนำให้ใช้ทำ portfolio
+type CurriculumRecommendedTemplate struct {
+	gorm.Model
+	CurriculumID uint        `json:"curriculum_id" gorm:"uniqueIndex:idx_curriculum_template"`
+	Curriculum   *Curriculum `gorm:"foreignKey:CurriculumID" json:"curriculum,omitempty"`
+
+	TemplatesID uint       `json:"templates_id" gorm:"uniqueIndex:idx_curriculum_template"`
+	Templates   *Templates `gorm:"foreignKey:TemplatesID" json:"templates,omitempty"`
+
+	OrderIndex int    `json:"order_index"`
+	Note       string `json:"note"`
+}
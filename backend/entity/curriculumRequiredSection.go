@@ -0,0 +1,15 @@
+package entity
+
+import "gorm.io/gorm"
+
+// CurriculumRequiredSection section ที่หลักสูตรกำหนดให้ portfolio ต้องมี (เทียบกับ PortfolioSection.SectionPortKey)
+type CurriculumRequiredSection struct {
+	gorm.Model
+	CurriculumID uint        `json:"curriculum_id" gorm:"uniqueIndex:idx_curriculum_section_key"`
+	Curriculum   *Curriculum `gorm:"foreignKey:CurriculumID" json:"curriculum,omitempty"`
+
+	SectionPortKey string `json:"section_port_key" gorm:"uniqueIndex:idx_curriculum_section_key"`
+	Title          string `json:"title"`
+	Note           string `json:"note"`
+	OrderIndex     int    `json:"order_index"`
+}
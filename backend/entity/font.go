@@ -16,6 +16,8 @@ type Font struct {
 	FontVariant  string `json:"font_variant" valid:"optional,stringlength(0|50)~Font variant must not exceed 50 characters"`
 	FontURL      string `json:"font_url" valid:"optional,url~Font URL must be a valid URL"`
 	IsActive     bool   `json:"is_active"`
+	// FontFile ชื่อไฟล์ฟอนต์ที่ admin อัปโหลดไว้ใน local storage (FontURL จะชี้ไปที่ /uploads/<FontFile>)
+	FontFile string `json:"font_file"`
 
 	//FK
 	Portfolio []Portfolio `gorm:"foreignKey:FontID" json:"portfolio"`
@@ -62,7 +64,7 @@ func (f *Font) Validate() error {
 	}
 
 	// Validate font URL if provided
-	if f.FontURL != "" {
+	if f.FontURL != "" && !(f.FontFile != "" && strings.HasPrefix(f.FontURL, "/uploads/")) {
 		if !govalidator.IsURL(f.FontURL) {
 			return errors.New("font URL must be a valid URL")
 		}
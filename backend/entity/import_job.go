@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+// ImportJob เก็บสถานะของงาน import ที่รันอยู่เบื้องหลัง (เช่น นำเข้ารายชื่อโรงเรียน)
+type ImportJob struct {
+	gorm.Model
+	JobType   string          `json:"job_type" gorm:"size:50;index;not null"`
+	FileName  string          `json:"file_name" gorm:"size:255"`
+	Status    ImportJobStatus `json:"status" gorm:"size:20;index;default:'pending'"`
+	TotalRows int             `json:"total_rows"`
+	Processed int             `json:"processed"`
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Skipped   int             `json:"skipped"`
+
+	// รายการปัญหาระดับแถว (แถวซ้ำ, ประเภทโรงเรียนไม่ถูกต้อง ฯลฯ)
+	RowErrors    datatypes.JSON `json:"row_errors"`
+	ErrorMessage string         `json:"error_message,omitempty" gorm:"type:text"`
+
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+
+	UserID uint  `json:"user_id" gorm:"index"`
+	User   *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// Progress คืนค่าความคืบหน้าเป็นเปอร์เซ็นต์ (0-100)
+func (j *ImportJob) Progress() int {
+	if j.TotalRows <= 0 {
+		if j.Status == ImportJobCompleted {
+			return 100
+		}
+		return 0
+	}
+	return j.Processed * 100 / j.TotalRows
+}
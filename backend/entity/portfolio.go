@@ -19,8 +19,16 @@ type Portfolio struct {
 	CoverImage     string         `json:"cover_image" valid:"optional"`
 	ContentDescription string     `json:"content_description"`
 
+	// เพิ่มขึ้นทุกครั้งที่ portfolio / section / block ถูกแก้ไข ใช้ตรวจการแก้ไขชนกัน (ETag / If-Match)
+	Revision uint `json:"revision" gorm:"not null;default:1"`
+
+	// portfolio ต้นฉบับที่ถูก clone มา (variant สำหรับหลักสูตรอื่น)
+	ClonedFromID *uint `json:"cloned_from_id"`
+
 	// FK
 	TemplateID *uint     `json:"template_id"`
+	// version ของ template ที่ใช้สร้าง (หรือ rebase ล่าสุด)
+	TemplateVersion uint `json:"template_version"`
 	Template   Templates `gorm:"foreignKey:TemplateID" json:"template"`
 	UserID     uint      `json:"user_id" valid:"required~User ID is required"`
 	User       User      `gorm:"foreignKey:UserID" json:"user"`
@@ -33,6 +41,7 @@ type Portfolio struct {
 	PortfolioSections []PortfolioSection `gorm:"foreignKey:PortfolioID" json:"portfolio_sections"`
 	PortfolioWorks    []PortfolioWork    `gorm:"foreignKey:PortfolioID" json:"portfolio_works"`
 	PortfolioSubmission []PortfolioSubmission `gorm:"foreignKey:PortfolioID" json:"Portfolio_submission"`
+	Curricula           []PortfolioCurriculum `gorm:"foreignKey:PortfolioID" json:"curricula,omitempty"`
 }
 
 // Validate validates the Portfolio struct
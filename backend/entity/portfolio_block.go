@@ -17,9 +17,30 @@ type PortfolioBlock struct {
 	BlockStyle    datatypes.JSON `json:"block_style"`
 	Content       datatypes.JSON `json:"content"`
 
+	// block ของ template ที่ block นี้ถูกสร้างมา (ใช้ตอน rebase)
+	TemplateBlockID *uint `json:"template_block_id"`
+
 	// FK
 	PortfolioSectionID uint             `json:"portfolio_section_id" valid:"required~Portfolio section ID is required"`
 	PortfolioSection   PortfolioSection `gorm:"foreignKey:PortfolioSectionID" json:"portfolio_section"`
+
+	// ข้อมูลล่าสุดของสิ่งที่ block อ้างถึง (กิจกรรม/ผลงาน/โปรไฟล์/ผลการเรียน) เติมตอนอ่าน ไม่ได้เก็บในฐานข้อมูล
+	Source *PortfolioBlockSource `gorm:"-" json:"source,omitempty"`
+}
+
+// สถานะของข้อมูลต้นทางที่ block อ้างถึง
+const (
+	BlockSourceOK      = "ok"
+	BlockSourceDeleted = "deleted" // ต้นทางถูกลบ (หรือไม่ใช่ของเจ้าของ portfolio)
+	BlockSourceMissing = "missing" // เจ้าของยังไม่ได้กรอกข้อมูล (เช่น ผลการเรียน)
+)
+
+// PortfolioBlockSource ข้อมูลต้นทางของ block แบบอ้างอิง
+type PortfolioBlockSource struct {
+	Type   string      `json:"type"`
+	ID     uint        `json:"id,omitempty"`
+	Status string      `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
 }
 
 // BlockStyleData represents the structure for block styling
@@ -67,6 +88,9 @@ type ImageContentData struct {
 // Valid block types
 var ValidBlockTypes = []string{
 	"activity",
+	"working",
+	"profile",
+	"academic_score",
 	"text",
 	"image",
 	"gallery",
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ประเภทของข้อมูลที่ถูกบันทึกใน PortfolioChange
+const (
+	PortfolioChangeSection = "section"
+	PortfolioChangeBlock   = "block"
+)
+
+// PortfolioChange บันทึกการแก้ไข section/block ของ portfolio แบบเพิ่มต่อท้ายอย่างเดียว
+// Before/After คือสถานะของแถวก่อนและหลังแก้ (null = ยังไม่มี/ถูกลบ) ใช้สำหรับ undo และย้อนกลับ
+type PortfolioChange struct {
+	gorm.Model
+	PortfolioID uint   `json:"portfolio_id" gorm:"index"`
+	UserID      uint   `json:"user_id"`
+	Revision    uint   `json:"revision"`
+	Action      string `json:"action"`
+	EntityType  string `json:"entity_type"`
+	EntityID    uint   `json:"entity_id"`
+
+	Before datatypes.JSON `json:"before"`
+	After  datatypes.JSON `json:"after"`
+
+	// รายการนี้เป็นการย้อนการแก้ไขของ change ไหน (undo / restore)
+	RevertsChangeID *uint `json:"reverts_change_id"`
+}
+
+// PortfolioRestorePoint จุดคืนค่าที่ผู้ใช้ตั้งชื่อไว้ เก็บ snapshot ของทุก section/block ในขณะนั้น
+type PortfolioRestorePoint struct {
+	gorm.Model
+	PortfolioID uint           `json:"portfolio_id" gorm:"index"`
+	UserID      uint           `json:"user_id"`
+	Name        string         `json:"name" valid:"required~Name is required,stringlength(1|100)~Name must be between 1 and 100 characters"`
+	Revision    uint           `json:"revision"`
+	Snapshot    datatypes.JSON `json:"-"`
+}
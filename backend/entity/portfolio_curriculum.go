@@ -0,0 +1,13 @@
+package entity
+
+import "gorm.io/gorm"
+
+// PortfolioCurriculum portfolio ที่นักเรียนเลือกใช้ยื่นกับหลักสูตรหนึ่ง
+// หนึ่งหลักสูตรมีได้หนึ่ง portfolio ต่อนักเรียน แต่ portfolio เดียวใช้กับหลายหลักสูตรได้
+type PortfolioCurriculum struct {
+	gorm.Model
+	UserID       uint        `json:"user_id" gorm:"not null;uniqueIndex:idx_portfolio_curriculum_user"`
+	CurriculumID uint        `json:"curriculum_id" gorm:"not null;uniqueIndex:idx_portfolio_curriculum_user"`
+	Curriculum   *Curriculum `gorm:"foreignKey:CurriculumID" json:"curriculum,omitempty"`
+	PortfolioID  uint        `json:"portfolio_id" gorm:"not null;index"`
+}
@@ -18,6 +18,9 @@ type PortfolioSection struct {
 	SectionOrder   int            `json:"section_order" valid:"range(0|100)~Section order must be between 0 and 100"`
 	SectionStyle   datatypes.JSON `json:"section_style"`
 
+	// section ของ template ที่ section นี้ถูกสร้างมา (ใช้ตอน rebase)
+	TemplateSectionID *uint `json:"template_section_id"`
+
 	// FK
 	PortfolioID uint      `json:"portfolio_id" valid:"required~Portfolio ID is required"`
 	Portfolio   Portfolio `gorm:"foreignKey:PortfolioID" json:"portfolio"`
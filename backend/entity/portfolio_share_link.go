@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PortfolioShareLink ลิงก์สาธารณะแบบอ่านอย่างเดียวของ portfolio (/p/:token)
+// ใช้ได้จนกว่าจะหมดอายุหรือถูกเพิกถอน ตั้งรหัสผ่านเพิ่มได้
+type PortfolioShareLink struct {
+	gorm.Model
+	PortfolioID  uint       `json:"portfolio_id" gorm:"index"`
+	CreatedByID  uint       `json:"created_by_id"`
+	Token        string     `json:"token" gorm:"uniqueIndex;size:64"`
+	Label        string     `json:"label" valid:"optional,stringlength(0|100)~Label must not exceed 100 characters"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"has_password"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ViewCount    int        `json:"view_count" gorm:"not null;default:0"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+
+	// นับรหัสผ่านผิดติดกัน ครบกำหนดแล้วล็อกลิงก์ไว้ชั่วคราวถึง LockedUntil
+	FailedAttempts int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil    *time.Time `json:"-"`
+}
+
+// IsActive ลิงก์ยังไม่ถูกเพิกถอนและยังไม่หมดอายุ ณ เวลา now
+func (l *PortfolioShareLink) IsActive(now time.Time) bool {
+	if l.RevokedAt != nil {
+		return false
+	}
+	return l.ExpiresAt == nil || now.Before(*l.ExpiresAt)
+}
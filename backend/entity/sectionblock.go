@@ -17,7 +17,7 @@ type SectionBlock struct {
 	OrderIndex       int             `json:"order_index"`
 
 	//Layout datatypes.JSON `json:"layout"`
-	LayoutType 		string 			`json:"layout_type" gorm: "default: 'grid'"` 	//grid or flex
+	LayoutType 		string 			`json:"layout_type" gorm:"default:grid"` 	//grid or flex
 	Position  		datatypes.JSON 	`json:"position"` 								//สำหรับเก็บตำแหน่ง top, left, right, bottom
 	FlexSettings 	datatypes.JSON 	`json:"flex_settings"`							//สำหรับเก็บค่าต่างๆของ flex เช่น direction, align-items, justify-content, etc.
 	GridSettings 	datatypes.JSON 	`json:"grid_settings"`							//สำหรับเก็บค่าต่างๆของ grid เช่น columns, rows, gap, etc.	
@@ -11,4 +11,7 @@ type Selection struct {
 	Curriculum   *Curriculum `gorm:"foreignKey:CurriculumID" json:"curriculum"`
 
 	IsNotified   bool        `json:"is_notified" gorm:"default:false"`
-}
\ No newline at end of file
+
+	// ลำดับความสำคัญภายในรอบเดียวกัน (1 = อันดับแรก)
+	Priority     int         `json:"priority" gorm:"default:0"`
+}
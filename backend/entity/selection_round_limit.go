@@ -0,0 +1,12 @@
+package entity
+
+import "gorm.io/gorm"
+
+// SelectionRoundLimit จำนวนหลักสูตรสูงสุดที่นักเรียนเลือกได้ในแต่ละรอบ (ปีการศึกษา + ชื่อรอบ)
+// รอบที่ไม่ได้ตั้งค่าไว้จะไม่จำกัดจำนวน
+type SelectionRoundLimit struct {
+	gorm.Model
+	AcademicYear string `json:"academic_year" gorm:"uniqueIndex:idx_selection_round_limit" valid:"required~Academic year is required"`
+	RoundName    string `json:"round_name" gorm:"uniqueIndex:idx_selection_round_limit" valid:"required~Round name is required"`
+	MaxChoices   int    `json:"max_choices" valid:"required~Max choices is required,range(1|50)~Max choices must be between 1 and 50"`
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"errors"
+
+	"github.com/asaskevich/govalidator"
+	"gorm.io/gorm"
+)
+
+// TemplateRating คะแนนดาว (1-5) ที่นักเรียนให้ template หนึ่งคนต่อหนึ่ง template
+type TemplateRating struct {
+	gorm.Model
+	Stars   int    `json:"stars" valid:"required~Stars is required,range(1|5)~Stars must be between 1 and 5"`
+	Comment string `json:"comment" valid:"optional,stringlength(0|500)~Comment must not exceed 500 characters"`
+
+	TemplatesID uint       `json:"templates_id" gorm:"uniqueIndex:idx_template_rating_user"`
+	Templates   *Templates `gorm:"foreignKey:TemplatesID" json:"templates,omitempty"`
+	UserID      uint       `json:"user_id" gorm:"uniqueIndex:idx_template_rating_user"`
+	User        *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// Validate ตรวจคะแนนและความยาวความคิดเห็น
+func (r TemplateRating) Validate() error {
+	if ok, err := govalidator.ValidateStruct(r); err != nil {
+		return err
+	} else if !ok {
+		return errors.New("validation failed")
+	}
+	return nil
+}
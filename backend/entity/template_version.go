@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TemplateVersion snapshot ของ template ทั้งชุด (section/block) ณ เวลาที่ถูกแก้ไข
+// Snapshot ใช้รูปแบบเดียวกับ manifest ของ template package
+type TemplateVersion struct {
+	gorm.Model
+	TemplatesID uint           `json:"templates_id" gorm:"uniqueIndex:idx_template_version"`
+	Version     uint           `json:"version" gorm:"uniqueIndex:idx_template_version"`
+	Snapshot    datatypes.JSON `json:"snapshot"`
+}
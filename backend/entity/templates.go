@@ -14,6 +14,9 @@ type Templates struct {
 	Description  string `json:"description" valid:"optional,stringlength(0|100)~Description must not exceed 100 characters "`
 	Thumbnail    string `gorm:"type:text" json:"thumbnail" valid:"optional,url~Thumbnail must be a valid URL"`
 
+	// version ล่าสุดใน TemplateVersion (0 = ยังไม่เคยบันทึก version)
+	CurrentVersion uint `json:"current_version" gorm:"not null;default:0"`
+
 	//FK
 	Portfolio            []Portfolio           `gorm:"foreignKey:TemplateID" json:"portfolio"`
 	TemplateSectionLinks []TemplateSectionLink `gorm:"foreignKey:TemplatesID" json:"template_section_links"`
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UploadedFile ไฟล์หนึ่งไฟล์ใน ./uploads ของผู้ใช้หนึ่งคน
+// ชื่อไฟล์ที่เก็บ (StoredName) มาจาก SHA-256 ของไฟล์ที่อัปโหลด ไฟล์เนื้อหาเดียวกันจึงเก็บครั้งเดียว
+// ผู้ใช้หลายคนอัปโหลดไฟล์เดียวกันได้ (คนละแถว ชี้ไปที่ไฟล์เดียวกัน)
+type UploadedFile struct {
+	gorm.Model
+	// OwnerID ผู้อัปโหลด (nil = ไฟล์เก่าที่ไม่รู้เจ้าของ)
+	OwnerID *uint  `json:"owner_id" gorm:"uniqueIndex:idx_uploaded_file_owner_sha"`
+	Owner   *User  `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	SHA256  string `json:"sha256" gorm:"size:64;uniqueIndex:idx_uploaded_file_owner_sha"`
+
+	StoredName   string `json:"stored_name" gorm:"index"`
+	URL          string `json:"url"`
+	OriginalName string `json:"original_name"`
+	Size         int64  `json:"size"`
+	MimeType     string `json:"mime_type"`
+
+	// ReferenceCount จำนวนที่พบ URL ของไฟล์ในข้อมูลอื่น (คำนวณใหม่ทุกครั้งที่ GC ทำงาน)
+	ReferenceCount int `json:"reference_count"`
+	// UnreferencedSince เวลาที่เริ่มไม่มีใครอ้างถึง (nil = ยังถูกใช้อยู่) ใช้นับ grace period ก่อนลบ
+	UnreferencedSince *time.Time `json:"unreferenced_since"`
+}
@@ -4,6 +4,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// ลำดับของ UserTypes ตาม seed (Student, Teacher, Admin)
+const (
+	UserTypeStudent uint = 1
+	UserTypeTeacher uint = 2
+	UserTypeAdmin   uint = 3
+)
+
 type UserTypes struct {
 	gorm.Model
 	TypeName string `json:"type_name"`
@@ -7,6 +7,9 @@ import (
 type WorkingImage struct {
 	gorm.Model
 	WorkingImageURL string `json:"working_image_url" valid:"required~Image URL is required,url~Image URL must be a valid URL"`
+	// ภาพย่อที่สร้างตอนอัปโหลด (WorkingImageURL คือภาพขนาดเต็ม) ว่างได้สำหรับรูปเก่าหรือรูปจากภายนอก
+	ThumbnailURL string `json:"thumbnail_url" valid:"optional,matches(^https?://[^ ]+$|^/uploads/[^ ]+$|^uploads/[^ ]+$)~Thumbnail URL must be a valid URL"`
+	MediumURL    string `json:"medium_url" valid:"optional,matches(^https?://[^ ]+$|^/uploads/[^ ]+$|^uploads/[^ ]+$)~Medium URL must be a valid URL"`
 
 	WorkingDetailID uint `json:"working_detail_id"`
 	WorkingDetail *WorkingDetail `gorm:"foreignKey:WorkingDetailID" json:"working_detail"`
@@ -13,16 +13,17 @@ import (
 func main() {
 	loadEnv()
 
-	// Initialize Local Storage
-	if err := services.InitLocalStorage(); err != nil {
-		log.Printf("Warning: Local Storage not initialized: %v", err)
+	// Initialize Storage (STORAGE_BACKEND: local, s3 หรือ azure)
+	if err := services.InitStorage(); err != nil {
+		log.Printf("Warning: Storage not initialized: %v", err)
 		log.Println("File uploads will not work!")
 	} else {
-		log.Println("Local Storage initialized successfully")
+		log.Println("Storage initialized successfully")
 	}
 
 	services.StartNotificationScheduler()
 	config.ConnectionDatabase()
+	services.StartUploadGCScheduler()
 
 	r := router.SetupRoutes()
 	port := resolvePort()
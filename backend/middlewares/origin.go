@@ -0,0 +1,21 @@
+package middlewares
+
+import "strings"
+
+// IsAllowedOrigin รายชื่อ Origin ที่อนุญาตให้เรียก API (ใช้ทั้ง CORS และตรวจ Origin ของ WebSocket)
+func IsAllowedOrigin(origin string) bool {
+	// 1. อนุญาต Localhost (สำหรับ Dev)
+	if strings.HasPrefix(origin, "http://localhost") || strings.HasPrefix(origin, "http://127.0.0.1") {
+		return true
+	}
+	// 2. อนุญาต Local Network (สำหรับ Dev ผ่านวงแลน)
+	if strings.HasPrefix(origin, "http://192.168") || strings.HasPrefix(origin, "http://10.") || strings.HasPrefix(origin, "http://172.") {
+		return true
+	}
+	// 3. ✅✅✅ อนุญาตโดเมนจริง (Production) ✅✅✅
+	if origin == "https://sutportfolio.online" || origin == "https://www.sutportfolio.online" {
+		return true
+	}
+
+	return false // ❌ บล็อกเว็บอื่นๆ ที่ไม่ได้ระบุ
+}
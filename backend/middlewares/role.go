@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// RequireAccountTypes อนุญาตเฉพาะผู้ใช้ที่มี AccountTypeID ตรงกับที่กำหนด (ต้องใช้หลัง Authorization)
+// และเก็บ account_type_id ไว้ใน context ให้ handler ใช้ต่อ
+func RequireAccountTypes(types ...uint) gin.HandlerFunc {
+	db := config.GetDB()
+
+	return func(c *gin.Context) {
+		userIDVal, exists := c.Get("user_id")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+			return
+		}
+
+		userID, ok := userIDVal.(uint)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in context"})
+			return
+		}
+
+		var user entity.User
+		if err := db.Select("id", "account_type_id").First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, t := range types {
+			if user.AccountTypeID == t {
+				c.Set("account_type_id", user.AccountTypeID)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+	}
+}
+
+// RequireAdmin ใช้กับ route ที่เฉพาะแอดมินเท่านั้น
+func RequireAdmin() gin.HandlerFunc {
+	return RequireAccountTypes(entity.UserTypeAdmin)
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/middlewares"
+	"gorm.io/gorm"
+)
+
+func RegisterAnalyticsRoutes(r *gin.Engine, db *gorm.DB) {
+	c := controller.ReviewAnalyticsController{DB: db}
+	group := r.Group("/api/admin/analytics")
+	group.Use(middlewares.Authorization(), middlewares.RequireAdmin())
+	{
+		group.GET("/reviews", c.GetReviewAnalytics)
+	}
+}
@@ -3,9 +3,17 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/middlewares"
 )
 
 func ColorsRoutes(r *gin.Engine) {
 	r.GET("/colors", controller.GetAllColors)
 	r.GET("/colors/:id", controller.GetColorByID)
-}
\ No newline at end of file
+
+	// ธีมสีส่วนตัวของผู้ใช้
+	mine := r.Group("/colors", middlewares.Authorization())
+	mine.GET("/mine", controller.GetMyColors)
+	mine.POST("", controller.CreateColorTheme)
+	mine.PUT("/:id", controller.UpdateColorTheme)
+	mine.DELETE("/:id", controller.DeleteColorTheme)
+}
@@ -3,10 +3,16 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/middlewares"
 )
 
 func FontRoutes(r *gin.Engine) {
 	r.GET("/fonts", controller.GetAllFonts)
 	r.GET("/fonts/active", controller.GetActiveFonts)
 	r.GET("/fonts/:id", controller.GetFontByID)
-}
\ No newline at end of file
+
+	admin := r.Group("/fonts", middlewares.Authorization(), middlewares.RequireAdmin())
+	admin.POST("/upload", controller.UploadFont)
+	admin.PATCH("/:id", controller.UpdateFont)
+	admin.DELETE("/:id", controller.DeleteFont)
+}
@@ -15,10 +15,34 @@ func PortfolioRoutes(r *gin.RouterGroup) {
 		group.GET("/my", controller.GetMyPortfolio)
 		group.GET("", controller.GetPortfolioByStatusActive)
 		group.GET("/:id", controller.GetPortfolioById) 
+		group.GET("/:id/export.pdf", controller.ExportPortfolioPDF)
+		group.GET("/:id/checklist", controller.GetPortfolioChecklist)
+		group.GET("/:id/accessibility", controller.GetPortfolioAccessibility)
+		group.PATCH("/:id/batch", controller.PatchPortfolioBatch)
+
+		// History / Undo / Restore points
+		group.GET("/:id/history", controller.GetPortfolioHistory)
+		group.POST("/:id/undo", controller.UndoPortfolioChange)
+		group.POST("/:id/restore", controller.RestorePortfolio)
+		group.GET("/:id/restore-points", controller.ListPortfolioRestorePoints)
+		group.POST("/:id/restore-points", controller.CreatePortfolioRestorePoint)
+		group.DELETE("/:id/restore-points/:pointId", controller.DeletePortfolioRestorePoint)
+
+		// Variants ต่อหลักสูตร
+		group.POST("/:id/clone", controller.ClonePortfolio)
+		group.PUT("/:id/curricula/:curriculumId", controller.DesignatePortfolioCurriculum)
+		group.DELETE("/:id/curricula/:curriculumId", controller.RemovePortfolioCurriculum)
+
+		// Share links (ลิงก์สาธารณะแบบอ่านอย่างเดียว)
+		group.GET("/:id/shares", controller.ListPortfolioShareLinks)
+		group.POST("/:id/shares", controller.CreatePortfolioShareLink)
+		group.DELETE("/:id/shares/:shareId", controller.RevokePortfolioShareLink)
 		
 		// Template
 		group.POST("/template", controller.CreateTemplate)
 		group.POST("/use-template/:id", controller.UseTemplate)
+		group.GET("/:id/template-rebase", controller.PreviewTemplateRebase)
+		group.POST("/:id/template-rebase", controller.ApplyTemplateRebase)
 		
 		// Section
 		group.POST("/section", controller.CreatePortfolioSection)
@@ -26,6 +50,7 @@ func PortfolioRoutes(r *gin.RouterGroup) {
 		group.DELETE("/section/:id", controller.DeletePortfolioSection) // ✅ NEW: Delete Section
 		
 		// ✅ Block CRUD
+		group.GET("/block-types", controller.GetBlockTypes)
 		group.POST("/block", controller.CreatePortfolioBlock)
 		group.PATCH("/block/:id", controller.UpdatePortfolioBlock) // ✅ NEW
 		group.DELETE("/block/:id", controller.DeletePortfolioBlock) // ✅ NEW
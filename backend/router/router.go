@@ -42,31 +42,22 @@ func SetupRoutes() *gin.Engine {
 
 	// --- 🔒 CORS Config (Production Ready) ---
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOriginFunc = func(origin string) bool {
-		// 1. อนุญาต Localhost (สำหรับ Dev)
-		if strings.HasPrefix(origin, "http://localhost") || strings.HasPrefix(origin, "http://127.0.0.1") {
-			return true
-		}
-		// 2. อนุญาต Local Network (สำหรับ Dev ผ่านวงแลน)
-		if strings.HasPrefix(origin, "http://192.168") || strings.HasPrefix(origin, "http://10.") || strings.HasPrefix(origin, "http://172.") {
-			return true
-		}
-		// 3. ✅✅✅ อนุญาตโดเมนจริง (Production) ✅✅✅
-		if origin == "https://sutportfolio.online" || origin == "https://www.sutportfolio.online" {
-			return true
-		}
-		
-		return false // ❌ บล็อกเว็บอื่นๆ ที่ไม่ได้ระบุ
-	}
+	corsConfig.AllowOriginFunc = middlewares.IsAllowedOrigin
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept", "User-Agent", "Cache-Control", "Pragma"}
-	corsConfig.ExposeHeaders = []string{"Content-Length"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept", "User-Agent", "Cache-Control", "Pragma", "If-Match", "X-Room-Session", "X-Share-Password"}
+	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Disposition", "X-Page-Count", "ETag"}
 	corsConfig.AllowCredentials = true
 	corsConfig.MaxAge = 12 * time.Hour
 	r.Use(cors.New(corsConfig))
 
-	// Serve static files
-	r.Static("/uploads", "./uploads")
+	// Serve static files (S3/Azure redirect ไปที่ signed URL แทน)
+	uploadController := controller.NewUploadController()
+	if services.LocalStorage != nil || services.Storage == nil {
+		r.Static("/uploads", "./uploads")
+	} else {
+		r.GET("/uploads/:filename", uploadController.ServeUpload)
+		r.HEAD("/uploads/:filename", uploadController.ServeUpload)
+	}
 
 	// --- Init Services & Controllers ---
 	db := config.GetDB()
@@ -92,13 +83,13 @@ func SetupRoutes() *gin.Engine {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
 
-	// Upload Route (Public)
-	uploadController := controller.NewUploadController()
-	r.POST("/upload", uploadController.UploadFile)
-	r.DELETE("/upload/:filename", uploadController.DeleteFile)
-
 	// WebSocket Route (Public)
 	r.GET("/ws", controller.WebSocketHandler)
+	// ห้องแก้ไข portfolio (ตรวจ token จาก Sec-WebSocket-Protocol และ Origin เอง)
+	r.GET("/ws/portfolio/:id", controller.PortfolioRoomHandler)
+
+	// Shared Portfolio (Public, อ่านอย่างเดียวผ่าน token)
+	r.GET("/p/:token", controller.GetSharedPortfolio)
 	
 	// Test Notification (Dev Only - ลบออกได้ตอนขึ้น Production จริงๆ หรือจะเก็บไว้เทสก็ได้)
 	r.GET("/test-noti", func(c *gin.Context) {
@@ -133,6 +124,15 @@ func SetupRoutes() *gin.Engine {
 	protected.PUT("/users/me/ged-score", profileController.UpsertGEDScore)
 	protected.PUT("/users/me/language-scores", profileController.ReplaceLanguageScores)
 
+	// Upload Route (ต้อง Login เพื่อบันทึกเจ้าของไฟล์, ใช้ได้ระหว่าง onboarding)
+	protected.POST("/upload", uploadController.UploadFile)
+	protected.DELETE("/upload/:filename", uploadController.DeleteFile)
+
+	// หลักสูตรที่เลือกของตัวเอง (ผู้ใช้มาจาก token) และการจัดลำดับ
+	protected.POST("/selections", selectionController.ToggleSelection)
+	protected.GET("/selections", selectionController.GetMySelections)
+	protected.PUT("/selections/priority", selectionController.UpdatePriorities)
+
 	// --- Onboarded Routes (ต้องผ่านการ Onboard) ---
 	protectedOnboarded := protected.Group("")
 	protectedOnboarded.Use(middlewares.RequireOnboarding())
@@ -152,8 +152,6 @@ func SetupRoutes() *gin.Engine {
 	courseGroupController.RegisterRoutes(r, protectedOnboarded)
 
 	// Selection & Notification Routes
-	r.POST("/selections", selectionController.ToggleSelection)
-	r.GET("/selections", selectionController.GetMySelections)
 	r.POST("/selections/notify", selectionController.ToggleNotification)
 	r.GET("/notifications", selectionController.GetNotifications)
 	r.PATCH("/notifications/:id/read", selectionController.MarkAsRead)
@@ -164,6 +162,16 @@ func SetupRoutes() *gin.Engine {
 		adminProtected.GET("/users/:id/profile", profileController.GetUserProfile)
 		// ✅ ย้าย Route สถิติมาไว้ตรงนี้ เพื่อความปลอดภัย
 		adminProtected.GET("/curricula/stats", curriculumController.GetSelectionStats)
+		adminProtected.GET("/curricula/stats/export", middlewares.RequireAdmin(), curriculumController.ExportSelectionStats)
+
+		// จำนวนหลักสูตรที่นักเรียนเลือกได้ต่อรอบ
+		selectionLimits := adminProtected.Group("/selection-limits", middlewares.RequireAdmin())
+		selectionLimits.GET("", selectionController.ListRoundLimits)
+		selectionLimits.PUT("", selectionController.UpsertRoundLimit)
+		selectionLimits.DELETE("/:id", selectionController.DeleteRoundLimit)
+
+		// เก็บกวาดไฟล์อัปโหลดที่ไม่มีใครใช้
+		adminProtected.POST("/uploads/gc", middlewares.RequireAdmin(), uploadController.CollectUploadGarbage)
 	}
 
 	// Education reference management (admin)
@@ -190,6 +198,10 @@ func SetupRoutes() *gin.Engine {
 	RegisterScoreCriteriaRoutes(r, db)
 	RegisterScorecardRoutes(r, db)
 
+	// Admin analytics
+	RegisterAnalyticsRoutes(r, db)
+	RegisterBlockSchemaRoutes(r, db)
+
 	// Announcement & Others
 	AnnouncementRouter(r)
 	CetagoryRouter(r)
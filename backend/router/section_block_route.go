@@ -2,6 +2,7 @@ package router
 
 import(
 	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/middlewares"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,4 +11,8 @@ func SectionBlockRoutes(router *gin.Engine) {
 	// router.POST("/section-blocks", controller.CreateSectionBlock)
 	// router.GET("/section-blocks", controller.GetSectionBlocks)
 	router.GET("/sections/:id/section-blocks", controller.GetSectionBlocksBySectionID)
+
+	designer := router.Group("", middlewares.Authorization(), middlewares.RequireAdmin())
+	designer.PATCH("/section-blocks/:id", controller.UpdateSectionBlock)
+	designer.DELETE("/section-blocks/:id", controller.DeleteSectionBlock)
 }
\ No newline at end of file
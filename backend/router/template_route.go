@@ -3,12 +3,27 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/middlewares"
 )
 
 func TemplateRoutes(router *gin.Engine) {
 	router.GET("/templates", controller.GetTemplates)
+	router.GET("/templates/gallery", controller.GetTemplateGallery)
 	router.GET("/templates/:id", controller.GetTemplateByID)
 	router.POST("/templates", controller.CreateTemplate)
 	router.PUT("/templates/:id", controller.UpdateTemplate)
 	router.DELETE("/templates/:id", controller.DeleteTemplate)
+	router.GET("/templates/:id/versions", controller.GetTemplateVersions)
+	router.GET("/templates/:id/versions/:version", controller.GetTemplateVersion)
+
+	// คะแนนดาวจากนักเรียน (ให้คะแนนได้เฉพาะบัญชีนักเรียน)
+	router.GET("/templates/:id/ratings", controller.GetTemplateRatings)
+	router.GET("/templates/:id/rating", middlewares.Authorization(), controller.GetMyTemplateRating)
+	router.PUT("/templates/:id/rating", middlewares.Authorization(), middlewares.RequireAccountTypes(entity.UserTypeStudent), controller.RateTemplate)
+	router.DELETE("/templates/:id/rating", middlewares.Authorization(), controller.DeleteTemplateRating)
+
+	// Template package (zip) สำหรับย้าย template ข้ามระบบ
+	router.GET("/templates/:id/export", controller.ExportTemplatePackage)
+	router.POST("/templates/import", middlewares.Authorization(), middlewares.RequireAdmin(), controller.ImportTemplatePackage)
 }
@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/middlewares"
 	"github.com/gin-gonic/gin"
 )
 
@@ -9,4 +10,10 @@ func TemplateBlockRoutes(router *gin.Engine) {
 
     // --- ROUTER ---
     router.GET("/template-blocks", controller.GetTemplateBlocks)
+    router.GET("/template-blocks/:id", controller.GetTemplateBlockByID)
+
+    designer := router.Group("", middlewares.Authorization(), middlewares.RequireAdmin())
+    designer.POST("/template-blocks", controller.CreateTemplateBlock)
+    designer.PUT("/template-blocks/:id", controller.UpdateTemplateBlock)
+    designer.DELETE("/template-blocks/:id", controller.DeleteTemplateBlock)
 }	
\ No newline at end of file
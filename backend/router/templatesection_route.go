@@ -1,6 +1,7 @@
 package router
 
 import "github.com/sut68/team14/backend/controller"
+import "github.com/sut68/team14/backend/middlewares"
 import "github.com/gin-gonic/gin"
 
 func TemplateSectionsRoutes(router *gin.Engine) {
@@ -8,4 +9,13 @@ func TemplateSectionsRoutes(router *gin.Engine) {
     router.POST("/sections", controller.CreateSection)
     router.GET("/template_sections", controller.GetSections)
 	router.GET("/sections/:id", controller.GetSectionByID)
+	router.GET("/sections/:id/usage", controller.GetSectionUsage)
+	router.GET("/sections/:id/validate", controller.ValidateSection)
+
+	// จัดการ section สำหรับผู้ออกแบบ template (admin)
+	designer := router.Group("", middlewares.Authorization(), middlewares.RequireAdmin())
+	designer.PUT("/sections/:id", controller.UpdateSection)
+	designer.DELETE("/sections/:id", controller.DeleteSection)
+	designer.POST("/sections/:id/section-blocks", controller.AddSectionBlock)
+	designer.PUT("/sections/:id/section-blocks/order", controller.ReorderSectionBlocks)
 }
\ No newline at end of file
@@ -4,14 +4,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
 )
 
 type AzureStorageService struct {
@@ -23,11 +27,23 @@ type AzureStorageService struct {
 var AzureStorage *AzureStorageService
 
 func InitAzureStorage() error {
+	azure, err := NewAzureStorageFromEnv()
+	if err != nil {
+		return err
+	}
+	AzureStorage = azure
+	Storage = azure
+	return nil
+}
+
+// NewAzureStorageFromEnv เก็บไฟล์ใน container AZURE_STORAGE_CONTAINER (ค่าเริ่มต้น uploads)
+// URL ของไฟล์จึงลงท้ายด้วย /uploads/<name> เหมือน local
+func NewAzureStorageFromEnv() (*AzureStorageService, error) {
 	connectionString := os.Getenv("AZURE_STORAGE_CONNECTION_STRING")
 	containerName := os.Getenv("AZURE_STORAGE_CONTAINER")
 
 	if connectionString == "" {
-		return fmt.Errorf("AZURE_STORAGE_CONNECTION_STRING is not set")
+		return nil, fmt.Errorf("AZURE_STORAGE_CONNECTION_STRING is not set")
 	}
 
 	if containerName == "" {
@@ -36,19 +52,17 @@ func InitAzureStorage() error {
 
 	client, err := azblob.NewClientFromConnectionString(connectionString, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create Azure Blob client: %v", err)
+		return nil, fmt.Errorf("failed to create Azure Blob client: %v", err)
 	}
 
 	// Extract account name from connection string
 	accountName := extractAccountName(connectionString)
 
-	AzureStorage = &AzureStorageService{
+	return &AzureStorageService{
 		client:        client,
 		containerName: containerName,
 		accountName:   accountName,
-	}
-
-	return nil
+	}, nil
 }
 
 func extractAccountName(connectionString string) string {
@@ -62,14 +76,27 @@ func extractAccountName(connectionString string) string {
 }
 
 func (s *AzureStorageService) UploadFile(file io.Reader, fileName string, contentType string) (string, error) {
-	ctx := context.Background()
-
 	// Generate unique filename
 	ext := filepath.Ext(fileName)
 	uniqueName := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
 
+	if contentType == "" {
+		contentType = contentTypeByName(uniqueName)
+	}
+	return s.upload(file, uniqueName, contentType)
+}
+
+// SaveFile เก็บไฟล์ด้วยชื่อที่กำหนด (ทับ blob เดิมถ้ามี) content type เดาจากนามสกุล
+func (s *AzureStorageService) SaveFile(file io.Reader, name string) (string, error) {
+	name = path.Base(name)
+	return s.upload(file, name, contentTypeByName(name))
+}
+
+func (s *AzureStorageService) upload(file io.Reader, name, contentType string) (string, error) {
+	ctx := context.Background()
+
 	// Upload to Azure Blob Storage
-	_, err := s.client.UploadStream(ctx, s.containerName, uniqueName, file, &blockblob.UploadStreamOptions{
+	_, err := s.client.UploadStream(ctx, s.containerName, name, file, &blockblob.UploadStreamOptions{
 		HTTPHeaders: &blob.HTTPHeaders{
 			BlobContentType: &contentType,
 		},
@@ -79,15 +106,49 @@ func (s *AzureStorageService) UploadFile(file io.Reader, fileName string, conten
 		return "", fmt.Errorf("failed to upload file: %v", err)
 	}
 
-	// Return the URL
-	url := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s",
-		s.accountName, s.containerName, uniqueName)
+	return s.fileURL(name), nil
+}
 
-	return url, nil
+func (s *AzureStorageService) fileURL(name string) string {
+	return fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", s.accountName, s.containerName, name)
 }
 
 func (s *AzureStorageService) DeleteFile(fileName string) error {
 	ctx := context.Background()
-	_, err := s.client.DeleteBlob(ctx, s.containerName, fileName, nil)
+	_, err := s.client.DeleteBlob(ctx, s.containerName, path.Base(fileName), nil)
 	return err
 }
+
+func (s *AzureStorageService) OpenFile(name string) (io.ReadCloser, error) {
+	resp, err := s.client.DownloadStream(context.Background(), s.containerName, path.Base(name), nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+			return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// SignedURL สร้าง SAS แบบอ่านอย่างเดียว (ต้องใช้ connection string ที่มี AccountKey)
+func (s *AzureStorageService) SignedURL(name string, expiry time.Duration) (string, error) {
+	blobClient := s.client.ServiceClient().NewContainerClient(s.containerName).NewBlobClient(path.Base(name))
+	return blobClient.GetSASURL(sas.BlobPermissions{Read: true}, time.Now().Add(expiry), nil)
+}
+
+func (s *AzureStorageService) ListFiles() ([]string, error) {
+	var names []string
+	pager := s.client.NewListBlobsFlatPager(s.containerName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name != nil {
+				names = append(names, *item.Name)
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// BlockTypeDefinition ชนิดของ block พร้อม JSON Schema ของ content และ block_style
+type BlockTypeDefinition struct {
+	Name          string          `json:"name"`
+	Label         string          `json:"label"`
+	ContentSchema json.RawMessage `json:"content_schema"`
+	StyleSchema   json.RawMessage `json:"style_schema"`
+
+	content *jsonschema.Schema
+	style   *jsonschema.Schema
+}
+
+// BlockValidationError content หรือ block_style ไม่ตรงกับ schema ของชนิด block
+type BlockValidationError struct {
+	BlockType string   `json:"block_type"`
+	Field     string   `json:"field"`
+	Errors    []string `json:"errors"`
+}
+
+func (e *BlockValidationError) Error() string {
+	if e.BlockType == "" {
+		return fmt.Sprintf("invalid %s: %s", e.Field, strings.Join(e.Errors, "; "))
+	}
+	return fmt.Sprintf("invalid %s for %q block: %s", e.Field, e.BlockType, strings.Join(e.Errors, "; "))
+}
+
+// ErrUnknownBlockType ไม่มีชนิด block นี้ใน registry
+var ErrUnknownBlockType = errors.New("unknown block type")
+
+// BlockSchemaRegistry เก็บ schema ของ block แต่ละชนิด (ปลอดภัยต่อการเรียกพร้อมกัน)
+type BlockSchemaRegistry struct {
+	mu    sync.RWMutex
+	types map[string]*BlockTypeDefinition
+}
+
+func NewBlockSchemaRegistry() *BlockSchemaRegistry {
+	return &BlockSchemaRegistry{types: map[string]*BlockTypeDefinition{}}
+}
+
+// Register compile schema แล้วเพิ่ม (หรือแทนที่) ชนิด block ถ้าไม่ระบุ StyleSchema จะใช้ style กลาง
+func (r *BlockSchemaRegistry) Register(def BlockTypeDefinition) error {
+	def.Name = strings.ToLower(strings.TrimSpace(def.Name))
+	if def.Name == "" {
+		return errors.New("block type name is required")
+	}
+	if len(def.StyleSchema) == 0 {
+		def.StyleSchema = json.RawMessage(commonBlockStyleSchema)
+	}
+
+	var err error
+	if def.content, err = compileBlockSchema("block/"+def.Name+"/content.json", def.ContentSchema); err != nil {
+		return fmt.Errorf("block type %q: content schema: %w", def.Name, err)
+	}
+	if def.style, err = compileBlockSchema("block/"+def.Name+"/style.json", def.StyleSchema); err != nil {
+		return fmt.Errorf("block type %q: style schema: %w", def.Name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[def.Name] = &def
+	return nil
+}
+
+func (r *BlockSchemaRegistry) mustRegister(def BlockTypeDefinition) {
+	if err := r.Register(def); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup คืนชนิด block ตามชื่อ (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+func (r *BlockSchemaRegistry) Lookup(name string) (BlockTypeDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.types[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return BlockTypeDefinition{}, false
+	}
+	return *def, true
+}
+
+// Types คืนชนิด block ทั้งหมดเรียงตามชื่อ
+func (r *BlockSchemaRegistry) Types() []BlockTypeDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]BlockTypeDefinition, 0, len(r.types))
+	for _, def := range r.types {
+		defs = append(defs, *def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// ValidateBlock ตรวจ content และ style ของ block
+// ชนิดที่ใช้ตรวจคือ content.type (ที่ frontend กำหนด) ถ้ามี ไม่เช่นนั้นใช้ blockType
+// content / style ที่ว่างถือว่าถูกต้อง (block เปล่าที่ยังไม่ได้กรอก)
+func (r *BlockSchemaRegistry) ValidateBlock(blockType string, content, style []byte) error {
+	contentValue, err := decodeBlockJSON(content)
+	if err != nil {
+		return &BlockValidationError{BlockType: blockType, Field: "content", Errors: []string{err.Error()}}
+	}
+
+	effective := ResolveBlockType(blockType, contentValue)
+	def, ok := r.Lookup(effective)
+	if !ok {
+		return &BlockValidationError{
+			BlockType: effective,
+			Field:     "block_type",
+			Errors:    []string{fmt.Sprintf("%s %q, must be one of: %s", ErrUnknownBlockType, effective, strings.Join(r.names(), ", "))},
+		}
+	}
+
+	if contentValue != nil {
+		if errs := schemaErrors(def.content, contentValue); len(errs) > 0 {
+			return &BlockValidationError{BlockType: def.Name, Field: "content", Errors: errs}
+		}
+	}
+
+	styleValue, err := decodeBlockJSON(style)
+	if err != nil {
+		return &BlockValidationError{BlockType: def.Name, Field: "block_style", Errors: []string{err.Error()}}
+	}
+	if styleValue != nil {
+		if errs := schemaErrors(def.style, styleValue); len(errs) > 0 {
+			return &BlockValidationError{BlockType: def.Name, Field: "block_style", Errors: errs}
+		}
+	}
+	return nil
+}
+
+func (r *BlockSchemaRegistry) names() []string {
+	defs := r.Types()
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name
+	}
+	return names
+}
+
+// ResolveBlockType ชนิดจริงของ block: content.type ก่อน แล้วค่อยใช้ block_port_type
+func ResolveBlockType(blockType string, content interface{}) string {
+	if m, ok := content.(map[string]interface{}); ok {
+		if t, ok := m["type"].(string); ok && strings.TrimSpace(t) != "" {
+			return strings.ToLower(strings.TrimSpace(t))
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(blockType))
+}
+
+// decodeBlockJSON แปลงคอลัมน์ JSON เป็นค่าที่ใช้ตรวจกับ schema
+// null / ว่าง คืน nil, JSON string ที่ซ้อนอีกชั้น (ข้อมูลเก่าจาก frontend) จะถูกแกะออกก่อน
+func decodeBlockJSON(raw []byte) (interface{}, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	value, err := decodeJSONNumber(bytes.NewReader(raw))
+	if err != nil {
+		return nil, errors.New("must be valid JSON")
+	}
+	if inner, ok := value.(string); ok {
+		if strings.TrimSpace(inner) == "" {
+			return nil, nil
+		}
+		if value, err = decodeJSONNumber(strings.NewReader(inner)); err != nil {
+			return nil, errors.New("must be a JSON object")
+		}
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return nil, errors.New("must be a JSON object")
+	}
+	return value, nil
+}
+
+// decodeJSONNumber ใช้ json.Number เพื่อให้ schema แยก integer กับทศนิยมได้
+func decodeJSONNumber(r io.Reader) (interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+func compileBlockSchema(url string, schema json.RawMessage) (*jsonschema.Schema, error) {
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type":"object"}`)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, bytes.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+// schemaErrors แปลงผลการตรวจเป็นรายการข้อความ "ตำแหน่ง: ปัญหา" (เฉพาะสาเหตุปลายทาง)
+func schemaErrors(schema *jsonschema.Schema, value interface{}) []string {
+	err := schema.Validate(value)
+	if err == nil {
+		return nil
+	}
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return []string{err.Error()}
+	}
+	var errs []string
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			errs = append(errs, location+": "+e.Message)
+			return
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(ve)
+	return errs
+}
+
+// ===== Schema ของ block ที่ระบบรองรับ =====
+// schema เปิดให้มี field อื่นเพิ่มได้ (frontend เก็บค่าประกอบหลายอย่าง) แต่ field ที่รู้จักต้องถูกชนิด
+
+const blockColorPattern = `^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`
+
+var commonBlockStyleSchema = `{
+	"type": "object",
+	"properties": {
+		"background_color": {"type": "string", "pattern": "` + blockColorPattern + `"},
+		"text_color": {"type": "string", "pattern": "` + blockColorPattern + `"},
+		"font_size": {"type": ["string", "number"]},
+		"font_weight": {"enum": ["normal", "bold", "lighter", "bolder", "100", "200", "300", "400", "500", "600", "700", "800", "900", 100, 200, 300, 400, 500, 600, 700, 800, 900]},
+		"text_align": {"enum": ["left", "center", "right", "justify"]},
+		"padding": {"type": ["string", "number"]},
+		"margin": {"type": ["string", "number"]},
+		"border_radius": {"type": ["string", "number"]},
+		"width": {"type": ["string", "number"]},
+		"height": {"type": ["string", "number"]}
+	}
+}`
+
+// block ที่อ้างถึงข้อมูลของนักเรียน (Activity / Working) ผ่าน data_id และอาจมีสำเนาใน data
+func referenceContentSchema(legacyIDField string) string {
+	return `{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"data_id": {"type": "integer", "minimum": 1},
+			"data": {"type": "object"},
+			"` + legacyIDField + `": {"type": "integer", "minimum": 1}
+		},
+		"anyOf": [
+			{"required": ["data_id"]},
+			{"required": ["data"]},
+			{"required": ["` + legacyIDField + `"]}
+		]
+	}`
+}
+
+const listContentSchema = `{
+	"type": "object",
+	"properties": {
+		"type": {"type": "string"},
+		"title": {"type": "string", "maxLength": 200},
+		"items": {"type": "array", "maxItems": 100}
+	}
+}`
+
+// BlockSchemas registry กลางที่ใช้ตรวจ block ทั้ง portfolio และ template
+var BlockSchemas = newDefaultBlockSchemaRegistry()
+
+func newDefaultBlockSchemaRegistry() *BlockSchemaRegistry {
+	r := NewBlockSchemaRegistry()
+	r.mustRegister(BlockTypeDefinition{Name: "text", Label: "ข้อความ", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"text": {"type": "string", "maxLength": 10000},
+			"font_size": {"type": ["string", "number"]},
+			"font_color": {"type": "string", "pattern": "` + blockColorPattern + `"},
+			"font_style": {"type": "string"},
+			"text_align": {"enum": ["left", "center", "right", "justify"]}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "header", Label: "หัวข้อ", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"text": {"type": "string", "maxLength": 500},
+			"level": {"type": "integer", "minimum": 1, "maximum": 6}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "image", Label: "รูปภาพ", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"url": {"type": "string", "maxLength": 2048},
+			"image_url": {"type": "string", "maxLength": 2048},
+			"alt_text": {"type": "string", "maxLength": 200},
+			"caption": {"type": "string", "maxLength": 500},
+			"width": {"type": ["string", "number"]},
+			"height": {"type": ["string", "number"]}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "gallery", Label: "แกลเลอรี", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"images": {
+				"type": "array",
+				"maxItems": 50,
+				"items": {
+					"oneOf": [
+						{"type": "string", "maxLength": 2048},
+						{
+							"type": "object",
+							"properties": {
+								"url": {"type": "string", "maxLength": 2048},
+								"alt_text": {"type": "string", "maxLength": 200},
+								"caption": {"type": "string", "maxLength": 500}
+							},
+							"required": ["url"]
+						}
+					]
+				}
+			},
+			"columns": {"type": "integer", "minimum": 1, "maximum": 6}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "activity", Label: "กิจกรรม", ContentSchema: json.RawMessage(referenceContentSchema("activity_id"))})
+	r.mustRegister(BlockTypeDefinition{Name: "working", Label: "ผลงาน", ContentSchema: json.RawMessage(referenceContentSchema("working_id"))})
+	r.mustRegister(BlockTypeDefinition{Name: "profile", Label: "ข้อมูลส่วนตัว", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"fields": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+			"show_image": {"type": "boolean"}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "academic_score", Label: "ผลการเรียน", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"fields": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "education", Label: "การศึกษา", ContentSchema: json.RawMessage(listContentSchema)})
+	r.mustRegister(BlockTypeDefinition{Name: "skills", Label: "ทักษะ", ContentSchema: json.RawMessage(listContentSchema)})
+	r.mustRegister(BlockTypeDefinition{Name: "awards", Label: "รางวัล", ContentSchema: json.RawMessage(listContentSchema)})
+	r.mustRegister(BlockTypeDefinition{Name: "experience", Label: "ประสบการณ์", ContentSchema: json.RawMessage(listContentSchema)})
+	r.mustRegister(BlockTypeDefinition{Name: "contact", Label: "ช่องทางติดต่อ", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"email": {"type": "string", "maxLength": 200},
+			"phone": {"type": "string", "maxLength": 20},
+			"links": {"type": "array", "maxItems": 20}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "divider", Label: "เส้นคั่น", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"thickness": {"type": "number", "minimum": 0},
+			"color": {"type": "string", "pattern": "` + blockColorPattern + `"}
+		}
+	}`)})
+	r.mustRegister(BlockTypeDefinition{Name: "spacer", Label: "ช่องว่าง", ContentSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"type": {"type": "string"},
+			"height": {"type": ["string", "number"]}
+		}
+	}`)})
+	return r
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	InvalidBlockSourcePortfolio = "portfolio_block"
+	InvalidBlockSourceTemplate  = "templates_block"
+
+	blockReportBatchSize = 500
+)
+
+// InvalidBlock block ที่มีอยู่แล้วในฐานข้อมูลแต่ไม่ผ่าน schema
+type InvalidBlock struct {
+	Source      string   `json:"source"`
+	ID          uint     `json:"id"`
+	PortfolioID uint     `json:"portfolio_id,omitempty"`
+	SectionID   uint     `json:"section_id,omitempty"`
+	BlockType   string   `json:"block_type"`
+	Field       string   `json:"field"`
+	Errors      []string `json:"errors"`
+}
+
+// BlockSchemaReport รายงานก่อน migrate ว่ามี block ใดบ้างที่ต้องแก้ข้อมูล
+type BlockSchemaReport struct {
+	GeneratedAt            time.Time      `json:"generated_at"`
+	PortfolioBlocksChecked int            `json:"portfolio_blocks_checked"`
+	TemplateBlocksChecked  int            `json:"template_blocks_checked"`
+	InvalidCount           int            `json:"invalid_count"`
+	InvalidByType          map[string]int `json:"invalid_by_type"`
+	Invalid                []InvalidBlock `json:"invalid"`
+}
+
+type reportBlockRow struct {
+	ID          uint
+	BlockType   string
+	Content     datatypes.JSON
+	Style       datatypes.JSON
+	SectionID   uint
+	PortfolioID uint
+}
+
+// BuildBlockSchemaReport ตรวจ portfolio block และ template block ทั้งหมด (ที่ยังไม่ถูกลบ) กับ registry
+// อ่านทีละชุดตาม id เพื่อไม่โหลดทั้งตารางเข้าหน่วยความจำ
+func BuildBlockSchemaReport(db *gorm.DB, registry *BlockSchemaRegistry) (BlockSchemaReport, error) {
+	report := BlockSchemaReport{
+		GeneratedAt:   time.Now(),
+		InvalidByType: map[string]int{},
+		Invalid:       []InvalidBlock{},
+	}
+
+	portfolioQuery := db.Table("portfolio_blocks").
+		Select("portfolio_blocks.id, portfolio_blocks.block_port_type AS block_type, portfolio_blocks.content, portfolio_blocks.block_style AS style, portfolio_blocks.portfolio_section_id AS section_id, portfolio_sections.portfolio_id").
+		Joins("LEFT JOIN portfolio_sections ON portfolio_sections.id = portfolio_blocks.portfolio_section_id").
+		Where("portfolio_blocks.deleted_at IS NULL")
+	checked, err := scanReportBlocks(portfolioQuery, "portfolio_blocks.id", func(row reportBlockRow) {
+		report.add(registry, InvalidBlockSourcePortfolio, row)
+	})
+	if err != nil {
+		return report, err
+	}
+	report.PortfolioBlocksChecked = checked
+
+	templateQuery := db.Table("templates_blocks").
+		Select("id, block_type, default_content AS content, default_style AS style").
+		Where("deleted_at IS NULL")
+	checked, err = scanReportBlocks(templateQuery, "id", func(row reportBlockRow) {
+		report.add(registry, InvalidBlockSourceTemplate, row)
+	})
+	if err != nil {
+		return report, err
+	}
+	report.TemplateBlocksChecked = checked
+
+	report.InvalidCount = len(report.Invalid)
+	return report, nil
+}
+
+func (r *BlockSchemaReport) add(registry *BlockSchemaRegistry, source string, row reportBlockRow) {
+	err := registry.ValidateBlock(row.BlockType, row.Content, row.Style)
+	if err == nil {
+		return
+	}
+	invalid := InvalidBlock{
+		Source:      source,
+		ID:          row.ID,
+		PortfolioID: row.PortfolioID,
+		SectionID:   row.SectionID,
+		BlockType:   row.BlockType,
+		Errors:      []string{err.Error()},
+	}
+	var validationErr *BlockValidationError
+	if errors.As(err, &validationErr) {
+		invalid.BlockType = validationErr.BlockType
+		invalid.Field = validationErr.Field
+		invalid.Errors = validationErr.Errors
+	}
+	r.Invalid = append(r.Invalid, invalid)
+	r.InvalidByType[invalid.BlockType]++
+}
+
+func scanReportBlocks(query *gorm.DB, idColumn string, visit func(reportBlockRow)) (int, error) {
+	var lastID uint
+	total := 0
+	for {
+		var rows []reportBlockRow
+		err := query.Session(&gorm.Session{}).
+			Where(idColumn+" > ?", lastID).
+			Order(idColumn + " ASC").
+			Limit(blockReportBatchSize).
+			Scan(&rows).Error
+		if err != nil {
+			return total, err
+		}
+		for _, row := range rows {
+			visit(row)
+		}
+		total += len(rows)
+		if len(rows) < blockReportBatchSize {
+			return total, nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// ErrRequiredSectionInvalid รายการ section บังคับของหลักสูตรไม่ถูกต้อง
+var ErrRequiredSectionInvalid = errors.New("invalid required section")
+
+// NormalizeSectionPortKey key ของ section เทียบแบบไม่สนตัวพิมพ์และช่องว่างหัวท้าย
+func NormalizeSectionPortKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+// ReplaceCurriculumRequiredSections แทนที่ section บังคับทั้งหมดของหลักสูตร (ลำดับตาม slice)
+func ReplaceCurriculumRequiredSections(tx *gorm.DB, curriculumID uint, sections []entity.CurriculumRequiredSection) error {
+	seen := map[string]bool{}
+	for i := range sections {
+		key := NormalizeSectionPortKey(sections[i].SectionPortKey)
+		if key == "" {
+			return fmt.Errorf("%w: section_port_key is required", ErrRequiredSectionInvalid)
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate section_port_key %q", ErrRequiredSectionInvalid, key)
+		}
+		seen[key] = true
+		sections[i].ID = 0
+		sections[i].CurriculumID = curriculumID
+		sections[i].SectionPortKey = key
+		sections[i].Title = strings.TrimSpace(sections[i].Title)
+		sections[i].OrderIndex = i
+	}
+
+	// ลบถาวรเพราะ unique index (curriculum_id, section_port_key) รวมแถวที่ soft delete
+	if err := tx.Unscoped().Where("curriculum_id = ?", curriculumID).Delete(&entity.CurriculumRequiredSection{}).Error; err != nil {
+		return err
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+	return tx.Omit("Curriculum").Create(&sections).Error
+}
+
+// ReplaceCurriculumRecommendedTemplates แทนที่ template ที่หลักสูตรแนะนำ (ลำดับตาม slice)
+func ReplaceCurriculumRecommendedTemplates(tx *gorm.DB, curriculumID uint, templateIDs []uint) error {
+	if err := tx.Unscoped().Where("curriculum_id = ?", curriculumID).Delete(&entity.CurriculumRecommendedTemplate{}).Error; err != nil {
+		return err
+	}
+	seen := map[uint]bool{}
+	for i, id := range templateIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if err := tx.First(&entity.Templates{}, id).Error; err != nil {
+			return err
+		}
+		link := entity.CurriculumRecommendedTemplate{CurriculumID: curriculumID, TemplatesID: id, OrderIndex: i}
+		if err := tx.Omit("Curriculum", "Templates").Create(&link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingRequiredSections section บังคับที่ portfolio ยังไม่มี (ถือว่ามีเมื่อ section ที่เปิดอยู่มี section_port_key ตรงกัน)
+func missingRequiredSections(portfolio *entity.Portfolio, curriculum *entity.Curriculum) []ChecklistItem {
+	present := map[string]bool{}
+	for _, section := range portfolio.PortfolioSections {
+		if section.IsEnabled {
+			present[NormalizeSectionPortKey(section.SectionPortKey)] = true
+		}
+	}
+
+	var items []ChecklistItem
+	for _, required := range curriculum.RequiredSections {
+		if present[NormalizeSectionPortKey(required.SectionPortKey)] {
+			continue
+		}
+		title := required.Title
+		if title == "" {
+			title = required.SectionPortKey
+		}
+		message := "Curriculum \"" + curriculum.Name + "\" requires a \"" + title + "\" section"
+		if required.Note != "" {
+			message += " (" + required.Note + ")"
+		}
+		items = append(items, ChecklistItem{
+			Code: CheckMissingRequiredSection, Severity: ChecklistWarning, Field: required.SectionPortKey, Message: message,
+		})
+	}
+	return items
+}
+
+// CheckRequiredSections ตรวจ section บังคับของหลักสูตร curriculumID
+// ถ้าไม่ระบุหลักสูตรจะตรวจทุกหลักสูตรที่ portfolio นี้ถูกกำหนดไว้
+func CheckRequiredSections(db *gorm.DB, portfolioID, curriculumID uint) ([]ChecklistItem, error) {
+	var portfolio entity.Portfolio
+	if err := db.Preload("PortfolioSections").First(&portfolio, portfolioID).Error; err != nil {
+		return nil, err
+	}
+
+	curriculumIDs := []uint{curriculumID}
+	if curriculumID == 0 {
+		curriculumIDs = nil
+		if err := db.Model(&entity.PortfolioCurriculum{}).Where("portfolio_id = ?", portfolioID).
+			Order("curriculum_id ASC").Pluck("curriculum_id", &curriculumIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(curriculumIDs) == 0 {
+		return []ChecklistItem{}, nil
+	}
+
+	var curricula []entity.Curriculum
+	if err := db.Preload("RequiredSections", func(db *gorm.DB) *gorm.DB {
+		return db.Order("order_index ASC")
+	}).Where("id IN ?", curriculumIDs).Order("id ASC").Find(&curricula).Error; err != nil {
+		return nil, err
+	}
+
+	items := []ChecklistItem{}
+	for i := range curricula {
+		items = append(items, missingRequiredSections(&portfolio, &curricula[i])...)
+	}
+	return items, nil
+}
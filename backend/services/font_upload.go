@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxFontFileSize ขนาดไฟล์ฟอนต์สูงสุด (ฟอนต์ไทยแบบ ttf มักใหญ่กว่ารูปภาพ)
+const MaxFontFileSize = 10 * 1024 * 1024
+
+// FontFileTypes นามสกุลไฟล์ฟอนต์ที่อัปโหลดได้ และ content type ที่ใช้เก็บ
+var FontFileTypes = map[string]string{
+	".woff2": "font/woff2",
+	".woff":  "font/woff",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+}
+
+var (
+	ErrInvalidFontFile       = errors.New("invalid font file")
+	ErrStorageNotInitialized = errors.New("storage service not initialized")
+)
+
+// DetectFontFormat ดูชนิดฟอนต์จาก 4 byte แรกของไฟล์ คืนนามสกุล หรือ "" ถ้าไม่ใช่ไฟล์ฟอนต์
+func DetectFontFormat(head []byte) string {
+	if len(head) < 4 {
+		return ""
+	}
+	switch string(head[:4]) {
+	case "wOF2":
+		return ".woff2"
+	case "wOFF":
+		return ".woff"
+	case "\x00\x01\x00\x00", "true":
+		return ".ttf"
+	case "OTTO":
+		return ".otf"
+	}
+	return ""
+}
+
+// SaveFontFile ตรวจว่าเนื้อไฟล์ตรงกับนามสกุลแล้วเก็บลง local storage
+// คืน URL สำหรับ @font-face และชื่อไฟล์ที่เก็บจริง (ใช้ลบภายหลัง)
+func SaveFontFile(file io.Reader, fileName string) (string, string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	contentType, ok := FontFileTypes[ext]
+	if !ok {
+		return "", "", fmt.Errorf("%w: only WOFF2, WOFF, TTF and OTF files are allowed", ErrInvalidFontFile)
+	}
+
+	head := make([]byte, 4)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", err
+	}
+	head = head[:n]
+	detected := DetectFontFormat(head)
+	// .otf ที่เป็น TrueType outline ก็มีอยู่จริง จึงยอมให้สลับกันระหว่าง ttf/otf
+	sfnt := (detected == ".ttf" || detected == ".otf") && (ext == ".ttf" || ext == ".otf")
+	if detected != ext && !sfnt {
+		return "", "", fmt.Errorf("%w: file content is not a %s font", ErrInvalidFontFile, strings.TrimPrefix(ext, "."))
+	}
+
+	if Storage == nil {
+		return "", "", ErrStorageNotInitialized
+	}
+	url, err := Storage.UploadFile(io.MultiReader(bytes.NewReader(head), file), fileName, contentType)
+	if err != nil {
+		return "", "", err
+	}
+	return url, path.Base(url), nil
+}
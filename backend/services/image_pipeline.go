@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	// decoder ของรูปแบบที่รับอัปโหลด
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+)
+
+// ชื่อของภาพแต่ละขนาดที่สร้างจากรูปที่อัปโหลด
+const (
+	ImageRenditionThumbnail = "thumbnail"
+	ImageRenditionMedium    = "medium"
+	ImageRenditionFull      = "full"
+)
+
+// ImageRendition ขนาดด้านยาวสุด (px) ของภาพแต่ละแบบ และ suffix ของชื่อไฟล์
+type ImageRendition struct {
+	Name    string
+	MaxSide int
+	Suffix  string
+}
+
+// ImageRenditions ภาพที่สร้างทุกครั้ง (ไม่ขยายรูปที่เล็กกว่าขนาดที่กำหนด)
+// full ใช้ชื่อไฟล์หลักเพื่อให้ URL เดิมที่ frontend ใช้ยังเป็นรูปขนาดเต็ม
+var ImageRenditions = []ImageRendition{
+	{Name: ImageRenditionThumbnail, MaxSide: 320, Suffix: "_thumb"},
+	{Name: ImageRenditionMedium, MaxSide: 1024, Suffix: "_medium"},
+	{Name: ImageRenditionFull, MaxSide: 2048},
+}
+
+const (
+	// MaxImageUploadSize รูปถูกย่อก่อนเก็บ จึงรับไฟล์ต้นฉบับจากกล้องมือถือที่ใหญ่กว่าเอกสารได้
+	MaxImageUploadSize = 20 * 1024 * 1024
+	// maxImagePixels กัน decompression bomb (ไฟล์เล็กแต่ขนาดภาพใหญ่มาก)
+	maxImagePixels = 50_000_000
+	jpegQuality    = 82
+)
+
+var (
+	ErrInvalidImage  = errors.New("invalid image file")
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// ImageRenditionFile ไฟล์ของภาพหนึ่งขนาด
+type ImageRenditionFile struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ProcessedImage ผลการประมวลผลรูปที่อัปโหลด (URL คือภาพ full)
+type ProcessedImage struct {
+	URL        string                        `json:"url"`
+	Format     string                        `json:"format"`
+	Width      int                           `json:"width"`
+	Height     int                           `json:"height"`
+	Renditions map[string]ImageRenditionFile `json:"renditions"`
+}
+
+// ProcessUploadedImage หมุนรูปตาม EXIF orientation, ย่อเป็นทุกขนาดใน ImageRenditions
+// แล้ว encode ใหม่เป็น JPEG (หรือ PNG ถ้ารูปมีส่วนโปร่งใส) ก่อนเก็บลง Storage
+// การ encode ใหม่ตัด metadata ทั้งหมดของต้นฉบับ (รวมพิกัด GPS) และไม่เก็บไฟล์ต้นฉบับไว้
+// GIF แบบเคลื่อนไหวจะเหลือเฉพาะเฟรมแรก
+// WebP รับเข้าได้อย่างเดียว: golang.org/x/image มีแค่ decoder ของ WebP การ encode เป็น WebP
+// ต้องใช้ cgo (libwebp) ซึ่งขัดกับข้อกำหนดว่าต้องเป็น pure Go จึงส่งออกเป็น JPEG/PNG เท่านั้น
+// baseName เป็นชื่อไฟล์ภาพ full (ไม่รวมนามสกุล) ภาพย่อต่อ suffix ของแต่ละขนาดท้ายชื่อนี้
+func ProcessUploadedImage(data []byte, baseName string) (*ProcessedImage, error) {
+	if Storage == nil {
+		return nil, ErrStorageNotInitialized
+	}
+	if len(data) > MaxImageUploadSize {
+		return nil, fmt.Errorf("%w: file must not exceed %dMB", ErrInvalidImage, MaxImageUploadSize/1024/1024)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	orientation := jpegOrientation(data)
+	format, ext := "jpeg", ".jpg"
+	if !isOpaque(src) {
+		format, ext = "png", ".png"
+	}
+
+	result := &ProcessedImage{Format: format, Renditions: map[string]ImageRenditionFile{}}
+	var saved []string
+	for _, rendition := range ImageRenditions {
+		img := applyOrientation(scaleToFit(src, rendition.MaxSide), orientation)
+
+		var buf bytes.Buffer
+		if format == "png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err == nil {
+			name := baseName + rendition.Suffix + ext
+			var url string
+			if url, err = Storage.SaveFile(&buf, name); err == nil {
+				saved = append(saved, name)
+				bounds := img.Bounds()
+				result.Renditions[rendition.Name] = ImageRenditionFile{URL: url, Width: bounds.Dx(), Height: bounds.Dy()}
+				continue
+			}
+		}
+		for _, name := range saved {
+			Storage.DeleteFile(name)
+		}
+		return nil, err
+	}
+
+	full := result.Renditions[ImageRenditionFull]
+	result.URL, result.Width, result.Height = full.URL, full.Width, full.Height
+	return result, nil
+}
+
+// ImageRenditionURLs URL ของ thumbnail และ medium ของรูป full ที่ผ่าน ProcessUploadedImage
+// คืนค่าว่างถ้าไม่ใช่ไฟล์ใน Storage หรือไม่มีไฟล์ขนาดนั้น (เช่น รูปที่อัปโหลดก่อนมี pipeline)
+func ImageRenditionURLs(fullURL string) (thumbnail, medium string) {
+	idx := strings.Index(fullURL, "/uploads/")
+	if idx < 0 {
+		return "", ""
+	}
+	name := fullURL[idx+len("/uploads/"):]
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", ""
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	find := func(suffix string) string {
+		if !storageFileExists(base + suffix + ext) {
+			return ""
+		}
+		return fullURL[:idx] + "/uploads/" + base + suffix + ext
+	}
+	for _, rendition := range ImageRenditions {
+		switch rendition.Name {
+		case ImageRenditionThumbnail:
+			thumbnail = find(rendition.Suffix)
+		case ImageRenditionMedium:
+			medium = find(rendition.Suffix)
+		}
+	}
+	return thumbnail, medium
+}
+
+// loadProcessedImage สร้าง ProcessedImage จากไฟล์ที่ ProcessUploadedImage เก็บไว้แล้ว (ใช้ตอนอัปโหลดซ้ำ)
+// fullURL คือ URL ของภาพ full, ภาพย่อที่หาไม่เจอจะไม่อยู่ใน Renditions
+func loadProcessedImage(fullURL, storedName string) (*ProcessedImage, error) {
+	ext := filepath.Ext(storedName)
+	base := strings.TrimSuffix(storedName, ext)
+	prefix := strings.TrimSuffix(fullURL, storedName)
+	format := "jpeg"
+	if ext == ".png" {
+		format = "png"
+	}
+
+	result := &ProcessedImage{URL: fullURL, Format: format, Renditions: map[string]ImageRenditionFile{}}
+	for _, rendition := range ImageRenditions {
+		name := base + rendition.Suffix + ext
+		f, err := Storage.OpenFile(name)
+		if err != nil {
+			if rendition.Name == ImageRenditionFull {
+				return nil, err
+			}
+			continue
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		result.Renditions[rendition.Name] = ImageRenditionFile{URL: prefix + name, Width: cfg.Width, Height: cfg.Height}
+	}
+	full := result.Renditions[ImageRenditionFull]
+	result.Width, result.Height = full.Width, full.Height
+	return result, nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// scaleToFit ย่อให้ด้านยาวสุดไม่เกิน maxSide (ไม่ขยาย) ได้ภาพ NRGBA เสมอ
+// ย่อก่อนหมุนตาม orientation ได้เพราะด้านยาวสุดไม่เปลี่ยนเมื่อหมุน
+func scaleToFit(src image.Image, maxSide int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	long := w
+	if h > long {
+		long = h
+	}
+	if long > maxSide {
+		w = max(1, w*maxSide/long)
+		h = max(1, h*maxSide/long)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	}
+	return dst
+}
+
+// applyOrientation หมุน/กลับภาพตาม EXIF orientation (1-8) ให้ได้ภาพที่ตั้งตรง
+func applyOrientation(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation อ่าน tag Orientation (0x0112) จาก EXIF ใน APP1 ของ JPEG คืน 1 ถ้าไม่มี
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF: // fill byte
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8):
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9: // เริ่มข้อมูลภาพแล้ว ไม่มี EXIF
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(segment) >= 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for k := 0; k < entries; k++ {
+		entry := ifd + 2 + k*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
+
+// DeleteImageRenditions ลบภาพย่อของไฟล์ full ใน Storage (ไฟล์ที่ไม่มีถือว่าลบแล้ว)
+func DeleteImageRenditions(fileName string) {
+	if Storage == nil {
+		return
+	}
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(filepath.Base(fileName), ext)
+	for _, rendition := range ImageRenditions {
+		if rendition.Suffix != "" {
+			Storage.DeleteFile(base + rendition.Suffix + ext)
+		}
+	}
+}
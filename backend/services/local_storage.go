@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -13,9 +14,22 @@ type LocalStorageService struct {
 	baseURL   string
 }
 
+// LocalStorage backend local ที่ใช้อยู่ (nil ถ้า Storage เป็น backend อื่น)
 var LocalStorage *LocalStorageService
 
+// InitLocalStorage ใช้ local เป็น Storage
 func InitLocalStorage() error {
+	local, err := NewLocalStorageFromEnv()
+	if err != nil {
+		return err
+	}
+	LocalStorage = local
+	Storage = local
+	return nil
+}
+
+// NewLocalStorageFromEnv เก็บไฟล์ใน UPLOAD_DIR (ค่าเริ่มต้น ./uploads)
+func NewLocalStorageFromEnv() (*LocalStorageService, error) {
 	uploadDir := os.Getenv("UPLOAD_DIR")
 	if uploadDir == "" {
 		uploadDir = "./uploads"
@@ -28,15 +42,13 @@ func InitLocalStorage() error {
 
 	// Create upload directory if not exists
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return fmt.Errorf("failed to create upload directory: %v", err)
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
 	}
 
-	LocalStorage = &LocalStorageService{
+	return &LocalStorageService{
 		uploadDir: uploadDir,
 		baseURL:   baseURL,
-	}
-
-	return nil
+	}, nil
 }
 
 func (s *LocalStorageService) UploadFile(file io.Reader, fileName string, contentType string) (string, error) {
@@ -44,8 +56,13 @@ func (s *LocalStorageService) UploadFile(file io.Reader, fileName string, conten
 	ext := filepath.Ext(fileName)
 	uniqueName := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
 
+	return s.SaveFile(file, uniqueName)
+}
+
+// SaveFile เก็บไฟล์ด้วยชื่อที่ผู้เรียกกำหนด (ทับไฟล์เดิมถ้ามี) แล้วคืน URL แบบเดียวกับ UploadFile
+func (s *LocalStorageService) SaveFile(file io.Reader, name string) (string, error) {
 	// Create file path
-	filePath := filepath.Join(s.uploadDir, uniqueName)
+	filePath := filepath.Join(s.uploadDir, filepath.Base(name))
 
 	// Create the file
 	dst, err := os.Create(filePath)
@@ -59,21 +76,47 @@ func (s *LocalStorageService) UploadFile(file io.Reader, fileName string, conten
 		return "", fmt.Errorf("failed to write file: %v", err)
 	}
 
-	// ✅ Return URL based on BASE_URL
-	var url string
+	return s.fileURL(name), nil
+}
+
+// fileURL URL ของไฟล์ตาม BASE_URL
+func (s *LocalStorageService) fileURL(name string) string {
 	if s.baseURL != "" {
 		// ถ้ามี BASE_URL (development) ให้ใช้ absolute URL
-		url = fmt.Sprintf("%s/uploads/%s", s.baseURL, uniqueName)
-	} else {
-		// ✅ ถ้าไม่มี BASE_URL (production) ให้ใช้ relative path
-		// Frontend จะต้อง prepend API_URL เอง หรือใช้ผ่าน nginx proxy
-		url = fmt.Sprintf("/uploads/%s", uniqueName)
+		return fmt.Sprintf("%s/uploads/%s", s.baseURL, filepath.Base(name))
 	}
-
-	return url, nil
+	// ✅ ถ้าไม่มี BASE_URL (production) ให้ใช้ relative path
+	// Frontend จะต้อง prepend API_URL เอง หรือใช้ผ่าน nginx proxy
+	return fmt.Sprintf("/uploads/%s", filepath.Base(name))
 }
 
 func (s *LocalStorageService) DeleteFile(fileName string) error {
-	filePath := filepath.Join(s.uploadDir, fileName)
+	filePath := filepath.Join(s.uploadDir, filepath.Base(fileName))
 	return os.Remove(filePath)
 }
+
+func (s *LocalStorageService) OpenFile(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.uploadDir, filepath.Base(name)))
+}
+
+// SignedURL ไฟล์ใน local เปิดให้ดาวน์โหลดผ่าน /uploads อยู่แล้ว จึงคืน URL ปกติ (ไม่มีวันหมดอายุ)
+func (s *LocalStorageService) SignedURL(name string, expiry time.Duration) (string, error) {
+	if _, err := os.Stat(filepath.Join(s.uploadDir, filepath.Base(name))); err != nil {
+		return "", err
+	}
+	return s.fileURL(name), nil
+}
+
+func (s *LocalStorageService) ListFiles() ([]string, error) {
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
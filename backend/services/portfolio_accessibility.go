@@ -0,0 +1,327 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// รหัสของรายการใน accessibility audit (รูปไม่มี alt text ใช้ CheckMissingAltText ร่วมกับ checklist)
+const (
+	A11yLowContrast  = "low_contrast"
+	A11ySmallFont    = "small_font_size"
+	A11yHeadingOrder = "heading_order"
+	A11yInvalidColor = "invalid_color"
+)
+
+// ขนาดตัวอักษรขั้นต่ำที่ยังอ่านได้ และขนาดที่ WCAG ถือเป็นตัวอักษรใหญ่ (18pt หรือ 14pt ตัวหนา)
+const (
+	MinReadableFontSizePx = 12
+	largeTextPx           = 24
+	largeBoldTextPx       = 18.66
+)
+
+// สีพื้นและสีข้อความเมื่อไม่ได้กำหนด (ตรงกับที่ใช้ใน PDF)
+const (
+	a11yDefaultBackground = "#FFFFFF"
+	a11yDefaultText       = "#282828"
+)
+
+// ขนาดของ token ที่ frontend ใช้ใน font_size (px)
+var fontSizeTokens = map[string]float64{
+	"xs": 12, "sm": 14, "md": 16, "lg": 18, "xl": 20, "2xl": 24, "3xl": 30,
+}
+
+// block ที่ไม่มีข้อความ ไม่ต้องตรวจ contrast/ขนาดตัวอักษร
+var textlessBlockTypes = map[string]bool{"image": true, "gallery": true, "divider": true, "spacer": true}
+
+// AccessibilityIssue ปัญหาที่พบ พร้อมสีและอัตราส่วนที่ใช้ตัดสินสำหรับ low_contrast
+type AccessibilityIssue struct {
+	ChecklistItem
+	Foreground string  `json:"foreground,omitempty"`
+	Background string  `json:"background,omitempty"`
+	Ratio      float64 `json:"ratio,omitempty"`
+	Required   float64 `json:"required,omitempty"`
+}
+
+// AccessibilityAudit ผลตรวจ accessibility ของ portfolio
+type AccessibilityAudit struct {
+	PortfolioID uint                 `json:"portfolio_id"`
+	Score       int                  `json:"score"`
+	Passed      bool                 `json:"passed"` // ไม่มีรายการระดับ error
+	Counts      map[string]int       `json:"counts"`
+	Issues      []AccessibilityIssue `json:"issues"`
+	CheckedAt   time.Time            `json:"checked_at"`
+}
+
+func (a *AccessibilityAudit) add(issue AccessibilityIssue) {
+	a.Issues = append(a.Issues, issue)
+}
+
+// a11yPaint สีที่ส่งต่อจาก theme → section → block
+// explicit = มี section/block กำหนดสีเอง (ธีมตรวจแยกครั้งเดียว ไม่ต้องรายงานซ้ำทุก block)
+type a11yPaint struct {
+	background entity.RGBA
+	text       entity.RGBA
+	explicit   bool
+	fontSize   float64
+	bold       bool
+}
+
+// AuditPortfolioAccessibility โหลด portfolio แล้วตรวจ accessibility
+func AuditPortfolioAccessibility(db *gorm.DB, portfolioID uint) (*AccessibilityAudit, error) {
+	portfolio, _, err := LoadPortfolioForPDF(db, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	return AuditPortfolio(portfolio), nil
+}
+
+// AuditPortfolio ตรวจ contrast ตาม WCAG AA, ขนาดตัวอักษรเล็กเกินไป, รูปไม่มี alt text
+// และลำดับหัวข้อที่ข้ามระดับ โดยดูเฉพาะ section ที่เปิดอยู่
+// (portfolio ต้องโหลด Colors และเรียง section/block ตามลำดับแล้ว)
+func AuditPortfolio(portfolio *entity.Portfolio) *AccessibilityAudit {
+	audit := &AccessibilityAudit{PortfolioID: portfolio.ID, Counts: map[string]int{}, Issues: []AccessibilityIssue{}}
+
+	page := a11yPaint{}
+	page.background, _ = entity.ParseHexColor(a11yDefaultBackground)
+	page.text, _ = entity.ParseHexColor(a11yDefaultText)
+	primary := page.text
+	if portfolio.Colors.ID != 0 {
+		audit.auditTheme(&portfolio.Colors)
+		if bg, err := entity.ParseHexColor(portfolio.Colors.BackgroundColor); err == nil {
+			page.background = bg.Over(page.background)
+		}
+		if c, err := entity.ParseHexColor(portfolio.Colors.PrimaryColor); err == nil {
+			primary = c
+		}
+	}
+
+	for _, section := range portfolio.PortfolioSections {
+		if !section.IsEnabled {
+			continue
+		}
+		sectionPaint := audit.applyStyle(page, parseBlockContent(section.SectionStyle), "section_style", section.ID, 0)
+
+		// หัวข้อของ portfolio เป็นระดับ 1 และชื่อ section เป็นระดับ 2
+		previousLevel := 2
+		for _, block := range section.PortfolioBlocks {
+			content := parseBlockContent(block.Content)
+			blockType := blockContentType(block, content)
+
+			if !blockIsEmpty(blockType, content) {
+				for _, field := range imagesMissingAltText(blockType, content) {
+					audit.add(AccessibilityIssue{ChecklistItem: ChecklistItem{
+						Code: CheckMissingAltText, Severity: ChecklistWarning, SectionID: section.ID, BlockID: block.ID, Field: "content." + field,
+						Message: "Image has no alt text; describe the image for screen readers",
+					}})
+				}
+			}
+			if textlessBlockTypes[blockType] {
+				continue
+			}
+
+			paint := sectionPaint
+			if blockType == "header" && !paint.explicit {
+				// หัวข้อใช้สีหลักของธีม
+				paint.text = primary
+			}
+			paint = audit.applyStyle(paint, parseBlockContent(block.BlockStyle), "block_style", section.ID, block.ID)
+			paint = audit.applyStyle(paint, map[string]interface{}{
+				"text_color": content["font_color"],
+				"font_size":  content["font_size"],
+			}, "content", section.ID, block.ID)
+
+			if blockType == "header" {
+				previousLevel = audit.checkHeadingLevel(content, previousLevel, section.ID, block.ID)
+			}
+			audit.checkFontSize(paint, section.ID, block.ID)
+			if paint.explicit {
+				large := blockType == "header" || paint.fontSize >= largeTextPx || (paint.bold && paint.fontSize >= largeBoldTextPx)
+				audit.checkContrast(paint, large, section.ID, block.ID)
+			}
+		}
+	}
+
+	score, passed := 100, true
+	for _, issue := range audit.Issues {
+		audit.Counts[issue.Code]++
+		score -= checklistPenalty[issue.Severity]
+		if issue.Severity == ChecklistError {
+			passed = false
+		}
+	}
+	if score < 0 {
+		score = 0
+	}
+	sort.SliceStable(audit.Issues, func(i, j int) bool {
+		return checklistPenalty[audit.Issues[i].Severity] > checklistPenalty[audit.Issues[j].Severity]
+	})
+	audit.Score, audit.Passed = score, passed
+	audit.CheckedAt = time.Now()
+	return audit
+}
+
+// auditTheme ธีมของระบบอาจสร้างก่อนมีการตรวจ contrast จึงตรวจซ้ำที่นี่
+func (a *AccessibilityAudit) auditTheme(colors *entity.Colors) {
+	background, err := entity.ParseHexColor(colors.BackgroundColor)
+	if err != nil {
+		return
+	}
+	checks := []struct {
+		field, color string
+		min          float64
+	}{
+		{"colors.primary_color", colors.PrimaryColor, entity.MinTextContrast},
+		{"colors.secondary_color", colors.SecondaryColor, entity.MinUIContrast},
+	}
+	for _, check := range checks {
+		fg, err := entity.ParseHexColor(check.color)
+		if err != nil {
+			continue
+		}
+		ratio := entity.ContrastRatioRGBA(fg.Over(background), background)
+		if ratio < check.min {
+			a.add(AccessibilityIssue{
+				ChecklistItem: ChecklistItem{
+					Code: A11yLowContrast, Severity: ChecklistError, Field: check.field,
+					Message: fmt.Sprintf("Color theme \"%s\": %s has a contrast of %s:1 against the background, at least %.1f:1 is required; pick another theme",
+						colors.ColorsName, strings.TrimPrefix(check.field, "colors."), formatRatio(ratio), check.min),
+				},
+				Foreground: fg.Hex(), Background: background.Hex(), Ratio: floorRatio(ratio), Required: check.min,
+			})
+		}
+	}
+}
+
+// applyStyle ใช้ background_color / text_color / font_size / font_weight จาก style ทับค่าที่สืบทอดมา
+func (a *AccessibilityAudit) applyStyle(paint a11yPaint, style map[string]interface{}, prefix string, sectionID, blockID uint) a11yPaint {
+	color := func(key string) (entity.RGBA, bool) {
+		raw, _ := style[key].(string)
+		if strings.TrimSpace(raw) == "" {
+			return entity.RGBA{}, false
+		}
+		c, err := entity.ParseHexColor(raw)
+		if err != nil {
+			a.add(AccessibilityIssue{ChecklistItem: ChecklistItem{
+				Code: A11yInvalidColor, Severity: ChecklistWarning, SectionID: sectionID, BlockID: blockID, Field: prefix + "." + key,
+				Message: fmt.Sprintf("Color %q is not a valid hex color and cannot be checked for contrast", raw),
+			}})
+			return entity.RGBA{}, false
+		}
+		return c, true
+	}
+
+	if bg, ok := color("background_color"); ok {
+		paint.background = bg.Over(paint.background)
+		paint.explicit = true
+	}
+	if fg, ok := color("text_color"); ok {
+		paint.text = fg
+		paint.explicit = true
+	}
+	if size, ok := cssFontSizePx(style["font_size"]); ok {
+		paint.fontSize = size
+	}
+	switch weight := fmt.Sprint(style["font_weight"]); weight {
+	case "bold", "bolder", "600", "700", "800", "900":
+		paint.bold = true
+	case "normal", "lighter", "100", "200", "300", "400", "500":
+		paint.bold = false
+	}
+	return paint
+}
+
+func (a *AccessibilityAudit) checkContrast(paint a11yPaint, large bool, sectionID, blockID uint) {
+	required := entity.MinTextContrast
+	if large {
+		required = entity.MinUIContrast
+	}
+	fg := paint.text.Over(paint.background)
+	ratio := entity.ContrastRatioRGBA(fg, paint.background)
+	if ratio >= required {
+		return
+	}
+	a.add(AccessibilityIssue{
+		ChecklistItem: ChecklistItem{
+			Code: A11yLowContrast, Severity: ChecklistError, SectionID: sectionID, BlockID: blockID,
+			Message: fmt.Sprintf("Text color %s on %s has a contrast of %s:1, at least %.1f:1 is required",
+				fg.Hex(), paint.background.Hex(), formatRatio(ratio), required),
+		},
+		Foreground: fg.Hex(), Background: paint.background.Hex(), Ratio: floorRatio(ratio), Required: required,
+	})
+}
+
+func (a *AccessibilityAudit) checkFontSize(paint a11yPaint, sectionID, blockID uint) {
+	if paint.fontSize == 0 || paint.fontSize >= MinReadableFontSizePx {
+		return
+	}
+	a.add(AccessibilityIssue{ChecklistItem: ChecklistItem{
+		Code: A11ySmallFont, Severity: ChecklistWarning, SectionID: sectionID, BlockID: blockID, Field: "font_size",
+		Message: fmt.Sprintf("Font size %spx is too small to read; use at least %dpx", strconv.FormatFloat(paint.fontSize, 'f', -1, 64), MinReadableFontSizePx),
+	}})
+}
+
+// checkHeadingLevel หัวข้อห้ามข้ามระดับ (เช่น 2 → 4) และระดับ 1 สงวนไว้ให้ชื่อ portfolio
+// คืนระดับที่ใช้เทียบกับหัวข้อถัดไป
+func (a *AccessibilityAudit) checkHeadingLevel(content map[string]interface{}, previous int, sectionID, blockID uint) int {
+	level := int(toUint(content["level"]))
+	if level == 0 {
+		return previous
+	}
+	issue := func(message string) {
+		a.add(AccessibilityIssue{ChecklistItem: ChecklistItem{
+			Code: A11yHeadingOrder, Severity: ChecklistWarning, SectionID: sectionID, BlockID: blockID, Field: "content.level", Message: message,
+		}})
+	}
+	switch {
+	case level == 1:
+		issue("Level 1 is reserved for the portfolio title; use level 2 or lower for headings inside a section")
+	case level > previous+1:
+		issue(fmt.Sprintf("Heading level %d skips level %d; screen reader users navigate by heading order", level, previous+1))
+	}
+	return level
+}
+
+// cssFontSizePx แปลง font_size (ตัวเลข px, "14px", "0.75rem", "10pt" หรือ token xs-3xl) เป็น px
+func cssFontSizePx(v interface{}) (float64, bool) {
+	switch size := v.(type) {
+	case float64:
+		return size, size > 0
+	case string:
+		size = strings.ToLower(strings.TrimSpace(size))
+		if px, ok := fontSizeTokens[size]; ok {
+			return px, true
+		}
+		units := []struct {
+			suffix string
+			scale  float64
+		}{{"px", 1}, {"rem", 16}, {"em", 16}, {"pt", 4.0 / 3}, {"", 1}}
+		for _, unit := range units {
+			if !strings.HasSuffix(size, unit.suffix) {
+				continue
+			}
+			n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(size, unit.suffix)), 64)
+			if err != nil || n <= 0 {
+				return 0, false
+			}
+			return n * unit.scale, true
+		}
+	}
+	return 0, false
+}
+
+// floorRatio ปัดลงเพื่อไม่ให้ 4.499 แสดงเป็น 4.5 ทั้งที่ไม่ผ่าน
+func floorRatio(ratio float64) float64 {
+	return math.Floor(ratio*100) / 100
+}
+
+func formatRatio(ratio float64) string {
+	return strconv.FormatFloat(floorRatio(ratio), 'f', 2, 64)
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ชนิดของ block ที่อ้างถึงข้อมูลของเจ้าของ portfolio แทนการคัดลอกมาเก็บใน content
+const (
+	BlockRefActivity      = "activity"
+	BlockRefWorking       = "working"
+	BlockRefProfile       = "profile"
+	BlockRefAcademicScore = "academic_score"
+)
+
+// ข้อมูลโปรไฟล์ที่ block "profile" แสดง (ไม่รวมเลขบัตรประชาชน)
+// อีเมล/เบอร์โทรเว้นว่างไว้เมื่อแสดงผ่านลิงก์สาธารณะ
+type profileSourceData struct {
+	FirstNameTH     string            `json:"first_name_th"`
+	LastNameTH      string            `json:"last_name_th"`
+	FirstNameEN     string            `json:"first_name_en"`
+	LastNameEN      string            `json:"last_name_en"`
+	Email           string            `json:"email,omitempty"`
+	Phone           string            `json:"phone,omitempty"`
+	ProfileImageURL string            `json:"profile_image_url"`
+	Education       *entity.Education `json:"education,omitempty"`
+}
+
+// referencedItems กิจกรรม/ผลงานที่ block อ้างถึง ตาม id
+type referencedItems struct {
+	Activities map[uint]entity.Activity
+	Workings   map[uint]entity.Working
+}
+
+// loadReferencedItems โหลดกิจกรรม/ผลงานตาม id (เฉพาะที่ยังไม่ถูกลบ)
+func loadReferencedItems(db *gorm.DB, activityIDs, workingIDs []uint) (referencedItems, error) {
+	items := referencedItems{
+		Activities: map[uint]entity.Activity{},
+		Workings:   map[uint]entity.Working{},
+	}
+	if len(activityIDs) > 0 {
+		var activities []entity.Activity
+		if err := db.
+			Preload("ActivityDetail.TypeActivity").
+			Preload("ActivityDetail.LevelActivity").
+			Preload("ActivityDetail.Images").
+			Preload("Reward").
+			Where("id IN ?", activityIDs).
+			Find(&activities).Error; err != nil {
+			return items, err
+		}
+		for _, a := range activities {
+			items.Activities[a.ID] = a
+		}
+	}
+	if len(workingIDs) > 0 {
+		var workings []entity.Working
+		if err := db.
+			Preload("WorkingDetail.TypeWorking").
+			Preload("WorkingDetail.Images").
+			Preload("WorkingDetail.Links").
+			Where("id IN ?", workingIDs).
+			Find(&workings).Error; err != nil {
+			return items, err
+		}
+		for _, w := range workings {
+			items.Workings[w.ID] = w
+		}
+	}
+	return items, nil
+}
+
+// blockReference ชนิดและ id ของสิ่งที่ block อ้างถึง (ok = false ถ้าไม่ใช่ block แบบอ้างอิง)
+func blockReference(block entity.PortfolioBlock) (string, uint, bool) {
+	content := parseBlockContent(block.Content)
+	switch refType := blockContentType(block, content); refType {
+	case BlockRefActivity, BlockRefWorking:
+		id := toUint(content["data_id"])
+		return refType, id, id != 0
+	case BlockRefProfile, BlockRefAcademicScore:
+		return refType, 0, true
+	}
+	return "", 0, false
+}
+
+// HydratePortfolioBlocks เติม Source ของ block แบบอ้างอิงด้วยข้อมูลล่าสุดจากต้นทาง
+// กิจกรรม/ผลงานต้องเป็นของเจ้าของ portfolio เท่านั้น ถ้าถูกลบแล้วจะได้ status "deleted"
+func HydratePortfolioBlocks(db *gorm.DB, portfolios []entity.Portfolio) error {
+	return hydratePortfolioBlocks(db, portfolios, false)
+}
+
+// HydratePublicPortfolioBlocks เหมือน HydratePortfolioBlocks แต่ใช้กับลิงก์สาธารณะ (/p/:token)
+// block โปรไฟล์จึงไม่มีอีเมลและเบอร์โทรของเจ้าของ
+func HydratePublicPortfolioBlocks(db *gorm.DB, portfolios []entity.Portfolio) error {
+	return hydratePortfolioBlocks(db, portfolios, true)
+}
+
+func hydratePortfolioBlocks(db *gorm.DB, portfolios []entity.Portfolio, public bool) error {
+	var activityIDs, workingIDs, userIDs []uint
+	needProfile, needScore := map[uint]bool{}, map[uint]bool{}
+	for _, p := range portfolios {
+		for _, section := range p.PortfolioSections {
+			for _, block := range section.PortfolioBlocks {
+				refType, id, ok := blockReference(block)
+				if !ok {
+					continue
+				}
+				switch refType {
+				case BlockRefActivity:
+					activityIDs = append(activityIDs, id)
+				case BlockRefWorking:
+					workingIDs = append(workingIDs, id)
+				case BlockRefProfile:
+					needProfile[p.UserID] = true
+				case BlockRefAcademicScore:
+					needScore[p.UserID] = true
+				}
+			}
+		}
+	}
+
+	items, err := loadReferencedItems(db, activityIDs, workingIDs)
+	if err != nil {
+		return err
+	}
+
+	profiles := map[uint]profileSourceData{}
+	if len(needProfile) > 0 {
+		for id := range needProfile {
+			userIDs = append(userIDs, id)
+		}
+		columns := []string{"id", "first_name_th", "last_name_th", "first_name_en", "last_name_en", "profile_image_url"}
+		if !public {
+			columns = append(columns, "email", "phone")
+		}
+		var users []entity.User
+		if err := db.Select(columns).Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return err
+		}
+		var educations []entity.Education
+		if err := db.Preload("EducationLevel").Preload("School").
+			Where("user_id IN ?", userIDs).Find(&educations).Error; err != nil {
+			return err
+		}
+		for _, u := range users {
+			profiles[u.ID] = profileSourceData{
+				FirstNameTH:     u.FirstNameTH,
+				LastNameTH:      u.LastNameTH,
+				FirstNameEN:     u.FirstNameEN,
+				LastNameEN:      u.LastNameEN,
+				Email:           u.Email,
+				Phone:           u.Phone,
+				ProfileImageURL: u.ProfileImageURL,
+			}
+		}
+		for i := range educations {
+			if profile, ok := profiles[educations[i].UserID]; ok {
+				profile.Education = &educations[i]
+				profiles[educations[i].UserID] = profile
+			}
+		}
+	}
+
+	scores := map[uint]entity.AcademicScore{}
+	if len(needScore) > 0 {
+		var ids []uint
+		for id := range needScore {
+			ids = append(ids, id)
+		}
+		var rows []entity.AcademicScore
+		if err := db.Where("user_id IN ?", ids).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, s := range rows {
+			scores[s.UserID] = s
+		}
+	}
+
+	for pi := range portfolios {
+		owner := portfolios[pi].UserID
+		for si := range portfolios[pi].PortfolioSections {
+			blocks := portfolios[pi].PortfolioSections[si].PortfolioBlocks
+			for bi := range blocks {
+				refType, id, ok := blockReference(blocks[bi])
+				if !ok {
+					continue
+				}
+				source := &entity.PortfolioBlockSource{Type: refType, ID: id, Status: entity.BlockSourceDeleted}
+				switch refType {
+				case BlockRefActivity:
+					if a, found := items.Activities[id]; found && a.UserID == owner {
+						source.Status, source.Data = entity.BlockSourceOK, a
+					}
+				case BlockRefWorking:
+					if w, found := items.Workings[id]; found && w.UserID == owner {
+						source.Status, source.Data = entity.BlockSourceOK, w
+					}
+				case BlockRefProfile:
+					source.ID = owner
+					if profile, found := profiles[owner]; found {
+						source.Status, source.Data = entity.BlockSourceOK, profile
+					}
+				case BlockRefAcademicScore:
+					source.ID = owner
+					source.Status = entity.BlockSourceMissing
+					if score, found := scores[owner]; found {
+						source.ID = score.ID
+						source.Status, source.Data = entity.BlockSourceOK, score
+					}
+				}
+				blocks[bi].Source = source
+			}
+		}
+	}
+	return nil
+}
+
+// NormalizeReferenceContent ลบสำเนาข้อมูล (data) ออกจาก content ของ block กิจกรรม/ผลงานที่มี data_id
+// เพื่อให้ block เก็บเฉพาะ id และแสดงข้อมูลล่าสุดเสมอ
+func NormalizeReferenceContent(blockType string, content datatypes.JSON) datatypes.JSON {
+	value, err := decodeBlockJSON(content)
+	if err != nil || value == nil {
+		return content
+	}
+	m := value.(map[string]interface{})
+	refType := ResolveBlockType(blockType, m)
+	if refType != BlockRefActivity && refType != BlockRefWorking {
+		return content
+	}
+	if _, hasID := m["data_id"]; !hasID {
+		return content
+	}
+	if _, hasData := m["data"]; !hasData {
+		return content
+	}
+	delete(m, "data")
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return content
+	}
+	return datatypes.JSON(raw)
+}
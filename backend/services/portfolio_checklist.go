@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// ระดับความสำคัญของรายการใน checklist
+const (
+	ChecklistError   = "error"
+	ChecklistWarning = "warning"
+	ChecklistInfo    = "info"
+)
+
+// รหัสของรายการใน checklist (frontend ใช้เลือกปุ่มแก้ไข)
+const (
+	CheckEmptySection           = "empty_section"
+	CheckEmptyBlock             = "empty_block"
+	CheckMissingAltText         = "missing_alt_text"
+	CheckDeletedReference       = "deleted_reference"
+	CheckMissingProfile         = "missing_profile"
+	CheckMissingEducation       = "missing_education"
+	CheckMissingAcademicScore   = "missing_academic_score"
+	CheckMissingRequiredDoc     = "missing_required_document"
+	CheckMissingRequiredSection = "missing_required_section"
+	CheckPageLimitExceeded      = "page_limit_exceeded"
+	CheckPageCountUnavailable   = "page_count_unavailable"
+)
+
+// คะแนนที่หักต่อรายการ (คะแนนเต็ม 100)
+var checklistPenalty = map[string]int{
+	ChecklistError:   20,
+	ChecklistWarning: 5,
+	ChecklistInfo:    0,
+}
+
+// ChecklistItem สิ่งที่ควรแก้ก่อนส่ง portfolio
+type ChecklistItem struct {
+	Code      string `json:"code"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	SectionID uint   `json:"section_id,omitempty"`
+	BlockID   uint   `json:"block_id,omitempty"`
+	Field     string `json:"field,omitempty"`
+}
+
+// PortfolioChecklist ผลตรวจความครบถ้วนของ portfolio
+type PortfolioChecklist struct {
+	PortfolioID  uint            `json:"portfolio_id"`
+	CurriculumID uint            `json:"curriculum_id,omitempty"`
+	Score        int             `json:"score"`
+	Ready        bool            `json:"ready"`
+	PageCount    int             `json:"page_count"`
+	MaxPages     int             `json:"max_pages,omitempty"`
+	Items        []ChecklistItem `json:"items"`
+	CheckedAt    time.Time       `json:"checked_at"`
+}
+
+func (c *PortfolioChecklist) add(item ChecklistItem) {
+	c.Items = append(c.Items, item)
+}
+
+// BuildPortfolioChecklist ตรวจ portfolio ก่อนส่ง
+// curriculumID = 0 จะใช้หลักสูตรที่ portfolio นี้ถูกกำหนดไว้ (ถ้ามีหลายหลักสูตรใช้ตัวแรก)
+func BuildPortfolioChecklist(db *gorm.DB, portfolioID, curriculumID uint) (*PortfolioChecklist, error) {
+	portfolio, sources, err := LoadPortfolioForPDF(db, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	if curriculumID == 0 {
+		var designation entity.PortfolioCurriculum
+		err := db.Where("portfolio_id = ?", portfolioID).Order("curriculum_id ASC").First(&designation).Error
+		if err == nil {
+			curriculumID = designation.CurriculumID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	checklist := &PortfolioChecklist{PortfolioID: portfolioID, CurriculumID: curriculumID, Items: []ChecklistItem{}}
+
+	portfolios := []entity.Portfolio{*portfolio}
+	if err := HydratePortfolioBlocks(db, portfolios); err != nil {
+		return nil, err
+	}
+	checkPortfolioContent(checklist, &portfolios[0])
+
+	if err := checkPortfolioOwnerData(db, checklist, portfolio); err != nil {
+		return nil, err
+	}
+
+	var curriculum entity.Curriculum
+	if curriculumID != 0 {
+		if err := db.Preload("RequiredDocuments.DocumentType").Preload("RequiredSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).First(&curriculum, curriculumID).Error; err != nil {
+			return nil, err
+		}
+		checkRequiredDocuments(checklist, portfolio, curriculum.RequiredDocuments)
+		for _, item := range missingRequiredSections(portfolio, &curriculum) {
+			checklist.add(item)
+		}
+	}
+
+	// ประมาณจำนวนหน้าจากการ render PDF จริง
+	checklist.MaxPages = curriculum.PortfolioMaxPages
+	if result, err := RenderPortfolioPDF(portfolio, sources, DefaultPortfolioPDFOptions()); err != nil {
+		checklist.add(ChecklistItem{Code: CheckPageCountUnavailable, Severity: ChecklistInfo, Message: "Page count could not be estimated: " + err.Error()})
+	} else {
+		checklist.PageCount = result.PageCount
+		if checklist.MaxPages > 0 && result.PageCount > checklist.MaxPages {
+			checklist.add(ChecklistItem{
+				Code:     CheckPageLimitExceeded,
+				Severity: ChecklistError,
+				Message:  "Portfolio is longer than the curriculum allows; remove or shorten sections to fit the page limit",
+			})
+		}
+	}
+
+	score, ready := 100, true
+	for _, item := range checklist.Items {
+		score -= checklistPenalty[item.Severity]
+		if item.Severity == ChecklistError {
+			ready = false
+		}
+	}
+	if score < 0 {
+		score = 0
+	}
+	sort.SliceStable(checklist.Items, func(i, j int) bool {
+		return checklistPenalty[checklist.Items[i].Severity] > checklistPenalty[checklist.Items[j].Severity]
+	})
+	checklist.Score, checklist.Ready = score, ready
+	checklist.CheckedAt = time.Now()
+	return checklist, nil
+}
+
+// checkPortfolioContent section ที่เปิดอยู่แต่ว่าง, block ที่ไม่มีเนื้อหา, รูปที่ไม่มี alt text และ block ที่อ้างถึงข้อมูลที่ถูกลบ
+func checkPortfolioContent(checklist *PortfolioChecklist, portfolio *entity.Portfolio) {
+	for _, section := range portfolio.PortfolioSections {
+		if !section.IsEnabled {
+			continue
+		}
+		filled := 0
+		for _, block := range section.PortfolioBlocks {
+			content := parseBlockContent(block.Content)
+			blockType := blockContentType(block, content)
+
+			if block.Source != nil && block.Source.Status == entity.BlockSourceDeleted {
+				checklist.add(ChecklistItem{
+					Code: CheckDeletedReference, Severity: ChecklistError, SectionID: section.ID, BlockID: block.ID,
+					Message: "Block refers to a " + blockType + " that no longer exists; pick another one or remove the block",
+				})
+				continue
+			}
+			if blockIsEmpty(blockType, content) {
+				checklist.add(ChecklistItem{
+					Code: CheckEmptyBlock, Severity: ChecklistWarning, SectionID: section.ID, BlockID: block.ID,
+					Message: "Block \"" + blockType + "\" in section \"" + section.SectionTitle + "\" has no content",
+				})
+				continue
+			}
+			filled++
+
+			for _, field := range imagesMissingAltText(blockType, content) {
+				checklist.add(ChecklistItem{
+					Code: CheckMissingAltText, Severity: ChecklistWarning, SectionID: section.ID, BlockID: block.ID, Field: field,
+					Message: "Image has no alt text; describe the image for screen readers",
+				})
+			}
+		}
+		if filled == 0 {
+			checklist.add(ChecklistItem{
+				Code: CheckEmptySection, Severity: ChecklistWarning, SectionID: section.ID,
+				Message: "Section \"" + section.SectionTitle + "\" is enabled but empty; add content or hide the section",
+			})
+		}
+	}
+}
+
+// blockIsEmpty block ที่ไม่มีอะไรแสดง (divider/spacer และ block แบบอ้างอิงถือว่าไม่ว่าง)
+func blockIsEmpty(blockType string, content map[string]interface{}) bool {
+	switch blockType {
+	case "text", "header":
+		// บล็อกข้อความจากหน้าแก้ไข section บันทึกเป็น {type: "text", title, detail}
+		return contentString(content, "text", "detail", "title") == ""
+	case "image":
+		return contentString(content, "url", "image_url") == ""
+	case "gallery", "education", "skills", "awards", "experience":
+		key := "items"
+		if blockType == "gallery" {
+			key = "images"
+		}
+		list, _ := content[key].([]interface{})
+		return len(list) == 0
+	case "contact":
+		links, _ := content["links"].([]interface{})
+		return contentString(content, "email", "phone") == "" && len(links) == 0
+	}
+	return false
+}
+
+// imagesMissingAltText field ของรูปที่ไม่มี alt_text เช่น "alt_text" หรือ "images[2].alt_text"
+func imagesMissingAltText(blockType string, content map[string]interface{}) []string {
+	var fields []string
+	switch blockType {
+	case "image":
+		if contentString(content, "alt_text") == "" {
+			fields = append(fields, "alt_text")
+		}
+	case "gallery":
+		images, _ := content["images"].([]interface{})
+		for i, img := range images {
+			m, _ := img.(map[string]interface{})
+			if m == nil || contentString(m, "alt_text") == "" {
+				fields = append(fields, "images["+strconv.Itoa(i)+"].alt_text")
+			}
+		}
+	}
+	return fields
+}
+
+// checkPortfolioOwnerData ข้อมูลส่วนตัว การศึกษา และผลการเรียนของเจ้าของ portfolio
+func checkPortfolioOwnerData(db *gorm.DB, checklist *PortfolioChecklist, portfolio *entity.Portfolio) error {
+	user := portfolio.User
+	profileFields := []struct{ field, value string }{
+		{"first_name_th", user.FirstNameTH},
+		{"last_name_th", user.LastNameTH},
+		{"first_name_en", user.FirstNameEN},
+		{"last_name_en", user.LastNameEN},
+		{"email", user.Email},
+		{"phone", user.Phone},
+		{"profile_image_url", user.ProfileImageURL},
+	}
+	for _, f := range profileFields {
+		if strings.TrimSpace(f.value) == "" {
+			checklist.add(ChecklistItem{
+				Code: CheckMissingProfile, Severity: ChecklistWarning, Field: f.field,
+				Message: "Profile is missing " + strings.ReplaceAll(f.field, "_", " ") + "; complete it on the profile page",
+			})
+		}
+	}
+
+	var educations, scores int64
+	if err := db.Model(&entity.Education{}).Where("user_id = ?", portfolio.UserID).Count(&educations).Error; err != nil {
+		return err
+	}
+	if educations == 0 {
+		checklist.add(ChecklistItem{Code: CheckMissingEducation, Severity: ChecklistError, Message: "Education information is missing; add your current school"})
+	}
+	if err := db.Model(&entity.AcademicScore{}).Where("user_id = ?", portfolio.UserID).Count(&scores).Error; err != nil {
+		return err
+	}
+	if scores == 0 {
+		checklist.add(ChecklistItem{Code: CheckMissingAcademicScore, Severity: ChecklistWarning, Message: "Academic scores are missing; add your GPAX"})
+	}
+	return nil
+}
+
+// checkRequiredDocuments เอกสารที่หลักสูตรบังคับต้องมีใน portfolio
+// ถือว่ามีเมื่อ block มี document_type_id ตรงกัน หรือชื่อ section ตรงกับชื่อประเภทเอกสาร
+func checkRequiredDocuments(checklist *PortfolioChecklist, portfolio *entity.Portfolio, required []entity.CurriculumRequiredDocument) {
+	referenced := map[uint]bool{}
+	var titles []string
+	for _, section := range portfolio.PortfolioSections {
+		if !section.IsEnabled {
+			continue
+		}
+		titles = append(titles, strings.ToLower(section.SectionTitle), strings.ToLower(section.SectionPortKey))
+		for _, block := range section.PortfolioBlocks {
+			if id := toUint(parseBlockContent(block.Content)["document_type_id"]); id != 0 {
+				referenced[id] = true
+			}
+		}
+	}
+
+	for _, doc := range required {
+		if doc.IsOptional || referenced[doc.DocumentTypeID] {
+			continue
+		}
+		name := ""
+		if doc.DocumentType != nil {
+			name = strings.TrimSpace(doc.DocumentType.Name)
+		}
+		if name != "" && containsAny(titles, strings.ToLower(name)) {
+			continue
+		}
+		if name == "" {
+			name = "document #" + strconv.Itoa(int(doc.DocumentTypeID))
+		}
+		message := "Required document \"" + name + "\" is not in the portfolio"
+		if doc.Note != "" {
+			message += " (" + doc.Note + ")"
+		}
+		checklist.add(ChecklistItem{Code: CheckMissingRequiredDoc, Severity: ChecklistError, Field: name, Message: message})
+	}
+}
+
+func containsAny(values []string, needle string) bool {
+	for _, v := range values {
+		if v != "" && strings.Contains(v, needle) {
+			return true
+		}
+	}
+	return false
+}
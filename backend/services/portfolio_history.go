@@ -0,0 +1,509 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// action ของรายการที่เกิดจากการย้อนประวัติ
+const (
+	PortfolioChangeUndo    = "undo"
+	PortfolioChangeRestore = "restore"
+)
+
+// ค่า retention เริ่มต้น ปรับได้ผ่าน env PORTFOLIO_HISTORY_MAX_CHANGES / PORTFOLIO_HISTORY_MAX_DAYS / PORTFOLIO_MAX_RESTORE_POINTS
+const (
+	DefaultPortfolioHistoryMaxChanges = 500
+	DefaultPortfolioHistoryMaxDays    = 90
+	DefaultPortfolioMaxRestorePoints  = 20
+)
+
+var (
+	ErrNothingToUndo          = errors.New("nothing to undo")
+	ErrRestorePointLimit      = errors.New("restore point limit reached")
+	ErrPortfolioChangeExpired = errors.New("change not found in retained history")
+)
+
+// PortfolioHistoryRetention ขีดจำกัดการเก็บประวัติต่อ portfolio
+type PortfolioHistoryRetention struct {
+	MaxChanges       int
+	MaxAge           time.Duration
+	MaxRestorePoints int
+}
+
+// DefaultPortfolioHistoryRetention อ่านค่าจาก env ถ้าไม่ตั้งใช้ค่าเริ่มต้น
+func DefaultPortfolioHistoryRetention() PortfolioHistoryRetention {
+	return PortfolioHistoryRetention{
+		MaxChanges:       envInt("PORTFOLIO_HISTORY_MAX_CHANGES", DefaultPortfolioHistoryMaxChanges),
+		MaxAge:           time.Duration(envInt("PORTFOLIO_HISTORY_MAX_DAYS", DefaultPortfolioHistoryMaxDays)) * 24 * time.Hour,
+		MaxRestorePoints: envInt("PORTFOLIO_MAX_RESTORE_POINTS", DefaultPortfolioMaxRestorePoints),
+	}
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// sectionState คือข้อมูลของ section ที่ถูกเก็บใน Before/After
+type sectionState struct {
+	PortfolioID    uint           `json:"portfolio_id"`
+	SectionPortKey string         `json:"section_port_key"`
+	SectionTitle   string         `json:"section_title"`
+	IsEnabled      bool           `json:"is_enabled"`
+	SectionOrder   int            `json:"section_order"`
+	SectionStyle   datatypes.JSON `json:"section_style"`
+}
+
+// blockState คือข้อมูลของ block ที่ถูกเก็บใน Before/After
+type blockState struct {
+	PortfolioSectionID uint           `json:"portfolio_section_id"`
+	BlockPortType      string         `json:"block_port_type"`
+	BlockOrder         int            `json:"block_order"`
+	BlockStyle         datatypes.JSON `json:"block_style"`
+	Content            datatypes.JSON `json:"content"`
+}
+
+// SnapshotPortfolioEntity อ่านสถานะปัจจุบันของ section/block คืน nil ถ้าไม่มีหรือถูกลบไปแล้ว
+func SnapshotPortfolioEntity(tx *gorm.DB, entityType string, id uint) (datatypes.JSON, error) {
+	if id == 0 {
+		return nil, nil
+	}
+
+	var state interface{}
+	switch entityType {
+	case entity.PortfolioChangeSection:
+		var s entity.PortfolioSection
+		if err := tx.First(&s, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		state = sectionState{s.PortfolioID, s.SectionPortKey, s.SectionTitle, s.IsEnabled, s.SectionOrder, s.SectionStyle}
+	case entity.PortfolioChangeBlock:
+		var b entity.PortfolioBlock
+		if err := tx.First(&b, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		state = blockState{b.PortfolioSectionID, b.BlockPortType, b.BlockOrder, b.BlockStyle, b.Content}
+	default:
+		return nil, errors.New("unknown entity type " + entityType)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// applyPortfolioEntityState ทำให้ section/block มีสถานะตาม state
+// state ว่าง = ลบ (soft delete), ถ้าแถวถูกลบไปแล้วจะกู้คืนด้วย ID เดิม
+func applyPortfolioEntityState(tx *gorm.DB, entityType string, id uint, state datatypes.JSON) error {
+	var model interface{}
+	var values map[string]interface{}
+
+	switch entityType {
+	case entity.PortfolioChangeSection:
+		model = &entity.PortfolioSection{}
+		if !isNullState(state) {
+			var s sectionState
+			if err := json.Unmarshal(state, &s); err != nil {
+				return err
+			}
+			values = map[string]interface{}{
+				"portfolio_id":     s.PortfolioID,
+				"section_port_key": s.SectionPortKey,
+				"section_title":    s.SectionTitle,
+				"is_enabled":       s.IsEnabled,
+				"section_order":    s.SectionOrder,
+				"section_style":    s.SectionStyle,
+			}
+		}
+	case entity.PortfolioChangeBlock:
+		model = &entity.PortfolioBlock{}
+		if !isNullState(state) {
+			var b blockState
+			if err := json.Unmarshal(state, &b); err != nil {
+				return err
+			}
+			values = map[string]interface{}{
+				"portfolio_section_id": b.PortfolioSectionID,
+				"block_port_type":      b.BlockPortType,
+				"block_order":          b.BlockOrder,
+				"block_style":          b.BlockStyle,
+				"content":              b.Content,
+			}
+		}
+	default:
+		return errors.New("unknown entity type " + entityType)
+	}
+
+	if values == nil {
+		return tx.Delete(model, id).Error
+	}
+
+	values["deleted_at"] = nil
+	values["updated_at"] = time.Now()
+	result := tx.Unscoped().Model(model).Where("id = ?", id).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// แถวถูกลบถาวรไปแล้ว สร้างใหม่ด้วย ID เดิม
+	values["id"] = id
+	values["created_at"] = time.Now()
+	return tx.Model(model).Create(values).Error
+}
+
+func isNullState(state datatypes.JSON) bool {
+	trimmed := bytes.TrimSpace(state)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+func sameState(a, b datatypes.JSON) bool {
+	if isNullState(a) || isNullState(b) {
+		return isNullState(a) == isNullState(b)
+	}
+	return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+}
+
+// PortfolioChangeSet รวมการแก้ไขที่เกิดใน revision เดียวกัน (หนึ่ง request) undo จะย้อนทั้งชุด
+type PortfolioChangeSet struct {
+	tx          *gorm.DB
+	PortfolioID uint
+	UserID      uint
+	Revision    uint
+
+	// Changes รายการที่บันทึกแล้วในชุดนี้ (ใช้แจ้งห้องแก้ไขหลัง commit)
+	Changes []entity.PortfolioChange
+}
+
+// BeginPortfolioChangeSet เพิ่ม revision ของ portfolio แล้วเริ่มชุดการแก้ไขใหม่ (ต้องเรียกใน transaction)
+func BeginPortfolioChangeSet(tx *gorm.DB, portfolioID, userID uint) (*PortfolioChangeSet, error) {
+	if err := tx.Model(&entity.Portfolio{}).Where("id = ?", portfolioID).
+		UpdateColumn("revision", gorm.Expr("revision + 1")).Error; err != nil {
+		return nil, err
+	}
+	var portfolio entity.Portfolio
+	if err := tx.Select("id", "revision").First(&portfolio, portfolioID).Error; err != nil {
+		return nil, err
+	}
+	return NewPortfolioChangeSet(tx, portfolioID, userID, portfolio.Revision)
+}
+
+// NewPortfolioChangeSet เริ่มชุดการแก้ไขของ revision ที่จองไว้แล้ว (เช่น batch ที่เพิ่ม revision เอง)
+// ตัดประวัติเก่าที่เกิน retention ก่อนเริ่มบันทึก
+func NewPortfolioChangeSet(tx *gorm.DB, portfolioID, userID, revision uint) (*PortfolioChangeSet, error) {
+	if err := PrunePortfolioHistory(tx, portfolioID, DefaultPortfolioHistoryRetention()); err != nil {
+		return nil, err
+	}
+	return &PortfolioChangeSet{tx: tx, PortfolioID: portfolioID, UserID: userID, Revision: revision}, nil
+}
+
+// Track เก็บสถานะก่อน/หลัง mutate แล้วบันทึกเป็น PortfolioChange
+// entityID = 0 สำหรับการสร้างใหม่ ให้ mutate คืน ID ที่สร้าง
+func (s *PortfolioChangeSet) Track(action, entityType string, entityID uint, mutate func() (uint, error)) error {
+	before, err := SnapshotPortfolioEntity(s.tx, entityType, entityID)
+	if err != nil {
+		return err
+	}
+	id, err := mutate()
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		id = entityID
+	}
+	after, err := SnapshotPortfolioEntity(s.tx, entityType, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.record(action, entityType, id, before, after, nil)
+	return err
+}
+
+// record บันทึก change ถ้าสถานะเปลี่ยนจริง คืน true เมื่อมีการบันทึก
+func (s *PortfolioChangeSet) record(action, entityType string, id uint, before, after datatypes.JSON, reverts *uint) (bool, error) {
+	if sameState(before, after) {
+		return false, nil
+	}
+	change := entity.PortfolioChange{
+		PortfolioID:     s.PortfolioID,
+		UserID:          s.UserID,
+		Revision:        s.Revision,
+		Action:          action,
+		EntityType:      entityType,
+		EntityID:        id,
+		Before:          before,
+		After:           after,
+		RevertsChangeID: reverts,
+	}
+	if err := s.tx.Create(&change).Error; err != nil {
+		return false, err
+	}
+	s.Changes = append(s.Changes, change)
+	return true, nil
+}
+
+// setState เปลี่ยน entity เป็น state แล้วบันทึกเป็น change ของชุดนี้
+func (s *PortfolioChangeSet) setState(action, entityType string, id uint, state datatypes.JSON, reverts *uint) (bool, error) {
+	before, err := SnapshotPortfolioEntity(s.tx, entityType, id)
+	if err != nil {
+		return false, err
+	}
+	if sameState(before, state) {
+		return false, nil
+	}
+	if err := applyPortfolioEntityState(s.tx, entityType, id, state); err != nil {
+		return false, err
+	}
+	after, err := SnapshotPortfolioEntity(s.tx, entityType, id)
+	if err != nil {
+		return false, err
+	}
+	return s.record(action, entityType, id, before, after, reverts)
+}
+
+// UndoLastChange ย้อนการแก้ไขชุดล่าสุด (revision ล่าสุด) ที่ยังไม่ถูก undo
+// รายการ undo เองจะไม่ถูก undo ซ้ำ เพื่อให้กด undo ต่อเนื่องย้อนไปทีละขั้นได้
+func (s *PortfolioChangeSet) UndoLastChange() (int, error) {
+	undone := s.tx.Model(&entity.PortfolioChange{}).
+		Select("reverts_change_id").
+		Where("portfolio_id = ? AND reverts_change_id IS NOT NULL", s.PortfolioID)
+
+	var last entity.PortfolioChange
+	err := s.tx.Where("portfolio_id = ? AND reverts_change_id IS NULL AND id NOT IN (?)", s.PortfolioID, undone).
+		Order("revision DESC, id DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrNothingToUndo
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var changes []entity.PortfolioChange
+	if err := s.tx.Where("portfolio_id = ? AND revision = ? AND reverts_change_id IS NULL AND id NOT IN (?)", s.PortfolioID, last.Revision, undone).
+		Order("id DESC").Find(&changes).Error; err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, change := range changes {
+		id := change.ID
+		changed, err := s.setState(PortfolioChangeUndo, change.EntityType, change.EntityID, change.Before, &id)
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// RollbackToChange คืน portfolio เป็นสถานะทันทีหลัง change นั้น
+// สถานะของแต่ละ entity คือ Before ของการแก้ไขครั้งแรกหลังจาก change นั้น
+func (s *PortfolioChangeSet) RollbackToChange(changeID uint) (int, error) {
+	var target entity.PortfolioChange
+	if err := s.tx.Where("portfolio_id = ?", s.PortfolioID).First(&target, changeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrPortfolioChangeExpired
+		}
+		return 0, err
+	}
+
+	var later []entity.PortfolioChange
+	if err := s.tx.Where("portfolio_id = ? AND id > ?", s.PortfolioID, changeID).
+		Order("id ASC").Find(&later).Error; err != nil {
+		return 0, err
+	}
+
+	states := map[portfolioEntityKey]datatypes.JSON{}
+	for _, change := range later {
+		key := portfolioEntityKey{change.EntityType, change.EntityID}
+		if _, seen := states[key]; !seen {
+			states[key] = change.Before
+		}
+	}
+	return s.applyStates(states)
+}
+
+// RestoreSnapshot คืน portfolio ตาม snapshot ของ restore point
+// section/block ที่ไม่มีใน snapshot จะถูกลบ
+func (s *PortfolioChangeSet) RestoreSnapshot(snapshot datatypes.JSON) (int, error) {
+	var saved portfolioSnapshot
+	if err := json.Unmarshal(snapshot, &saved); err != nil {
+		return 0, err
+	}
+
+	current, err := takePortfolioSnapshot(s.tx, s.PortfolioID)
+	if err != nil {
+		return 0, err
+	}
+
+	states := map[portfolioEntityKey]datatypes.JSON{}
+	for id := range current.Sections {
+		states[portfolioEntityKey{entity.PortfolioChangeSection, id}] = nil
+	}
+	for id := range current.Blocks {
+		states[portfolioEntityKey{entity.PortfolioChangeBlock, id}] = nil
+	}
+	for id, state := range saved.Sections {
+		states[portfolioEntityKey{entity.PortfolioChangeSection, id}] = state
+	}
+	for id, state := range saved.Blocks {
+		states[portfolioEntityKey{entity.PortfolioChangeBlock, id}] = state
+	}
+	return s.applyStates(states)
+}
+
+type portfolioEntityKey struct {
+	EntityType string
+	ID         uint
+}
+
+// applyStates ตั้งค่า section ก่อน block (block ต้องมี section ให้อ้างถึง) แล้วบันทึกเป็น restore
+func (s *PortfolioChangeSet) applyStates(states map[portfolioEntityKey]datatypes.JSON) (int, error) {
+	keys := make([]portfolioEntityKey, 0, len(states))
+	for key := range states {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].EntityType != keys[j].EntityType {
+			return keys[i].EntityType == entity.PortfolioChangeSection
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	applied := 0
+	for _, key := range keys {
+		changed, err := s.setState(PortfolioChangeRestore, key.EntityType, key.ID, states[key], nil)
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// portfolioSnapshot สถานะของทุก section/block ที่ยังไม่ถูกลบ แยกตาม ID
+type portfolioSnapshot struct {
+	Sections map[uint]datatypes.JSON `json:"sections"`
+	Blocks   map[uint]datatypes.JSON `json:"blocks"`
+}
+
+func takePortfolioSnapshot(tx *gorm.DB, portfolioID uint) (portfolioSnapshot, error) {
+	snapshot := portfolioSnapshot{Sections: map[uint]datatypes.JSON{}, Blocks: map[uint]datatypes.JSON{}}
+
+	var sectionIDs []uint
+	if err := tx.Model(&entity.PortfolioSection{}).Where("portfolio_id = ?", portfolioID).Pluck("id", &sectionIDs).Error; err != nil {
+		return snapshot, err
+	}
+	for _, id := range sectionIDs {
+		state, err := SnapshotPortfolioEntity(tx, entity.PortfolioChangeSection, id)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.Sections[id] = state
+	}
+
+	var blockIDs []uint
+	if err := tx.Model(&entity.PortfolioBlock{}).Where("portfolio_section_id IN (?)", sectionIDs).Pluck("id", &blockIDs).Error; err != nil {
+		return snapshot, err
+	}
+	for _, id := range blockIDs {
+		state, err := SnapshotPortfolioEntity(tx, entity.PortfolioChangeBlock, id)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.Blocks[id] = state
+	}
+	return snapshot, nil
+}
+
+// CreatePortfolioRestorePoint บันทึก snapshot ของ portfolio ในปัจจุบันเป็นจุดคืนค่า
+func CreatePortfolioRestorePoint(tx *gorm.DB, portfolioID, userID uint, name string, retention PortfolioHistoryRetention) (*entity.PortfolioRestorePoint, error) {
+	var count int64
+	if err := tx.Model(&entity.PortfolioRestorePoint{}).Where("portfolio_id = ?", portfolioID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if retention.MaxRestorePoints > 0 && int(count) >= retention.MaxRestorePoints {
+		return nil, ErrRestorePointLimit
+	}
+
+	var portfolio entity.Portfolio
+	if err := tx.Select("id", "revision").First(&portfolio, portfolioID).Error; err != nil {
+		return nil, err
+	}
+	snapshot, err := takePortfolioSnapshot(tx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	point := entity.PortfolioRestorePoint{
+		PortfolioID: portfolioID,
+		UserID:      userID,
+		Name:        name,
+		Revision:    portfolio.Revision,
+		Snapshot:    datatypes.JSON(data),
+	}
+	if err := tx.Create(&point).Error; err != nil {
+		return nil, err
+	}
+	return &point, nil
+}
+
+// PrunePortfolioHistory ลบประวัติที่เก่ากว่า MaxAge และเกิน MaxChanges รายการล่าสุด (ลบถาวร)
+func PrunePortfolioHistory(tx *gorm.DB, portfolioID uint, retention PortfolioHistoryRetention) error {
+	if retention.MaxAge > 0 {
+		if err := tx.Unscoped().Where("portfolio_id = ? AND created_at < ?", portfolioID, time.Now().Add(-retention.MaxAge)).
+			Delete(&entity.PortfolioChange{}).Error; err != nil {
+			return err
+		}
+	}
+	if retention.MaxChanges > 0 {
+		var cutoff []uint
+		if err := tx.Model(&entity.PortfolioChange{}).Where("portfolio_id = ?", portfolioID).
+			Order("id DESC").Offset(retention.MaxChanges).Limit(1).Pluck("id", &cutoff).Error; err != nil {
+			return err
+		}
+		if len(cutoff) > 0 {
+			return tx.Unscoped().Where("portfolio_id = ? AND id <= ?", portfolioID, cutoff[0]).
+				Delete(&entity.PortfolioChange{}).Error
+		}
+	}
+	return nil
+}
+
+// DeletePortfolioHistory ลบประวัติและจุดคืนค่าทั้งหมดของ portfolio (ใช้ตอนลบ portfolio)
+func DeletePortfolioHistory(tx *gorm.DB, portfolioID uint) error {
+	if err := tx.Unscoped().Where("portfolio_id = ?", portfolioID).Delete(&entity.PortfolioChange{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("portfolio_id = ?", portfolioID).Delete(&entity.PortfolioRestorePoint{}).Error
+}
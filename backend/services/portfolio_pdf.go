@@ -0,0 +1,605 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/sut68/team14/backend/entity"
+	"golang.org/x/image/font/sfnt"
+	"gorm.io/gorm"
+)
+
+// ฟอนต์ภาษาไทยที่ใช้เมื่อไม่พบไฟล์ฟอนต์ของ portfolio ใน PDF_FONT_DIR (เช่น Sarabun-Regular.ttf)
+var DefaultPDFFontNames = []string{"Sarabun", "NotoSansThai"}
+
+// ErrNoThaiPDFFont ไม่พบไฟล์ฟอนต์ที่มีอักษรไทยใน PDF_FONT_DIR จึง export PDF ไม่ได้
+var ErrNoThaiPDFFont = errors.New("no Thai font found for PDF export")
+
+const (
+	pdfFontFamily = "portfolio"
+	pdfMargin     = 15.0
+	pdfLineHeight = 6.0
+	pdfImageMaxH  = 90.0
+)
+
+// PortfolioPDF ผลลัพธ์การ render portfolio เป็น PDF
+type PortfolioPDF struct {
+	Data      []byte
+	PageCount int
+}
+
+// PortfolioPDFOptions ที่อยู่ของไฟล์ฟอนต์ (.ttf) และ backend ที่เก็บไฟล์ที่อัปโหลด
+type PortfolioPDFOptions struct {
+	FontDir string
+	// Uploads ใช้อ่านรูปที่อัปโหลดไว้ (nil = ไม่ใส่รูป)
+	Uploads StorageBackend
+}
+
+// PortfolioPDFSources ข้อมูลผลงาน/กิจกรรมที่ block อ้างอิงถึงผ่าน data_id
+type PortfolioPDFSources struct {
+	Activities map[uint]entity.Activity
+	Workings   map[uint]entity.Working
+}
+
+// DefaultPortfolioPDFOptions อ่านฟอนต์จาก PDF_FONT_DIR และรูปจาก Storage ที่ใช้อยู่
+func DefaultPortfolioPDFOptions() PortfolioPDFOptions {
+	opts := PortfolioPDFOptions{
+		FontDir: os.Getenv("PDF_FONT_DIR"),
+		Uploads: Storage,
+	}
+	if opts.FontDir == "" {
+		opts.FontDir = "./fonts"
+	}
+	return opts
+}
+
+// ExportPortfolioPDF โหลด portfolio พร้อมข้อมูลที่เกี่ยวข้องแล้ว render เป็น PDF
+func ExportPortfolioPDF(db *gorm.DB, portfolioID uint) (*PortfolioPDF, *entity.Portfolio, error) {
+	portfolio, sources, err := LoadPortfolioForPDF(db, portfolioID)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := RenderPortfolioPDF(portfolio, sources, DefaultPortfolioPDFOptions())
+	if err != nil {
+		return nil, portfolio, err
+	}
+	return result, portfolio, nil
+}
+
+// LoadPortfolioForPDF โหลด portfolio (sections/blocks เรียงตามลำดับ) และกิจกรรม/ผลงานของเจ้าของที่ block อ้างถึง
+func LoadPortfolioForPDF(db *gorm.DB, portfolioID uint) (*entity.Portfolio, PortfolioPDFSources, error) {
+	sources := PortfolioPDFSources{
+		Activities: map[uint]entity.Activity{},
+		Workings:   map[uint]entity.Working{},
+	}
+
+	var portfolio entity.Portfolio
+	err := db.
+		Preload("Colors").
+		Preload("Font").
+		Preload("User").
+		Preload("PortfolioSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("section_order ASC")
+		}).
+		Preload("PortfolioSections.PortfolioBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("block_order ASC")
+		}).
+		First(&portfolio, portfolioID).Error
+	if err != nil {
+		return nil, sources, err
+	}
+
+	var activityIDs, workingIDs []uint
+	for _, section := range portfolio.PortfolioSections {
+		for _, block := range section.PortfolioBlocks {
+			content := parseBlockContent(block.Content)
+			id := toUint(content["data_id"])
+			if id == 0 {
+				continue
+			}
+			switch blockContentType(block, content) {
+			case "activity":
+				activityIDs = append(activityIDs, id)
+			case "working":
+				workingIDs = append(workingIDs, id)
+			}
+		}
+	}
+
+	items, err := loadReferencedItems(db, activityIDs, workingIDs)
+	if err != nil {
+		return nil, sources, err
+	}
+	for id, a := range items.Activities {
+		if a.UserID == portfolio.UserID {
+			sources.Activities[id] = a
+		}
+	}
+	for id, w := range items.Workings {
+		if w.UserID == portfolio.UserID {
+			sources.Workings[id] = w
+		}
+	}
+
+	return &portfolio, sources, nil
+}
+
+// RenderPortfolioPDF render portfolio เป็น PDF (A4) ด้วย Go ล้วน
+// ใช้สีจาก Colors และฟอนต์จาก Font (ไฟล์ .ttf ใน FontDir) รูปภาพอ่านจาก Uploads เท่านั้น
+func RenderPortfolioPDF(portfolio *entity.Portfolio, sources PortfolioPDFSources, opts PortfolioPDFOptions) (*PortfolioPDF, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin+5)
+	pdf.AliasNbPages("")
+	pdf.SetTitle(portfolio.PortfolioName, true)
+
+	r := &pdfRenderer{
+		pdf:       pdf,
+		opts:      opts,
+		sources:   sources,
+		portfolio: portfolio,
+		primary:   parseHexColor(portfolio.Colors.PrimaryColor, pdfColor{33, 33, 33}),
+		text:      pdfColor{40, 40, 40},
+		muted:     pdfColor{110, 110, 110},
+	}
+	hasBold, err := r.loadFonts()
+	if err != nil {
+		return nil, err
+	}
+	r.hasBold = hasBold
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin)
+		r.setFont("", 9)
+		r.setColor(r.muted)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	r.renderCover()
+
+	for _, section := range portfolio.PortfolioSections {
+		if !section.IsEnabled {
+			continue
+		}
+		r.renderSection(section)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return &PortfolioPDF{Data: buf.Bytes(), PageCount: pdf.PageCount()}, nil
+}
+
+type pdfColor struct{ R, G, B int }
+
+type pdfRenderer struct {
+	pdf       *fpdf.Fpdf
+	opts      PortfolioPDFOptions
+	sources   PortfolioPDFSources
+	portfolio *entity.Portfolio
+	hasBold   bool
+	imageSeq  int
+
+	primary, text, muted pdfColor
+}
+
+// loadFonts ลงทะเบียนฟอนต์ของ portfolio ถ้าไม่พบไฟล์จะใช้ Sarabun / NotoSansThai
+// ข้ามไฟล์ที่ไม่มีอักษรไทย และคืน ErrNoThaiPDFFont ถ้าไม่มีฟอนต์ที่ใช้ได้เลย (แทนที่จะได้ PDF ที่อ่านภาษาไทยไม่ออก)
+func (r *pdfRenderer) loadFonts() (hasBold bool, err error) {
+	names := append([]string{r.portfolio.Font.FontName, firstFontFamily(r.portfolio.Font.FontFamily)}, DefaultPDFFontNames...)
+	candidates := []string{}
+	for _, name := range names {
+		name = strings.ReplaceAll(strings.TrimSpace(name), " ", "")
+		if name != "" {
+			candidates = append(candidates, name)
+		}
+	}
+
+	for _, name := range candidates {
+		regular := readThaiFont(firstExistingFile(r.opts.FontDir, name+"-Regular.ttf", name+".ttf"))
+		if regular == nil {
+			continue
+		}
+		r.pdf.AddUTF8FontFromBytes(pdfFontFamily, "", regular)
+		if bold := readThaiFont(firstExistingFile(r.opts.FontDir, name+"-Bold.ttf")); bold != nil {
+			r.pdf.AddUTF8FontFromBytes(pdfFontFamily, "B", bold)
+			return true, nil
+		}
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%w in %q (tried %s); set PDF_FONT_DIR to a folder containing e.g. Sarabun-Regular.ttf",
+		ErrNoThaiPDFFont, r.opts.FontDir, strings.Join(candidates, ", "))
+}
+
+// readThaiFont อ่านไฟล์ .ttf และคืน nil ถ้าอ่านไม่ได้หรือฟอนต์ไม่มีอักษรไทย
+func readThaiFont(path string) []byte {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil
+	}
+	var buf sfnt.Buffer
+	if glyph, err := f.GlyphIndex(&buf, 'ก'); err != nil || glyph == 0 {
+		return nil
+	}
+	return data
+}
+
+func (r *pdfRenderer) setFont(style string, size float64) {
+	if style == "B" && !r.hasBold {
+		style = ""
+	}
+	r.pdf.SetFont(pdfFontFamily, style, size)
+}
+
+func (r *pdfRenderer) setColor(c pdfColor) {
+	r.pdf.SetTextColor(c.R, c.G, c.B)
+}
+
+func (r *pdfRenderer) paragraph(text string, style string, size float64, color pdfColor, align string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	r.setFont(style, size)
+	r.setColor(color)
+	r.pdf.MultiCell(0, size*0.45+1, text, "", align, false)
+}
+
+func (r *pdfRenderer) renderCover() {
+	p := r.portfolio
+	r.paragraph(p.PortfolioName, "B", 24, r.primary, "L")
+
+	if name := pdfUserName(p.User); name != "" {
+		r.pdf.Ln(2)
+		r.paragraph(name, "", 14, r.text, "L")
+	}
+	if p.Decription != "" {
+		r.pdf.Ln(2)
+		r.paragraph(p.Decription, "", 11, r.muted, "L")
+	}
+	if p.ContentDescription != "" {
+		r.pdf.Ln(2)
+		r.paragraph(p.ContentDescription, "", 11, r.text, "L")
+	}
+	if p.CoverImage != "" {
+		r.pdf.Ln(4)
+		r.image(p.CoverImage, pdfImageMaxH+30)
+	}
+	r.pdf.Ln(6)
+}
+
+func (r *pdfRenderer) renderSection(section entity.PortfolioSection) {
+	r.pdf.Ln(2)
+	r.paragraph(section.SectionTitle, "B", 16, r.primary, "L")
+
+	left, _, right, _ := r.pdf.GetMargins()
+	pageW, _ := r.pdf.GetPageSize()
+	y := r.pdf.GetY() + 1
+	r.pdf.SetDrawColor(r.primary.R, r.primary.G, r.primary.B)
+	r.pdf.Line(left, y, pageW-right, y)
+	r.pdf.Ln(4)
+
+	blocks := append([]entity.PortfolioBlock(nil), section.PortfolioBlocks...)
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].BlockOrder < blocks[j].BlockOrder })
+
+	for _, block := range blocks {
+		r.renderBlock(block)
+	}
+	r.pdf.Ln(4)
+}
+
+func (r *pdfRenderer) renderBlock(block entity.PortfolioBlock) {
+	content := parseBlockContent(block.Content)
+	data, _ := content["data"].(map[string]interface{})
+
+	switch blockContentType(block, content) {
+	case "profile":
+		r.paragraph(pdfUserName(r.portfolio.User), "B", 13, r.text, "L")
+		r.paragraph(r.portfolio.User.Email, "", 11, r.muted, "L")
+
+	case "activity":
+		if a, ok := r.sources.Activities[toUint(content["data_id"])]; ok {
+			r.renderItem(activityItem(a))
+		} else if data != nil {
+			r.renderItem(snapshotItem(data, "activity_name"))
+		}
+
+	case "working":
+		if w, ok := r.sources.Workings[toUint(content["data_id"])]; ok {
+			r.renderItem(workingItem(w))
+		} else if data != nil {
+			r.renderItem(snapshotItem(data, "working_name"))
+		}
+
+	case "image", "gallery":
+		if url := contentString(content, "url", "image_url"); url != "" {
+			r.image(url, pdfImageMaxH)
+		}
+		r.paragraph(contentString(content, "caption", "alt_text"), "", 10, r.muted, "C")
+
+	case "header":
+		r.paragraph(contentString(content, "text", "title"), "B", 14, r.text, pdfAlign(content))
+
+	case "divider":
+		left, _, right, _ := r.pdf.GetMargins()
+		pageW, _ := r.pdf.GetPageSize()
+		y := r.pdf.GetY() + 2
+		r.pdf.SetDrawColor(200, 200, 200)
+		r.pdf.Line(left, y, pageW-right, y)
+		r.pdf.Ln(4)
+
+	case "spacer":
+		r.pdf.Ln(pdfLineHeight * 2)
+
+	default:
+		// บล็อกข้อความจากหน้าแก้ไข section บันทึกเป็น {type: "text", title, detail}
+		r.paragraph(contentString(content, "title"), "B", 12, r.text, pdfAlign(content))
+		r.paragraph(contentString(content, "text", "detail", "description"), "", 11, r.text, pdfAlign(content))
+	}
+	r.pdf.Ln(3)
+}
+
+// pdfItem ข้อมูลกิจกรรม/ผลงานที่จะแสดงเป็นการ์ด (รูปแรก + รายละเอียด) แบบเดียวกับหน้าเว็บ
+type pdfItem struct {
+	Title       string
+	Meta        []string
+	Description string
+	Image       string
+}
+
+func (r *pdfRenderer) renderItem(item pdfItem) {
+	r.paragraph(item.Title, "B", 13, r.text, "L")
+	if len(item.Meta) > 0 {
+		r.paragraph(strings.Join(item.Meta, "  •  "), "", 10, r.muted, "L")
+	}
+	if item.Image != "" {
+		r.pdf.Ln(1)
+		r.image(item.Image, pdfImageMaxH)
+	}
+	if item.Description != "" && item.Description != "-" {
+		r.pdf.Ln(1)
+		r.paragraph(item.Description, "", 11, r.text, "L")
+	}
+}
+
+// image วางรูปที่อัปโหลดไว้ให้พอดีความกว้างหน้า (สูงไม่เกิน maxH) ถ้าอ่านไม่ได้จะข้ามไป
+func (r *pdfRenderer) image(url string, maxH float64) {
+	if r.opts.Uploads == nil {
+		return
+	}
+	stored := uploadNameFromURL(url)
+	if stored == "" {
+		return
+	}
+	imageType := pdfImageType(stored)
+	if imageType == "" {
+		return
+	}
+	f, err := r.opts.Uploads.OpenFile(stored)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	r.imageSeq++
+	name := fmt.Sprintf("img%d", r.imageSeq)
+	info := r.pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: imageType, ReadDpi: true}, f)
+	if !r.pdf.Ok() || info == nil {
+		// รูปเสียไม่ควรทำให้ทั้งเอกสารล้มเหลว
+		r.pdf.ClearError()
+		return
+	}
+
+	left, _, right, _ := r.pdf.GetMargins()
+	pageW, _ := r.pdf.GetPageSize()
+	maxW := pageW - left - right
+	w, h := info.Width(), info.Height()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	scale := maxW / w
+	if h*scale > maxH {
+		scale = maxH / h
+	}
+	w, h = w*scale, h*scale
+
+	x := left + (maxW-w)/2
+	r.pdf.ImageOptions(name, x, -1, w, h, true, fpdf.ImageOptions{ImageType: imageType}, 0, "")
+	r.pdf.Ln(2)
+}
+
+func activityItem(a entity.Activity) pdfItem {
+	item := pdfItem{Title: a.ActivityName}
+	if d := a.ActivityDetail; d != nil {
+		if d.TypeActivity != nil {
+			item.Meta = append(item.Meta, d.TypeActivity.TypeName)
+		}
+		if d.LevelActivity != nil {
+			item.Meta = append(item.Meta, d.LevelActivity.LevelName)
+		}
+		if !d.ActivityAt.IsZero() {
+			item.Meta = append(item.Meta, d.ActivityAt.Format("02/01/2006"))
+		}
+		if d.Institution != "" {
+			item.Meta = append(item.Meta, d.Institution)
+		}
+		item.Description = d.Description
+		if len(d.Images) > 0 {
+			item.Image = d.Images[0].ImageURL
+		}
+	}
+	if a.Reward != nil && a.Reward.Reward_Name != "" {
+		item.Meta = append(item.Meta, a.Reward.Reward_Name)
+	}
+	return item
+}
+
+func workingItem(w entity.Working) pdfItem {
+	item := pdfItem{Title: w.WorkingName}
+	if d := w.WorkingDetail; d != nil {
+		if d.TypeWorking != nil {
+			item.Meta = append(item.Meta, d.TypeWorking.TypeName)
+		}
+		if !d.WorkingAt.IsZero() {
+			item.Meta = append(item.Meta, d.WorkingAt.Format("02/01/2006"))
+		}
+		item.Description = d.Description
+		if len(d.Images) > 0 {
+			item.Image = d.Images[0].WorkingImageURL
+		}
+	}
+	return item
+}
+
+// snapshotItem ใช้ข้อมูลที่ frontend เก็บไว้ใน content.data เมื่อไม่พบรายการจริงในฐานข้อมูล
+func snapshotItem(data map[string]interface{}, titleKey string) pdfItem {
+	item := pdfItem{
+		Title:       contentString(data, titleKey, "title", "name"),
+		Description: contentString(data, "description"),
+	}
+	for _, key := range []string{"category", "level", "date", "location", "award"} {
+		if v := contentString(data, key); v != "" {
+			item.Meta = append(item.Meta, v)
+		}
+	}
+	if images, ok := data["images"].([]interface{}); ok && len(images) > 0 {
+		switch img := images[0].(type) {
+		case string:
+			item.Image = img
+		case map[string]interface{}:
+			item.Image = contentString(img, "image_url", "working_image_url", "url")
+		}
+	}
+	return item
+}
+
+func parseBlockContent(raw []byte) map[string]interface{} {
+	content := map[string]interface{}{}
+	if len(raw) == 0 {
+		return content
+	}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		// บาง block เก็บ content เป็น JSON string ซ้อนอีกชั้น
+		var inner string
+		if json.Unmarshal(raw, &inner) == nil {
+			json.Unmarshal([]byte(inner), &content)
+		}
+	}
+	return content
+}
+
+// blockContentType ใช้ content.type (ที่ frontend กำหนด) ก่อน แล้วค่อยใช้ BlockPortType
+func blockContentType(block entity.PortfolioBlock, content map[string]interface{}) string {
+	if t, ok := content["type"].(string); ok && t != "" {
+		return strings.ToLower(t)
+	}
+	return strings.ToLower(block.BlockPortType)
+}
+
+func contentString(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := m[key].(type) {
+		case string:
+			if strings.TrimSpace(v) != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+func toUint(v interface{}) uint {
+	switch n := v.(type) {
+	case float64:
+		if n > 0 {
+			return uint(n)
+		}
+	case string:
+		if id, err := strconv.ParseUint(n, 10, 64); err == nil {
+			return uint(id)
+		}
+	}
+	return 0
+}
+
+func pdfAlign(content map[string]interface{}) string {
+	switch contentString(content, "text_align") {
+	case "center":
+		return "C"
+	case "right":
+		return "R"
+	case "justify":
+		return "J"
+	}
+	return "L"
+}
+
+func pdfUserName(u entity.User) string {
+	if name := strings.TrimSpace(u.FirstNameTH + " " + u.LastNameTH); name != "" {
+		return name
+	}
+	return strings.TrimSpace(u.FirstNameEN + " " + u.LastNameEN)
+}
+
+// parseHexColor รองรับ #RGB และ #RRGGBB
+func parseHexColor(hex string, fallback pdfColor) pdfColor {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) < 6 {
+		return fallback
+	}
+	v, err := strconv.ParseUint(hex[:6], 16, 32)
+	if err != nil {
+		return fallback
+	}
+	return pdfColor{int(v >> 16 & 0xFF), int(v >> 8 & 0xFF), int(v & 0xFF)}
+}
+
+func pdfImageType(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg":
+		return "JPG"
+	case ".png":
+		return "PNG"
+	case ".gif":
+		return "GIF"
+	}
+	return ""
+}
+
+func firstFontFamily(family string) string {
+	name := strings.Split(family, ",")[0]
+	return strings.Trim(strings.TrimSpace(name), `"'`)
+}
+
+func firstExistingFile(dir string, names ...string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ชนิดข้อความในห้องแก้ไข portfolio (/ws/portfolio/:id)
+const (
+	RoomMsgWelcome    = "welcome"     // server → ผู้ที่เพิ่งเข้าห้อง: สมาชิก, lock และ session ของตัวเอง
+	RoomMsgJoin       = "join"        // มีคนเข้าห้อง
+	RoomMsgLeave      = "leave"       // มีคนออกจากห้อง
+	RoomMsgPresence   = "presence"    // cursor/selection ของแต่ละ session
+	RoomMsgLock       = "lock"        // ขอ/ได้ lock block
+	RoomMsgUnlock     = "unlock"      // ปล่อย lock block
+	RoomMsgLockDenied = "lock_denied" // block ถูก lock โดยคนอื่นอยู่
+	RoomMsgChange     = "change"      // section/block ถูกแก้ไข (ส่งหลัง commit)
+	RoomMsgPing       = "ping"
+	RoomMsgPong       = "pong"
+	RoomMsgError      = "error"
+)
+
+// DefaultBlockLockTTL lock หมดอายุเองถ้าไม่ต่ออายุ (ส่ง lock ซ้ำ) ภายในเวลานี้
+const DefaultBlockLockTTL = 2 * time.Minute
+
+// ขนาด buffer ของข้อความที่รอส่งต่อ session ถ้าเต็ม (client ช้า/ค้าง) จะตัดการเชื่อมต่อ
+const roomSendBuffer = 64
+
+// PortfolioRoomMessage ข้อความที่รับ/ส่งผ่าน WebSocket ของห้อง portfolio
+type PortfolioRoomMessage struct {
+	Type        string                `json:"type"`
+	PortfolioID uint                  `json:"portfolio_id,omitempty"`
+	SessionID   string                `json:"session_id,omitempty"`
+	UserID      uint                  `json:"user_id,omitempty"`
+	Name        string                `json:"name,omitempty"`
+	BlockID     uint                  `json:"block_id,omitempty"`
+	Revision    uint                  `json:"revision,omitempty"`
+	Selection   json.RawMessage       `json:"selection,omitempty"`
+	Members     []PortfolioRoomMember `json:"members,omitempty"`
+	Locks       []PortfolioBlockLock  `json:"locks,omitempty"`
+	Lock        *PortfolioBlockLock   `json:"lock,omitempty"`
+	Changes     interface{}           `json:"changes,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+// PortfolioRoomMember สมาชิกหนึ่ง session ในห้อง (ผู้ใช้คนเดียวเปิดหลายแท็บได้)
+type PortfolioRoomMember struct {
+	SessionID string          `json:"session_id"`
+	UserID    uint            `json:"user_id"`
+	Name      string          `json:"name"`
+	CanEdit   bool            `json:"can_edit"`
+	Selection json.RawMessage `json:"selection,omitempty"`
+	JoinedAt  time.Time       `json:"joined_at"`
+}
+
+// PortfolioBlockLock lock ของ block ที่กำลังถูกแก้ไข
+type PortfolioBlockLock struct {
+	BlockID   uint      `json:"block_id"`
+	SessionID string    `json:"session_id"`
+	UserID    uint      `json:"user_id"`
+	Name      string    `json:"name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PortfolioSession การเชื่อมต่อหนึ่งรายการในห้อง ข้อความที่ต้องส่งออกอ่านจาก Outbox()
+type PortfolioSession struct {
+	member      PortfolioRoomMember
+	portfolioID uint
+	send        chan PortfolioRoomMessage
+	closed      bool
+}
+
+func (s *PortfolioSession) ID() string        { return s.member.SessionID }
+func (s *PortfolioSession) PortfolioID() uint { return s.portfolioID }
+
+// Outbox ช่องข้อความที่ต้องเขียนลง WebSocket จะถูกปิดเมื่อออกจากห้อง
+func (s *PortfolioSession) Outbox() <-chan PortfolioRoomMessage { return s.send }
+
+type portfolioRoom struct {
+	sessions map[string]*PortfolioSession
+	locks    map[uint]*PortfolioBlockLock
+}
+
+// PortfolioRoomHub จัดการห้องแก้ไขของแต่ละ portfolio
+type PortfolioRoomHub struct {
+	mu      sync.Mutex
+	rooms   map[uint]*portfolioRoom
+	LockTTL time.Duration
+}
+
+func NewPortfolioRoomHub() *PortfolioRoomHub {
+	return &PortfolioRoomHub{rooms: map[uint]*portfolioRoom{}, LockTTL: DefaultBlockLockTTL}
+}
+
+// PortfolioRooms hub ที่ใช้ร่วมกันทั้งระบบ (controller อื่นเรียก BroadcastChanges / BlockLockedByOther)
+var PortfolioRooms = NewPortfolioRoomHub()
+
+func newRoomSessionID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Join เพิ่ม session เข้าห้อง ส่ง welcome ให้ตัวเองและแจ้ง join ให้คนอื่น
+func (h *PortfolioRoomHub) Join(portfolioID, userID uint, name string, canEdit bool) *PortfolioSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[portfolioID]
+	if !ok {
+		room = &portfolioRoom{sessions: map[string]*PortfolioSession{}, locks: map[uint]*PortfolioBlockLock{}}
+		h.rooms[portfolioID] = room
+	}
+	h.expireLocks(room)
+
+	session := &PortfolioSession{
+		member: PortfolioRoomMember{
+			SessionID: newRoomSessionID(),
+			UserID:    userID,
+			Name:      name,
+			CanEdit:   canEdit,
+			JoinedAt:  time.Now(),
+		},
+		portfolioID: portfolioID,
+		send:        make(chan PortfolioRoomMessage, roomSendBuffer),
+	}
+	room.sessions[session.ID()] = session
+
+	h.deliver(session, PortfolioRoomMessage{
+		Type:        RoomMsgWelcome,
+		PortfolioID: portfolioID,
+		SessionID:   session.ID(),
+		UserID:      userID,
+		Members:     roomMembers(room),
+		Locks:       roomLocks(room),
+	})
+	h.broadcast(room, session, PortfolioRoomMessage{
+		Type:      RoomMsgJoin,
+		SessionID: session.ID(),
+		UserID:    userID,
+		Name:      name,
+	})
+	return session
+}
+
+// Leave เอา session ออกจากห้อง ปล่อย lock ทั้งหมดของ session และปิด Outbox
+func (h *PortfolioRoomHub) Leave(session *PortfolioSession) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(session)
+}
+
+func (h *PortfolioRoomHub) leave(session *PortfolioSession) {
+	room, ok := h.rooms[session.portfolioID]
+	if !ok || room.sessions[session.ID()] != session {
+		return
+	}
+	delete(room.sessions, session.ID())
+	if !session.closed {
+		session.closed = true
+		close(session.send)
+	}
+
+	for blockID, lock := range room.locks {
+		if lock.SessionID == session.ID() {
+			delete(room.locks, blockID)
+			h.broadcast(room, nil, PortfolioRoomMessage{Type: RoomMsgUnlock, BlockID: blockID, SessionID: lock.SessionID, UserID: lock.UserID})
+		}
+	}
+	h.broadcast(room, nil, PortfolioRoomMessage{Type: RoomMsgLeave, SessionID: session.ID(), UserID: session.member.UserID})
+
+	if len(room.sessions) == 0 {
+		delete(h.rooms, session.portfolioID)
+	}
+}
+
+// Handle ประมวลผลข้อความจาก client (presence / lock / unlock / ping)
+func (h *PortfolioRoomHub) Handle(session *PortfolioSession, msg PortfolioRoomMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[session.portfolioID]
+	if !ok || room.sessions[session.ID()] != session {
+		return
+	}
+	h.expireLocks(room)
+
+	switch msg.Type {
+	case RoomMsgPing:
+		h.deliver(session, PortfolioRoomMessage{Type: RoomMsgPong})
+
+	case RoomMsgPresence:
+		session.member.Selection = msg.Selection
+		h.broadcast(room, session, PortfolioRoomMessage{
+			Type:      RoomMsgPresence,
+			SessionID: session.ID(),
+			UserID:    session.member.UserID,
+			Name:      session.member.Name,
+			BlockID:   msg.BlockID,
+			Selection: msg.Selection,
+		})
+
+	case RoomMsgLock:
+		if !session.member.CanEdit {
+			h.deliver(session, PortfolioRoomMessage{Type: RoomMsgError, BlockID: msg.BlockID, Error: "permission denied"})
+			return
+		}
+		if msg.BlockID == 0 {
+			h.deliver(session, PortfolioRoomMessage{Type: RoomMsgError, Error: "block_id is required"})
+			return
+		}
+		if lock, held := room.locks[msg.BlockID]; held && lock.SessionID != session.ID() {
+			copied := *lock
+			h.deliver(session, PortfolioRoomMessage{Type: RoomMsgLockDenied, BlockID: msg.BlockID, Lock: &copied})
+			return
+		}
+		// lock ซ้ำโดยเจ้าของเดิม = ต่ออายุ
+		lock := &PortfolioBlockLock{
+			BlockID:   msg.BlockID,
+			SessionID: session.ID(),
+			UserID:    session.member.UserID,
+			Name:      session.member.Name,
+			ExpiresAt: time.Now().Add(h.LockTTL),
+		}
+		room.locks[msg.BlockID] = lock
+		copied := *lock
+		h.broadcast(room, nil, PortfolioRoomMessage{Type: RoomMsgLock, BlockID: msg.BlockID, SessionID: session.ID(), UserID: session.member.UserID, Lock: &copied})
+
+	case RoomMsgUnlock:
+		if lock, held := room.locks[msg.BlockID]; held && lock.SessionID == session.ID() {
+			delete(room.locks, msg.BlockID)
+			h.broadcast(room, nil, PortfolioRoomMessage{Type: RoomMsgUnlock, BlockID: msg.BlockID, SessionID: session.ID(), UserID: session.member.UserID})
+		}
+
+	default:
+		h.deliver(session, PortfolioRoomMessage{Type: RoomMsgError, Error: "unknown message type " + msg.Type})
+	}
+}
+
+// BroadcastChanges แจ้งทุก session ในห้องว่า portfolio ถูกแก้ไขเป็น revision ใหม่
+func (h *PortfolioRoomHub) BroadcastChanges(portfolioID, revision, userID uint, changes interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[portfolioID]
+	if !ok {
+		return
+	}
+	h.broadcast(room, nil, PortfolioRoomMessage{
+		Type:        RoomMsgChange,
+		PortfolioID: portfolioID,
+		UserID:      userID,
+		Revision:    revision,
+		Changes:     changes,
+	})
+}
+
+// BlockLockedByOther ตรวจว่า block ถูก lock โดย session อื่นที่ไม่ใช่ sessionID หรือไม่
+// request ที่ไม่ได้ระบุ session (sessionID ว่าง) ถือเป็น session อื่น
+func (h *PortfolioRoomHub) BlockLockedByOther(portfolioID, blockID uint, sessionID string) (PortfolioBlockLock, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[portfolioID]
+	if !ok {
+		return PortfolioBlockLock{}, false
+	}
+	h.expireLocks(room)
+	lock, held := room.locks[blockID]
+	if !held || lock.SessionID == sessionID {
+		return PortfolioBlockLock{}, false
+	}
+	return *lock, true
+}
+
+// Members สมาชิกปัจจุบันของห้อง
+func (h *PortfolioRoomHub) Members(portfolioID uint) []PortfolioRoomMember {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if room, ok := h.rooms[portfolioID]; ok {
+		return roomMembers(room)
+	}
+	return nil
+}
+
+func (h *PortfolioRoomHub) expireLocks(room *portfolioRoom) {
+	now := time.Now()
+	for blockID, lock := range room.locks {
+		if now.After(lock.ExpiresAt) {
+			delete(room.locks, blockID)
+			h.broadcast(room, nil, PortfolioRoomMessage{Type: RoomMsgUnlock, BlockID: blockID, SessionID: lock.SessionID, UserID: lock.UserID})
+		}
+	}
+}
+
+// broadcast ส่งข้อความถึงทุก session ในห้อง ยกเว้น except (ถ้ามี)
+func (h *PortfolioRoomHub) broadcast(room *portfolioRoom, except *PortfolioSession, msg PortfolioRoomMessage) {
+	for _, s := range room.sessions {
+		if s != except {
+			h.deliver(s, msg)
+		}
+	}
+}
+
+// deliver ส่งแบบไม่รอ ถ้า buffer ของ session เต็มให้ตัด session นั้นออก
+func (h *PortfolioRoomHub) deliver(session *PortfolioSession, msg PortfolioRoomMessage) {
+	if session.closed {
+		return
+	}
+	select {
+	case session.send <- msg:
+	default:
+		h.leave(session)
+	}
+}
+
+func roomMembers(room *portfolioRoom) []PortfolioRoomMember {
+	members := make([]PortfolioRoomMember, 0, len(room.sessions))
+	for _, s := range room.sessions {
+		members = append(members, s.member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].JoinedAt.Before(members[j].JoinedAt) })
+	return members
+}
+
+func roomLocks(room *portfolioRoom) []PortfolioBlockLock {
+	locks := make([]PortfolioBlockLock, 0, len(room.locks))
+	for _, lock := range room.locks {
+		locks = append(locks, *lock)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].BlockID < locks[j].BlockID })
+	return locks
+}
+
+// Notify ส่งข้อความถึง session เดียว (เช่น แจ้ง error จาก controller)
+func (h *PortfolioRoomHub) Notify(session *PortfolioSession, msg PortfolioRoomMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deliver(session, msg)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// ErrNoPortfolioForCurriculum นักเรียนยังไม่มี portfolio ที่ใช้กับหลักสูตรนี้ (และไม่มี portfolio หลัก)
+var ErrNoPortfolioForCurriculum = errors.New("no portfolio for this curriculum")
+
+// ClonePortfolio คัดลอก portfolio พร้อม section และ block เป็น variant ใหม่ (สถานะ draft) ของ userID
+// ประวัติการแก้ไข ลิงก์แชร์ และการส่งตรวจไม่ถูกคัดลอก
+func ClonePortfolio(tx *gorm.DB, sourceID, userID uint, name string) (*entity.Portfolio, error) {
+	var source entity.Portfolio
+	err := tx.
+		Preload("PortfolioSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("section_order ASC")
+		}).
+		Preload("PortfolioSections.PortfolioBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("block_order ASC")
+		}).
+		First(&source, sourceID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = source.PortfolioName + " (copy)"
+	}
+	clone := entity.Portfolio{
+		PortfolioName:      name,
+		Decription:         source.Decription,
+		Status:             "draft",
+		PortfolioStyle:     source.PortfolioStyle,
+		CoverImage:         source.CoverImage,
+		ContentDescription: source.ContentDescription,
+		ClonedFromID:       &source.ID,
+		TemplateID:         source.TemplateID,
+		TemplateVersion:    source.TemplateVersion,
+		UserID:             userID,
+		ColorsID:           source.ColorsID,
+		FontID:             source.FontID,
+	}
+	if err := tx.Omit("Template", "User", "Colors", "Font", "PortfolioSections").Create(&clone).Error; err != nil {
+		return nil, err
+	}
+
+	for _, s := range source.PortfolioSections {
+		section := entity.PortfolioSection{
+			SectionTitle:      s.SectionTitle,
+			SectionPortKey:    s.SectionPortKey,
+			IsEnabled:         s.IsEnabled,
+			SectionOrder:      s.SectionOrder,
+			SectionStyle:      s.SectionStyle,
+			TemplateSectionID: s.TemplateSectionID,
+			PortfolioID:       clone.ID,
+		}
+		if err := tx.Omit("Portfolio", "PortfolioBlocks").Create(&section).Error; err != nil {
+			return nil, err
+		}
+		for _, b := range s.PortfolioBlocks {
+			block := entity.PortfolioBlock{
+				BlockPortType:      b.BlockPortType,
+				BlockOrder:         b.BlockOrder,
+				BlockStyle:         b.BlockStyle,
+				Content:            b.Content,
+				TemplateBlockID:    b.TemplateBlockID,
+				PortfolioSectionID: section.ID,
+			}
+			if err := tx.Omit("PortfolioSection").Create(&block).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &clone, nil
+}
+
+// DesignatePortfolioForCurriculum กำหนดให้ portfolio เป็นตัวที่ใช้ยื่นหลักสูตรนี้ (แทนที่ตัวเดิมถ้ามี)
+func DesignatePortfolioForCurriculum(tx *gorm.DB, userID, portfolioID, curriculumID uint) (*entity.PortfolioCurriculum, error) {
+	// ลบแบบถาวรเพราะ (user_id, curriculum_id) เป็น unique index
+	if err := tx.Unscoped().
+		Where("user_id = ? AND curriculum_id = ?", userID, curriculumID).
+		Delete(&entity.PortfolioCurriculum{}).Error; err != nil {
+		return nil, err
+	}
+	designation := entity.PortfolioCurriculum{UserID: userID, CurriculumID: curriculumID, PortfolioID: portfolioID}
+	if err := tx.Omit("Curriculum").Create(&designation).Error; err != nil {
+		return nil, err
+	}
+	return &designation, nil
+}
+
+// ActivePortfolioIDForCurriculum หา portfolio ที่นักเรียนใช้กับหลักสูตร
+// ถ้ายังไม่ได้กำหนดไว้จะใช้ portfolio หลัก (status active) แทน คืน designated = false
+func ActivePortfolioIDForCurriculum(db *gorm.DB, userID, curriculumID uint) (portfolioID uint, designated bool, err error) {
+	if curriculumID != 0 {
+		var designation entity.PortfolioCurriculum
+		err = db.Where("user_id = ? AND curriculum_id = ?", userID, curriculumID).First(&designation).Error
+		if err == nil {
+			return designation.PortfolioID, true, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, err
+		}
+	}
+
+	var portfolio entity.Portfolio
+	err = db.Select("id").Where("user_id = ? AND status = ?", userID, "active").First(&portfolio).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, ErrNoPortfolioForCurriculum
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return portfolio.ID, false, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// สถานะ PortfolioSubmission ที่ยังรอการตรวจ (อยู่ในคิว)
+var PendingSubmissionStatuses = []string{"awaiting_review", "awaiting", "under_review"}
+
+// ScoreBucket ช่วงคะแนน (เปอร์เซ็นต์) สำหรับทำ histogram
+type ScoreBucket struct {
+	Label string `json:"label"`
+	Min   int    `json:"min"`
+	Max   int    `json:"max"`
+	Count int    `json:"count"`
+}
+
+// MedianDuration คืนค่ามัธยฐานของช่วงเวลา (0 ถ้าไม่มีข้อมูล)
+func MedianDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// ScorePercent แปลงคะแนนเป็นเปอร์เซ็นต์ของคะแนนเต็ม (คะแนนเต็ม <= 0 ถือว่าเต็ม 100)
+func ScorePercent(score, maxScore float64) float64 {
+	if maxScore <= 0 {
+		maxScore = 100
+	}
+	percent := score * 100 / maxScore
+	return math.Max(0, math.Min(100, percent))
+}
+
+// ScoreHistogram แบ่งคะแนน (0-100) เป็นช่วงละ bucketSize คะแนน ช่วงสุดท้ายรวม 100 ด้วย
+func ScoreHistogram(percentages []float64, bucketSize int) []ScoreBucket {
+	if bucketSize <= 0 || bucketSize > 100 {
+		bucketSize = 10
+	}
+
+	var buckets []ScoreBucket
+	for min := 0; min < 100; min += bucketSize {
+		max := min + bucketSize - 1
+		if min+bucketSize >= 100 {
+			max = 100
+		}
+		buckets = append(buckets, ScoreBucket{
+			Label: fmt.Sprintf("%d-%d", min, max),
+			Min:   min,
+			Max:   max,
+		})
+	}
+
+	for _, p := range percentages {
+		i := int(p) / bucketSize
+		if i >= len(buckets) {
+			i = len(buckets) - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// RoundHours แปลง duration เป็นชั่วโมง (ทศนิยม 1 ตำแหน่ง) สำหรับส่งให้ frontend
+func RoundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*10) / 10
+}
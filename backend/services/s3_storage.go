@@ -0,0 +1,399 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config ค่าตั้งของ S3StorageService
+type S3Config struct {
+	// Endpoint เช่น https://s3.ap-southeast-1.amazonaws.com หรือ http://localhost:9000 (MinIO)
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Prefix โฟลเดอร์ใน bucket (ค่าเริ่มต้น uploads) ทำให้ URL ลงท้ายด้วย /uploads/<name> เหมือน local
+	Prefix string
+	// PathStyle ใช้ URL แบบ <endpoint>/<bucket>/<key> (MinIO ส่วนใหญ่ต้องใช้) แทน <bucket>.<host>/<key>
+	PathStyle bool
+	// PublicURL URL ฐานของไฟล์ที่เปิดสาธารณะ (เช่น CDN) ว่าง = ใช้ URL ของ object ตรงๆ
+	PublicURL string
+	// Client ว่าง = http.DefaultClient
+	Client *http.Client
+	// Now นาฬิกาที่ใช้เซ็น request ว่าง = time.Now
+	Now func() time.Time
+}
+
+// S3StorageService เก็บไฟล์ใน bucket ที่ใช้ API แบบ S3 (AWS S3, MinIO, R2, ...)
+// เซ็น request ด้วย AWS Signature Version 4 เอง จึงไม่ต้องพึ่ง AWS SDK
+type S3StorageService struct {
+	cfg      S3Config
+	endpoint *url.URL
+}
+
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// NewS3Storage ตรวจค่าตั้งและสร้าง S3StorageService
+func NewS3Storage(cfg S3Config) (*S3StorageService, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3 bucket is not set")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 access key and secret key are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &S3StorageService{cfg: cfg, endpoint: endpoint}, nil
+}
+
+// NewS3StorageFromEnv อ่านค่าจาก S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY,
+// S3_PREFIX, S3_FORCE_PATH_STYLE และ S3_PUBLIC_URL
+// ถ้ากำหนด S3_ENDPOINT (เช่น MinIO) จะใช้ path-style เป็นค่าเริ่มต้น
+func NewS3StorageFromEnv() (*S3StorageService, error) {
+	cfg := S3Config{
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		Region:    os.Getenv("S3_REGION"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		Prefix:    "uploads",
+		PublicURL: os.Getenv("S3_PUBLIC_URL"),
+	}
+	if prefix, ok := os.LookupEnv("S3_PREFIX"); ok {
+		cfg.Prefix = prefix
+	}
+	cfg.PathStyle = cfg.Endpoint != ""
+	if raw := os.Getenv("S3_FORCE_PATH_STYLE"); raw != "" {
+		pathStyle, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3_FORCE_PATH_STYLE: %v", err)
+		}
+		cfg.PathStyle = pathStyle
+	}
+	return NewS3Storage(cfg)
+}
+
+func (s *S3StorageService) UploadFile(file io.Reader, fileName string, contentType string) (string, error) {
+	// Generate unique filename
+	uniqueName := fmt.Sprintf("%d%s", time.Now().UnixNano(), filepath.Ext(fileName))
+	if contentType == "" {
+		contentType = contentTypeByName(uniqueName)
+	}
+	return s.put(file, uniqueName, contentType)
+}
+
+// SaveFile เก็บไฟล์ด้วยชื่อที่กำหนด (ทับ object เดิมถ้ามี) content type เดาจากนามสกุล
+func (s *S3StorageService) SaveFile(file io.Reader, name string) (string, error) {
+	name = path.Base(name)
+	return s.put(file, name, contentTypeByName(name))
+}
+
+func (s *S3StorageService) put(file io.Reader, name, contentType string) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	resp, err := s.do(http.MethodPut, s.key(name), nil, header, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+	resp.Body.Close()
+	return s.fileURL(name), nil
+}
+
+func (s *S3StorageService) OpenFile(name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.key(path.Base(name)), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DeleteFile S3 ไม่แจ้ง error เมื่อลบ object ที่ไม่มี
+func (s *S3StorageService) DeleteFile(fileName string) error {
+	resp, err := s.do(http.MethodDelete, s.key(path.Base(fileName)), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SignedURL presigned URL สำหรับ GET (S3 จำกัดอายุไม่เกิน 7 วัน)
+func (s *S3StorageService) SignedURL(name string, expiry time.Duration) (string, error) {
+	seconds := int(expiry / time.Second)
+	if seconds < 1 || seconds > 7*24*3600 {
+		return "", fmt.Errorf("signed URL expiry must be between 1 second and 7 days")
+	}
+	now := s.cfg.Now().UTC()
+	u := s.objectURL(s.key(path.Base(name)))
+	amzDate := now.Format("20060102T150405Z")
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(seconds))
+	query.Set("X-Amz-SignedHeaders", "host")
+	u.RawQuery = s3CanonicalQuery(query)
+
+	header := http.Header{}
+	header.Set("Host", u.Host)
+	signature := s.signature(http.MethodGet, u, header, s3UnsignedPayload, now)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListFiles ชื่อ object ใต้ Prefix (ไม่รวม object ในโฟลเดอร์ย่อย)
+func (s *S3StorageService) ListFiles() ([]string, error) {
+	prefix := s.key("")
+	var names []string
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse S3 list response: %v", err)
+		}
+		for _, item := range result.Contents {
+			name := strings.TrimPrefix(item.Key, prefix)
+			if name != "" && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// key ชื่อ object ใน bucket ของไฟล์ name
+func (s *S3StorageService) key(name string) string {
+	if s.cfg.Prefix == "" {
+		return name
+	}
+	return s.cfg.Prefix + "/" + name
+}
+
+func (s *S3StorageService) fileURL(name string) string {
+	if s.cfg.PublicURL != "" {
+		return s.cfg.PublicURL + "/" + s3EscapePath(s.key(name))
+	}
+	return s.objectURL(s.key(name)).String()
+}
+
+// objectURL URL ของ object (key ว่าง = ตัว bucket)
+func (s *S3StorageService) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		u.Path = basePath + "/" + s.cfg.Bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+// s3Error รูปแบบ error ที่ S3 ส่งกลับ
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do ส่ง request ที่เซ็นแล้ว สถานะที่ไม่ใช่ 2xx กลายเป็น error (404 ห่อ fs.ErrNotExist)
+func (s *S3StorageService) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.objectURL(key)
+	if query != nil {
+		u.RawQuery = s3CanonicalQuery(query)
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+
+	now := s.cfg.Now().UTC()
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("Host", u.Host)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signed := s.signedHeaders(req.Header)
+	signature := s.signature(method, u, req.Header, payloadHash, now)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, s.scope(now), strings.Join(signed, ";"), signature))
+	req.Header.Del("Host")
+	req.Host = u.Host
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var apiErr s3Error
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = xml.Unmarshal(raw, &apiErr)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s: %w", method, key, fs.ErrNotExist)
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = resp.Status
+	}
+	return nil, fmt.Errorf("s3 %s %s: %s %s", method, key, apiErr.Code, apiErr.Message)
+}
+
+func (s *S3StorageService) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signedHeaders header ที่นำมาเซ็น: host, content-type และ x-amz-*
+func (s *S3StorageService) signedHeaders(header http.Header) []string {
+	var names []string
+	for k := range header {
+		lower := strings.ToLower(k)
+		if lower == "host" || lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// signature ลายเซ็น SigV4 ของ request (u.RawQuery ต้องเป็น canonical query แล้ว)
+func (s *S3StorageService) signature(method string, u *url.URL, header http.Header, payloadHash string, t time.Time) string {
+	signed := s.signedHeaders(header)
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		t.Format("20060102T150405Z"),
+		s.scope(t),
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := s3HMAC([]byte("AWS4"+s.cfg.SecretKey), t.Format("20060102"))
+	key = s3HMAC(key, s.cfg.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape URI encode ตาม SigV4 (เว้นเฉพาะ A-Z a-z 0-9 - _ . ~)
+func s3Escape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ImportJobTypeSchools = "schools"
+
+	// จำนวนแถวที่ upsert ต่อหนึ่งรอบ (อัปเดต progress ทุกรอบ)
+	schoolImportBatchSize = 500
+)
+
+var schoolSheetRequiredColumns = []string{"code", "name", "school_type"}
+
+// SchoolImportRow หนึ่งแถวจากไฟล์รายชื่อโรงเรียน (ยังไม่ได้ resolve ประเภทโรงเรียน)
+type SchoolImportRow struct {
+	Row            int
+	Code           string
+	Name           string
+	SchoolType     string
+	IsProjectBased bool
+}
+
+// ParseSchoolRows ตรวจ header และแปลงแถวเป็น SchoolImportRow
+// แถวที่มี code ซ้ำกันในไฟล์จะใช้แถวแรก และรายงานแถวหลังเป็น error
+func ParseSchoolRows(rows [][]string) ([]SchoolImportRow, []SpreadsheetRowError, error) {
+	if len(rows) < 2 {
+		return nil, nil, errors.New("file has no data rows")
+	}
+
+	index := HeaderIndex(rows[0])
+	for _, col := range schoolSheetRequiredColumns {
+		if _, ok := index[col]; !ok {
+			return nil, nil, fmt.Errorf("missing required column: %s", col)
+		}
+	}
+
+	var (
+		items     []SchoolImportRow
+		rowErrors []SpreadsheetRowError
+		seen      = map[string]int{}
+	)
+
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		if IsEmptyRow(row) {
+			continue
+		}
+
+		item := SchoolImportRow{
+			Row:        rowNum,
+			Code:       NormalizeSchoolCode(CellValue(row, index, "code")),
+			Name:       CellValue(row, index, "name"),
+			SchoolType: CellValue(row, index, "school_type"),
+		}
+
+		var errs []SpreadsheetRowError
+		if item.Code == "" {
+			errs = append(errs, SpreadsheetRowError{Row: rowNum, Column: "code", Message: "code is required"})
+		} else if len(item.Code) > 50 {
+			errs = append(errs, SpreadsheetRowError{Row: rowNum, Column: "code", Message: "code must not exceed 50 characters"})
+		}
+		if item.Name == "" {
+			errs = append(errs, SpreadsheetRowError{Row: rowNum, Column: "name", Message: "name is required"})
+		}
+		if item.SchoolType == "" {
+			errs = append(errs, SpreadsheetRowError{Row: rowNum, Column: "school_type", Message: "school_type is required"})
+		}
+
+		projectBased, err := parseImportBool(CellValue(row, index, "is_project_based"))
+		if err != nil {
+			errs = append(errs, SpreadsheetRowError{Row: rowNum, Column: "is_project_based", Message: err.Error()})
+		}
+		item.IsProjectBased = projectBased
+
+		if item.Code != "" {
+			if first, dup := seen[item.Code]; dup {
+				errs = append(errs, SpreadsheetRowError{
+					Row:     rowNum,
+					Column:  "code",
+					Message: fmt.Sprintf("duplicate code %s (already used in row %d)", item.Code, first),
+				})
+			} else if len(errs) == 0 {
+				seen[item.Code] = rowNum
+			}
+		}
+
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, rowErrors, nil
+}
+
+// NormalizeSchoolCode ทำให้รหัสโรงเรียนเป็นรูปแบบเดียวกัน (ตัดช่องว่าง, ตัวพิมพ์ใหญ่)
+// เพราะ unique index ของ code แยกตัวพิมพ์เล็กใหญ่ ต้องใช้ทุกที่ที่บันทึก School.Code
+func NormalizeSchoolCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "0", "no", "n", "ไม่ใช่":
+		return false, nil
+	case "true", "1", "yes", "y", "ใช่":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", value)
+}
+
+// StartSchoolImport สร้าง ImportJob แล้วรันการ upsert โรงเรียนใน goroutine แยก
+// ผู้เรียกใช้ดู progress ได้จาก ImportJob ที่คืนกลับไป
+func StartSchoolImport(db *gorm.DB, userID uint, fileName string, items []SchoolImportRow, parseErrors []SpreadsheetRowError) (*entity.ImportJob, error) {
+	job := entity.ImportJob{
+		JobType:   ImportJobTypeSchools,
+		FileName:  fileName,
+		Status:    entity.ImportJobPending,
+		TotalRows: len(items) + countErrorRows(parseErrors),
+		Skipped:   countErrorRows(parseErrors),
+		Processed: countErrorRows(parseErrors),
+		UserID:    userID,
+	}
+	job.RowErrors = encodeRowErrors(parseErrors)
+
+	if err := db.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	go runSchoolImport(db, job.ID, items, parseErrors)
+
+	return &job, nil
+}
+
+func runSchoolImport(db *gorm.DB, jobID uint, items []SchoolImportRow, rowErrors []SpreadsheetRowError) {
+	startedAt := time.Now()
+	db.Model(&entity.ImportJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":     entity.ImportJobRunning,
+		"started_at": startedAt,
+	})
+
+	fail := func(err error) {
+		log.Printf("school import job %d failed: %v\n", jobID, err)
+		finishedAt := time.Now()
+		db.Model(&entity.ImportJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+			"status":        entity.ImportJobFailed,
+			"error_message": err.Error(),
+			"row_errors":    encodeRowErrors(rowErrors),
+			"finished_at":   finishedAt,
+		})
+	}
+
+	// ประเภทโรงเรียน: อ้างอิงได้ทั้งชื่อ และ ID
+	var schoolTypes []entity.SchoolType
+	if err := db.Find(&schoolTypes).Error; err != nil {
+		fail(err)
+		return
+	}
+	typeByKey := make(map[string]uint, len(schoolTypes)*2)
+	for _, st := range schoolTypes {
+		typeByKey[strings.ToLower(strings.TrimSpace(st.Name))] = st.ID
+		typeByKey[strconv.FormatUint(uint64(st.ID), 10)] = st.ID
+	}
+
+	processed := countErrorRows(rowErrors)
+	skipped := processed
+	created, updated := 0, 0
+
+	for start := 0; start < len(items); start += schoolImportBatchSize {
+		end := start + schoolImportBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		batch := make([]entity.School, 0, end-start)
+		codes := make([]string, 0, end-start)
+		for _, item := range items[start:end] {
+			typeID, ok := typeByKey[strings.ToLower(item.SchoolType)]
+			if !ok {
+				rowErrors = append(rowErrors, SpreadsheetRowError{
+					Row:     item.Row,
+					Column:  "school_type",
+					Message: fmt.Sprintf("invalid school type %q", item.SchoolType),
+				})
+				skipped++
+				continue
+			}
+			batch = append(batch, entity.School{
+				Code:           item.Code,
+				Name:           item.Name,
+				SchoolTypeID:   typeID,
+				IsProjectBased: item.IsProjectBased,
+			})
+			codes = append(codes, item.Code)
+		}
+
+		if len(batch) > 0 {
+			// โรงเรียนเดิมที่บันทึก code เป็นตัวพิมพ์เล็กไว้ ให้ upsert ชนกับแถวเดิมแทนการสร้างซ้ำ
+			// (ข้ามถ้ามี code ตัวพิมพ์ใหญ่อยู่แล้ว เพื่อไม่ให้ชน unique index)
+			if err := db.Unscoped().Model(&entity.School{}).
+				Where("UPPER(code) IN ? AND code <> UPPER(code)", codes).
+				Where("UPPER(code) NOT IN (?)", db.Unscoped().Model(&entity.School{}).Select("code").Where("code IS NOT NULL")).
+				Update("code", gorm.Expr("UPPER(code)")).Error; err != nil {
+				fail(err)
+				return
+			}
+
+			var existing int64
+			if err := db.Unscoped().Model(&entity.School{}).Where("code IN ?", codes).Count(&existing).Error; err != nil {
+				fail(err)
+				return
+			}
+
+			// upsert ตาม code (รวมถึงโรงเรียนที่เคยถูก soft delete ไปแล้วด้วย)
+			if err := db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "code"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "school_type_id", "is_project_based", "updated_at", "deleted_at"}),
+			}).Create(&batch).Error; err != nil {
+				fail(err)
+				return
+			}
+
+			updated += int(existing)
+			created += len(batch) - int(existing)
+		}
+
+		processed += end - start
+		db.Model(&entity.ImportJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+			"processed": processed,
+			"created":   created,
+			"updated":   updated,
+			"skipped":   skipped,
+		})
+	}
+
+	finishedAt := time.Now()
+	db.Model(&entity.ImportJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":      entity.ImportJobCompleted,
+		"processed":   processed,
+		"created":     created,
+		"updated":     updated,
+		"skipped":     skipped,
+		"row_errors":  encodeRowErrors(rowErrors),
+		"finished_at": finishedAt,
+	})
+	log.Printf("school import job %d completed: %d created, %d updated, %d skipped\n", jobID, created, updated, skipped)
+}
+
+// countErrorRows นับจำนวนแถว (ไม่ใช่จำนวน error) เพราะหนึ่งแถวอาจมีหลาย error
+func countErrorRows(rowErrors []SpreadsheetRowError) int {
+	rows := map[int]bool{}
+	for _, e := range rowErrors {
+		rows[e.Row] = true
+	}
+	return len(rows)
+}
+
+func encodeRowErrors(rowErrors []SpreadsheetRowError) datatypes.JSON {
+	if rowErrors == nil {
+		rowErrors = []SpreadsheetRowError{}
+	}
+	data, _ := json.Marshal(rowErrors)
+	return datatypes.JSON(data)
+}
@@ -0,0 +1,33 @@
+package services
+
+import "fmt"
+
+// ReorderSelections จัดลำดับ curriculum ID ใหม่ตาม ordered
+// ID ที่ระบุมาจะขึ้นก่อนตามลำดับที่ส่งมา ส่วนที่ไม่ได้ระบุจะต่อท้ายโดยคงลำดับเดิม
+// คืน error ถ้ามี ID ที่ไม่ได้อยู่ใน current หรือระบุซ้ำ
+func ReorderSelections(current []uint, ordered []uint) ([]uint, error) {
+	known := make(map[uint]bool, len(current))
+	for _, id := range current {
+		known[id] = true
+	}
+
+	used := make(map[uint]bool, len(ordered))
+	result := make([]uint, 0, len(current))
+	for _, id := range ordered {
+		if !known[id] {
+			return nil, fmt.Errorf("curriculum %d is not in your selections for this round", id)
+		}
+		if used[id] {
+			return nil, fmt.Errorf("curriculum %d is listed more than once", id)
+		}
+		used[id] = true
+		result = append(result, id)
+	}
+
+	for _, id := range current {
+		if !used[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var ErrUnsupportedSpreadsheet = errors.New("only .csv and .xlsx files are supported")
+
+const (
+	SpreadsheetFormatCSV  = "csv"
+	SpreadsheetFormatXLSX = "xlsx"
+
+	CSVContentType  = "text/csv; charset=utf-8"
+	XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// SpreadsheetFormatFromName คืนค่า format จากนามสกุลไฟล์ (csv / xlsx)
+func SpreadsheetFormatFromName(fileName string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return SpreadsheetFormatCSV, nil
+	case ".xlsx":
+		return SpreadsheetFormatXLSX, nil
+	}
+	return "", ErrUnsupportedSpreadsheet
+}
+
+// ReadSpreadsheet อ่านไฟล์ CSV หรือ XLSX (sheet แรก) แล้วคืนค่าเป็นแถว ๆ
+// แถวแรกคือ header เสมอ
+func ReadSpreadsheet(r io.Reader, format string) ([][]string, error) {
+	var rows [][]string
+
+	switch format {
+	case SpreadsheetFormatCSV:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		// ตัด BOM ที่ Excel ใส่มาให้ตอน Save as CSV UTF-8
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err = reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %v", err)
+		}
+	case SpreadsheetFormatXLSX:
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx: %v", err)
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("xlsx file has no sheets")
+		}
+		rows, err = f.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx: %v", err)
+		}
+	default:
+		return nil, ErrUnsupportedSpreadsheet
+	}
+
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+	return rows, nil
+}
+
+// IsEmptyRow ใช้ข้ามแถวว่าง (ไม่ลบทิ้งตอนอ่าน เพื่อให้เลขแถวตรงกับไฟล์)
+func IsEmptyRow(row []string) bool {
+	for _, v := range row {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteSpreadsheet เขียน header + rows ออกเป็น CSV หรือ XLSX
+func WriteSpreadsheet(w io.Writer, format string, sheetName string, header []string, rows [][]string) error {
+	switch format {
+	case SpreadsheetFormatCSV:
+		// ใส่ BOM เพื่อให้ Excel เปิดภาษาไทยได้ถูกต้อง
+		if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+			return err
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	case SpreadsheetFormatXLSX:
+		if sheetName == "" {
+			sheetName = "Sheet1"
+		}
+		return WriteXLSXSheets(w, []SpreadsheetSheet{{Name: sheetName, Header: header, Rows: rows}})
+	}
+	return ErrUnsupportedSpreadsheet
+}
+
+func writeXLSXRow(f *excelize.File, sheet string, rowNum int, values []string) error {
+	cell, err := excelize.CoordinatesToCellName(1, rowNum)
+	if err != nil {
+		return err
+	}
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	return f.SetSheetRow(sheet, cell, &row)
+}
+
+// SpreadsheetContentType คืนค่า Content-Type สำหรับ response ตาม format
+func SpreadsheetContentType(format string) string {
+	if format == SpreadsheetFormatXLSX {
+		return XLSXContentType
+	}
+	return CSVContentType
+}
+
+// HeaderIndex สร้าง map จากชื่อคอลัมน์ (ตัวพิมพ์เล็ก) ไปยัง index
+func HeaderIndex(header []string) map[string]int {
+	index := make(map[string]int, len(header))
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	return index
+}
+
+// CellValue ดึงค่าจากแถวตามชื่อคอลัมน์ (คืนค่าว่างถ้าไม่มีคอลัมน์/ค่า)
+func CellValue(row []string, index map[string]int, column string) string {
+	i, ok := index[column]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// SplitList แยกค่าที่คั่นด้วย ";" ในเซลล์เดียว (เช่น รายชื่อทักษะ)
+func SplitList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// SpreadsheetRowError ข้อผิดพลาดระดับแถว (Row นับแบบเดียวกับที่ผู้ใช้เห็นใน Excel คือ header = แถว 1)
+type SpreadsheetRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// SpreadsheetSheet ข้อมูลหนึ่ง sheet สำหรับ WriteXLSXSheets
+type SpreadsheetSheet struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// WriteXLSXSheets เขียนหลาย sheet ลงในไฟล์ XLSX เดียว (CSV ทำแบบนี้ไม่ได้)
+func WriteXLSXSheets(w io.Writer, sheets []SpreadsheetSheet) error {
+	if len(sheets) == 0 {
+		return errors.New("no sheets to write")
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), sheet.Name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.Name); err != nil {
+			return err
+		}
+
+		if err := writeXLSXRow(f, sheet.Name, 1, sheet.Header); err != nil {
+			return err
+		}
+		for j, row := range sheet.Rows {
+			if err := writeXLSXRow(f, sheet.Name, j+2, row); err != nil {
+				return err
+			}
+		}
+	}
+	return f.Write(w)
+}
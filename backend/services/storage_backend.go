@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StorageBackend ที่เก็บไฟล์ที่ผู้ใช้อัปโหลด (local, S3-compatible หรือ Azure Blob)
+// ชื่อไฟล์ (name) เป็นชื่อเดียวกับที่อยู่ท้าย URL ".../uploads/<name>" ทุก backend
+type StorageBackend interface {
+	// UploadFile เก็บไฟล์โดยตั้งชื่อใหม่ที่ไม่ซ้ำ (คงนามสกุลเดิม) แล้วคืน URL
+	UploadFile(file io.Reader, fileName string, contentType string) (string, error)
+	// SaveFile เก็บไฟล์ด้วยชื่อที่ผู้เรียกกำหนด (ทับไฟล์เดิมถ้ามี) แล้วคืน URL
+	SaveFile(file io.Reader, name string) (string, error)
+	// OpenFile เปิดไฟล์เพื่ออ่าน ไฟล์ที่ไม่มีคืน error ที่ errors.Is(err, fs.ErrNotExist)
+	OpenFile(name string) (io.ReadCloser, error)
+	DeleteFile(fileName string) error
+	// SignedURL URL สำหรับดาวน์โหลดที่ใช้ได้ภายในเวลาที่กำหนด
+	SignedURL(name string, expiry time.Duration) (string, error)
+	// ListFiles ชื่อไฟล์ทั้งหมดที่เก็บอยู่
+	ListFiles() ([]string, error)
+}
+
+// ชื่อ backend ที่เลือกได้ด้วย STORAGE_BACKEND
+const (
+	StorageBackendLocal = "local"
+	StorageBackendS3    = "s3"
+	StorageBackendAzure = "azure"
+)
+
+// Storage backend ที่ใช้เก็บไฟล์อัปโหลด (nil = ยังไม่ได้ InitStorage)
+var Storage StorageBackend
+
+// InitStorage เลือก backend จาก STORAGE_BACKEND (ค่าเริ่มต้น local)
+func InitStorage() error {
+	backend, err := NewStorageBackend(os.Getenv("STORAGE_BACKEND"))
+	if err != nil {
+		return err
+	}
+	if local, ok := backend.(*LocalStorageService); ok {
+		LocalStorage = local
+	}
+	Storage = backend
+	return nil
+}
+
+// NewStorageBackend สร้าง backend ตามชื่อ โดยอ่านค่าตั้งจาก environment ของแต่ละ backend
+func NewStorageBackend(name string) (StorageBackend, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", StorageBackendLocal:
+		return NewLocalStorageFromEnv()
+	case StorageBackendS3:
+		return NewS3StorageFromEnv()
+	case StorageBackendAzure:
+		return NewAzureStorageFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (use local, s3 or azure)", name)
+	}
+}
+
+// storageFileExists ตรวจว่ามีไฟล์ชื่อนี้ใน Storage
+func storageFileExists(name string) bool {
+	if Storage == nil || name == "" {
+		return false
+	}
+	f, err := Storage.OpenFile(filepath.Base(name))
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// contentTypeByName เดา MIME type จากนามสกุลไฟล์ (ไม่รู้จักใช้ application/octet-stream)
+func contentTypeByName(name string) string {
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// MigrateStorageOptions ตัวเลือกของ MigrateStorage
+type MigrateStorageOptions struct {
+	// Overwrite คัดลอกทับไฟล์ที่ปลายทางมีอยู่แล้ว (ปกติข้าม)
+	Overwrite bool
+	DryRun    bool
+}
+
+// MigrateStorageResult สรุปผลการคัดลอก
+type MigrateStorageResult struct {
+	Copied  []string          `json:"copied"`
+	Skipped []string          `json:"skipped"`
+	Failed  map[string]string `json:"failed"`
+}
+
+// MigrateStorage คัดลอกทุกไฟล์จาก src ไป dst ด้วยชื่อเดิม (ไม่ลบต้นทาง)
+// ไฟล์ที่คัดลอกไม่สำเร็จถูกบันทึกใน Failed แล้วทำไฟล์ถัดไปต่อ
+func MigrateStorage(src, dst StorageBackend, opts MigrateStorageOptions) (*MigrateStorageResult, error) {
+	names, err := src.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list source files: %w", err)
+	}
+	result := &MigrateStorageResult{Copied: []string{}, Skipped: []string{}, Failed: map[string]string{}}
+	for _, name := range names {
+		if !opts.Overwrite {
+			existing, err := dst.OpenFile(name)
+			if err == nil {
+				existing.Close()
+				result.Skipped = append(result.Skipped, name)
+				continue
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				result.Failed[name] = err.Error()
+				continue
+			}
+		}
+		if opts.DryRun {
+			result.Copied = append(result.Copied, name)
+			continue
+		}
+		if err := copyStorageFile(src, dst, name); err != nil {
+			result.Failed[name] = err.Error()
+			continue
+		}
+		result.Copied = append(result.Copied, name)
+	}
+	return result, nil
+}
+
+func copyStorageFile(src, dst StorageBackend, name string) error {
+	f, err := src.OpenFile(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = dst.SaveFile(f, name)
+	return err
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// การเรียงลำดับของ template gallery
+const (
+	TemplateSortPopular = "popular" // จำนวน portfolio ที่ใช้ template
+	TemplateSortRating  = "rating"
+	TemplateSortNewest  = "newest"
+	TemplateSortName    = "name"
+)
+
+// TemplateGalleryQuery เงื่อนไขของ gallery
+type TemplateGalleryQuery struct {
+	CategoryID uint
+	Search     string
+	Sort       string
+	Page       int
+	Limit      int
+}
+
+// TemplateSummary ข้อมูลย่อของ template สำหรับหน้า gallery (ไม่มี section/block)
+type TemplateSummary struct {
+	ID                 uint      `json:"ID"`
+	TemplateName       string    `json:"template_name"`
+	Description        string    `json:"description"`
+	Thumbnail          string    `json:"thumbnail"`
+	CategoryTemplateID uint      `json:"category_template_id"`
+	CategoryName       string    `json:"category_name"`
+	CurrentVersion     uint      `json:"current_version"`
+	SectionCount       int64     `json:"section_count"`
+	UsageCount         int64     `json:"usage_count"`
+	RatingAverage      float64   `json:"rating_average"`
+	RatingCount        int64     `json:"rating_count"`
+	CreatedAt          time.Time `json:"CreatedAt"`
+	UpdatedAt          time.Time `json:"UpdatedAt"`
+}
+
+// TemplateRatingStats ค่าเฉลี่ยและจำนวนคะแนนของ template
+type TemplateRatingStats struct {
+	TemplatesID   uint        `json:"templates_id"`
+	RatingAverage float64     `json:"rating_average"`
+	RatingCount   int64       `json:"rating_count"`
+	Distribution  map[int]int `json:"distribution"` // จำนวนคนต่อจำนวนดาว 1-5
+}
+
+const templateSummaryColumns = `templates.id, templates.template_name, templates.description, templates.thumbnail,
+	templates.category_template_id, category_templates.category_name, templates.current_version,
+	templates.created_at, templates.updated_at,
+	(SELECT COUNT(*) FROM template_section_links WHERE template_section_links.templates_id = templates.id AND template_section_links.deleted_at IS NULL) AS section_count,
+	(SELECT COUNT(*) FROM portfolios WHERE portfolios.template_id = templates.id AND portfolios.deleted_at IS NULL) AS usage_count,
+	COALESCE((SELECT AVG(stars) FROM template_ratings WHERE template_ratings.templates_id = templates.id AND template_ratings.deleted_at IS NULL), 0) AS rating_average,
+	(SELECT COUNT(*) FROM template_ratings WHERE template_ratings.templates_id = templates.id AND template_ratings.deleted_at IS NULL) AS rating_count`
+
+// ListTemplateGallery รายการ template แบบแบ่งหน้า พร้อมจำนวนการใช้งานและคะแนน
+func ListTemplateGallery(db *gorm.DB, q TemplateGalleryQuery) ([]TemplateSummary, int64, error) {
+	query := db.Model(&entity.Templates{}).
+		Joins("LEFT JOIN category_templates ON category_templates.id = templates.category_template_id AND category_templates.deleted_at IS NULL")
+	if q.CategoryID != 0 {
+		query = query.Where("templates.category_template_id = ?", q.CategoryID)
+	}
+	if search := strings.TrimSpace(q.Search); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(templates.template_name) LIKE ? OR LOWER(templates.description) LIKE ?", like, like)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	switch q.Sort {
+	case TemplateSortRating:
+		query = query.Order("rating_average DESC").Order("rating_count DESC")
+	case TemplateSortNewest:
+		query = query.Order("templates.created_at DESC")
+	case TemplateSortName:
+		query = query.Order("templates.template_name ASC")
+	default:
+		query = query.Order("usage_count DESC")
+	}
+
+	summaries := []TemplateSummary{}
+	err := query.Select(templateSummaryColumns).Order("templates.id DESC").
+		Limit(q.Limit).Offset((q.Page - 1) * q.Limit).
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range summaries {
+		summaries[i].RatingAverage = roundRating(summaries[i].RatingAverage)
+	}
+	return summaries, total, nil
+}
+
+// GetTemplateRatingStats สรุปคะแนนของ template
+func GetTemplateRatingStats(db *gorm.DB, templateID uint) (TemplateRatingStats, error) {
+	stats := TemplateRatingStats{TemplatesID: templateID, Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	var rows []struct {
+		Stars int
+		Count int
+	}
+	if err := db.Model(&entity.TemplateRating{}).Select("stars, COUNT(*) AS count").
+		Where("templates_id = ?", templateID).Group("stars").Scan(&rows).Error; err != nil {
+		return stats, err
+	}
+	sum := 0
+	for _, row := range rows {
+		stats.Distribution[row.Stars] = row.Count
+		stats.RatingCount += int64(row.Count)
+		sum += row.Stars * row.Count
+	}
+	if stats.RatingCount > 0 {
+		stats.RatingAverage = roundRating(float64(sum) / float64(stats.RatingCount))
+	}
+	return stats, nil
+}
+
+// RateTemplate บันทึกหรือแก้คะแนนของผู้ใช้ต่อ template (ผู้เรียกตรวจ stars/comment ด้วย TemplateRating.Validate ก่อน)
+func RateTemplate(db *gorm.DB, templateID, userID uint, stars int, comment string) (*entity.TemplateRating, error) {
+	rating := entity.TemplateRating{TemplatesID: templateID, UserID: userID}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("templates_id = ? AND user_id = ?", templateID, userID).
+			FirstOrInit(&rating).Error; err != nil {
+			return err
+		}
+		rating.Stars = stars
+		rating.Comment = strings.TrimSpace(comment)
+		return tx.Omit("Templates", "User").Save(&rating).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// DeleteTemplateRating ลบคะแนนแบบถาวร เพื่อให้ให้คะแนนใหม่ได้ (unique index รวมแถวที่ soft delete)
+func DeleteTemplateRating(db *gorm.DB, templateID, userID uint) (bool, error) {
+	result := db.Unscoped().Where("templates_id = ? AND user_id = ?", templateID, userID).Delete(&entity.TemplateRating{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func roundRating(v float64) float64 {
+	return math.Round(v*100) / 100
+}
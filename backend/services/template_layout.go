@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// ชนิดการจัดวาง block ภายใน section (SectionBlock.LayoutType)
+const (
+	BlockLayoutGrid = "grid"
+	BlockLayoutFlex = "flex"
+)
+
+// ErrTemplateComponentInUse section/block ถูกใช้โดย template อื่นอยู่
+var ErrTemplateComponentInUse = errors.New("template component is used by other templates")
+
+// TemplateInUseError section หรือ block ที่ถูกใช้ร่วมกันหลาย template (แก้แล้วกระทบทุก template)
+type TemplateInUseError struct {
+	Entity      string `json:"entity"`
+	ID          uint   `json:"id"`
+	TemplateIDs []uint `json:"template_ids"`
+	SectionIDs  []uint `json:"section_ids,omitempty"`
+}
+
+func (e *TemplateInUseError) Error() string {
+	return fmt.Sprintf("%s %d is used by %d template(s)", e.Entity, e.ID, len(e.TemplateIDs))
+}
+
+func (e *TemplateInUseError) Unwrap() error { return ErrTemplateComponentInUse }
+
+// TemplateLayoutError layout ของ section ที่ frontend render ไม่ได้
+type TemplateLayoutError struct {
+	SectionID uint     `json:"section_id,omitempty"`
+	Problems  []string `json:"problems"`
+}
+
+func (e *TemplateLayoutError) Error() string {
+	return "section layout is not renderable: " + strings.Join(e.Problems, "; ")
+}
+
+// ชื่อ CSS property แบบ camelCase หรือ kebab-case (เช่น marginTop, grid-column)
+var cssPropertyName = regexp.MustCompile(`^-?[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// NormalizeBlockLayout ค่าว่างถือเป็น grid (ค่า default ของ section_blocks)
+func NormalizeBlockLayout(layout string) string {
+	layout = strings.ToLower(strings.TrimSpace(layout))
+	if layout == "" {
+		return BlockLayoutGrid
+	}
+	return layout
+}
+
+// ValidateSectionLayout ตรวจว่า section และ block ในนั้นจัดวางได้จริง
+// - section ต้องมีชื่อ layout และมี block อย่างน้อยหนึ่งตัว
+// - layout_type ของ block เป็น grid หรือ flex และ order_index ไม่ซ้ำ
+// - position / flex_settings / grid_settings / custom_style เป็น object ของ CSS property ที่มีค่าเป็น string หรือตัวเลข
+// - grid_settings ใช้กับ grid เท่านั้น และ flex_settings ใช้กับ flex เท่านั้น
+// - default content/style ของ block ผ่าน schema ของชนิด block
+func ValidateSectionLayout(section *entity.TemplatesSection) error {
+	layoutErr := &TemplateLayoutError{SectionID: section.ID}
+	if strings.TrimSpace(section.SectionName) == "" {
+		layoutErr.Problems = append(layoutErr.Problems, "section_name is required")
+	}
+	if strings.TrimSpace(section.LayoutType) == "" {
+		layoutErr.Problems = append(layoutErr.Problems, "layout_type is required")
+	}
+	if len(section.SectionBlocks) == 0 {
+		layoutErr.Problems = append(layoutErr.Problems, "section has no blocks")
+	}
+
+	orders := map[int]uint{}
+	for _, sb := range section.SectionBlocks {
+		label := fmt.Sprintf("section block %d", sb.ID)
+		if sb.OrderIndex < 0 {
+			layoutErr.Problems = append(layoutErr.Problems, label+": order_index must not be negative")
+		}
+		if other, dup := orders[sb.OrderIndex]; dup {
+			layoutErr.Problems = append(layoutErr.Problems, fmt.Sprintf("%s: order_index %d is also used by section block %d", label, sb.OrderIndex, other))
+		}
+		orders[sb.OrderIndex] = sb.ID
+
+		layout := NormalizeBlockLayout(sb.LayoutType)
+		if layout != BlockLayoutGrid && layout != BlockLayoutFlex {
+			layoutErr.Problems = append(layoutErr.Problems, fmt.Sprintf("%s: layout_type must be grid or flex, got %q", label, sb.LayoutType))
+		}
+		settings := []struct {
+			field string
+			raw   []byte
+		}{
+			{"position", sb.Position},
+			{"flex_settings", sb.FlexSettings},
+			{"grid_settings", sb.GridSettings},
+			{"custom_style", sb.CustomStyle},
+		}
+		for _, s := range settings {
+			for _, problem := range cssSettingsProblems(s.raw) {
+				layoutErr.Problems = append(layoutErr.Problems, label+": "+s.field+" "+problem)
+			}
+		}
+		if layout == BlockLayoutFlex && canonicalJSON(sb.GridSettings) != "" && canonicalJSON(sb.GridSettings) != "{}" {
+			layoutErr.Problems = append(layoutErr.Problems, label+": grid_settings is set but layout_type is flex")
+		}
+		if layout == BlockLayoutGrid && canonicalJSON(sb.FlexSettings) != "" && canonicalJSON(sb.FlexSettings) != "{}" {
+			layoutErr.Problems = append(layoutErr.Problems, label+": flex_settings is set but layout_type is grid")
+		}
+
+		if sb.TemplatesBlock == nil || sb.TemplatesBlock.ID == 0 {
+			layoutErr.Problems = append(layoutErr.Problems, fmt.Sprintf("%s: template block %d not found", label, sb.TemplatesBlockID))
+			continue
+		}
+		if err := BlockSchemas.ValidateBlock(sb.TemplatesBlock.BlockType, sb.TemplatesBlock.DefaultContent, sb.TemplatesBlock.DefaultStyle); err != nil {
+			layoutErr.Problems = append(layoutErr.Problems, fmt.Sprintf("%s: template block %q: %v", label, sb.TemplatesBlock.BlockName, err))
+		}
+	}
+
+	if len(layoutErr.Problems) > 0 {
+		return layoutErr
+	}
+	return nil
+}
+
+// cssSettingsProblems settings ต้องเป็น object { cssProperty: string | number }
+func cssSettingsProblems(raw []byte) []string {
+	if canonicalJSON(raw) == "" {
+		return nil
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return []string{"must be a JSON object"}
+	}
+	var problems []string
+	for key, value := range settings {
+		if !cssPropertyName.MatchString(key) {
+			problems = append(problems, fmt.Sprintf("has invalid property name %q", key))
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if strings.ContainsAny(v, "{};<>") {
+				problems = append(problems, fmt.Sprintf("%s has an invalid value", key))
+			}
+		case float64:
+		default:
+			problems = append(problems, fmt.Sprintf("%s must be a string or number", key))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// LoadTemplateSection โหลด section พร้อม block เรียงตาม order_index
+func LoadTemplateSection(db *gorm.DB, sectionID uint) (*entity.TemplatesSection, error) {
+	var section entity.TemplatesSection
+	err := db.
+		Preload("SectionBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC").Order("id ASC")
+		}).
+		Preload("SectionBlocks.TemplatesBlock").
+		First(&section, sectionID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+// CheckSectionLayout โหลด section ใน transaction แล้วตรวจ layout (ใช้หลังแก้ไขก่อน commit)
+func CheckSectionLayout(tx *gorm.DB, sectionID uint) error {
+	section, err := LoadTemplateSection(tx, sectionID)
+	if err != nil {
+		return err
+	}
+	return ValidateSectionLayout(section)
+}
+
+// SectionTemplateIDs template ที่ใช้ section นี้
+func SectionTemplateIDs(db *gorm.DB, sectionID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&entity.TemplateSectionLink{}).Distinct("templates_id").
+		Where("templates_section_id = ?", sectionID).Order("templates_id ASC").Pluck("templates_id", &ids).Error
+	return ids, err
+}
+
+// BlockUsage section และ template ที่ใช้ block นี้
+func BlockUsage(db *gorm.DB, blockID uint) (sectionIDs, templateIDs []uint, err error) {
+	if err = db.Model(&entity.SectionBlock{}).Distinct("templates_section_id").
+		Where("templates_block_id = ?", blockID).Order("templates_section_id ASC").Pluck("templates_section_id", &sectionIDs).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(sectionIDs) == 0 {
+		return sectionIDs, nil, nil
+	}
+	err = db.Model(&entity.TemplateSectionLink{}).Distinct("templates_id").
+		Where("templates_section_id IN ?", sectionIDs).Order("templates_id ASC").Pluck("templates_id", &templateIDs).Error
+	return sectionIDs, templateIDs, err
+}
+
+// CheckSectionShared คืน TemplateInUseError ถ้า section ถูกใช้มากกว่า maxTemplates template
+func CheckSectionShared(db *gorm.DB, sectionID uint, maxTemplates int) ([]uint, error) {
+	templateIDs, err := SectionTemplateIDs(db, sectionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(templateIDs) > maxTemplates {
+		return templateIDs, &TemplateInUseError{Entity: "section", ID: sectionID, TemplateIDs: templateIDs}
+	}
+	return templateIDs, nil
+}
+
+// CheckBlockShared คืน TemplateInUseError ถ้า block ถูกใช้มากกว่า maxTemplates template
+// (block ที่อยู่ใน section ที่ยังไม่ได้ผูกกับ template นับเมื่อ maxTemplates = 0)
+func CheckBlockShared(db *gorm.DB, blockID uint, maxTemplates int) ([]uint, error) {
+	sectionIDs, templateIDs, err := BlockUsage(db, blockID)
+	if err != nil {
+		return nil, err
+	}
+	if len(templateIDs) > maxTemplates || (maxTemplates == 0 && len(sectionIDs) > 0) {
+		return templateIDs, &TemplateInUseError{Entity: "block", ID: blockID, TemplateIDs: templateIDs, SectionIDs: sectionIDs}
+	}
+	return templateIDs, nil
+}
+
+// RefreshTemplateVersions บันทึก version ใหม่ของ template ที่ได้รับผลจากการแก้ section/block
+func RefreshTemplateVersions(tx *gorm.DB, templateIDs []uint) error {
+	for _, id := range templateIDs {
+		if _, err := EnsureTemplateVersion(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,551 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// รูปแบบไฟล์ template package: zip ที่มี manifest.json และรูปใน assets/
+const (
+	TemplatePackageFormat   = "team14-template"
+	TemplatePackageVersion  = 1
+	templateManifestName    = "manifest.json"
+	templateAssetDir        = "assets/"
+	maxTemplateManifestSize = 2 << 20
+	maxTemplateAssetSize    = 10 << 20
+)
+
+var (
+	// ErrInvalidTemplatePackage ไฟล์ package เสียหายหรือ manifest ไม่ถูกต้อง
+	ErrInvalidTemplatePackage = errors.New("invalid template package")
+	// ErrStorageUnavailable ยังไม่ได้เตรียมที่เก็บไฟล์ (InitStorage)
+	ErrStorageUnavailable = errors.New("file storage is not configured")
+)
+
+var templateAssetExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".svg": true}
+
+// TemplatePackage manifest ของ template ทั้งชุด
+// section และ block ที่ใช้ซ้ำจะอยู่ใน manifest เพียงครั้งเดียวและอ้างถึงกันด้วย key
+type TemplatePackage struct {
+	Format     string                   `json:"format"`
+	Version    int                      `json:"version"`
+	ExportedAt time.Time                `json:"exported_at"`
+	Template   TemplatePackageTemplate  `json:"template"`
+	Sections   []TemplatePackageSection `json:"sections"`
+	Blocks     []TemplatePackageBlock   `json:"blocks"`
+}
+
+type TemplatePackageTemplate struct {
+	TemplateName string `json:"template_name"`
+	Description  string `json:"description"`
+	// URL ภายนอก หรือ path ในไฟล์ zip เช่น "assets/thumbnail.png"
+	Thumbnail string   `json:"thumbnail"`
+	Category  string   `json:"category"`
+	Sections  []string `json:"sections"` // key ของ section เรียงตามลำดับ
+}
+
+type TemplatePackageSection struct {
+	Key         string                        `json:"key"`
+	SectionName string                        `json:"section_name"`
+	LayoutType  string                        `json:"layout_type"`
+	Blocks      []TemplatePackageSectionBlock `json:"blocks"`
+}
+
+type TemplatePackageSectionBlock struct {
+	Block        string         `json:"block"` // key ของ block
+	OrderIndex   int            `json:"order_index"`
+	LayoutType   string         `json:"layout_type"`
+	Position     datatypes.JSON `json:"position,omitempty"`
+	FlexSettings datatypes.JSON `json:"flex_settings,omitempty"`
+	GridSettings datatypes.JSON `json:"grid_settings,omitempty"`
+	CustomStyle  datatypes.JSON `json:"custom_style,omitempty"`
+}
+
+type TemplatePackageBlock struct {
+	Key            string         `json:"key"`
+	BlockName      string         `json:"block_name"`
+	BlockType      string         `json:"block_type"`
+	OrderIndex     uint           `json:"order_index"`
+	DefaultContent datatypes.JSON `json:"default_content,omitempty"`
+	DefaultStyle   datatypes.JSON `json:"default_style,omitempty"`
+}
+
+// TemplateImportResult สรุปว่าแถวไหนสร้างใหม่ แถวไหนใช้ของเดิมที่เหมือนกันทุกประการ
+type TemplateImportResult struct {
+	SectionsCreated int  `json:"sections_created"`
+	SectionsReused  int  `json:"sections_reused"`
+	BlocksCreated   int  `json:"blocks_created"`
+	BlocksReused    int  `json:"blocks_reused"`
+	CategoryCreated bool `json:"category_created"`
+}
+
+// LoadTemplateGraph โหลด template พร้อม section/block ทั้งหมดเรียงตามลำดับ
+func LoadTemplateGraph(db *gorm.DB, templateID uint) (*entity.Templates, error) {
+	var template entity.Templates
+	err := db.
+		Preload("Category").
+		Preload("TemplateSectionLinks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Preload("TemplateSectionLinks.TemplatesSection").
+		Preload("TemplateSectionLinks.TemplatesSection.SectionBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Preload("TemplateSectionLinks.TemplatesSection.SectionBlocks.TemplatesBlock").
+		First(&template, templateID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ExportTemplatePackage เขียน template เป็นไฟล์ zip ลง w
+// thumbnail ที่อยู่ใน uploads จะถูกแนบไปใน assets/ ส่วน URL ภายนอกเก็บไว้ตามเดิม
+func ExportTemplatePackage(db *gorm.DB, templateID uint, w io.Writer) error {
+	template, err := LoadTemplateGraph(db, templateID)
+	if err != nil {
+		return err
+	}
+
+	pkg := buildTemplatePackage(template)
+	pkg.ExportedAt = time.Now()
+
+	zw := zip.NewWriter(w)
+	if stored := uploadNameFromURL(template.Thumbnail); stored != "" && Storage != nil {
+		if rc, err := Storage.OpenFile(stored); err == nil {
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			name := templateAssetDir + "thumbnail" + strings.ToLower(filepath.Ext(stored))
+			f, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(data); err != nil {
+				return err
+			}
+			pkg.Template.Thumbnail = name
+		}
+	}
+
+	f, err := zw.Create(templateManifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(pkg); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// buildTemplatePackage แปลง template (ที่โหลดด้วย LoadTemplateGraph) เป็น manifest
+// key ของ section/block คือ "section-<id>" / "block-<id>"
+func buildTemplatePackage(template *entity.Templates) TemplatePackage {
+	pkg := TemplatePackage{
+		Format:  TemplatePackageFormat,
+		Version: TemplatePackageVersion,
+		Template: TemplatePackageTemplate{
+			TemplateName: template.TemplateName,
+			Description:  template.Description,
+			Thumbnail:    template.Thumbnail,
+			Sections:     []string{},
+		},
+		Sections: []TemplatePackageSection{},
+		Blocks:   []TemplatePackageBlock{},
+	}
+	if template.Category != nil {
+		pkg.Template.Category = template.Category.CategoryName
+	}
+
+	seenSections, seenBlocks := map[uint]bool{}, map[uint]bool{}
+	for _, link := range template.TemplateSectionLinks {
+		section := link.TemplatesSection
+		if section == nil {
+			continue
+		}
+		key := templateSectionKey(section.ID)
+		pkg.Template.Sections = append(pkg.Template.Sections, key)
+		if seenSections[section.ID] {
+			continue
+		}
+		seenSections[section.ID] = true
+
+		out := TemplatePackageSection{Key: key, SectionName: section.SectionName, LayoutType: section.LayoutType, Blocks: []TemplatePackageSectionBlock{}}
+		for _, sb := range section.SectionBlocks {
+			if sb.TemplatesBlock == nil {
+				continue
+			}
+			block := sb.TemplatesBlock
+			blockKey := templateBlockKey(block.ID)
+			out.Blocks = append(out.Blocks, TemplatePackageSectionBlock{
+				Block:        blockKey,
+				OrderIndex:   sb.OrderIndex,
+				LayoutType:   sb.LayoutType,
+				Position:     sb.Position,
+				FlexSettings: sb.FlexSettings,
+				GridSettings: sb.GridSettings,
+				CustomStyle:  sb.CustomStyle,
+			})
+			if seenBlocks[block.ID] {
+				continue
+			}
+			seenBlocks[block.ID] = true
+			pkg.Blocks = append(pkg.Blocks, TemplatePackageBlock{
+				Key:            blockKey,
+				BlockName:      block.BlockName,
+				BlockType:      block.BlockType,
+				OrderIndex:     block.OrderIndex,
+				DefaultContent: block.DefaultContent,
+				DefaultStyle:   block.DefaultStyle,
+			})
+		}
+		pkg.Sections = append(pkg.Sections, out)
+	}
+	return pkg
+}
+
+// ImportTemplatePackage สร้าง template ทั้งชุดจากไฟล์ zip ใน transaction เดียว
+// section/block ที่มีอยู่แล้วและเหมือนกันทุกประการจะถูกใช้ซ้ำแทนการสร้างใหม่
+// categoryID = 0 จะหา category ตามชื่อใน manifest (สร้างใหม่ถ้ายังไม่มี)
+func ImportTemplatePackage(db *gorm.DB, data []byte, categoryID uint) (*entity.Templates, *TemplateImportResult, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTemplatePackage, err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[path.Clean(f.Name)] = f
+	}
+
+	manifestFile, ok := files[templateManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s not found", ErrInvalidTemplatePackage, templateManifestName)
+	}
+	raw, err := readZipFile(manifestFile, maxTemplateManifestSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTemplatePackage, err)
+	}
+	var pkg TemplatePackage
+	if err := json.Unmarshal(raw, &pkg); err != nil {
+		return nil, nil, fmt.Errorf("%w: manifest: %v", ErrInvalidTemplatePackage, err)
+	}
+	if err := validateTemplatePackage(&pkg); err != nil {
+		return nil, nil, err
+	}
+
+	template := entity.Templates{
+		TemplateName:       strings.TrimSpace(pkg.Template.TemplateName),
+		Description:        pkg.Template.Description,
+		CategoryTemplateID: categoryID,
+	}
+	assetName := ""
+	if strings.HasPrefix(pkg.Template.Thumbnail, templateAssetDir) {
+		assetName = path.Clean(pkg.Template.Thumbnail)
+	} else {
+		template.Thumbnail = pkg.Template.Thumbnail
+	}
+	check := template
+	if check.CategoryTemplateID == 0 {
+		check.CategoryTemplateID = 1 // category จริงหาจากชื่อใน transaction
+	}
+	if err := check.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTemplatePackage, err)
+	}
+
+	// บันทึกรูป thumbnail ก่อนเริ่ม transaction (ลบทิ้งถ้า import ไม่สำเร็จ)
+	var storedThumbnail string
+	if assetName != "" {
+		f, ok := files[assetName]
+		ext := strings.ToLower(path.Ext(assetName))
+		if !ok || !templateAssetExtensions[ext] {
+			return nil, nil, fmt.Errorf("%w: thumbnail asset %q not found or not an image", ErrInvalidTemplatePackage, assetName)
+		}
+		asset, err := readZipFile(f, maxTemplateAssetSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTemplatePackage, err)
+		}
+		if Storage == nil {
+			return nil, nil, ErrStorageUnavailable
+		}
+		if storedThumbnail, err = Storage.UploadFile(bytes.NewReader(asset), assetName, ""); err != nil {
+			return nil, nil, err
+		}
+		template.Thumbnail = storedThumbnail
+	}
+
+	result := &TemplateImportResult{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if template.CategoryTemplateID == 0 {
+			created, err := findOrCreateTemplateCategory(tx, pkg.Template.Category, &template.CategoryTemplateID)
+			if err != nil {
+				return err
+			}
+			result.CategoryCreated = created
+		}
+
+		blockIDs := map[string]uint{}
+		for _, b := range pkg.Blocks {
+			block := entity.TemplatesBlock{
+				BlockName:      b.BlockName,
+				BlockType:      b.BlockType,
+				OrderIndex:     b.OrderIndex,
+				DefaultContent: b.DefaultContent,
+				DefaultStyle:   b.DefaultStyle,
+			}
+			id, reused, err := findOrCreateTemplateBlock(tx, block)
+			if err != nil {
+				return err
+			}
+			blockIDs[b.Key] = id
+			if reused {
+				result.BlocksReused++
+			} else {
+				result.BlocksCreated++
+			}
+		}
+
+		sectionIDs := map[string]uint{}
+		for _, s := range pkg.Sections {
+			links := make([]entity.SectionBlock, len(s.Blocks))
+			for i, sb := range s.Blocks {
+				links[i] = entity.SectionBlock{
+					TemplatesBlockID: blockIDs[sb.Block],
+					OrderIndex:       sb.OrderIndex,
+					LayoutType:       sb.LayoutType,
+					Position:         sb.Position,
+					FlexSettings:     sb.FlexSettings,
+					GridSettings:     sb.GridSettings,
+					CustomStyle:      sb.CustomStyle,
+				}
+			}
+			id, reused, err := findOrCreateTemplateSection(tx, s.SectionName, s.LayoutType, links)
+			if err != nil {
+				return err
+			}
+			sectionIDs[s.Key] = id
+			if reused {
+				result.SectionsReused++
+			} else {
+				result.SectionsCreated++
+			}
+		}
+
+		if err := tx.Omit("Category").Create(&template).Error; err != nil {
+			return err
+		}
+		for i, key := range pkg.Template.Sections {
+			link := entity.TemplateSectionLink{TemplatesID: template.ID, TemplatesSectionID: sectionIDs[key], OrderIndex: uint(i)}
+			if err := tx.Omit("Templates", "TemplatesSection").Create(&link).Error; err != nil {
+				return err
+			}
+		}
+		_, err := EnsureTemplateVersion(tx, template.ID)
+		return err
+	})
+	if err != nil {
+		if storedThumbnail != "" {
+			Storage.DeleteFile(path.Base(storedThumbnail))
+		}
+		return nil, nil, err
+	}
+
+	imported, err := LoadTemplateGraph(db, template.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return imported, result, nil
+}
+
+// validateTemplatePackage ตรวจรูปแบบ manifest, key ที่อ้างถึงกัน และ schema ของ block ก่อนเขียนฐานข้อมูล
+func validateTemplatePackage(pkg *TemplatePackage) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTemplatePackage, fmt.Sprintf(format, args...))
+	}
+	if pkg.Format != TemplatePackageFormat {
+		return invalid("unsupported format %q", pkg.Format)
+	}
+	if pkg.Version < 1 || pkg.Version > TemplatePackageVersion {
+		return invalid("unsupported version %d", pkg.Version)
+	}
+	if len(pkg.Template.Sections) < 2 {
+		return invalid("at least 2 sections are required")
+	}
+
+	blocks := map[string]bool{}
+	for _, b := range pkg.Blocks {
+		if b.Key == "" || blocks[b.Key] {
+			return invalid("block key %q is empty or duplicated", b.Key)
+		}
+		blocks[b.Key] = true
+		if err := BlockSchemas.ValidateBlock(b.BlockType, b.DefaultContent, b.DefaultStyle); err != nil {
+			return fmt.Errorf("template block %q: %w", b.BlockName, err)
+		}
+	}
+	sections := map[string]bool{}
+	for _, s := range pkg.Sections {
+		if s.Key == "" || sections[s.Key] {
+			return invalid("section key %q is empty or duplicated", s.Key)
+		}
+		sections[s.Key] = true
+		for _, sb := range s.Blocks {
+			if !blocks[sb.Block] {
+				return invalid("section %q refers to unknown block %q", s.Key, sb.Block)
+			}
+		}
+	}
+	for _, key := range pkg.Template.Sections {
+		if !sections[key] {
+			return invalid("template refers to unknown section %q", key)
+		}
+	}
+	return nil
+}
+
+func findOrCreateTemplateCategory(tx *gorm.DB, name string, id *uint) (bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, fmt.Errorf("%w: category is required", ErrInvalidTemplatePackage)
+	}
+	var category entity.CategoryTemplate
+	err := tx.Where("category_name = ?", name).First(&category).Error
+	if err == nil {
+		*id = category.ID
+		return false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	category = entity.CategoryTemplate{CategoryName: name}
+	if err := tx.Create(&category).Error; err != nil {
+		return false, err
+	}
+	*id = category.ID
+	return true, nil
+}
+
+// findOrCreateTemplateBlock ใช้ block เดิมถ้าชื่อ ชนิด ลำดับ และ content/style ตรงกัน
+func findOrCreateTemplateBlock(tx *gorm.DB, block entity.TemplatesBlock) (uint, bool, error) {
+	var candidates []entity.TemplatesBlock
+	if err := tx.Where("block_name = ? AND block_type = ? AND order_index = ?", block.BlockName, block.BlockType, block.OrderIndex).
+		Order("id ASC").Find(&candidates).Error; err != nil {
+		return 0, false, err
+	}
+	for _, c := range candidates {
+		if sameJSON(c.DefaultContent, block.DefaultContent) && sameJSON(c.DefaultStyle, block.DefaultStyle) {
+			return c.ID, true, nil
+		}
+	}
+	if err := tx.Create(&block).Error; err != nil {
+		return 0, false, err
+	}
+	return block.ID, false, nil
+}
+
+// findOrCreateTemplateSection ใช้ section เดิมถ้าชื่อ layout และรายการ block (รวมตำแหน่ง/การจัดวาง) ตรงกันทั้งหมด
+func findOrCreateTemplateSection(tx *gorm.DB, name, layout string, blocks []entity.SectionBlock) (uint, bool, error) {
+	var candidates []entity.TemplatesSection
+	if err := tx.Preload("SectionBlocks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("order_index ASC").Order("id ASC")
+	}).Where("section_name = ? AND layout_type = ?", name, layout).Order("id ASC").Find(&candidates).Error; err != nil {
+		return 0, false, err
+	}
+	for _, c := range candidates {
+		if sameSectionBlocks(c.SectionBlocks, blocks) {
+			return c.ID, true, nil
+		}
+	}
+
+	section := entity.TemplatesSection{SectionName: name, LayoutType: layout}
+	if err := tx.Create(&section).Error; err != nil {
+		return 0, false, err
+	}
+	for i := range blocks {
+		blocks[i].TemplatesSectionID = section.ID
+		if err := tx.Omit("TemplatesBlock", "TemplatesSection").Create(&blocks[i]).Error; err != nil {
+			return 0, false, err
+		}
+	}
+	return section.ID, false, nil
+}
+
+func sameSectionBlocks(existing, wanted []entity.SectionBlock) bool {
+	if len(existing) != len(wanted) {
+		return false
+	}
+	// เทียบแบบไม่สนลำดับใน manifest แต่ต้องมี order_index ตรงกัน
+	used := make([]bool, len(existing))
+	for _, w := range wanted {
+		found := false
+		for i, e := range existing {
+			if used[i] || e.TemplatesBlockID != w.TemplatesBlockID || e.OrderIndex != w.OrderIndex || e.LayoutType != w.LayoutType {
+				continue
+			}
+			if sameJSON(e.Position, w.Position) && sameJSON(e.FlexSettings, w.FlexSettings) &&
+				sameJSON(e.GridSettings, w.GridSettings) && sameJSON(e.CustomStyle, w.CustomStyle) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sameJSON เทียบ JSON โดยไม่สนช่องว่างและลำดับ key (ว่าง/null ถือว่าเท่ากัน)
+func sameJSON(a, b []byte) bool {
+	return canonicalJSON(a) == canonicalJSON(b)
+}
+
+func canonicalJSON(raw []byte) string {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(trimmed, &v); err != nil {
+		return string(trimmed)
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
+
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	return data, nil
+}
+
+func templateSectionKey(id uint) string { return fmt.Sprintf("section-%d", id) }
+
+func templateBlockKey(id uint) string { return fmt.Sprintf("block-%d", id) }
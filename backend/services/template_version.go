@@ -0,0 +1,520 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPortfolioHasNoTemplate  = errors.New("portfolio was not created from a template")
+	ErrTemplateVersionNotFound = errors.New("template version not found")
+	ErrTemplateRebaseOutdated  = errors.New("template changed since the preview, preview the rebase again")
+)
+
+// action ของ PortfolioChange ที่เกิดจากการ rebase
+const PortfolioChangeTemplateRebase = "template_rebase"
+
+// รายการเปลี่ยนแปลงใน preview ของการ rebase
+const (
+	RebaseAddSection         = "add_section"
+	RebaseAddBlock           = "add_block"
+	RebaseUpdateBlockStyle   = "update_block_style"
+	RebaseKeepCustomStyle    = "keep_custom_style"    // template เปลี่ยน style แต่นักเรียนแก้ style เองไว้ จึงไม่แตะ
+	RebaseKeepRemovedSection = "keep_removed_section" // section ถูกเอาออกจาก template แต่ยังเก็บไว้ใน portfolio
+	RebaseKeepRemovedBlock   = "keep_removed_block"
+)
+
+// TemplateRebaseChange หนึ่งรายการใน preview
+type TemplateRebaseChange struct {
+	Action             string         `json:"action"`
+	Title              string         `json:"title,omitempty"`
+	BlockType          string         `json:"block_type,omitempty"`
+	TemplateSectionID  uint           `json:"template_section_id,omitempty"`
+	TemplateBlockID    uint           `json:"template_block_id,omitempty"`
+	PortfolioSectionID uint           `json:"portfolio_section_id,omitempty"`
+	PortfolioBlockID   uint           `json:"portfolio_block_id,omitempty"`
+	Style              datatypes.JSON `json:"style,omitempty"`
+
+	section      *TemplatePackageSection
+	sectionBlock *TemplatePackageSectionBlock
+	block        *TemplatePackageBlock
+}
+
+// TemplateRebasePlan ผลการเทียบ portfolio กับ template version ล่าสุด
+type TemplateRebasePlan struct {
+	PortfolioID uint                   `json:"portfolio_id"`
+	TemplateID  uint                   `json:"template_id"`
+	FromVersion uint                   `json:"from_version"`
+	ToVersion   uint                   `json:"to_version"`
+	UpToDate    bool                   `json:"up_to_date"`
+	Changes     []TemplateRebaseChange `json:"changes"`
+
+	// section/block เดิม (สร้างก่อนมี version) ที่จับคู่กับ template ได้จากชื่อ/ชนิด
+	sectionLinks map[uint]uint
+	blockLinks   map[uint]uint
+	latestBlocks map[string]*TemplatePackageBlock
+}
+
+// templateContent ส่วนของ manifest ที่ใช้เทียบว่า template เปลี่ยนหรือไม่ (ไม่รวมเวลา export)
+func templateContent(pkg TemplatePackage) string {
+	data, _ := json.Marshal(struct {
+		Template TemplatePackageTemplate
+		Sections []TemplatePackageSection
+		Blocks   []TemplatePackageBlock
+	}{pkg.Template, pkg.Sections, pkg.Blocks})
+	return string(data)
+}
+
+// EnsureTemplateVersion บันทึก version ใหม่ถ้า template ในปัจจุบันต่างจาก version ล่าสุด
+// (รวมการแก้ section/block ผ่าน endpoint ย่อย) ถ้าไม่ต่างคืน version ล่าสุด
+func EnsureTemplateVersion(tx *gorm.DB, templateID uint) (*entity.TemplateVersion, error) {
+	template, err := LoadTemplateGraph(tx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	pkg := buildTemplatePackage(template)
+
+	if template.CurrentVersion > 0 {
+		var latest entity.TemplateVersion
+		err := tx.Where("templates_id = ? AND version = ?", templateID, template.CurrentVersion).First(&latest).Error
+		if err == nil {
+			var saved TemplatePackage
+			if json.Unmarshal(latest.Snapshot, &saved) == nil && templateContent(saved) == templateContent(pkg) {
+				return &latest, nil
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	pkg.ExportedAt = time.Now()
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		return nil, err
+	}
+	version := entity.TemplateVersion{TemplatesID: templateID, Version: template.CurrentVersion + 1, Snapshot: datatypes.JSON(data)}
+	if err := tx.Create(&version).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&entity.Templates{}).Where("id = ?", templateID).
+		UpdateColumn("current_version", version.Version).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// จำนวนครั้งที่ลองบันทึก version ใหม่เมื่อชนกับ request อื่นที่บันทึก version เดียวกันไปก่อน
+const templateVersionAttempts = 3
+
+// SaveTemplateVersion เรียก EnsureTemplateVersion ใน transaction ของตัวเอง
+// ถ้ามี request อื่นบันทึก version เลขเดียวกันไปก่อน (ชน idx_template_version) จะอ่านใหม่แล้วลองอีกครั้ง
+func SaveTemplateVersion(db *gorm.DB, templateID uint) (*entity.TemplateVersion, error) {
+	var version *entity.TemplateVersion
+	var err error
+	for attempt := 0; attempt < templateVersionAttempts; attempt++ {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var txErr error
+			version, txErr = EnsureTemplateVersion(tx, templateID)
+			return txErr
+		})
+		if err == nil || !isUniqueViolation(err) {
+			return version, err
+		}
+	}
+	return nil, err
+}
+
+// isUniqueViolation error จาก unique index (ข้อความต่างกันระหว่าง postgres กับ sqlite)
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate key") || strings.Contains(msg, "unique constraint")
+}
+
+// LoadTemplateVersion อ่าน snapshot ของ template version
+func LoadTemplateVersion(db *gorm.DB, templateID, version uint) (*TemplatePackage, error) {
+	var row entity.TemplateVersion
+	err := db.Where("templates_id = ? AND version = ?", templateID, version).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pkg TemplatePackage
+	if err := json.Unmarshal(row.Snapshot, &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// latestTemplateVersion version ล่าสุดที่บันทึกไว้ของ template (อ่านอย่างเดียว)
+func latestTemplateVersion(db *gorm.DB, templateID uint) (*entity.TemplateVersion, error) {
+	var row entity.TemplateVersion
+	err := db.Omit("snapshot").Where("templates_id = ?", templateID).Order("version DESC").First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// PlanTemplateRebase เทียบ portfolio กับ template version ล่าสุดที่บันทึกไว้
+// เพิ่ม section/block ที่ template มีเพิ่ม และปรับ style ของ block ที่นักเรียนยังไม่ได้แก้เอง
+// ไม่แตะ content ของ block และไม่ลบ section/block ที่ template เอาออก
+// ไม่บันทึก version ใหม่เอง ผู้เรียกต้อง SaveTemplateVersion ก่อนถ้าต้องการเทียบกับ template ปัจจุบัน
+func PlanTemplateRebase(tx *gorm.DB, portfolioID uint) (*TemplateRebasePlan, error) {
+	var portfolio entity.Portfolio
+	err := tx.
+		Preload("PortfolioSections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("section_order ASC").Order("id ASC")
+		}).
+		Preload("PortfolioSections.PortfolioBlocks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("block_order ASC").Order("id ASC")
+		}).
+		First(&portfolio, portfolioID).Error
+	if err != nil {
+		return nil, err
+	}
+	if portfolio.TemplateID == nil {
+		return nil, ErrPortfolioHasNoTemplate
+	}
+
+	latestVersion, err := latestTemplateVersion(tx, *portfolio.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := LoadTemplateVersion(tx, *portfolio.TemplateID, latestVersion.Version)
+	if err != nil {
+		return nil, err
+	}
+	baseBlocks := map[string]TemplatePackageBlock{}
+	baseSections := map[string]bool{}
+	baseSectionBlocks := map[string]bool{} // "section-1/block-2"
+	if portfolio.TemplateVersion > 0 {
+		base, err := LoadTemplateVersion(tx, *portfolio.TemplateID, portfolio.TemplateVersion)
+		if err != nil && !errors.Is(err, ErrTemplateVersionNotFound) {
+			return nil, err
+		}
+		if base != nil {
+			for _, b := range base.Blocks {
+				baseBlocks[b.Key] = b
+			}
+			for _, key := range base.Template.Sections {
+				baseSections[key] = true
+			}
+			for _, section := range base.Sections {
+				for _, sb := range section.Blocks {
+					baseSectionBlocks[section.Key+"/"+sb.Block] = true
+				}
+			}
+		}
+	}
+
+	plan := &TemplateRebasePlan{
+		PortfolioID:  portfolio.ID,
+		TemplateID:   *portfolio.TemplateID,
+		FromVersion:  portfolio.TemplateVersion,
+		ToVersion:    latestVersion.Version,
+		Changes:      []TemplateRebaseChange{},
+		sectionLinks: map[uint]uint{},
+		blockLinks:   map[uint]uint{},
+	}
+
+	latestSections := map[string]*TemplatePackageSection{}
+	for i := range latest.Sections {
+		latestSections[latest.Sections[i].Key] = &latest.Sections[i]
+	}
+	latestBlocks := map[string]*TemplatePackageBlock{}
+	for i := range latest.Blocks {
+		latestBlocks[latest.Blocks[i].Key] = &latest.Blocks[i]
+	}
+	plan.latestBlocks = latestBlocks
+
+	// จับคู่ section ของ portfolio กับ section ของ template
+	matched := map[string]*entity.PortfolioSection{}
+	for i := range portfolio.PortfolioSections {
+		ps := &portfolio.PortfolioSections[i]
+		if ps.TemplateSectionID == nil {
+			continue
+		}
+		key := templateSectionKey(*ps.TemplateSectionID)
+		if _, ok := latestSections[key]; !ok {
+			plan.Changes = append(plan.Changes, TemplateRebaseChange{
+				Action: RebaseKeepRemovedSection, Title: ps.SectionTitle, TemplateSectionID: *ps.TemplateSectionID, PortfolioSectionID: ps.ID,
+			})
+			continue
+		}
+		if matched[key] == nil {
+			matched[key] = ps
+		}
+	}
+	// portfolio ที่สร้างก่อนมีการผูก section: UseTemplate ใช้ชื่อ section เป็น section_port_key
+	for _, key := range latest.Template.Sections {
+		if matched[key] != nil {
+			continue
+		}
+		for i := range portfolio.PortfolioSections {
+			ps := &portfolio.PortfolioSections[i]
+			if ps.TemplateSectionID == nil && plan.sectionLinks[ps.ID] == 0 && ps.SectionPortKey == latestSections[key].SectionName {
+				matched[key] = ps
+				plan.sectionLinks[ps.ID] = templateKeyID(key)
+				break
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, key := range latest.Template.Sections {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ts := latestSections[key]
+		ps := matched[key]
+		if ps == nil && baseSections[key] {
+			continue // มีตั้งแต่ version เดิมแต่นักเรียนลบ section ออกเอง ไม่เพิ่มกลับ
+		}
+		if ps == nil {
+			plan.Changes = append(plan.Changes, TemplateRebaseChange{
+				Action: RebaseAddSection, Title: ts.SectionName, TemplateSectionID: templateKeyID(key), section: ts,
+			})
+			continue
+		}
+		plan.planSectionBlocks(ps, ts, baseBlocks, baseSectionBlocks)
+	}
+
+	plan.UpToDate = plan.FromVersion == plan.ToVersion
+	for _, change := range plan.Changes {
+		if change.applies() {
+			plan.UpToDate = false
+		}
+	}
+	return plan, nil
+}
+
+// planSectionBlocks เทียบ block ใน section ที่จับคู่ได้แล้ว
+func (plan *TemplateRebasePlan) planSectionBlocks(ps *entity.PortfolioSection, ts *TemplatePackageSection, baseBlocks map[string]TemplatePackageBlock, baseSectionBlocks map[string]bool) {
+	inTemplate := map[uint]bool{}
+	used := map[uint]bool{}
+	for i := range ts.Blocks {
+		sb := &ts.Blocks[i]
+		block := plan.latestBlocks[sb.Block]
+		if block == nil {
+			continue
+		}
+		blockID := templateKeyID(sb.Block)
+		inTemplate[blockID] = true
+
+		var pb *entity.PortfolioBlock
+		for j := range ps.PortfolioBlocks {
+			b := &ps.PortfolioBlocks[j]
+			if !used[b.ID] && b.TemplateBlockID != nil && *b.TemplateBlockID == blockID {
+				pb = b
+				break
+			}
+		}
+		if pb == nil {
+			// block เดิมที่ยังไม่ได้ผูก: จับคู่จากชนิดและลำดับ
+			for j := range ps.PortfolioBlocks {
+				b := &ps.PortfolioBlocks[j]
+				if !used[b.ID] && b.TemplateBlockID == nil && plan.blockLinks[b.ID] == 0 && b.BlockPortType == block.BlockType && b.BlockOrder == sb.OrderIndex {
+					pb = b
+					plan.blockLinks[b.ID] = blockID
+					break
+				}
+			}
+		}
+		if pb == nil && baseSectionBlocks[ts.Key+"/"+sb.Block] {
+			continue // นักเรียนลบ block นี้ออกเอง
+		}
+		if pb == nil {
+			plan.Changes = append(plan.Changes, TemplateRebaseChange{
+				Action: RebaseAddBlock, Title: block.BlockName, BlockType: block.BlockType,
+				TemplateSectionID: templateKeyID(ts.Key), TemplateBlockID: blockID, PortfolioSectionID: ps.ID,
+				sectionBlock: sb, block: block,
+			})
+			continue
+		}
+		used[pb.ID] = true
+
+		base, hasBase := baseBlocks[sb.Block]
+		if hasBase && sameJSON(base.DefaultStyle, block.DefaultStyle) {
+			continue // template ไม่ได้เปลี่ยน style
+		}
+		if sameJSON(pb.BlockStyle, block.DefaultStyle) {
+			continue
+		}
+		change := TemplateRebaseChange{
+			Title: block.BlockName, BlockType: block.BlockType, TemplateBlockID: blockID,
+			PortfolioSectionID: ps.ID, PortfolioBlockID: pb.ID, Style: block.DefaultStyle,
+		}
+		if canonicalJSON(pb.BlockStyle) == "" || (hasBase && sameJSON(pb.BlockStyle, base.DefaultStyle)) {
+			change.Action = RebaseUpdateBlockStyle
+		} else {
+			change.Action = RebaseKeepCustomStyle
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, b := range ps.PortfolioBlocks {
+		if b.TemplateBlockID != nil && !inTemplate[*b.TemplateBlockID] {
+			plan.Changes = append(plan.Changes, TemplateRebaseChange{
+				Action: RebaseKeepRemovedBlock, BlockType: b.BlockPortType, TemplateBlockID: *b.TemplateBlockID,
+				PortfolioSectionID: ps.ID, PortfolioBlockID: b.ID,
+			})
+		}
+	}
+}
+
+// applies รายการที่ต้องแก้ portfolio จริง (ที่เหลือเป็นเพียงข้อมูลใน preview)
+func (c TemplateRebaseChange) applies() bool {
+	return c.Action == RebaseAddSection || c.Action == RebaseAddBlock || c.Action == RebaseUpdateBlockStyle
+}
+
+// ApplyTemplateRebase ทำตาม plan แล้วบันทึกเป็นประวัติของ portfolio (undo ได้) คืนจำนวนรายการที่แก้
+func (s *PortfolioChangeSet) ApplyTemplateRebase(plan *TemplateRebasePlan) (int, error) {
+	for id, templateSectionID := range plan.sectionLinks {
+		if err := s.tx.Model(&entity.PortfolioSection{}).Where("id = ?", id).
+			UpdateColumn("template_section_id", templateSectionID).Error; err != nil {
+			return 0, err
+		}
+	}
+	for id, templateBlockID := range plan.blockLinks {
+		if err := s.tx.Model(&entity.PortfolioBlock{}).Where("id = ?", id).
+			UpdateColumn("template_block_id", templateBlockID).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var maxOrder struct{ Max int }
+	if err := s.tx.Model(&entity.PortfolioSection{}).Select("COALESCE(MAX(section_order), -1) AS max").
+		Where("portfolio_id = ?", s.PortfolioID).Scan(&maxOrder).Error; err != nil {
+		return 0, err
+	}
+	nextSectionOrder := maxOrder.Max + 1
+
+	applied := 0
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case RebaseAddSection:
+			err = s.addTemplateSection(change, nextSectionOrder, plan)
+			nextSectionOrder++
+		case RebaseAddBlock:
+			err = s.Track(PortfolioChangeTemplateRebase, entity.PortfolioChangeBlock, 0, func() (uint, error) {
+				var order struct{ Max int }
+				if err := s.tx.Model(&entity.PortfolioBlock{}).Select("COALESCE(MAX(block_order), -1) AS max").
+					Where("portfolio_section_id = ?", change.PortfolioSectionID).Scan(&order).Error; err != nil {
+					return 0, err
+				}
+				block := newTemplatePortfolioBlock(change.PortfolioSectionID, change.sectionBlock, change.block)
+				block.BlockOrder = order.Max + 1
+				err := s.tx.Omit("PortfolioSection").Create(&block).Error
+				return block.ID, err
+			})
+		case RebaseUpdateBlockStyle:
+			err = s.Track(PortfolioChangeTemplateRebase, entity.PortfolioChangeBlock, change.PortfolioBlockID, func() (uint, error) {
+				return change.PortfolioBlockID, s.tx.Model(&entity.PortfolioBlock{}).Where("id = ?", change.PortfolioBlockID).
+					Update("block_style", change.Style).Error
+			})
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		applied++
+	}
+
+	if err := s.tx.Model(&entity.Portfolio{}).Where("id = ?", s.PortfolioID).
+		UpdateColumn("template_version", plan.ToVersion).Error; err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+func (s *PortfolioChangeSet) addTemplateSection(change TemplateRebaseChange, order int, plan *TemplateRebasePlan) error {
+	var sectionID uint
+	err := s.Track(PortfolioChangeTemplateRebase, entity.PortfolioChangeSection, 0, func() (uint, error) {
+		templateSectionID := change.TemplateSectionID
+		section := entity.PortfolioSection{
+			SectionTitle:      change.section.SectionName,
+			SectionPortKey:    change.section.SectionName,
+			IsEnabled:         true,
+			SectionOrder:      order,
+			TemplateSectionID: &templateSectionID,
+			PortfolioID:       s.PortfolioID,
+		}
+		err := s.tx.Omit("Portfolio", "PortfolioBlocks").Create(&section).Error
+		sectionID = section.ID
+		return section.ID, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range change.section.Blocks {
+		sb := &change.section.Blocks[i]
+		templateBlock := plan.latestBlocks[sb.Block]
+		if templateBlock == nil {
+			continue
+		}
+		err := s.Track(PortfolioChangeTemplateRebase, entity.PortfolioChangeBlock, 0, func() (uint, error) {
+			block := newTemplatePortfolioBlock(sectionID, sb, templateBlock)
+			err := s.tx.Omit("PortfolioSection").Create(&block).Error
+			return block.ID, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newTemplatePortfolioBlock block ของ portfolio ที่สร้างจาก block ของ template (content/style ตั้งต้น)
+func newTemplatePortfolioBlock(sectionID uint, sb *TemplatePackageSectionBlock, block *TemplatePackageBlock) entity.PortfolioBlock {
+	templateBlockID := templateKeyID(block.Key)
+	return entity.PortfolioBlock{
+		BlockPortType:      block.BlockType,
+		BlockOrder:         sb.OrderIndex,
+		BlockStyle:         block.DefaultStyle,
+		Content:            block.DefaultContent,
+		TemplateBlockID:    &templateBlockID,
+		PortfolioSectionID: sectionID,
+	}
+}
+
+// templateKeyID แปลง key "section-12" / "block-5" กลับเป็น id
+func templateKeyID(key string) uint {
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] == '-' {
+			return toUint(key[i+1:])
+		}
+	}
+	return 0
+}
+
+// RebaseOntoTemplate วางแผนและ rebase ใน transaction ของชุดนี้
+// expectedVersion (ถ้าระบุ) ต้องตรงกับ to_version ใน preview ที่ผู้ใช้เห็น
+func (s *PortfolioChangeSet) RebaseOntoTemplate(expectedVersion uint) (*TemplateRebasePlan, int, error) {
+	plan, err := PlanTemplateRebase(s.tx, s.PortfolioID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if expectedVersion != 0 && plan.ToVersion != expectedVersion {
+		return plan, 0, ErrTemplateRebaseOutdated
+	}
+	applied, err := s.ApplyTemplateRebase(plan)
+	return plan, applied, err
+}
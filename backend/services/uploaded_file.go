@@ -0,0 +1,507 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/gorm"
+)
+
+// DefaultUploadGCGracePeriod ไฟล์ที่ไม่มีใครอ้างถึงจะถูกลบหลังจากนี้ (เปลี่ยนได้ด้วย UPLOAD_GC_GRACE_HOURS)
+// ต้องนานพอให้ผู้ใช้อัปโหลดแล้วกลับมากดบันทึกฟอร์มได้
+const DefaultUploadGCGracePeriod = 7 * 24 * time.Hour
+
+var (
+	ErrUploadNotFound  = errors.New("file not found")
+	ErrUploadForbidden = errors.New("you can only delete files you uploaded")
+	ErrUploadInUse     = errors.New("file is still in use")
+)
+
+// StoredUpload ผลของ StoreUpload (Image มีค่าเฉพาะไฟล์รูป)
+type StoredUpload struct {
+	File         *entity.UploadedFile
+	Image        *ProcessedImage
+	Deduplicated bool
+}
+
+// StoreUpload เก็บไฟล์ที่ผู้ใช้อัปโหลดโดยตั้งชื่อตาม SHA-256 ของเนื้อหา
+// ถ้าเคยมีไฟล์เดียวกันอยู่แล้ว (ของผู้ใช้เองหรือของคนอื่น) จะไม่เขียนไฟล์ซ้ำ
+// รูปภาพผ่าน ProcessUploadedImage ก่อนเก็บ ส่วนไฟล์อื่นเก็บตามที่ได้รับ
+func StoreUpload(db *gorm.DB, ownerID uint, originalName, mimeType string, data []byte, isImage bool) (*StoredUpload, error) {
+	if Storage == nil {
+		return nil, ErrStorageNotInitialized
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	// ผู้ใช้อัปโหลดไฟล์เดิมซ้ำ: คืนแถวเดิม และเริ่มนับ grace period ใหม่ถ้ายังไม่ได้ใช้
+	var own entity.UploadedFile
+	err := db.Where("owner_id = ? AND sha256 = ?", ownerID, hash).First(&own).Error
+	if err == nil && storageFileExists(own.StoredName) {
+		if own.UnreferencedSince != nil {
+			own.UnreferencedSince = &now
+			if err := db.Model(&own).Update("unreferenced_since", now).Error; err != nil {
+				return nil, err
+			}
+		}
+		return storedUploadResult(&own, isImage, true)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	record := &own
+	if err != nil {
+		record = &entity.UploadedFile{OwnerID: &ownerID, SHA256: hash}
+	}
+	record.OriginalName = filepath.Base(originalName)
+	record.Size = int64(len(data))
+	record.ReferenceCount = 0
+	record.UnreferencedSince = &now
+
+	// ผู้ใช้อื่นเคยอัปโหลดไฟล์นี้แล้ว: ใช้ไฟล์เดียวกัน
+	var shared entity.UploadedFile
+	err = db.Where("sha256 = ?", hash).Order("id").First(&shared).Error
+	deduplicated := err == nil && storageFileExists(shared.StoredName)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var image *ProcessedImage
+	switch {
+	case deduplicated:
+		record.StoredName, record.URL, record.MimeType = shared.StoredName, shared.URL, shared.MimeType
+	case isImage:
+		if image, err = ProcessUploadedImage(data, hash); err != nil {
+			return nil, err
+		}
+		record.StoredName, record.URL = filepath.Base(image.URL), image.URL
+		record.MimeType = "image/" + image.Format
+	default:
+		name := hash + strings.ToLower(filepath.Ext(originalName))
+		if record.URL, err = Storage.SaveFile(bytes.NewReader(data), name); err != nil {
+			return nil, err
+		}
+		record.StoredName, record.MimeType = name, mimeType
+	}
+
+	if err := db.Save(record).Error; err != nil {
+		if !deduplicated {
+			removeStoredUpload(record.StoredName)
+		}
+		return nil, err
+	}
+	if image != nil {
+		return &StoredUpload{File: record, Image: image}, nil
+	}
+	return storedUploadResult(record, isImage, deduplicated)
+}
+
+func storedUploadResult(file *entity.UploadedFile, isImage, deduplicated bool) (*StoredUpload, error) {
+	result := &StoredUpload{File: file, Deduplicated: deduplicated}
+	if isImage {
+		image, err := loadProcessedImage(file.URL, file.StoredName)
+		if err != nil {
+			return nil, err
+		}
+		result.Image = image
+	}
+	return result, nil
+}
+
+// DeleteUpload ลบไฟล์ที่ผู้ใช้อัปโหลด fileName เป็นชื่อไฟล์ใน ./uploads (ชื่อภาพย่อถือเป็นไฟล์เดียวกับภาพ full)
+// เจ้าของลบได้เฉพาะไฟล์ของตัวเอง, admin ลบไฟล์เก่าที่ไม่มีเจ้าของได้
+// ไฟล์จริงถูกลบเมื่อไม่มีผู้ใช้คนอื่นอัปโหลดไฟล์เดียวกันไว้ และต้องไม่มีข้อมูลใดอ้างถึงอยู่
+// คืน true ถ้าไฟล์จริงถูกลบ
+func DeleteUpload(db *gorm.DB, fileName string, userID uint, isAdmin bool) (bool, error) {
+	name := normalizeUploadName(fileName)
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return false, ErrUploadNotFound
+	}
+
+	var files []entity.UploadedFile
+	if err := db.Where("stored_name = ?", name).Find(&files).Error; err != nil {
+		return false, err
+	}
+	var remove []uint
+	ownedByOthers := 0
+	for _, f := range files {
+		switch {
+		case f.OwnerID != nil && *f.OwnerID == userID:
+			remove = append(remove, f.ID)
+		case f.OwnerID != nil:
+			ownedByOthers++
+		}
+	}
+	if len(remove) == 0 {
+		if len(files) == 0 && !storageFileExists(name) {
+			return false, ErrUploadNotFound
+		}
+		if !isAdmin || ownedByOthers > 0 {
+			return false, ErrUploadForbidden
+		}
+		for _, f := range files {
+			remove = append(remove, f.ID)
+		}
+	}
+
+	// ถ้ายังมีผู้ใช้อื่นถือไฟล์นี้อยู่ ลบแค่แถวของตัวเอง ไฟล์ยังอยู่
+	lastCopy := len(files)-len(remove) == 0
+	if lastCopy {
+		refs, err := scanUploadReferences(db, strings.TrimSuffix(name, filepath.Ext(name)))
+		if err != nil {
+			return false, err
+		}
+		if refs[name] > 0 {
+			return false, ErrUploadInUse
+		}
+	}
+
+	if len(remove) > 0 {
+		if err := db.Unscoped().Delete(&entity.UploadedFile{}, remove).Error; err != nil {
+			return false, err
+		}
+	}
+	if !lastCopy {
+		return false, nil
+	}
+	removeStoredUpload(name)
+	return true, nil
+}
+
+// UploadGCOptions ตัวเลือกของ CollectUnreferencedUploads
+type UploadGCOptions struct {
+	GracePeriod time.Duration
+	// AdoptUntracked สร้างแถว UploadedFile (ไม่มีเจ้าของ) ให้ไฟล์เก่าใน ./uploads ที่ยังไม่มีแถว
+	// เพื่อให้ถูกลบได้ในรอบถัดไปถ้าไม่มีใครใช้ ปิดไว้เป็นค่าเริ่มต้นเพราะไฟล์บางอย่างอาจถูกอ้างถึงจากที่ที่ไม่ได้สแกน
+	AdoptUntracked bool
+	// DryRun รายงานอย่างเดียว ไม่แก้ฐานข้อมูลและไม่ลบไฟล์
+	DryRun bool
+}
+
+// UploadGCResult สรุปผลการเก็บกวาดหนึ่งรอบ
+type UploadGCResult struct {
+	Scanned    int      `json:"scanned"`
+	Referenced int      `json:"referenced"`
+	Pending    int      `json:"pending"`
+	Adopted    int      `json:"adopted"`
+	Removed    []string `json:"removed"`
+	DryRun     bool     `json:"dry_run"`
+}
+
+// CollectUnreferencedUploads นับการอ้างถึงของทุกไฟล์ใหม่ แล้วลบไฟล์ที่ไม่มีใครอ้างถึงนานเกิน grace period
+func CollectUnreferencedUploads(db *gorm.DB, opts UploadGCOptions) (*UploadGCResult, error) {
+	result := &UploadGCResult{Removed: []string{}, DryRun: opts.DryRun}
+	refs, err := scanUploadReferences(db, "")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if opts.AdoptUntracked {
+		adopted, err := adoptUntrackedUploads(db, now, opts.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		result.Adopted = adopted
+	}
+
+	var files []entity.UploadedFile
+	if err := db.Find(&files).Error; err != nil {
+		return nil, err
+	}
+	result.Scanned = len(files)
+
+	var expired []entity.UploadedFile
+	for i := range files {
+		f := &files[i]
+		count := refs[f.StoredName]
+		updates := map[string]interface{}{}
+		switch {
+		case count > 0:
+			result.Referenced++
+			if f.ReferenceCount != count || f.UnreferencedSince != nil {
+				updates["reference_count"], updates["unreferenced_since"] = count, nil
+			}
+		case f.UnreferencedSince == nil:
+			result.Pending++
+			updates["reference_count"], updates["unreferenced_since"] = 0, now
+		case now.Sub(*f.UnreferencedSince) >= opts.GracePeriod:
+			expired = append(expired, *f)
+		default:
+			result.Pending++
+			if f.ReferenceCount != 0 {
+				updates["reference_count"] = 0
+			}
+		}
+		if len(updates) > 0 && !opts.DryRun {
+			if err := db.Model(f).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	removed := map[string]bool{}
+	for _, f := range expired {
+		if !opts.DryRun {
+			if err := db.Unscoped().Delete(&entity.UploadedFile{}, f.ID).Error; err != nil {
+				return nil, err
+			}
+		}
+		if removed[f.StoredName] {
+			continue
+		}
+		// ไฟล์ที่ผู้ใช้อื่นเพิ่งอัปโหลดซ้ำยังอยู่ใน grace period ของแถวนั้น
+		var remaining int64
+		if err := db.Model(&entity.UploadedFile{}).Where("stored_name = ? AND id NOT IN ?", f.StoredName, expiredIDs(expired)).Count(&remaining).Error; err != nil {
+			return nil, err
+		}
+		if remaining > 0 {
+			continue
+		}
+		removed[f.StoredName] = true
+		result.Removed = append(result.Removed, f.StoredName)
+		if !opts.DryRun {
+			removeStoredUpload(f.StoredName)
+		}
+	}
+	return result, nil
+}
+
+func expiredIDs(files []entity.UploadedFile) []uint {
+	ids := make([]uint, len(files))
+	for i, f := range files {
+		ids[i] = f.ID
+	}
+	return ids
+}
+
+// adoptUntrackedUploads สร้างแถวให้ไฟล์ใน Storage ที่ยังไม่มีแถว (ไม่นับไฟล์ภาพย่อ)
+func adoptUntrackedUploads(db *gorm.DB, now time.Time, dryRun bool) (int, error) {
+	names, err := Storage.ListFiles()
+	if err != nil {
+		return 0, err
+	}
+	var tracked []string
+	if err := db.Model(&entity.UploadedFile{}).Distinct().Pluck("stored_name", &tracked).Error; err != nil {
+		return 0, err
+	}
+	known := map[string]bool{}
+	for _, name := range tracked {
+		known[name] = true
+	}
+
+	adopted := 0
+	for _, name := range names {
+		if strings.HasPrefix(name, ".") || known[name] {
+			continue
+		}
+		if base := normalizeUploadName(name); base != name && (known[base] || storageFileExists(base)) {
+			continue
+		}
+		adopted++
+		if dryRun {
+			continue
+		}
+		f, err := Storage.OpenFile(name)
+		if err != nil {
+			return adopted, err
+		}
+		hash := sha256.New()
+		size, err := io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return adopted, err
+		}
+		record := entity.UploadedFile{
+			SHA256:            hex.EncodeToString(hash.Sum(nil)),
+			StoredName:        name,
+			URL:               "/uploads/" + name,
+			OriginalName:      name,
+			Size:              size,
+			MimeType:          mime.TypeByExtension(filepath.Ext(name)),
+			UnreferencedSince: &now,
+		}
+		if err := db.Create(&record).Error; err != nil {
+			return adopted, err
+		}
+	}
+	return adopted, nil
+}
+
+// uploadReferenceSources คอลัมน์ที่เก็บ URL ของไฟล์ใน ./uploads (ทั้ง string และ JSON)
+// ข้อมูลที่ถูก soft delete ไม่นับ แต่ประวัติการแก้ไขและ restore point นับ เพื่อให้ undo ได้รูปกลับมา
+var uploadReferenceSources = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&entity.User{}, []string{"profile_image_url"}},
+	{&entity.ActivityImage{}, []string{"image_url", "thumbnail_url", "medium_url"}},
+	{&entity.WorkingImage{}, []string{"working_image_url", "thumbnail_url", "medium_url"}},
+	{&entity.Portfolio{}, []string{"cover_image", "portfolio_style"}},
+	{&entity.PortfolioSection{}, []string{"section_style"}},
+	{&entity.PortfolioBlock{}, []string{"content", "block_style"}},
+	{&entity.PortfolioChange{}, []string{"before", "after"}},
+	{&entity.PortfolioRestorePoint{}, []string{"snapshot"}},
+	{&entity.Templates{}, []string{"thumbnail"}},
+	{&entity.TemplatesBlock{}, []string{"default_content", "default_style"}},
+	{&entity.SectionBlock{}, []string{"custom_style"}},
+	{&entity.TemplateVersion{}, []string{"snapshot"}},
+	{&entity.Font{}, []string{"font_url"}},
+	{&entity.Announcement_Attachment{}, []string{"file_path"}},
+	{&entity.AcademicScore{}, []string{"transcript_file_path"}},
+	{&entity.GEDScore{}, []string{"cert_file_path"}},
+	{&entity.LanguageProficiencyScore{}, []string{"cert_file_path"}},
+}
+
+// uploadURLMarkers ส่วนของ URL ที่อยู่หน้าชื่อไฟล์ ใช้หาว่าข้อมูลไหนอ้างถึงไฟล์ที่อัปโหลด
+// "uploads/" ครอบคลุม local และ URL เดิมก่อนย้าย backend ส่วน S3/Azure ที่ตั้ง prefix หรือ container
+// เป็นชื่ออื่นต้องใช้ URL จริงของ backend ด้วย ไม่อย่างนั้น GC จะเห็นว่าไม่มีไฟล์ไหนถูกใช้แล้วลบทิ้งทั้งหมด
+func uploadURLMarkers() []string {
+	markers := []string{"uploads/"}
+	const probe = "x"
+	var url string
+	switch s := Storage.(type) {
+	case *S3StorageService:
+		url = s.fileURL(probe)
+	case *AzureStorageService:
+		url = s.fileURL(probe)
+	}
+	if prefix := strings.TrimSuffix(url, probe); prefix != "" && !strings.HasSuffix(prefix, "uploads/") {
+		markers = append(markers, prefix)
+	}
+	return markers
+}
+
+// uploadNameFromURL ชื่อไฟล์ใน Storage ของ URL ที่อัปโหลด (/uploads/x, uploads/x, http://host/uploads/x
+// หรือ URL ของ backend) URL ภายนอกหรือ path ที่มีโฟลเดอร์ย่อยคืนค่าว่าง
+func uploadNameFromURL(url string) string {
+	for _, marker := range uploadURLMarkers() {
+		idx := strings.Index(url, marker)
+		if idx < 0 || (marker == "uploads/" && idx > 0 && url[idx-1] != '/') {
+			continue
+		}
+		name := url[idx+len(marker):]
+		if q := strings.IndexAny(name, "?#"); q >= 0 {
+			name = name[:q]
+		}
+		if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+			return ""
+		}
+		return name
+	}
+	return ""
+}
+
+// scanUploadReferences นับจำนวนช่องข้อมูลที่อ้างถึงไฟล์แต่ละไฟล์ (key เป็นชื่อไฟล์ภาพ full)
+// namePrefix กรองเฉพาะแถวที่อ้างถึงไฟล์ที่ชื่อขึ้นต้นด้วยค่านี้ (ว่าง = ทุกไฟล์)
+func scanUploadReferences(db *gorm.DB, namePrefix string) (map[string]int, error) {
+	markers := uploadURLMarkers()
+	quoted := make([]string, len(markers))
+	for i, marker := range markers {
+		quoted[i] = regexp.QuoteMeta(marker)
+	}
+	pattern := regexp.MustCompile(`(?:` + strings.Join(quoted, "|") + `)([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+	refs := map[string]int{}
+	for _, source := range uploadReferenceSources {
+		for _, column := range source.columns {
+			expr := fmt.Sprintf(`CAST("%s" AS TEXT)`, column)
+			conditions := make([]string, len(markers))
+			args := make([]interface{}, len(markers))
+			for i, marker := range markers {
+				conditions[i], args[i] = expr+" LIKE ?", "%"+marker+namePrefix+"%"
+			}
+			var values []string
+			if err := db.Model(source.model).Where("("+strings.Join(conditions, " OR ")+")", args...).Pluck(expr, &values).Error; err != nil {
+				return nil, err
+			}
+			for _, value := range values {
+				seen := map[string]bool{}
+				for _, match := range pattern.FindAllStringSubmatch(value, -1) {
+					name := normalizeUploadName(match[1])
+					if !seen[name] {
+						seen[name] = true
+						refs[name]++
+					}
+				}
+			}
+		}
+	}
+	return refs, nil
+}
+
+// normalizeUploadName ตัด suffix ของภาพย่อออก ให้ได้ชื่อไฟล์ภาพ full
+func normalizeUploadName(name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for _, rendition := range ImageRenditions {
+		if rendition.Suffix != "" && strings.HasSuffix(base, rendition.Suffix) {
+			return strings.TrimSuffix(base, rendition.Suffix) + ext
+		}
+	}
+	return name
+}
+
+func removeStoredUpload(name string) {
+	if Storage == nil {
+		return
+	}
+	Storage.DeleteFile(name)
+	DeleteImageRenditions(name)
+}
+
+// ReadUpload อ่านไฟล์ที่อัปโหลดทั้งหมดไม่เกิน limit ไบต์ (เกินแล้วคืน error)
+func ReadUpload(file io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("file must not exceed %dMB", limit/1024/1024)
+	}
+	return data, nil
+}
+
+// StartUploadGCScheduler ลบไฟล์ที่ไม่มีใครใช้ทุกชั่วโมง (เรียกใช้ใน main.go หลังเชื่อมต่อฐานข้อมูล)
+func StartUploadGCScheduler() {
+	opts := UploadGCOptions{
+		GracePeriod:    DefaultUploadGCGracePeriod,
+		AdoptUntracked: os.Getenv("UPLOAD_GC_ADOPT_UNTRACKED") == "true",
+	}
+	if hours, err := strconv.Atoi(os.Getenv("UPLOAD_GC_GRACE_HOURS")); err == nil && hours > 0 {
+		opts.GracePeriod = time.Duration(hours) * time.Hour
+	}
+
+	fmt.Println("🧹 Upload GC Scheduler Started...")
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range ticker.C {
+			if Storage == nil {
+				continue
+			}
+			result, err := CollectUnreferencedUploads(config.GetDB(), opts)
+			if err != nil {
+				fmt.Println("Upload GC Error:", err)
+				continue
+			}
+			if len(result.Removed) > 0 {
+				fmt.Printf("Upload GC removed %d file(s)\n", len(result.Removed))
+			}
+		}
+	}()
+}
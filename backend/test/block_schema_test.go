@@ -0,0 +1,112 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+)
+
+func TestBlockSchemaRegistry(t *testing.T) {
+	g := NewWithT(t)
+	registry := services.BlockSchemas
+
+	valid := []struct {
+		name, blockType, content, style string
+	}{
+		{"empty content", "text", ``, ``},
+		{"text", "text", `{"text":"hello","text_align":"center","font_color":"#ff6414"}`, `{"background_color":"#fff"}`},
+		{"type from content", "text", `{"type":"activity","data_id":3}`, ``},
+		{"copied data", "text", `{"type":"working","data":{"working_name":"robot"}}`, ``},
+		{"double encoded", "text", `"{\"type\":\"activity\",\"data_id\":3}"`, ``},
+		{"seeded image", "image", `{"url":"","alt_text":""}`, ``},
+	}
+	for _, tc := range valid {
+		g.Expect(registry.ValidateBlock(tc.blockType, []byte(tc.content), []byte(tc.style))).To(Succeed(), tc.name)
+	}
+
+	invalid := []struct {
+		name, blockType, content, style, field string
+	}{
+		{"bad alignment", "text", `{"text":"x","text_align":"middle"}`, ``, "content"},
+		{"missing reference", "text", `{"type":"activity"}`, ``, "content"},
+		{"non positive id", "text", `{"type":"working","data_id":0}`, ``, "content"},
+		{"unknown type", "text", `{"type":"video"}`, ``, "block_type"},
+		{"array content", "activity", `[{"activity_id":1}]`, ``, "content"},
+		{"bad style color", "text", `{"text":"x"}`, `{"background_color":"red"}`, "block_style"},
+	}
+	for _, tc := range invalid {
+		err := registry.ValidateBlock(tc.blockType, []byte(tc.content), []byte(tc.style))
+		var validationErr *services.BlockValidationError
+		g.Expect(err).To(BeAssignableToTypeOf(validationErr), tc.name)
+		g.Expect(err.(*services.BlockValidationError).Field).To(Equal(tc.field), tc.name)
+	}
+
+	types := registry.Types()
+	names := make([]string, len(types))
+	for i, def := range types {
+		names[i] = def.Name
+	}
+	g.Expect(names).To(ContainElements("text", "image", "activity", "working", "profile", "gallery"))
+}
+
+// block ที่ไม่ผ่าน schema ต้องถูกปฏิเสธทั้ง endpoint ทีละรายการและ batch
+func TestBlockSchemaEnforcedOnEndpoints(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	r := portfolioOwnershipRouter()
+
+	g.Expect(doTestRequest(r, f.owner, "POST", "/portfolio/block", fmt.Sprintf(`{"portfolio_section_id":%d,"content":{"type":"activity","data_id":"abc"}}`, f.section)).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "POST", "/portfolio/block", fmt.Sprintf(`{"portfolio_section_id":%d,"block_port_type":"activity","content":{"data_id":4}}`, f.section)).Code).To(Equal(http.StatusCreated))
+
+	blockPath := fmt.Sprintf("/portfolio/block/%d", f.block)
+	g.Expect(doTestRequest(r, f.owner, "PATCH", blockPath, `{"content":{"text":5}}`).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", blockPath, `{"block_style":{"text_align":"middle"}}`).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", blockPath, `{"content":{"text":"ok"}}`).Code).To(Equal(http.StatusOK))
+
+	rev := portfolioRevision(g, f.portfolio)
+	w := batchRequest(f.owner, f.portfolio, fmt.Sprint(rev), fmt.Sprintf(`{"operations":[
+		{"op":"create_block","section_id":%d,"data":{"content":{"text":"fine"}}},
+		{"op":"update_block","id":%d,"data":{"content":{"type":"gallery","images":[{"caption":"no url"}]}}}
+	]}`, f.section, f.block))
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+	g.Expect(w.Body.String()).To(ContainSubstring(`"operation_index":1`))
+	g.Expect(w.Body.String()).To(ContainSubstring(`"validation"`))
+	g.Expect(portfolioRevision(g, f.portfolio)).To(Equal(rev))
+}
+
+// รายงานต้องระบุ block เดิมที่ไม่ผ่าน schema ทั้งของ portfolio และ template
+func TestBlockSchemaReport(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	broken := entity.PortfolioBlock{BlockPortType: "text", PortfolioSectionID: f.section, Content: datatypes.JSON(`{"type":"activity","data_id":-1}`)}
+	g.Expect(db.Omit("PortfolioSection").Create(&broken).Error).To(BeNil())
+	brokenTemplate := entity.TemplatesBlock{BlockName: "legacy", BlockType: "video", DefaultContent: datatypes.JSON(`{}`)}
+	g.Expect(db.Create(&brokenTemplate).Error).To(BeNil())
+
+	report, err := services.BuildBlockSchemaReport(db, services.BlockSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(report.PortfolioBlocksChecked).To(BeNumerically(">=", 2))
+	g.Expect(report.InvalidCount).To(Equal(len(report.Invalid)))
+
+	found := map[string]services.InvalidBlock{}
+	for _, item := range report.Invalid {
+		found[fmt.Sprintf("%s:%d", item.Source, item.ID)] = item
+	}
+	g.Expect(found).NotTo(HaveKey(fmt.Sprintf("%s:%d", services.InvalidBlockSourcePortfolio, f.block)))
+
+	item, ok := found[fmt.Sprintf("%s:%d", services.InvalidBlockSourcePortfolio, broken.ID)]
+	g.Expect(ok).To(BeTrue())
+	g.Expect(item.PortfolioID).To(Equal(f.portfolio))
+	g.Expect(item.BlockType).To(Equal("activity"))
+	g.Expect(found).To(HaveKey(fmt.Sprintf("%s:%d", services.InvalidBlockSourceTemplate, brokenTemplate.ID)))
+}
@@ -0,0 +1,218 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/gorm"
+)
+
+func colorFontRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.GET("/colors/mine", controller.GetMyColors)
+	r.POST("/colors", controller.CreateColorTheme)
+	r.PUT("/colors/:id", controller.UpdateColorTheme)
+	r.DELETE("/colors/:id", controller.DeleteColorTheme)
+	r.POST("/fonts/upload", controller.UploadFont)
+	r.DELETE("/fonts/:id", controller.DeleteFont)
+	return r
+}
+
+// ensureDefaultDecoration สร้างธีมสี/ฟอนต์ของระบบ ID 1 ที่ fixture ของ portfolio อ้างถึง
+// เพื่อให้ธีม/ฟอนต์ที่ test สร้างได้ ID อื่น
+func ensureDefaultDecoration(g *WithT) {
+	db := config.GetDB()
+	colors := entity.Colors{Model: gorm.Model{ID: 1}, ColorsName: "Default", PrimaryColor: "#000000", SecondaryColor: "#444444", BackgroundColor: "#FFFFFF"}
+	g.Expect(db.Where("id = ?", 1).FirstOrCreate(&colors).Error).To(BeNil())
+	font := entity.Font{Model: gorm.Model{ID: 1}, FontName: "Default", FontFamily: "sans-serif", IsActive: true}
+	g.Expect(db.Where("id = ?", 1).FirstOrCreate(&font).Error).To(BeNil())
+}
+
+func TestColorContrastRatio(t *testing.T) {
+	g := NewWithT(t)
+
+	ratio, err := entity.ContrastRatio("#000000", "#FFFFFF")
+	g.Expect(err).To(BeNil())
+	g.Expect(ratio).To(BeNumerically("~", 21, 0.01))
+
+	ratio, err = entity.ContrastRatio("#fff", "#ffffff")
+	g.Expect(err).To(BeNil())
+	g.Expect(ratio).To(BeNumerically("~", 1, 0.001))
+
+	// #767676 บนพื้นขาวคือค่าเทาที่อ่อนที่สุดที่ผ่าน 4.5:1
+	ratio, err = entity.ContrastRatio("#767676", "#FFFFFF")
+	g.Expect(err).To(BeNil())
+	g.Expect(ratio).To(BeNumerically(">=", entity.MinTextContrast))
+
+	_, err = entity.ContrastRatio("blue", "#FFFFFF")
+	g.Expect(err).NotTo(BeNil())
+
+	colors := entity.Colors{ColorsName: "Pale", PrimaryColor: "#AAAAAA", SecondaryColor: "#333333", BackgroundColor: "#FFFFFF"}
+	g.Expect(colors.Validate()).To(Succeed())
+	err = colors.ValidateContrast()
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("Primary color contrast"))
+
+	colors.PrimaryColor = "#1E3A8A"
+	colors.SecondaryColor = "#EEEEEE"
+	err = colors.ValidateContrast()
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("Secondary color contrast"))
+
+	colors.SecondaryColor = "#2563EB"
+	g.Expect(colors.ValidateContrast()).To(Succeed())
+}
+
+// ธีมสีส่วนตัว: แก้/ลบได้เฉพาะเจ้าของ และ portfolio เลือกได้เฉพาะธีมของระบบหรือของตัวเอง
+func TestPersonalColorThemes(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := colorFontRouter()
+	ensureDefaultDecoration(g)
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	g.Expect(doTestRequest(r, 0, "POST", "/colors", `{}`).Code).To(Equal(http.StatusUnauthorized))
+	w := doTestRequest(r, f.owner, "POST", "/colors", `{"colors_name":"Low","primary_color":"#CCCCCC","secondary_color":"#333333","background_color":"#FFFFFF"}`)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(ContainSubstring("contrast"))
+	g.Expect(doTestRequest(r, f.owner, "POST", "/colors", `{"colors_name":"Bad","primary_color":"navy","secondary_color":"#333333","background_color":"#FFFFFF"}`).Code).To(Equal(http.StatusBadRequest))
+
+	// user_id ใน body ถูกละเลย เจ้าของคือผู้ที่ login
+	w = doTestRequest(r, f.owner, "POST", "/colors", fmt.Sprintf(`{"colors_name":"Navy","primary_color":"#1E3A8A","secondary_color":"#2563EB","background_color":"#FFFFFF","user_id":%d}`, f.other))
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var created struct {
+		Data entity.Colors `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+	theme := created.Data
+	g.Expect(theme.UserID).NotTo(BeNil())
+	g.Expect(*theme.UserID).To(Equal(f.owner))
+
+	w = doTestRequest(r, f.owner, "GET", "/colors/mine", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(ContainSubstring(`"Navy"`))
+	g.Expect(doTestRequest(r, f.other, "GET", "/colors/mine", "").Body.String()).NotTo(ContainSubstring(`"Navy"`))
+
+	themePath := fmt.Sprintf("/colors/%d", theme.ID)
+	update := `{"colors_name":"Navy 2","primary_color":"#1E3A8A","secondary_color":"#2563EB","background_color":"#F8FAFC"}`
+	g.Expect(doTestRequest(r, f.other, "PUT", themePath, update).Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.owner, "PUT", themePath, `{"colors_name":"Navy 2","primary_color":"#1E3A8A","secondary_color":"#2563EB","background_color":"#1E3A8A"}`).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PUT", themePath, update).Code).To(Equal(http.StatusOK))
+
+	// แก้ธีมของระบบไม่ได้
+	system := entity.Colors{ColorsName: "System", PrimaryColor: "#000000", SecondaryColor: "#444444", BackgroundColor: "#FFFFFF"}
+	g.Expect(db.Create(&system).Error).To(BeNil())
+	g.Expect(doTestRequest(r, f.owner, "PUT", fmt.Sprintf("/colors/%d", system.ID), update).Code).To(Equal(http.StatusNotFound))
+
+	// portfolio ใช้ธีมของคนอื่นไม่ได้
+	portfolioPath := fmt.Sprintf("/portfolio/%d", f.portfolio)
+	other := entity.Colors{ColorsName: "Other", PrimaryColor: "#000000", SecondaryColor: "#444444", BackgroundColor: "#FFFFFF", UserID: &f.other}
+	g.Expect(db.Create(&other).Error).To(BeNil())
+	g.Expect(doTestRequest(r, f.owner, "PATCH", portfolioPath, fmt.Sprintf(`{"colors_id":%d}`, other.ID)).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", portfolioPath, `{"colors_id":"x"}`).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", portfolioPath, fmt.Sprintf(`{"colors_id":%d}`, system.ID)).Code).To(Equal(http.StatusOK))
+	w = doTestRequest(r, f.owner, "PATCH", portfolioPath, fmt.Sprintf(`{"colors_id":%d}`, theme.ID))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	// ธีมที่ portfolio ใช้อยู่ลบไม่ได้
+	g.Expect(doTestRequest(r, f.owner, "DELETE", themePath, "").Code).To(Equal(http.StatusConflict))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", portfolioPath, fmt.Sprintf(`{"colors_id":%d}`, system.ID)).Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.other, "DELETE", themePath, "").Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.owner, "DELETE", themePath, "").Code).To(Equal(http.StatusOK))
+}
+
+func fontUploadBody(g *WithT, fileName string, content []byte, fields map[string]string) ([]byte, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		g.Expect(mw.WriteField(k, v)).To(Succeed())
+	}
+	fw, err := mw.CreateFormFile("file", fileName)
+	g.Expect(err).To(BeNil())
+	_, err = fw.Write(content)
+	g.Expect(err).To(BeNil())
+	g.Expect(mw.Close()).To(Succeed())
+	return buf.Bytes(), mw.FormDataContentType()
+}
+
+func fontUploadRequest(r *gin.Engine, userID uint, body []byte, contentType string) *httptest.ResponseRecorder {
+	req := newTestRequest(userID, "POST", "/fonts/upload", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return serveTestRequest(r, req)
+}
+
+// admin อัปโหลดไฟล์ฟอนต์ลง local storage ตรวจเนื้อไฟล์จาก magic bytes และลบไฟล์เมื่อลบฟอนต์
+func TestFontUpload(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := colorFontRouter()
+	ensureDefaultDecoration(g)
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	uploadDir := t.TempDir()
+	t.Setenv("UPLOAD_DIR", uploadDir)
+	t.Setenv("BASE_URL", "")
+	g.Expect(services.InitLocalStorage()).To(Succeed())
+	t.Cleanup(func() { services.LocalStorage, services.Storage = nil, nil })
+
+	g.Expect(services.DetectFontFormat([]byte("wOF2...."))).To(Equal(".woff2"))
+	g.Expect(services.DetectFontFormat([]byte{0, 1, 0, 0})).To(Equal(".ttf"))
+	g.Expect(services.DetectFontFormat([]byte("PK\x03\x04"))).To(Equal(""))
+
+	fields := map[string]string{"font_name": "Sarabun Local", "font_family": "'Sarabun Local', sans-serif", "font_category": "sans-serif"}
+
+	body, ct := fontUploadBody(g, "evil.woff2", []byte("<script>alert(1)</script>"), fields)
+	w := fontUploadRequest(r, f.owner, body, ct)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+	body, ct = fontUploadBody(g, "font.exe", []byte("wOF2"), fields)
+	g.Expect(fontUploadRequest(r, f.owner, body, ct).Code).To(Equal(http.StatusBadRequest))
+	body, ct = fontUploadBody(g, "font.woff2", []byte("wOF2data"), map[string]string{"font_family": "x"})
+	g.Expect(fontUploadRequest(r, f.owner, body, ct).Code).To(Equal(http.StatusBadRequest))
+	entries, _ := os.ReadDir(uploadDir)
+	g.Expect(entries).To(BeEmpty())
+
+	body, ct = fontUploadBody(g, "Sarabun.woff2", []byte("wOF2-font-data"), fields)
+	w = fontUploadRequest(r, f.owner, body, ct)
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var created struct {
+		Data entity.Font `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+	font := created.Data
+	g.Expect(font.IsActive).To(BeTrue())
+	g.Expect(font.FontURL).To(Equal("/uploads/" + font.FontFile))
+	g.Expect(strings.HasSuffix(font.FontFile, ".woff2")).To(BeTrue())
+	stored, err := os.ReadFile(filepath.Join(uploadDir, font.FontFile))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(stored)).To(Equal("wOF2-font-data"))
+
+	// portfolio เลือกฟอนต์ที่อัปโหลดได้ แต่เลือกฟอนต์ที่ปิดใช้งานไม่ได้
+	inactive := entity.Font{FontName: "Old", FontFamily: "Old", IsActive: false}
+	g.Expect(db.Create(&inactive).Error).To(BeNil())
+	portfolioPath := fmt.Sprintf("/portfolio/%d", f.portfolio)
+	g.Expect(doTestRequest(r, f.owner, "PATCH", portfolioPath, fmt.Sprintf(`{"font_id":%d}`, inactive.ID)).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", portfolioPath, fmt.Sprintf(`{"font_id":%d}`, font.ID)).Code).To(Equal(http.StatusOK))
+
+	fontPath := fmt.Sprintf("/fonts/%d", font.ID)
+	g.Expect(doTestRequest(r, f.owner, "DELETE", fontPath, "").Code).To(Equal(http.StatusConflict))
+	g.Expect(db.Model(&entity.Portfolio{}).Where("id = ?", f.portfolio).Update("font_id", 1).Error).To(BeNil())
+	g.Expect(doTestRequest(r, f.owner, "DELETE", fontPath, "").Code).To(Equal(http.StatusOK))
+	_, err = os.Stat(filepath.Join(uploadDir, font.FontFile))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
@@ -0,0 +1,289 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+var curriculumImportHeader = []string{"code", "name", "faculty", "program", "skills"}
+
+var curriculumImportRows = [][]string{
+	{"CPE68", "วิศวกรรมคอมพิวเตอร์ 2568", "ENG", "CPE", "การเขียนโปรแกรม;คณิตศาสตร์"},
+	{"EE68", "วิศวกรรมไฟฟ้า 2568", "สำนักวิชาวิศวกรรมศาสตร์", "EE", ""},
+}
+
+// ไฟล์ที่ export ออกไปต้อง import กลับเข้ามาได้ทั้ง CSV และ XLSX
+func TestSpreadsheetRoundTrip(t *testing.T) {
+	for _, format := range []string{services.SpreadsheetFormatCSV, services.SpreadsheetFormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var buf bytes.Buffer
+			err := services.WriteSpreadsheet(&buf, format, "curricula", curriculumImportHeader, curriculumImportRows)
+			g.Expect(err).To(BeNil())
+
+			rows, err := services.ReadSpreadsheet(&buf, format)
+			g.Expect(err).To(BeNil())
+			g.Expect(rows).To(HaveLen(3))
+
+			index := services.HeaderIndex(rows[0])
+			g.Expect(services.CellValue(rows[1], index, "code")).To(Equal("CPE68"))
+			g.Expect(services.CellValue(rows[2], index, "faculty")).To(Equal("สำนักวิชาวิศวกรรมศาสตร์"))
+			g.Expect(services.SplitList(services.CellValue(rows[1], index, "skills"))).To(Equal([]string{"การเขียนโปรแกรม", "คณิตศาสตร์"}))
+			g.Expect(services.SplitList(services.CellValue(rows[2], index, "skills"))).To(BeEmpty())
+		})
+	}
+}
+
+// header ไม่สนตัวพิมพ์เล็กใหญ่ และคอลัมน์ที่ไม่มีจะได้ค่าว่าง
+func TestSpreadsheetHeaderLookup(t *testing.T) {
+	g := NewWithT(t)
+
+	csv := "\xef\xbb\xbfCode , Name\nCPE68,Computer\n,\n"
+	rows, err := services.ReadSpreadsheet(strings.NewReader(csv), services.SpreadsheetFormatCSV)
+	g.Expect(err).To(BeNil())
+	g.Expect(rows).To(HaveLen(3))
+	g.Expect(services.IsEmptyRow(rows[2])).To(BeTrue())
+
+	index := services.HeaderIndex(rows[0])
+	g.Expect(services.CellValue(rows[1], index, "code")).To(Equal("CPE68"))
+	g.Expect(services.CellValue(rows[1], index, "faculty")).To(Equal(""))
+}
+
+// นามสกุลไฟล์ที่ไม่รองรับต้องถูกปฏิเสธ
+func TestSpreadsheetFormatFromName(t *testing.T) {
+	g := NewWithT(t)
+
+	format, err := services.SpreadsheetFormatFromName("curricula.XLSX")
+	g.Expect(err).To(BeNil())
+	g.Expect(format).To(Equal(services.SpreadsheetFormatXLSX))
+
+	_, err = services.SpreadsheetFormatFromName("curricula.xls")
+	g.Expect(err).To(Equal(services.ErrUnsupportedSpreadsheet))
+}
+
+type curriculumImportFixture struct {
+	admin, student    uint
+	faculty, program  string
+	skill             string
+	suffix            int64
+	existingID, owner uint
+}
+
+// สร้างสำนักวิชา/สาขา/ทักษะที่ไม่ซ้ำกับเทสต์อื่น และหลักสูตรเดิมที่มีเจ้าของเป็นอาจารย์
+func createCurriculumImportFixture(g *WithT) curriculumImportFixture {
+	db := config.GetDB()
+	f := curriculumImportFixture{suffix: time.Now().UnixNano()}
+
+	newUser := func(name string, accountType uint) uint {
+		u := entity.User{Email: fmt.Sprintf("%s-%d@test.local", name, f.suffix), AccountTypeID: accountType}
+		g.Expect(db.Omit("AccountType", "IDDocType").Create(&u).Error).To(BeNil())
+		return u.ID
+	}
+	f.admin = newUser("import-admin", entity.UserTypeAdmin)
+	f.student = newUser("import-student", entity.UserTypeStudent)
+	f.owner = newUser("import-owner", entity.UserTypeTeacher)
+
+	faculty := entity.Faculty{Name: fmt.Sprintf("สำนักวิชาทดสอบ %d", f.suffix), ShortName: fmt.Sprintf("F%d", f.suffix)}
+	g.Expect(db.Create(&faculty).Error).To(BeNil())
+	program := entity.Program{Name: fmt.Sprintf("สาขาทดสอบ %d", f.suffix), ShortName: fmt.Sprintf("P%d", f.suffix), FacultyID: faculty.ID}
+	g.Expect(db.Omit("Faculty").Create(&program).Error).To(BeNil())
+	skill := entity.Skill{SkillNameTH: fmt.Sprintf("ทักษะ %d", f.suffix), SkillNameEN: fmt.Sprintf("Skill %d", f.suffix)}
+	g.Expect(db.Create(&skill).Error).To(BeNil())
+	f.faculty, f.program, f.skill = faculty.ShortName, program.ShortName, skill.SkillNameEN
+
+	existing := entity.Curriculum{
+		Code: f.code("OLD"), Name: "หลักสูตรเดิม", Link: "https://example.com/old", PortfolioMaxPages: 10, Quota: 20,
+		Status: "closed", ApplicationPeriod: "2020-01-01T00:00|2020-02-01T00:00",
+		FacultyID: faculty.ID, ProgramID: program.ID, UserID: f.owner,
+	}
+	g.Expect(db.Omit("Faculty", "Program", "User").Create(&existing).Error).To(BeNil())
+	f.existingID = existing.ID
+	return f
+}
+
+func (f curriculumImportFixture) code(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, f.suffix)
+}
+
+// row แถวข้อมูลที่ถูกต้องตามหัวตาราง curriculumImportCSVHeader
+func (f curriculumImportFixture) row(code, name string) string {
+	return strings.Join([]string{code, name, "https://example.com/" + code, "3.00", "10", "30",
+		"2020-01-01T00:00|2020-02-01T00:00", f.faculty, f.program, f.skill}, ",")
+}
+
+const curriculumImportCSVHeader = "code,name,link,gpax_min,portfolio_max_pages,quota,application_period,faculty,program,skills"
+
+func curriculumImportRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	controller.NewCurriculumController().RegisterRoutes(r, r.Group(""))
+	return r
+}
+
+func curriculumImportRequest(g *WithT, userID uint, csv string, dryRun bool) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "curricula.csv")
+	g.Expect(err).To(BeNil())
+	part.Write([]byte(csv))
+	if dryRun {
+		form.WriteField("dry_run", "true")
+	}
+	g.Expect(form.Close()).To(Succeed())
+
+	req := newTestRequest(userID, http.MethodPost, "/admin/curricula/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := serveTestRequest(curriculumImportRouter(), req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func countCurricula(g *WithT, codes ...string) int64 {
+	var count int64
+	g.Expect(config.GetDB().Model(&entity.Curriculum{}).Where("code IN ?", codes).Count(&count).Error).To(BeNil())
+	return count
+}
+
+// นักเรียนและอาจารย์ import หลักสูตรไม่ได้
+func TestCurriculumImportRequiresAdmin(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createCurriculumImportFixture(g)
+
+	csv := curriculumImportCSVHeader + "\n" + f.row(f.code("NEW"), "ใหม่")
+	for _, userID := range []uint{f.student, f.owner} {
+		w, _ := curriculumImportRequest(g, userID, csv, false)
+		g.Expect(w.Code).To(Equal(http.StatusForbidden))
+	}
+	g.Expect(countCurricula(g, f.code("NEW"))).To(BeZero())
+}
+
+// แถวที่ผิดถูกรายงานทุกคอลัมน์ (รวม code ซ้ำในไฟล์) และไม่มีแถวไหนถูกบันทึก
+func TestCurriculumImportValidation(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createCurriculumImportFixture(g)
+
+	good := f.row(f.code("NEW"), "ใหม่")
+	bad := strings.Join([]string{f.code("BAD"), "ผิด", "not a url", "abc", "10", "30",
+		"2020-01-01T00:00|2020-02-01T00:00", "ไม่มีสำนักวิชานี้", f.program, "ไม่มีทักษะนี้"}, ",")
+	dup := f.row(strings.ToLower(f.code("NEW")), "ซ้ำ")
+	csv := strings.Join([]string{curriculumImportCSVHeader, good, bad, dup}, "\n")
+
+	w, resp := curriculumImportRequest(g, f.admin, csv, false)
+	g.Expect(w.Code).To(Equal(http.StatusUnprocessableEntity), w.Body.String())
+
+	errs := map[string]bool{}
+	for _, e := range resp["errors"].([]interface{}) {
+		item := e.(map[string]interface{})
+		errs[fmt.Sprintf("%v:%v", item["row"], item["column"])] = true
+	}
+	g.Expect(errs).To(HaveKey("3:gpax_min"))
+	g.Expect(errs).To(HaveKey("3:faculty"))
+	g.Expect(errs).To(HaveKey("3:skills"))
+	g.Expect(errs).To(HaveKey("3:link"))
+	g.Expect(errs).NotTo(HaveKey("3:faculty_id"))
+	g.Expect(errs).To(HaveKey("4:code"))
+	g.Expect(errs).NotTo(HaveKey("2:code"))
+	g.Expect(countCurricula(g, f.code("NEW"), f.code("BAD"))).To(BeZero())
+}
+
+// dry_run นับสร้างใหม่/อัปเดตโดยไม่แตะฐานข้อมูล
+func TestCurriculumImportDryRun(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createCurriculumImportFixture(g)
+
+	csv := strings.Join([]string{curriculumImportCSVHeader, f.row(f.code("NEW"), "ใหม่"), f.row(f.code("OLD"), "ชื่อใหม่")}, "\n")
+	w, resp := curriculumImportRequest(g, f.admin, csv, true)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(resp["data"]).To(Equal(map[string]interface{}{"total": 2.0, "created": 1.0, "updated": 1.0, "dry_run": true}))
+
+	g.Expect(countCurricula(g, f.code("NEW"))).To(BeZero())
+	var existing entity.Curriculum
+	g.Expect(config.GetDB().First(&existing, f.existingID).Error).To(BeNil())
+	g.Expect(existing.Name).To(Equal("หลักสูตรเดิม"))
+}
+
+// หลักสูตรใหม่เป็นของผู้ import ส่วนหลักสูตรเดิมถูกอัปเดตแต่เจ้าของไม่เปลี่ยน
+func TestCurriculumImportCreateAndUpdate(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createCurriculumImportFixture(g)
+	db := config.GetDB()
+
+	csv := strings.Join([]string{curriculumImportCSVHeader, f.row(f.code("NEW"), "ใหม่"), f.row(f.code("OLD"), "ชื่อใหม่")}, "\n")
+	w, resp := curriculumImportRequest(g, f.admin, csv, false)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(resp["data"]).To(Equal(map[string]interface{}{"total": 2.0, "created": 1.0, "updated": 1.0}))
+
+	var existing entity.Curriculum
+	g.Expect(db.Preload("Skills").First(&existing, f.existingID).Error).To(BeNil())
+	g.Expect(existing.Name).To(Equal("ชื่อใหม่"))
+	g.Expect(existing.UserID).To(Equal(f.owner))
+	g.Expect(existing.GPAXMin).To(BeNumerically("==", 3))
+	g.Expect(existing.Skills).To(HaveLen(1))
+
+	var created entity.Curriculum
+	g.Expect(db.Where("code = ?", f.code("NEW")).First(&created).Error).To(BeNil())
+	g.Expect(created.UserID).To(Equal(f.admin))
+	g.Expect(created.Quota).To(Equal(30))
+
+	// import ซ้ำต้องไม่สร้างแถวเพิ่มและไม่ซ้ำความสัมพันธ์
+	w, resp = curriculumImportRequest(g, f.admin, csv, false)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(resp["data"]).To(Equal(map[string]interface{}{"total": 2.0, "created": 0.0, "updated": 2.0}))
+	g.Expect(countCurricula(g, f.code("NEW"), f.code("OLD"))).To(Equal(int64(2)))
+	var skills int64
+	db.Model(&entity.CurriculumSkill{}).Where("curriculum_id = ?", created.ID).Count(&skills)
+	g.Expect(skills).To(Equal(int64(1)))
+}
+
+// code ต่างกันแค่ตัวพิมพ์เป็นหลักสูตรเดียวกัน: ไฟล์ที่มีทั้งสองแบบถูกปฏิเสธ และ import ซ้ำด้วยตัวพิมพ์เล็กอัปเดตแถวเดิม
+func TestCurriculumImportCodeIgnoresCase(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createCurriculumImportFixture(g)
+	db := config.GetDB()
+
+	// หลักสูตรเก่าที่บันทึกเป็นตัวพิมพ์เล็กก่อนมีการ normalize
+	g.Expect(db.Model(&entity.Curriculum{}).Where("id = ?", f.existingID).Update("code", strings.ToLower(f.code("OLD"))).Error).To(BeNil())
+
+	lower := strings.ToLower(f.code("NEW"))
+	csv := strings.Join([]string{curriculumImportCSVHeader, f.row(lower, "ใหม่"), f.row(f.code("NEW"), "ใหม่")}, "\n")
+	w, resp := curriculumImportRequest(g, f.admin, csv, false)
+	g.Expect(w.Code).To(Equal(http.StatusUnprocessableEntity), w.Body.String())
+	g.Expect(resp["errors"]).To(HaveLen(1))
+
+	csv = strings.Join([]string{curriculumImportCSVHeader, f.row(lower, "ใหม่"), f.row(strings.ToLower(f.code("OLD")), "ชื่อใหม่")}, "\n")
+	w, resp = curriculumImportRequest(g, f.admin, csv, false)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(resp["data"]).To(Equal(map[string]interface{}{"total": 2.0, "created": 1.0, "updated": 1.0}))
+
+	w, resp = curriculumImportRequest(g, f.admin, strings.Join([]string{curriculumImportCSVHeader, f.row(f.code("NEW"), "ใหม่")}, "\n"), false)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(resp["data"]).To(Equal(map[string]interface{}{"total": 1.0, "created": 0.0, "updated": 1.0}))
+
+	// ทุกแถวถูกเก็บเป็นตัวพิมพ์ใหญ่
+	g.Expect(countCurricula(g, f.code("NEW"), f.code("OLD"))).To(Equal(int64(2)))
+	var existing entity.Curriculum
+	g.Expect(db.First(&existing, f.existingID).Error).To(BeNil())
+	g.Expect(existing.Code).To(Equal(f.code("OLD")))
+	g.Expect(existing.Name).To(Equal("ชื่อใหม่"))
+}
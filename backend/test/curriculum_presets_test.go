@@ -0,0 +1,110 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+func curriculumPresetRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	cc := controller.NewCurriculumController()
+	r.GET("/curricula/:id", cc.GetCurriculumByID)
+	r.PUT("/admin/curricula/:id/recommended-templates", cc.UpdateRecommendedTemplates)
+	r.PUT("/admin/curricula/:id/required-sections", cc.UpdateRequiredSections)
+	return r
+}
+
+// หลักสูตรแนะนำ template และกำหนด section ที่ต้องมี ส่ง portfolio ที่ขาด section ได้แต่มีคำเตือน
+func TestCurriculumRecommendedTemplatesAndRequiredSections(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("PDF_FONT_DIR", thaiTestFontDir(g, t))
+	setupSQLiteTestDB()
+	r := curriculumPresetRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	curriculum := entity.Curriculum{Code: "ENG", Name: "Engineering", Link: "https://example.com", Status: "open", FacultyID: 1, ProgramID: 1, ApplicationPeriod: "2026", Quota: 10, PortfolioMaxPages: 10}
+	g.Expect(db.Omit("Faculty", "Program", "User").Create(&curriculum).Error).To(BeNil())
+	first := createPackageTemplate(g, "")
+	second := createPackageTemplate(g, "")
+
+	base := fmt.Sprintf("/admin/curricula/%d", curriculum.ID)
+	g.Expect(doTestRequest(r, f.teacher, "PUT", base+"/recommended-templates", `{"template_ids":[999999]}`).Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.teacher, "PUT", "/admin/curricula/999999/recommended-templates", `{"template_ids":[]}`).Code).To(Equal(http.StatusNotFound))
+	w := doTestRequest(r, f.teacher, "PUT", base+"/recommended-templates", fmt.Sprintf(`{"template_ids":[%d,%d,%d]}`, second.ID, first.ID, second.ID))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	g.Expect(doTestRequest(r, f.teacher, "PUT", base+"/required-sections", `{"sections":[{"section_port_key":"works"},{"section_port_key":" WORKS "}]}`).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.teacher, "PUT", base+"/required-sections", `{"sections":[{"section_port_key":""}]}`).Code).To(Equal(http.StatusBadRequest))
+	w = doTestRequest(r, f.teacher, "PUT", base+"/required-sections", `{"sections":[{"section_port_key":"Works","title":"Works"},{"section_port_key":"projects","title":"Project experience","note":"at least one engineering project"}]}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/curricula/%d", curriculum.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data entity.Curriculum `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Data.RecommendedTemplates).To(HaveLen(2))
+	g.Expect(resp.Data.RecommendedTemplates[0].TemplatesID).To(Equal(second.ID))
+	g.Expect(resp.Data.RecommendedTemplates[0].Templates.TemplateName).To(Equal(second.TemplateName))
+	g.Expect(resp.Data.RequiredSections).To(HaveLen(2))
+	g.Expect(resp.Data.RequiredSections[0].SectionPortKey).To(Equal("works"))
+
+	// fixture มี section "works" แต่ไม่มี "projects"
+	w = doTestRequest(r, f.owner, "POST", "/submissions", fmt.Sprintf(`{"portfolio_id":%d,"curriculum_id":%d}`, f.portfolio, curriculum.ID))
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var submitted struct {
+		Warnings []services.ChecklistItem `json:"warnings"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &submitted)).To(Succeed())
+	g.Expect(submitted.Warnings).To(HaveLen(1))
+	g.Expect(submitted.Warnings[0].Code).To(Equal(services.CheckMissingRequiredSection))
+	g.Expect(submitted.Warnings[0].Field).To(Equal("projects"))
+	g.Expect(submitted.Warnings[0].Message).To(ContainSubstring("at least one engineering project"))
+
+	// ไม่ระบุหลักสูตร: ใช้หลักสูตรที่ portfolio ถูกกำหนดไว้
+	items, err := services.CheckRequiredSections(db, f.portfolio, 0)
+	g.Expect(err).To(BeNil())
+	g.Expect(items).To(BeEmpty())
+	g.Expect(db.Create(&entity.PortfolioCurriculum{PortfolioID: f.portfolio, CurriculumID: curriculum.ID, UserID: f.owner}).Error).To(BeNil())
+	items, err = services.CheckRequiredSections(db, f.portfolio, 0)
+	g.Expect(err).To(BeNil())
+	g.Expect(items).To(HaveLen(1))
+
+	// ปิด section แล้วถือว่าไม่มี
+	g.Expect(db.Model(&entity.PortfolioSection{}).Where("id = ?", f.section).Update("is_enabled", false).Error).To(BeNil())
+	items, err = services.CheckRequiredSections(db, f.portfolio, curriculum.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(items).To(HaveLen(2))
+}
+
+// route จริงแก้ template แนะนำ/section ที่ต้องมีได้เฉพาะแอดมิน
+func TestCurriculumPresetsRequireAdmin(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := curriculumImportRouter()
+	f := createCurriculumImportFixture(g)
+
+	base := fmt.Sprintf("/admin/curricula/%d", f.existingID)
+	requests := map[string]string{
+		base + "/recommended-templates": `{"template_ids":[]}`,
+		base + "/required-sections":     `{"sections":[]}`,
+	}
+	for path, body := range requests {
+		for _, userID := range []uint{f.student, f.owner} {
+			g.Expect(doTestRequest(r, userID, "PUT", path, body).Code).To(Equal(http.StatusForbidden), path)
+		}
+		w := doTestRequest(r, f.admin, "PUT", path, body)
+		g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	}
+}
@@ -0,0 +1,192 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+type imageUploadResponse struct {
+	URL        string                                 `json:"url"`
+	Format     string                                 `json:"format"`
+	Width      int                                    `json:"width"`
+	Height     int                                    `json:"height"`
+	Renditions map[string]services.ImageRenditionFile `json:"renditions"`
+}
+
+// uploadRouter ใช้ testAuth จำลอง Authorization middleware เหมือน portfolioOwnershipRouter
+func uploadRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(testAuth)
+	uploads := controller.NewUploadController()
+	r.POST("/upload", uploads.UploadFile)
+	r.DELETE("/upload/:filename", uploads.DeleteFile)
+	return r
+}
+
+func uploadImageRequest(g *WithT, userID uint, fileName string, data []byte) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", fileName)
+	g.Expect(err).To(BeNil())
+	_, err = fw.Write(data)
+	g.Expect(err).To(BeNil())
+	g.Expect(mw.Close()).To(Succeed())
+
+	req := newTestRequest(userID, "POST", "/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return serveTestRequest(uploadRouter(), req)
+}
+
+// jpegWithExif แทรก APP1 ที่มี Orientation และ GPS IFD (ข้อความ GPSSECRET) หลัง SOI
+func jpegWithExif(g *WithT, img image.Image, orientation uint16) []byte {
+	var encoded bytes.Buffer
+	g.Expect(jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 95})).To(Succeed())
+
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08")
+	be := binary.BigEndian
+	ifd := make([]byte, 2+2*12+4)
+	be.PutUint16(ifd, 2)
+	be.PutUint16(ifd[2:], 0x0112)
+	be.PutUint16(ifd[4:], 3)
+	be.PutUint32(ifd[6:], 1)
+	be.PutUint16(ifd[10:], orientation)
+	be.PutUint16(ifd[14:], 0x8825)
+	be.PutUint16(ifd[16:], 4)
+	be.PutUint32(ifd[18:], 1)
+	be.PutUint32(ifd[22:], uint32(len(tiff)+len(ifd)))
+	tiff = append(append(tiff, ifd...), []byte("GPSSECRET 13.7563N 100.5018E")...)
+
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	be.PutUint16(segment[2:], uint16(len(app1)+2))
+	segment = append(segment, app1...)
+
+	raw := encoded.Bytes()
+	out := append([]byte{}, raw[:2]...)
+	out = append(out, segment...)
+	return append(out, raw[2:]...)
+}
+
+// setupImageStorage ใช้โฟลเดอร์ชั่วคราวเป็น local storage และสร้างผู้ใช้สำหรับอัปโหลด
+func setupImageStorage(g *WithT, t *testing.T) (string, uint) {
+	setupSQLiteTestDB()
+	uploadDir := t.TempDir()
+	t.Setenv("UPLOAD_DIR", uploadDir)
+	t.Setenv("BASE_URL", "")
+	g.Expect(services.InitLocalStorage()).To(Succeed())
+	t.Cleanup(func() { services.LocalStorage, services.Storage = nil, nil })
+
+	user := entity.User{Email: fmt.Sprintf("uploader-%d@test.local", time.Now().UnixNano()), AccountTypeID: entity.UserTypeStudent}
+	g.Expect(config.GetDB().Omit("AccountType", "IDDocType").Create(&user).Error).To(BeNil())
+	return uploadDir, user.ID
+}
+
+// รูปถ่ายถูกหมุนตาม EXIF, ไม่มี metadata เหลือ และได้ภาพสามขนาด
+func TestImageUploadOrientationAndRenditions(t *testing.T) {
+	g := NewWithT(t)
+	uploadDir, userID := setupImageStorage(g, t)
+
+	// 3000x1000 ครึ่งซ้ายแดง ครึ่งขวาน้ำเงิน กล้องบันทึกไว้ว่าต้องหมุน 90 องศาตามเข็ม (orientation 6)
+	src := image.NewRGBA(image.Rect(0, 0, 3000, 1000))
+	for y := 0; y < 1000; y++ {
+		for x := 0; x < 3000; x++ {
+			c := color.RGBA{220, 20, 20, 255}
+			if x >= 1500 {
+				c = color.RGBA{20, 20, 220, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	w := uploadImageRequest(g, userID, "photo.JPG", jpegWithExif(g, src, 6))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp imageUploadResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+
+	g.Expect(resp.Format).To(Equal("jpeg"))
+	g.Expect(resp.Width).To(Equal(682))
+	g.Expect(resp.Height).To(Equal(2048))
+	g.Expect(resp.URL).To(Equal(resp.Renditions[services.ImageRenditionFull].URL))
+	g.Expect(resp.Renditions[services.ImageRenditionMedium].Height).To(Equal(1024))
+	g.Expect(resp.Renditions[services.ImageRenditionThumbnail].Height).To(Equal(320))
+
+	for _, rendition := range resp.Renditions {
+		data, err := os.ReadFile(filepath.Join(uploadDir, path.Base(rendition.URL)))
+		g.Expect(err).To(BeNil())
+		g.Expect(bytes.Contains(data, []byte("Exif"))).To(BeFalse())
+		g.Expect(bytes.Contains(data, []byte("GPSSECRET"))).To(BeFalse())
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		g.Expect(err).To(BeNil())
+		g.Expect(img.Bounds().Dx()).To(Equal(rendition.Width))
+		g.Expect(img.Bounds().Dy()).To(Equal(rendition.Height))
+	}
+
+	// หลังหมุน ด้านซ้ายเดิม (แดง) อยู่ด้านบน
+	data, _ := os.ReadFile(filepath.Join(uploadDir, path.Base(resp.Renditions[services.ImageRenditionThumbnail].URL)))
+	thumb, _ := jpeg.Decode(bytes.NewReader(data))
+	top := color.RGBAModel.Convert(thumb.At(thumb.Bounds().Dx()/2, 10)).(color.RGBA)
+	bottom := color.RGBAModel.Convert(thumb.At(thumb.Bounds().Dx()/2, thumb.Bounds().Dy()-10)).(color.RGBA)
+	g.Expect(top.R).To(BeNumerically(">", top.B))
+	g.Expect(bottom.B).To(BeNumerically(">", bottom.R))
+
+	// URL ของภาพย่อหาได้จาก URL ของภาพ full
+	thumbnail, medium := services.ImageRenditionURLs(resp.URL)
+	g.Expect(thumbnail).To(Equal(resp.Renditions[services.ImageRenditionThumbnail].URL))
+	g.Expect(medium).To(Equal(resp.Renditions[services.ImageRenditionMedium].URL))
+	thumbnail, medium = services.ImageRenditionURLs("https://example.com/a.jpg")
+	g.Expect(thumbnail).To(BeEmpty())
+	g.Expect(medium).To(BeEmpty())
+
+	// ลบภาพ full แล้วภาพย่อหายไปด้วย
+	g.Expect(deleteUploadRequest(userID, path.Base(resp.URL))).To(Equal(http.StatusOK))
+	entries, _ := os.ReadDir(uploadDir)
+	g.Expect(entries).To(BeEmpty())
+}
+
+// รูปโปร่งใสเก็บเป็น PNG, รูปเล็กไม่ถูกขยาย และไฟล์ที่ไม่ใช่รูปถูกปฏิเสธ
+func TestImageUploadTransparentAndInvalid(t *testing.T) {
+	g := NewWithT(t)
+	uploadDir, userID := setupImageStorage(g, t)
+
+	logo := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	logo.Set(10, 10, color.NRGBA{0, 0, 0, 255})
+	var buf bytes.Buffer
+	g.Expect(png.Encode(&buf, logo)).To(Succeed())
+
+	w := uploadImageRequest(g, userID, "logo.png", buf.Bytes())
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp imageUploadResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Format).To(Equal("png"))
+	g.Expect(filepath.Ext(resp.URL)).To(Equal(".png"))
+	g.Expect(resp.Width).To(Equal(200))
+	g.Expect(resp.Renditions[services.ImageRenditionMedium].Width).To(Equal(200))
+	g.Expect(resp.Renditions[services.ImageRenditionThumbnail].Width).To(Equal(200))
+
+	before, _ := os.ReadDir(uploadDir)
+	w = uploadImageRequest(g, userID, "fake.jpg", []byte("this is not an image"))
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+	after, _ := os.ReadDir(uploadDir)
+	g.Expect(after).To(HaveLen(len(before)))
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func a11yIssues(audit *services.AccessibilityAudit, code string) []services.AccessibilityIssue {
+	var issues []services.AccessibilityIssue
+	for _, issue := range audit.Issues {
+		if issue.Code == code {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func a11yBlock(id uint, blockType, content, style string) entity.PortfolioBlock {
+	block := entity.PortfolioBlock{Model: gorm.Model{ID: id}, BlockPortType: blockType, Content: datatypes.JSON(content)}
+	if style != "" {
+		block.BlockStyle = datatypes.JSON(style)
+	}
+	return block
+}
+
+// สีที่ section/block กำหนดเองต้องผ่าน contrast ตาม WCAG AA (ตัวใหญ่/หัวข้อใช้เกณฑ์ 3:1)
+func TestAccessibilityAuditContrast(t *testing.T) {
+	g := NewWithT(t)
+	portfolio := entity.Portfolio{
+		Colors: entity.Colors{Model: gorm.Model{ID: 1}, ColorsName: "Light", PrimaryColor: "#1E3A8A", SecondaryColor: "#2563EB", BackgroundColor: "#FFFFFF"},
+		PortfolioSections: []entity.PortfolioSection{
+			{
+				Model: gorm.Model{ID: 10}, SectionTitle: "Dark", IsEnabled: true,
+				SectionStyle: datatypes.JSON(`{"background_color":"#111111"}`),
+				PortfolioBlocks: []entity.PortfolioBlock{
+					a11yBlock(1, "text", `{"text":"inherits default text on dark"}`, ""),
+					a11yBlock(2, "text", `{"text":"white on dark"}`, `{"text_color":"#FFFFFF"}`),
+					a11yBlock(3, "text", `{"text":"grey","font_color":"#777777"}`, ""),
+					a11yBlock(4, "text", `{"text":"grey but large"}`, `{"text_color":"#777777","font_size":"24px"}`),
+				},
+			},
+			{
+				Model: gorm.Model{ID: 11}, SectionTitle: "Plain", IsEnabled: true,
+				PortfolioBlocks: []entity.PortfolioBlock{
+					a11yBlock(5, "text", `{"text":"theme defaults"}`, ""),
+					// สีโปร่งแสงต้องผสมกับพื้นก่อนคำนวณ
+					a11yBlock(6, "text", `{"text":"faded"}`, `{"text_color":"#00000040"}`),
+					a11yBlock(7, "text", `{"text":"bad color"}`, `{"text_color":"black"}`),
+				},
+			},
+			{
+				Model: gorm.Model{ID: 12}, SectionTitle: "Hidden", IsEnabled: false,
+				PortfolioBlocks: []entity.PortfolioBlock{a11yBlock(8, "text", `{"text":"x"}`, `{"text_color":"#FFFFFF"}`)},
+			},
+		},
+	}
+
+	audit := services.AuditPortfolio(&portfolio)
+	low := a11yIssues(audit, services.A11yLowContrast)
+	blocks := map[uint]services.AccessibilityIssue{}
+	for _, issue := range low {
+		blocks[issue.BlockID] = issue
+	}
+	g.Expect(blocks).To(HaveLen(3))
+	g.Expect(blocks).To(HaveKey(uint(1)))
+	g.Expect(blocks[1].Background).To(Equal("#111111"))
+	g.Expect(blocks[1].Required).To(Equal(entity.MinTextContrast))
+	g.Expect(blocks).To(HaveKey(uint(3)))
+	g.Expect(blocks[3].Ratio).To(BeNumerically("<", entity.MinTextContrast))
+	g.Expect(blocks).To(HaveKey(uint(6)))
+	g.Expect(blocks[6].Foreground).To(Equal("#BFBFBF"))
+	g.Expect(blocks).NotTo(HaveKey(uint(4)))
+
+	invalid := a11yIssues(audit, services.A11yInvalidColor)
+	g.Expect(invalid).To(HaveLen(1))
+	g.Expect(invalid[0].BlockID).To(Equal(uint(7)))
+	g.Expect(invalid[0].Field).To(Equal("block_style.text_color"))
+	g.Expect(audit.Passed).To(BeFalse())
+	g.Expect(audit.Counts[services.A11yLowContrast]).To(Equal(3))
+	g.Expect(audit.Issues[0].Severity).To(Equal(services.ChecklistError))
+
+	// ธีมที่สีหลักจางเกินไปรายงานครั้งเดียวที่ระดับ portfolio
+	portfolio.Colors.PrimaryColor = "#BBBBBB"
+	portfolio.PortfolioSections = nil
+	audit = services.AuditPortfolio(&portfolio)
+	low = a11yIssues(audit, services.A11yLowContrast)
+	g.Expect(low).To(HaveLen(1))
+	g.Expect(low[0].Field).To(Equal("colors.primary_color"))
+}
+
+// ขนาดตัวอักษร, alt text และลำดับหัวข้อ
+func TestAccessibilityAuditFontAltAndHeadings(t *testing.T) {
+	g := NewWithT(t)
+	portfolio := entity.Portfolio{
+		PortfolioSections: []entity.PortfolioSection{
+			{
+				Model: gorm.Model{ID: 20}, SectionTitle: "About", IsEnabled: true,
+				PortfolioBlocks: []entity.PortfolioBlock{
+					a11yBlock(1, "header", `{"text":"Intro","level":3}`, ""),
+					a11yBlock(2, "header", `{"text":"Detail","level":4}`, ""),
+					a11yBlock(3, "header", `{"text":"Title again","level":1}`, ""),
+					a11yBlock(4, "text", `{"text":"tiny","font_size":"0.5rem"}`, ""),
+					a11yBlock(5, "text", `{"text":"small token"}`, `{"font_size":"xs"}`),
+					a11yBlock(6, "text", `{"text":"points","font_size":"8pt"}`, ""),
+					a11yBlock(7, "image", `{"url":"/uploads/a.png"}`, ""),
+					a11yBlock(8, "gallery", `{"images":[{"url":"/uploads/b.png","alt_text":"ok"},{"url":"/uploads/c.png"}]}`, ""),
+				},
+			},
+			{
+				Model: gorm.Model{ID: 21}, SectionTitle: "Works", IsEnabled: true,
+				PortfolioBlocks: []entity.PortfolioBlock{
+					// ชื่อ section เป็นระดับ 2 จึงเริ่มที่ 3 ได้
+					a11yBlock(9, "header", `{"text":"Project","level":3}`, ""),
+					a11yBlock(10, "header", `{"text":"Skip","level":5}`, ""),
+				},
+			},
+		},
+	}
+
+	audit := services.AuditPortfolio(&portfolio)
+
+	headings := a11yIssues(audit, services.A11yHeadingOrder)
+	g.Expect(headings).To(HaveLen(2))
+	g.Expect(headings[0].BlockID).To(Equal(uint(3)))
+	g.Expect(headings[1].BlockID).To(Equal(uint(10)))
+	g.Expect(headings[1].Message).To(ContainSubstring("skips level 4"))
+
+	small := a11yIssues(audit, services.A11ySmallFont)
+	g.Expect(small).To(HaveLen(2))
+	g.Expect(small[0].BlockID).To(Equal(uint(4)))
+	g.Expect(small[1].BlockID).To(Equal(uint(6)))
+
+	alt := a11yIssues(audit, services.CheckMissingAltText)
+	g.Expect(alt).To(HaveLen(2))
+	g.Expect(alt[0].Field).To(Equal("content.alt_text"))
+	g.Expect(alt[1].Field).To(Equal("content.images[1].alt_text"))
+
+	g.Expect(audit.Passed).To(BeTrue())
+	g.Expect(audit.Score).To(Equal(100 - 6*5))
+}
+
+func TestAccessibilityAuditEndpoint(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	block := entity.PortfolioBlock{BlockPortType: "text", PortfolioSectionID: f.section,
+		Content: datatypes.JSON(`{"text":"hello"}`), BlockStyle: datatypes.JSON(`{"text_color":"#EEEEEE","background_color":"#FFFFFF"}`)}
+	g.Expect(db.Omit("PortfolioSection").Create(&block).Error).To(BeNil())
+
+	r := portfolioOwnershipRouter()
+	r.GET("/portfolio/:id/accessibility", controller.GetPortfolioAccessibility)
+	path := fmt.Sprintf("/portfolio/%d/accessibility", f.portfolio)
+	g.Expect(doTestRequest(r, f.other, "GET", path, "").Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.owner, "GET", "/portfolio/abc/accessibility", "").Code).To(Equal(http.StatusBadRequest))
+
+	w := doTestRequest(r, f.owner, "GET", path, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data services.AccessibilityAudit `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Data.PortfolioID).To(Equal(f.portfolio))
+	g.Expect(resp.Data.Passed).To(BeFalse())
+	low := a11yIssues(&resp.Data, services.A11yLowContrast)
+	g.Expect(low).NotTo(BeEmpty())
+	g.Expect(low[0].BlockID).To(Equal(block.ID))
+	g.Expect(low[0].Foreground).To(Equal("#EEEEEE"))
+}
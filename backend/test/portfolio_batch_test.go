@@ -0,0 +1,116 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+)
+
+func batchRequest(userID, portfolioID uint, ifMatch string, body string) *httptest.ResponseRecorder {
+	r := portfolioOwnershipRouter()
+	r.PATCH("/portfolio/:id/batch", controller.PatchPortfolioBatch)
+
+	req := newTestRequest(userID, "PATCH", fmt.Sprintf("/portfolio/%d/batch", portfolioID), strings.NewReader(body))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	return serveTestRequest(r, req)
+}
+
+func portfolioRevision(g *WithT, id uint) uint {
+	var p entity.Portfolio
+	g.Expect(config.GetDB().Select("id", "revision").First(&p, id).Error).To(BeNil())
+	return p.Revision
+}
+
+// ทุก operation ต้องถูกบันทึกใน transaction เดียว และ revision เพิ่มขึ้น 1
+func TestPortfolioBatchApply(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	rev := portfolioRevision(g, f.portfolio)
+
+	body := fmt.Sprintf(`{"operations":[
+		{"op":"create_section","temp_id":"s1","data":{"section_title":"New section","section_order":2}},
+		{"op":"create_block","temp_id":"b1","section_ref":"s1","data":{"content":{"text":"first"}}},
+		{"op":"update_block","id":%d,"data":{"content":{"text":"changed"}}},
+		{"op":"update_section","id":%d,"data":{"section_title":"Renamed"}}
+	]}`, f.block, f.section)
+
+	w := batchRequest(f.owner, f.portfolio, fmt.Sprintf(`"%d"`, rev), body)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(w.Header().Get("ETag")).To(Equal(fmt.Sprintf(`"%d"`, rev+1)))
+
+	var resp struct {
+		Revision uint            `json:"revision"`
+		Created  map[string]uint `json:"created"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Revision).To(Equal(rev + 1))
+	g.Expect(resp.Created).To(HaveKey("s1"))
+	g.Expect(resp.Created).To(HaveKey("b1"))
+
+	var block entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&block, resp.Created["b1"]).Error).To(BeNil())
+	g.Expect(block.PortfolioSectionID).To(Equal(resp.Created["s1"]))
+
+	var updated entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&updated, f.block).Error).To(BeNil())
+	g.Expect(string(updated.Content)).To(ContainSubstring("changed"))
+}
+
+// revision เก่าต้องได้ 409 พร้อมข้อมูลล่าสุด และไม่มีอะไรถูกแก้ไข
+func TestPortfolioBatchConflict(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	rev := portfolioRevision(g, f.portfolio)
+	body := fmt.Sprintf(`{"operations":[{"op":"delete_block","id":%d}]}`, f.block)
+
+	w := batchRequest(f.owner, f.portfolio, fmt.Sprintf(`"%d"`, rev+5), body)
+	g.Expect(w.Code).To(Equal(http.StatusConflict))
+	g.Expect(w.Body.String()).To(ContainSubstring(fmt.Sprintf(`"revision":%d`, rev)))
+	g.Expect(w.Body.String()).To(ContainSubstring("portfolio_sections"))
+	g.Expect(portfolioRevision(g, f.portfolio)).To(Equal(rev))
+
+	var count int64
+	config.GetDB().Model(&entity.PortfolioBlock{}).Where("id = ?", f.block).Count(&count)
+	g.Expect(count).To(Equal(int64(1)))
+
+	// ไม่ส่ง revision มาเลย
+	w = batchRequest(f.owner, f.portfolio, "", body)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionRequired))
+}
+
+// operation ที่ผิดต้อง rollback ทั้ง batch รวมถึง revision
+func TestPortfolioBatchRollback(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	other := createOwnershipFixture(g)
+	rev := portfolioRevision(g, f.portfolio)
+
+	body := fmt.Sprintf(`{"operations":[
+		{"op":"update_block","id":%d,"data":{"content":{"text":"changed"}}},
+		{"op":"delete_block","id":%d}
+	]}`, f.block, other.block)
+
+	w := batchRequest(f.owner, f.portfolio, fmt.Sprintf(`W/"%d"`, rev), body)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(ContainSubstring(`"operation_index":1`))
+	g.Expect(portfolioRevision(g, f.portfolio)).To(Equal(rev))
+
+	var block entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&block, f.block).Error).To(BeNil())
+	g.Expect(string(block.Content)).To(ContainSubstring("hello"))
+	var otherBlock entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&otherBlock, other.block).Error).To(BeNil())
+}
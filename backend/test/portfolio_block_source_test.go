@@ -0,0 +1,136 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+)
+
+type hydratedBlock struct {
+	ID      uint                         `json:"ID"`
+	Content json.RawMessage              `json:"content"`
+	Source  *entity.PortfolioBlockSource `json:"source"`
+}
+
+type hydratedPortfolio struct {
+	ID                uint `json:"ID"`
+	PortfolioSections []struct {
+		PortfolioBlocks []hydratedBlock `json:"portfolio_blocks"`
+	} `json:"portfolio_sections"`
+}
+
+func (p hydratedPortfolio) block(id uint) hydratedBlock {
+	for _, s := range p.PortfolioSections {
+		for _, b := range s.PortfolioBlocks {
+			if b.ID == id {
+				return b
+			}
+		}
+	}
+	return hydratedBlock{}
+}
+
+func sourceRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.GET("/portfolio/my", controller.GetMyPortfolio)
+	return r
+}
+
+func createSourceBlock(g *WithT, f ownershipFixture, content string) uint {
+	w := doTestRequest(sourceRouter(), f.owner, "POST", "/portfolio/block", fmt.Sprintf(`{"portfolio_section_id":%d,"content":%s}`, f.section, content))
+	g.Expect(w.Code).To(BeNumerically("<", 300), w.Body.String())
+	var resp struct {
+		Data entity.PortfolioBlock `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	return resp.Data.ID
+}
+
+func fetchHydratedPortfolio(g *WithT, f ownershipFixture) hydratedPortfolio {
+	w := doTestRequest(sourceRouter(), f.owner, "GET", fmt.Sprintf("/portfolio/%d", f.portfolio), "")
+	g.Expect(w.Code).To(BeNumerically("<", 300), w.Body.String())
+	var resp struct {
+		Data hydratedPortfolio `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	return resp.Data
+}
+
+// block แบบอ้างอิงเก็บเฉพาะ id และแสดงข้อมูลล่าสุดของกิจกรรม/ผลงาน/โปรไฟล์/ผลการเรียน
+func TestPortfolioReferenceBlocksHydrated(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+	suffix := time.Now().UnixNano() % 1000000
+
+	activity := entity.Activity{ActivityName: fmt.Sprintf("Camp %d", suffix), UserID: f.owner}
+	g.Expect(db.Omit("ActivityDetail", "User", "Reward").Create(&activity).Error).To(BeNil())
+	working := entity.Working{WorkingName: fmt.Sprintf("Robot %d", suffix), Status: "done", UserID: f.owner}
+	g.Expect(db.Omit("WorkingDetail", "User").Create(&working).Error).To(BeNil())
+	foreign := entity.Activity{ActivityName: fmt.Sprintf("Other %d", suffix), UserID: f.other}
+	g.Expect(db.Omit("ActivityDetail", "User", "Reward").Create(&foreign).Error).To(BeNil())
+	g.Expect(db.Model(&entity.User{}).Where("id = ?", f.owner).Update("first_name_th", "สมหญิง").Error).To(BeNil())
+
+	activityBlock := createSourceBlock(g, f, fmt.Sprintf(`{"type":"activity","data_id":%d,"data":{"activity_name":"stale copy"}}`, activity.ID))
+	workingBlock := createSourceBlock(g, f, fmt.Sprintf(`{"type":"working","data_id":%d}`, working.ID))
+	foreignBlock := createSourceBlock(g, f, fmt.Sprintf(`{"type":"activity","data_id":%d}`, foreign.ID))
+	profileBlock := createSourceBlock(g, f, `{"type":"profile"}`)
+	scoreBlock := createSourceBlock(g, f, `{"type":"academic_score"}`)
+
+	var stored entity.PortfolioBlock
+	g.Expect(db.First(&stored, activityBlock).Error).To(BeNil())
+	g.Expect(string(stored.Content)).NotTo(ContainSubstring("stale copy"))
+
+	p := fetchHydratedPortfolio(g, f)
+	g.Expect(p.block(f.block).Source).To(BeNil())
+
+	src := p.block(activityBlock).Source
+	g.Expect(src).NotTo(BeNil())
+	g.Expect(src.Status).To(Equal(entity.BlockSourceOK))
+	g.Expect(src.Data).To(HaveKeyWithValue("activity_name", activity.ActivityName))
+	g.Expect(p.block(workingBlock).Source.Data).To(HaveKeyWithValue("working_name", working.WorkingName))
+	g.Expect(p.block(foreignBlock).Source.Status).To(Equal(entity.BlockSourceDeleted))
+	g.Expect(p.block(foreignBlock).Source.Data).To(BeNil())
+	g.Expect(p.block(profileBlock).Source.Data).To(HaveKeyWithValue("first_name_th", "สมหญิง"))
+	g.Expect(p.block(profileBlock).Source.Data).NotTo(HaveKey("id_number"))
+	g.Expect(p.block(scoreBlock).Source.Status).To(Equal(entity.BlockSourceMissing))
+
+	// แก้กิจกรรมต้นทางแล้ว block แสดงค่าใหม่ทันที, ลบแล้วได้ status deleted
+	g.Expect(db.Model(&entity.Activity{}).Where("id = ?", activity.ID).UpdateColumn("activity_name", "Camp renamed").Error).To(BeNil())
+	score := entity.AcademicScore{UserID: f.owner, GPAX: 3.5}
+	g.Expect(db.Omit("User").Create(&score).Error).To(BeNil())
+	p = fetchHydratedPortfolio(g, f)
+	g.Expect(p.block(activityBlock).Source.Data).To(HaveKeyWithValue("activity_name", "Camp renamed"))
+	g.Expect(p.block(scoreBlock).Source.Data).To(HaveKeyWithValue("gpax", 3.5))
+
+	g.Expect(db.Delete(&entity.Working{}, working.ID).Error).To(BeNil())
+	p = fetchHydratedPortfolio(g, f)
+	g.Expect(p.block(workingBlock).Source.Status).To(Equal(entity.BlockSourceDeleted))
+	g.Expect(p.block(workingBlock).Source.ID).To(Equal(working.ID))
+
+	w := doTestRequest(sourceRouter(), f.owner, "GET", "/portfolio/my", "")
+	g.Expect(w.Code).To(BeNumerically("<", 300), w.Body.String())
+	g.Expect(w.Body.String()).To(ContainSubstring(`"status":"deleted"`))
+	g.Expect(w.Body.String()).To(ContainSubstring("Camp renamed"))
+}
+
+func TestNormalizeReferenceContentKeepsPlainBlocks(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+
+	// block รูปภาพ/ข้อความไม่ถูกแตะ แม้มี field ชื่อ data
+	id := createSourceBlock(g, f, `{"type":"image","url":"/uploads/a.png","data":{"keep":true}}`)
+	var stored entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&stored, id).Error).To(BeNil())
+	g.Expect(stored.Content).To(Equal(datatypes.JSON(`{"type":"image","url":"/uploads/a.png","data":{"keep":true}}`)))
+}
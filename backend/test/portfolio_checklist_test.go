@@ -0,0 +1,114 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+)
+
+func checklistCodes(c *services.PortfolioChecklist) map[string]int {
+	codes := map[string]int{}
+	for _, item := range c.Items {
+		codes[item.Code]++
+	}
+	return codes
+}
+
+// checklist ต้องชี้สิ่งที่ขาดและคะแนนต้องเพิ่มขึ้นเมื่อแก้ครบ
+func TestPortfolioChecklist(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("PDF_FONT_DIR", thaiTestFontDir(g, t))
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	docType := entity.DocumentType{Name: "Transcript"}
+	g.Expect(db.Create(&docType).Error).To(BeNil())
+	curriculum := entity.Curriculum{Code: "CHK", Name: "Checklist", Link: "https://example.com", Status: "open", FacultyID: 1, ProgramID: 1, ApplicationPeriod: "2026", Quota: 10, PortfolioMaxPages: 10}
+	g.Expect(db.Omit("Faculty", "Program", "User").Create(&curriculum).Error).To(BeNil())
+	g.Expect(db.Create(&entity.CurriculumRequiredDocument{CurriculumID: curriculum.ID, DocumentTypeID: docType.ID}).Error).To(BeNil())
+
+	empty := entity.PortfolioSection{SectionTitle: "Awards", SectionPortKey: "awards", IsEnabled: true, PortfolioID: f.portfolio, SectionOrder: 2}
+	g.Expect(db.Omit("Portfolio").Create(&empty).Error).To(BeNil())
+	image := entity.PortfolioBlock{BlockPortType: "image", PortfolioSectionID: f.section, Content: datatypes.JSON(`{"url":"/uploads/a.png"}`)}
+	g.Expect(db.Omit("PortfolioSection").Create(&image).Error).To(BeNil())
+
+	before, err := services.BuildPortfolioChecklist(db, f.portfolio, curriculum.ID)
+	g.Expect(err).To(BeNil())
+	codes := checklistCodes(before)
+	g.Expect(codes).To(HaveKey(services.CheckEmptySection))
+	g.Expect(codes).To(HaveKey(services.CheckMissingAltText))
+	g.Expect(codes).To(HaveKey(services.CheckMissingProfile))
+	g.Expect(codes).To(HaveKey(services.CheckMissingEducation))
+	g.Expect(codes).To(HaveKey(services.CheckMissingRequiredDoc))
+	g.Expect(codes).NotTo(HaveKey(services.CheckPageLimitExceeded))
+	g.Expect(before.PageCount).To(BeNumerically(">=", 1))
+	g.Expect(before.MaxPages).To(Equal(10))
+	g.Expect(before.Ready).To(BeFalse())
+	g.Expect(before.Items[0].Severity).To(Equal(services.ChecklistError))
+
+	// แก้ alt text, ซ่อน section ว่าง และเพิ่ม section เอกสาร
+	g.Expect(db.Model(&image).Update("content", datatypes.JSON(`{"url":"/uploads/a.png","alt_text":"Award photo"}`)).Error).To(BeNil())
+	g.Expect(db.Model(&empty).Update("is_enabled", false).Error).To(BeNil())
+	doc := entity.PortfolioBlock{BlockPortType: "text", PortfolioSectionID: f.section, Content: datatypes.JSON(fmt.Sprintf(`{"text":"see attached","document_type_id":%d}`, docType.ID))}
+	g.Expect(db.Omit("PortfolioSection").Create(&doc).Error).To(BeNil())
+
+	after, err := services.BuildPortfolioChecklist(db, f.portfolio, curriculum.ID)
+	g.Expect(err).To(BeNil())
+	codes = checklistCodes(after)
+	g.Expect(codes).NotTo(HaveKey(services.CheckEmptySection))
+	g.Expect(codes).NotTo(HaveKey(services.CheckMissingAltText))
+	g.Expect(codes).NotTo(HaveKey(services.CheckMissingRequiredDoc))
+	g.Expect(after.Score).To(BeNumerically(">", before.Score))
+
+	// endpoint: เจ้าของเห็น, คนอื่นไม่เห็น
+	r := portfolioOwnershipRouter()
+	r.GET("/portfolio/:id/checklist", controller.GetPortfolioChecklist)
+	path := fmt.Sprintf("/portfolio/%d/checklist?curriculum_id=%d", f.portfolio, curriculum.ID)
+	g.Expect(doTestRequest(r, f.other, "GET", path, "").Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/checklist?curriculum_id=abc", f.portfolio), "").Code).To(Equal(http.StatusBadRequest))
+
+	w := doTestRequest(r, f.owner, "GET", path, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data services.PortfolioChecklist `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Data.Score).To(Equal(after.Score))
+	g.Expect(resp.Data.CurriculumID).To(Equal(curriculum.ID))
+}
+
+// บล็อกข้อความแบบที่หน้าแก้ไขบันทึก ({type, title, detail}) ไม่ถูกนับว่าว่าง
+func TestPortfolioChecklistTextBlocks(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	section := entity.PortfolioSection{SectionTitle: "About", SectionPortKey: "about", IsEnabled: true, PortfolioID: f.portfolio, SectionOrder: 3}
+	g.Expect(db.Omit("Portfolio").Create(&section).Error).To(BeNil())
+	filled := entity.PortfolioBlock{BlockPortType: "text", PortfolioSectionID: section.ID, Content: datatypes.JSON(`{"type":"text","title":"ข้อความ","detail":"สวัสดีครับ"}`)}
+	blank := entity.PortfolioBlock{BlockPortType: "text", PortfolioSectionID: section.ID, Content: datatypes.JSON(`{"type":"text","detail":"  "}`)}
+	g.Expect(db.Omit("PortfolioSection").Create(&filled).Error).To(BeNil())
+	g.Expect(db.Omit("PortfolioSection").Create(&blank).Error).To(BeNil())
+
+	checklist, err := services.BuildPortfolioChecklist(db, f.portfolio, 0)
+	g.Expect(err).To(BeNil())
+	var emptyBlocks []uint
+	for _, item := range checklist.Items {
+		if item.Code == services.CheckEmptyBlock {
+			emptyBlocks = append(emptyBlocks, item.BlockID)
+		}
+		g.Expect(item.Code == services.CheckEmptySection && item.SectionID == section.ID).To(BeFalse())
+	}
+	g.Expect(emptyBlocks).To(ContainElement(blank.ID))
+	g.Expect(emptyBlocks).NotTo(ContainElement(filled.ID))
+}
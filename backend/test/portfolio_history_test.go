@@ -0,0 +1,170 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+func historyRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.PATCH("/portfolio/:id/batch", controller.PatchPortfolioBatch)
+	r.GET("/portfolio/:id/history", controller.GetPortfolioHistory)
+	r.POST("/portfolio/:id/undo", controller.UndoPortfolioChange)
+	r.POST("/portfolio/:id/restore", controller.RestorePortfolio)
+	r.GET("/portfolio/:id/restore-points", controller.ListPortfolioRestorePoints)
+	r.POST("/portfolio/:id/restore-points", controller.CreatePortfolioRestorePoint)
+	return r
+}
+
+func sectionExists(g *WithT, id uint) bool {
+	var count int64
+	g.Expect(config.GetDB().Model(&entity.PortfolioSection{}).Where("id = ?", id).Count(&count).Error).To(BeNil())
+	return count > 0
+}
+
+// ลบ section ผ่าน endpoint เดิมแล้ว undo ต้องได้ section กลับมาด้วย ID เดิม
+func TestPortfolioHistoryUndoDeletedSection(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := historyRouter()
+	f := createOwnershipFixture(g)
+
+	w := doTestRequest(r, f.owner, "DELETE", fmt.Sprintf("/portfolio/section/%d", f.section), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(sectionExists(g, f.section)).To(BeFalse())
+
+	var changes []entity.PortfolioChange
+	g.Expect(config.GetDB().Where("portfolio_id = ?", f.portfolio).Find(&changes).Error).To(BeNil())
+	g.Expect(changes).To(HaveLen(1))
+	g.Expect(changes[0].Action).To(Equal("delete_section"))
+	g.Expect(changes[0].UserID).To(Equal(f.owner))
+
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/undo", f.portfolio), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(sectionExists(g, f.section)).To(BeTrue())
+
+	var section entity.PortfolioSection
+	g.Expect(config.GetDB().First(&section, f.section).Error).To(BeNil())
+	g.Expect(section.SectionTitle).To(Equal("Works"))
+
+	// undo ไม่ย้อนรายการ undo เอง ไม่มีอะไรให้ undo แล้ว
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/undo", f.portfolio), "")
+	g.Expect(w.Code).To(Equal(http.StatusConflict))
+}
+
+// undo ย้อนทั้ง batch ในครั้งเดียว รวมถึง block ที่ถูกลบไปพร้อม section
+func TestPortfolioHistoryUndoBatch(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := historyRouter()
+	f := createOwnershipFixture(g)
+	rev := portfolioRevision(g, f.portfolio)
+
+	body := fmt.Sprintf(`{"base_revision":%d,"operations":[
+		{"op":"create_section","data":{"section_title":"Extra"}},
+		{"op":"delete_section","id":%d}
+	]}`, rev, f.section)
+	w := doTestRequest(r, f.owner, "PATCH", fmt.Sprintf("/portfolio/%d/batch", f.portfolio), body)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/undo", f.portfolio), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	var sections []entity.PortfolioSection
+	g.Expect(config.GetDB().Where("portfolio_id = ?", f.portfolio).Find(&sections).Error).To(BeNil())
+	g.Expect(sections).To(HaveLen(1))
+	g.Expect(sections[0].ID).To(Equal(f.section))
+
+	var block entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&block, f.block).Error).To(BeNil())
+	g.Expect(string(block.Content)).To(ContainSubstring("hello"))
+	g.Expect(portfolioRevision(g, f.portfolio)).To(Equal(rev + 2))
+}
+
+// restore point และการย้อนไปยัง change ที่ระบุ
+func TestPortfolioHistoryRestore(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := historyRouter()
+	f := createOwnershipFixture(g)
+
+	w := doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/restore-points", f.portfolio), `{"name":"before edits"}`)
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var created struct {
+		Data entity.PortfolioRestorePoint `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+
+	doTestRequest(r, f.owner, "PATCH", fmt.Sprintf("/portfolio/block/%d", f.block), `{"content":{"text":"v2"}}`)
+	doTestRequest(r, f.owner, "PATCH", fmt.Sprintf("/portfolio/block/%d", f.block), `{"content":{"text":"v3"}}`)
+	doTestRequest(r, f.owner, "POST", "/portfolio/section", fmt.Sprintf(`{"portfolio_id":%d,"section_title":"Later","section_port_key":"later"}`, f.portfolio))
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/history", f.portfolio), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	var history struct {
+		Data []entity.PortfolioChange `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &history)).To(Succeed())
+	g.Expect(history.Data).To(HaveLen(3))
+	firstEdit := history.Data[2]
+	g.Expect(string(firstEdit.After)).To(ContainSubstring("v2"))
+
+	// ย้อนไปหลังการแก้ครั้งแรก: block = v2 และ section ที่สร้างทีหลังหายไป
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/restore", f.portfolio), fmt.Sprintf(`{"change_id":%d}`, firstEdit.ID))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var block entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&block, f.block).Error).To(BeNil())
+	g.Expect(string(block.Content)).To(ContainSubstring("v2"))
+	var count int64
+	config.GetDB().Model(&entity.PortfolioSection{}).Where("portfolio_id = ?", f.portfolio).Count(&count)
+	g.Expect(count).To(Equal(int64(1)))
+
+	// กลับไปที่ restore point
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/restore", f.portfolio), fmt.Sprintf(`{"restore_point_id":%d}`, created.Data.ID))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	block = entity.PortfolioBlock{}
+	g.Expect(config.GetDB().First(&block, f.block).Error).To(BeNil())
+	g.Expect(string(block.Content)).To(ContainSubstring("hello"))
+
+	// คนอื่นย้อนประวัติไม่ได้
+	w = doTestRequest(r, f.other, "POST", fmt.Sprintf("/portfolio/%d/undo", f.portfolio), "")
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/restore", f.portfolio), `{}`)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+}
+
+// ประวัติถูกตัดให้เหลือไม่เกิน MaxChanges รายการล่าสุด
+func TestPortfolioHistoryRetention(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	for i := 0; i < 5; i++ {
+		change := entity.PortfolioChange{PortfolioID: f.portfolio, Action: "update_block", EntityType: entity.PortfolioChangeBlock, EntityID: f.block}
+		g.Expect(db.Create(&change).Error).To(BeNil())
+	}
+	g.Expect(services.PrunePortfolioHistory(db, f.portfolio, services.PortfolioHistoryRetention{MaxChanges: 2})).To(Succeed())
+
+	var count int64
+	db.Model(&entity.PortfolioChange{}).Where("portfolio_id = ?", f.portfolio).Count(&count)
+	g.Expect(count).To(Equal(int64(2)))
+
+	for i := 0; i < 2; i++ {
+		_, err := services.CreatePortfolioRestorePoint(db, f.portfolio, f.owner, fmt.Sprintf("p%d", i), services.PortfolioHistoryRetention{MaxRestorePoints: 1})
+		if i == 0 {
+			g.Expect(err).To(BeNil())
+		} else {
+			g.Expect(err).To(Equal(services.ErrRestorePointLimit))
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/router"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+)
+
+var sqliteOnce sync.Once
+
+// ใช้ sqlite in-memory สำหรับทดสอบ handler ที่ต้องใช้ฐานข้อมูล
+func setupSQLiteTestDB() {
+	sqliteOnce.Do(config.ConnectionSQLite)
+}
+
+type ownershipFixture struct {
+	owner, other, teacher uint
+	portfolio, section    uint
+	block                 uint
+}
+
+func createOwnershipFixture(g *WithT) ownershipFixture {
+	db := config.GetDB()
+	suffix := time.Now().UnixNano()
+
+	newUser := func(name string, accountType uint) uint {
+		u := entity.User{Email: fmt.Sprintf("%s-%d@test.local", name, suffix), AccountTypeID: accountType}
+		g.Expect(db.Omit("AccountType", "IDDocType").Create(&u).Error).To(BeNil())
+		return u.ID
+	}
+
+	f := ownershipFixture{
+		owner:   newUser("owner", entity.UserTypeStudent),
+		other:   newUser("other", entity.UserTypeStudent),
+		teacher: newUser("teacher", entity.UserTypeTeacher),
+	}
+
+	portfolio := entity.Portfolio{PortfolioName: "Owner portfolio", Status: "active", UserID: f.owner, ColorsID: 1, FontID: 1}
+	g.Expect(db.Omit("Template", "User", "Colors", "Font").Create(&portfolio).Error).To(BeNil())
+	section := entity.PortfolioSection{SectionTitle: "Works", SectionPortKey: "works", IsEnabled: true, PortfolioID: portfolio.ID}
+	g.Expect(db.Omit("Portfolio").Create(&section).Error).To(BeNil())
+	block := entity.PortfolioBlock{BlockPortType: "text", PortfolioSectionID: section.ID, Content: datatypes.JSON(`{"text":"hello"}`)}
+	g.Expect(db.Omit("PortfolioSection").Create(&block).Error).To(BeNil())
+
+	f.portfolio, f.section, f.block = portfolio.ID, section.ID, block.ID
+	return f
+}
+
+// testAuth จำลอง Authorization middleware: ใช้ user id จาก header X-Test-User
+func testAuth(c *gin.Context) {
+	if id, err := strconv.ParseUint(c.GetHeader("X-Test-User"), 10, 64); err == nil {
+		c.Set("user_id", uint(id))
+	}
+	c.Next()
+}
+
+func portfolioOwnershipRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(testAuth)
+
+	submissions := controller.PortfolioSubmissionController{DB: config.GetDB()}
+	r.GET("/portfolio/:id", controller.GetPortfolioById)
+	r.PATCH("/portfolio/:id", controller.UpdatePortfolio)
+	r.DELETE("/portfolio/:id", controller.DeletePortfolio)
+	r.POST("/portfolio/section", controller.CreatePortfolioSection)
+	r.PATCH("/portfolio/section/:id", controller.UpdatePortfolioSection)
+	r.DELETE("/portfolio/section/:id", controller.DeletePortfolioSection)
+	r.POST("/portfolio/block", controller.CreatePortfolioBlock)
+	r.PATCH("/portfolio/block/:id", controller.UpdatePortfolioBlock)
+	r.DELETE("/portfolio/block/:id", controller.DeletePortfolioBlock)
+	r.POST("/submissions", submissions.Create)
+	return r
+}
+
+// newTestRequest สร้าง JSON request ในฐานะ userID (0 = ไม่ได้ login)
+func newTestRequest(userID uint, method, path string, body io.Reader) *http.Request {
+	req, _ := http.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		req.Header.Set("X-Test-User", strconv.FormatUint(uint64(userID), 10))
+	}
+	return req
+}
+
+func serveTestRequest(r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// doTestRequest ส่ง JSON request เข้า router ในฐานะ userID (0 = ไม่ได้ login)
+func doTestRequest(r http.Handler, userID uint, method, path, body string) *httptest.ResponseRecorder {
+	return serveTestRequest(r, newTestRequest(userID, method, path, strings.NewReader(body)))
+}
+
+// createOnboardedUser สร้างผู้ใช้ที่ผ่าน onboarding แล้ว เพื่อเรียก route ใน protectedOnboarded ได้
+func createOnboardedUser(g *WithT, accountType uint) entity.User {
+	now := time.Now()
+	user := entity.User{
+		Email:            fmt.Sprintf("onboarded-%d@test.local", now.UnixNano()),
+		AccountTypeID:    accountType,
+		PDPAConsent:      true,
+		PDPAConsentAt:    &now,
+		ProfileCompleted: true,
+	}
+	g.Expect(config.GetDB().Omit("AccountType", "IDDocType").Create(&user).Error).To(BeNil())
+	return user
+}
+
+// doAppRequest ส่ง request เข้า router จริงของแอป พร้อม JWT ของ user เพื่อทดสอบ middleware ที่ผูกไว้ใน router.go
+func doAppRequest(g *WithT, user entity.User, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	token, err := services.NewJWTWrapper().GenerateToken(&user)
+	g.Expect(err).To(BeNil())
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return serveTestRequest(router.SetupRoutes(), req)
+}
+
+// นักเรียนคนอื่นต้องไม่เห็นและแก้ไข portfolio / section / block ของเจ้าของไม่ได้ (404)
+func TestPortfolioOwnershipOtherStudent(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := portfolioOwnershipRouter()
+	f := createOwnershipFixture(g)
+
+	cases := []struct{ method, path, body string }{
+		{"GET", fmt.Sprintf("/portfolio/%d", f.portfolio), ""},
+		{"PATCH", fmt.Sprintf("/portfolio/%d", f.portfolio), `{"portfolio_name":"hacked"}`},
+		{"DELETE", fmt.Sprintf("/portfolio/%d", f.portfolio), ""},
+		{"POST", "/portfolio/section", fmt.Sprintf(`{"portfolio_id":%d,"section_title":"x","section_port_key":"x"}`, f.portfolio)},
+		{"PATCH", fmt.Sprintf("/portfolio/section/%d", f.section), `{"section_title":"hacked"}`},
+		{"DELETE", fmt.Sprintf("/portfolio/section/%d", f.section), ""},
+		{"POST", "/portfolio/block", fmt.Sprintf(`{"portfolio_section_id":%d,"content":{"text":"x"}}`, f.section)},
+		{"PATCH", fmt.Sprintf("/portfolio/block/%d", f.block), `{"content":{"text":"hacked"}}`},
+		{"DELETE", fmt.Sprintf("/portfolio/block/%d", f.block), ""},
+		{"POST", "/submissions", fmt.Sprintf(`{"portfolio_id":%d}`, f.portfolio)},
+	}
+	for _, tc := range cases {
+		g.Expect(doTestRequest(r, f.other, tc.method, tc.path, tc.body).Code).To(Equal(http.StatusNotFound), tc.method+" "+tc.path)
+	}
+
+	// ข้อมูลต้องไม่ถูกแก้ไข
+	var block entity.PortfolioBlock
+	g.Expect(config.GetDB().First(&block, f.block).Error).To(BeNil())
+	g.Expect(string(block.Content)).To(ContainSubstring("hello"))
+}
+
+// อาจารย์อ่านได้เฉพาะ portfolio ที่ถูกส่งมาตรวจ และแก้ไขไม่ได้ (403)
+func TestPortfolioOwnershipTeacher(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := portfolioOwnershipRouter()
+	f := createOwnershipFixture(g)
+	path := fmt.Sprintf("/portfolio/%d", f.portfolio)
+
+	g.Expect(doTestRequest(r, f.teacher, "GET", path, "").Code).To(Equal(http.StatusNotFound))
+
+	submission := entity.PortfolioSubmission{PortfolioID: f.portfolio, UserID: f.owner, Status: "awaiting_review", Version: 1, Submission_at: time.Now()}
+	g.Expect(config.GetDB().Omit("Portfolio", "User").Create(&submission).Error).To(BeNil())
+
+	g.Expect(doTestRequest(r, f.teacher, "GET", path, "").Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.teacher, "PATCH", path, `{"portfolio_name":"x"}`).Code).To(Equal(http.StatusForbidden))
+	g.Expect(doTestRequest(r, f.teacher, "PATCH", fmt.Sprintf("/portfolio/block/%d", f.block), `{"content":{"text":"x"}}`).Code).To(Equal(http.StatusForbidden))
+	g.Expect(doTestRequest(r, f.teacher, "DELETE", fmt.Sprintf("/portfolio/section/%d", f.section), "").Code).To(Equal(http.StatusForbidden))
+}
+
+// เจ้าของยังแก้ไขได้ตามปกติ และเปลี่ยนเจ้าของผ่าน payload ไม่ได้
+func TestPortfolioOwnershipOwner(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := portfolioOwnershipRouter()
+	f := createOwnershipFixture(g)
+
+	g.Expect(doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d", f.portfolio), "").Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", fmt.Sprintf("/portfolio/block/%d", f.block), `{"content":{"text":"updated"}}`).Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.owner, "PATCH", fmt.Sprintf("/portfolio/%d", f.portfolio), fmt.Sprintf(`{"portfolio_name":"mine","user_id":%d}`, f.other)).Code).To(Equal(http.StatusOK))
+
+	var portfolio entity.Portfolio
+	g.Expect(config.GetDB().First(&portfolio, f.portfolio).Error).To(BeNil())
+	g.Expect(portfolio.UserID).To(Equal(f.owner))
+	g.Expect(portfolio.PortfolioName).To(Equal("mine"))
+
+	g.Expect(doTestRequest(r, f.owner, "DELETE", fmt.Sprintf("/portfolio/block/%d", f.block), "").Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.owner, "DELETE", fmt.Sprintf("/portfolio/block/%d", f.block), "").Code).To(Equal(http.StatusNotFound))
+}
+
+// PATCH /portfolio/:id แก้ได้เฉพาะคอลัมน์ที่อนุญาต ชื่อฟิลด์แบบอื่น (ColorsID) ข้ามการตรวจธีมไม่ได้
+func TestUpdatePortfolioAllowList(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	r := portfolioOwnershipRouter()
+	f := createOwnershipFixture(g)
+	path := fmt.Sprintf("/portfolio/%d", f.portfolio)
+
+	foreign := entity.Colors{ColorsName: "Foreign", PrimaryColor: "#000000", SecondaryColor: "#444444", BackgroundColor: "#FFFFFF", UserID: &f.other}
+	g.Expect(db.Create(&foreign).Error).To(BeNil())
+
+	w := doTestRequest(r, f.owner, "PATCH", path, fmt.Sprintf(`{"ColorsID":%d,"UserID":%d,"template_version":99,"revision":50,"cloned_from_id":1}`, foreign.ID, f.other))
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+
+	w = doTestRequest(r, f.owner, "PATCH", path, fmt.Sprintf(`{"ColorsID":%d,"template_version":99,"portfolio_name":"allowed","content_description":"intro"}`, foreign.ID))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	var portfolio entity.Portfolio
+	g.Expect(db.First(&portfolio, f.portfolio).Error).To(BeNil())
+	g.Expect(portfolio.PortfolioName).To(Equal("allowed"))
+	g.Expect(portfolio.ContentDescription).To(Equal("intro"))
+	g.Expect(portfolio.ColorsID).To(Equal(uint(1)))
+	g.Expect(portfolio.UserID).To(Equal(f.owner))
+	g.Expect(portfolio.TemplateVersion).To(BeZero())
+	g.Expect(portfolio.ClonedFromID).To(BeNil())
+}
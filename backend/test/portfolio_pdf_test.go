@@ -0,0 +1,287 @@
+package test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"gorm.io/datatypes"
+)
+
+func pdfTestPortfolio(blocks int) *entity.Portfolio {
+	section := entity.PortfolioSection{SectionTitle: "กิจกรรม", IsEnabled: true}
+	for i := 0; i < blocks; i++ {
+		section.PortfolioBlocks = append(section.PortfolioBlocks, entity.PortfolioBlock{
+			BlockPortType: "text",
+			BlockOrder:    i,
+			Content:       datatypes.JSON(fmt.Sprintf(`{"text": "ย่อหน้าที่ %d %s"}`, i, strings.Repeat("lorem ipsum ", 40))),
+		})
+	}
+	return &entity.Portfolio{
+		PortfolioName:     "แฟ้มสะสมผลงาน",
+		Colors:            entity.Colors{PrimaryColor: "#FF6414"},
+		Font:              entity.Font{FontName: "Sarabun", FontFamily: "Sarabun, sans-serif"},
+		User:              entity.User{FirstNameTH: "สมชาย", LastNameTH: "ใจดี"},
+		PortfolioSections: []entity.PortfolioSection{section},
+	}
+}
+
+// thaiTestFont สร้างฟอนต์ทดสอบที่มีอักษรไทยจาก Go font โดยเขียนตาราง cmap ใหม่
+// ให้ ASCII ใช้ glyph เดิม และอักษรไทย (U+0E01-U+0E5B) ใช้ glyph ของ 'o'
+// (ใน sandbox ไม่มีไฟล์ฟอนต์ไทยจริงให้ใช้ แต่ parser ของ fpdf / sfnt อ่านได้เหมือนฟอนต์ไทยทั่วไป)
+func thaiTestFont(g *WithT, ttf []byte) []byte {
+	font, err := sfnt.Parse(ttf)
+	g.Expect(err).To(BeNil())
+	var buf sfnt.Buffer
+	glyph := func(r rune) uint16 {
+		idx, err := font.GlyphIndex(&buf, r)
+		g.Expect(err).To(BeNil())
+		return uint16(idx)
+	}
+
+	type mapping struct{ code, glyph uint16 }
+	var mappings []mapping
+	for r := rune(0x20); r <= 0x7E; r++ {
+		mappings = append(mappings, mapping{uint16(r), glyph(r)})
+	}
+	for r := rune(0x0E01); r <= 0x0E5B; r++ {
+		mappings = append(mappings, mapping{uint16(r), glyph('o')})
+	}
+	mappings = append(mappings, mapping{0x2022, glyph('*')}, mapping{0xFFFF, 0})
+
+	// cmap format 4 หนึ่ง segment ต่อหนึ่งตัวอักษร (idDelta อย่างเดียว)
+	segCount := len(mappings)
+	var sub bytes.Buffer
+	w := func(v uint16) { binary.Write(&sub, binary.BigEndian, v) }
+	w(4)
+	w(uint16(16 + segCount*8))
+	w(0)
+	w(uint16(segCount * 2))
+	w(0)
+	w(0)
+	w(0)
+	for _, m := range mappings {
+		w(m.code)
+	}
+	w(0)
+	for _, m := range mappings {
+		w(m.code)
+	}
+	for _, m := range mappings {
+		if m.code == 0xFFFF {
+			w(1)
+		} else {
+			w(m.glyph - m.code)
+		}
+	}
+	for range mappings {
+		w(0)
+	}
+	var cmap bytes.Buffer
+	binary.Write(&cmap, binary.BigEndian, []uint16{0, 1, 3, 1})
+	binary.Write(&cmap, binary.BigEndian, uint32(12))
+	cmap.Write(sub.Bytes())
+
+	// ประกอบไฟล์ใหม่: ตารางเดิมทั้งหมด ยกเว้น cmap
+	numTables := int(binary.BigEndian.Uint16(ttf[4:]))
+	tables := map[string][]byte{}
+	tags := []string{}
+	for i := 0; i < numTables; i++ {
+		rec := ttf[12+16*i:]
+		tag := string(rec[:4])
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		tables[tag] = ttf[offset : offset+length]
+		tags = append(tags, tag)
+	}
+	tables["cmap"] = cmap.Bytes()
+	sort.Strings(tags)
+
+	var out bytes.Buffer
+	out.Write(ttf[:12])
+	offset := 12 + 16*numTables
+	var body bytes.Buffer
+	for _, tag := range tags {
+		data := tables[tag]
+		var sum uint32
+		padded := append(append([]byte{}, data...), make([]byte, (4-len(data)%4)%4)...)
+		for i := 0; i < len(padded); i += 4 {
+			sum += binary.BigEndian.Uint32(padded[i:])
+		}
+		out.WriteString(tag)
+		binary.Write(&out, binary.BigEndian, []uint32{sum, uint32(offset + body.Len()), uint32(len(data))})
+		body.Write(padded)
+	}
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// thaiTestFontDir โฟลเดอร์ฟอนต์ที่มี Sarabun (ทดสอบ) ทั้งตัวปกติและตัวหนา
+func thaiTestFontDir(g *WithT, t *testing.T) string {
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "Sarabun-Regular.ttf"), thaiTestFont(g, goregular.TTF), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "Sarabun-Bold.ttf"), thaiTestFont(g, gobold.TTF), 0644)).To(Succeed())
+	return dir
+}
+
+func TestRenderPortfolioPDF(t *testing.T) {
+	g := NewWithT(t)
+
+	opts := services.PortfolioPDFOptions{FontDir: thaiTestFontDir(g, t)}
+	result, err := services.RenderPortfolioPDF(pdfTestPortfolio(1), services.PortfolioPDFSources{}, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(bytes.HasPrefix(result.Data, []byte("%PDF"))).To(BeTrue())
+	g.Expect(result.PageCount).To(Equal(1))
+}
+
+// เนื้อหายาวต้องขึ้นหน้าใหม่ และจำนวนหน้าต้องนับถูกต้อง
+func TestRenderPortfolioPDFPageCount(t *testing.T) {
+	g := NewWithT(t)
+
+	opts := services.PortfolioPDFOptions{FontDir: thaiTestFontDir(g, t)}
+	result, err := services.RenderPortfolioPDF(pdfTestPortfolio(30), services.PortfolioPDFSources{}, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.PageCount).To(BeNumerically(">", 1))
+}
+
+// บล็อกข้อความที่หน้าแก้ไขบันทึก ({type, title, detail}) ต้องถูกพิมพ์ลง PDF ด้วย
+func TestRenderPortfolioPDFTextBlockDetail(t *testing.T) {
+	g := NewWithT(t)
+
+	portfolio := pdfTestPortfolio(0)
+	for i := 0; i < 30; i++ {
+		portfolio.PortfolioSections[0].PortfolioBlocks = append(portfolio.PortfolioSections[0].PortfolioBlocks, entity.PortfolioBlock{
+			BlockPortType: "text",
+			BlockOrder:    i,
+			Content:       datatypes.JSON(fmt.Sprintf(`{"type": "text", "title": "ข้อความ", "detail": "รายละเอียด %d %s"}`, i, strings.Repeat("lorem ipsum ", 40))),
+		})
+	}
+
+	opts := services.PortfolioPDFOptions{FontDir: thaiTestFontDir(g, t)}
+	result, err := services.RenderPortfolioPDF(portfolio, services.PortfolioPDFSources{}, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.PageCount).To(BeNumerically(">", 1))
+}
+
+func pdfCoverPNG(g *WithT) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			img.Set(x, y, color.RGBA{255, 100, 20, 255})
+		}
+	}
+	var buf bytes.Buffer
+	g.Expect(png.Encode(&buf, img)).To(Succeed())
+	return buf.Bytes()
+}
+
+// รูปจาก local storage ต้องถูกฝังลงใน PDF ส่วน URL ภายนอกหรือไฟล์ที่ไม่มีอยู่จะถูกข้าม
+func TestRenderPortfolioPDFImages(t *testing.T) {
+	g := NewWithT(t)
+
+	uploadDir := t.TempDir()
+	t.Setenv("UPLOAD_DIR", uploadDir)
+	local, err := services.NewLocalStorageFromEnv()
+	g.Expect(err).To(BeNil())
+	g.Expect(os.WriteFile(filepath.Join(uploadDir, "cover.png"), pdfCoverPNG(g), 0644)).To(Succeed())
+
+	portfolio := pdfTestPortfolio(0)
+	portfolio.CoverImage = "http://localhost:8080/uploads/cover.png"
+	portfolio.PortfolioSections[0].PortfolioBlocks = []entity.PortfolioBlock{
+		{BlockPortType: "image", Content: datatypes.JSON(`{"url": "/uploads/missing.png"}`)},
+		{BlockPortType: "image", Content: datatypes.JSON(`{"url": "https://example.com/a.png"}`)},
+		{BlockPortType: "image", Content: datatypes.JSON(`{"url": "/uploads/../../etc/passwd.png"}`)},
+	}
+
+	opts := services.PortfolioPDFOptions{FontDir: thaiTestFontDir(g, t), Uploads: local}
+	result, err := services.RenderPortfolioPDF(portfolio, services.PortfolioPDFSources{}, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(bytes.Count(result.Data, []byte("/Subtype /Image"))).To(Equal(1))
+}
+
+// รูปที่เก็บใน S3 (prefix ไม่ใช่ uploads) ต้องอ่านผ่าน Storage ได้เหมือน local
+func TestRenderPortfolioPDFImagesFromS3(t *testing.T) {
+	g := NewWithT(t)
+
+	_, server := newFakeS3(t, "portfolio")
+	s3, err := services.NewS3Storage(services.S3Config{
+		Endpoint: server.URL, Bucket: "portfolio", Region: "ap-southeast-1",
+		AccessKey: "minio", SecretKey: "minio-secret", Prefix: "media", PathStyle: true,
+	})
+	g.Expect(err).To(BeNil())
+	services.LocalStorage, services.Storage = nil, s3
+	t.Cleanup(func() { services.LocalStorage, services.Storage = nil, nil })
+	url, err := s3.SaveFile(bytes.NewReader(pdfCoverPNG(g)), "cover.png")
+	g.Expect(err).To(BeNil())
+
+	portfolio := pdfTestPortfolio(0)
+	portfolio.CoverImage = url
+	opts := services.DefaultPortfolioPDFOptions()
+	opts.FontDir = thaiTestFontDir(g, t)
+	result, err := services.RenderPortfolioPDF(portfolio, services.PortfolioPDFSources{}, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(bytes.Count(result.Data, []byte("/Subtype /Image"))).To(Equal(1))
+}
+
+// ไม่มีไฟล์ฟอนต์ หรือมีแต่ฟอนต์ที่ไม่มีอักษรไทย ต้อง export ไม่สำเร็จพร้อมบอกสาเหตุ
+func TestRenderPortfolioPDFRequiresThaiFont(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := services.RenderPortfolioPDF(pdfTestPortfolio(1), services.PortfolioPDFSources{}, services.PortfolioPDFOptions{FontDir: t.TempDir()})
+	g.Expect(errors.Is(err, services.ErrNoThaiPDFFont)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("PDF_FONT_DIR"))
+
+	latinOnly := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(latinOnly, "Sarabun-Regular.ttf"), goregular.TTF, 0644)).To(Succeed())
+	_, err = services.RenderPortfolioPDF(pdfTestPortfolio(1), services.PortfolioPDFSources{}, services.PortfolioPDFOptions{FontDir: latinOnly})
+	g.Expect(errors.Is(err, services.ErrNoThaiPDFFont)).To(BeTrue())
+}
+
+var pdfStreamPattern = regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`)
+
+// ข้อความภาษาไทยต้องถูกฝังด้วย glyph จริงของฟอนต์ไทย (ใน CIDToGIDMap อักษรไทยไม่ใช่ glyph 0)
+func TestRenderPortfolioPDFThaiText(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := thaiTestFontDir(g, t)
+	result, err := services.RenderPortfolioPDF(pdfTestPortfolio(1), services.PortfolioPDFSources{}, services.PortfolioPDFOptions{FontDir: dir})
+	g.Expect(err).To(BeNil())
+
+	maps := 0
+	for _, stream := range pdfStreamPattern.FindAllSubmatch(result.Data, -1) {
+		reader, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil || len(data) != 256*256*2 {
+			continue
+		}
+		maps++
+		thaiGlyphs := 0
+		for r := 0x0E01; r <= 0x0E5B; r++ {
+			if binary.BigEndian.Uint16(data[r*2:]) != 0 {
+				thaiGlyphs++
+			}
+		}
+		g.Expect(thaiGlyphs).To(BeNumerically(">", 0))
+	}
+	g.Expect(maps).To(BeNumerically(">", 0))
+}
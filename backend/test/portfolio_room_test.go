@@ -0,0 +1,175 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+func nextRoomMessage(g *WithT, session *services.PortfolioSession) services.PortfolioRoomMessage {
+	select {
+	case msg := <-session.Outbox():
+		return msg
+	case <-time.After(time.Second):
+		g.Expect("message").To(Equal("received"), "timed out waiting for room message")
+		return services.PortfolioRoomMessage{}
+	}
+}
+
+// presence และ lock ต้องถูกกระจายให้ session อื่นในห้องเดียวกัน
+func TestPortfolioRoomPresenceAndLocks(t *testing.T) {
+	g := NewWithT(t)
+	hub := services.NewPortfolioRoomHub()
+
+	student := hub.Join(1, 10, "student", true)
+	g.Expect(nextRoomMessage(g, student).Type).To(Equal(services.RoomMsgWelcome))
+
+	advisor := hub.Join(1, 20, "advisor", true)
+	welcome := nextRoomMessage(g, advisor)
+	g.Expect(welcome.Members).To(HaveLen(2))
+	g.Expect(nextRoomMessage(g, student).Type).To(Equal(services.RoomMsgJoin))
+
+	// ห้องของ portfolio อื่นต้องไม่ได้รับข้อความ
+	otherRoom := hub.Join(2, 30, "other", true)
+	nextRoomMessage(g, otherRoom)
+
+	hub.Handle(student, services.PortfolioRoomMessage{Type: services.RoomMsgPresence, BlockID: 5, Selection: []byte(`{"start":1,"end":4}`)})
+	presence := nextRoomMessage(g, advisor)
+	g.Expect(presence.Type).To(Equal(services.RoomMsgPresence))
+	g.Expect(presence.SessionID).To(Equal(student.ID()))
+	g.Expect(string(presence.Selection)).To(ContainSubstring(`"end":4`))
+
+	hub.Handle(student, services.PortfolioRoomMessage{Type: services.RoomMsgLock, BlockID: 5})
+	g.Expect(nextRoomMessage(g, student).Type).To(Equal(services.RoomMsgLock))
+	g.Expect(nextRoomMessage(g, advisor).Type).To(Equal(services.RoomMsgLock))
+
+	_, locked := hub.BlockLockedByOther(1, 5, advisor.ID())
+	g.Expect(locked).To(BeTrue())
+	_, locked = hub.BlockLockedByOther(1, 5, student.ID())
+	g.Expect(locked).To(BeFalse())
+
+	hub.Handle(advisor, services.PortfolioRoomMessage{Type: services.RoomMsgLock, BlockID: 5})
+	denied := nextRoomMessage(g, advisor)
+	g.Expect(denied.Type).To(Equal(services.RoomMsgLockDenied))
+	g.Expect(denied.Lock.UserID).To(Equal(uint(10)))
+
+	// ออกจากห้องแล้ว lock ต้องถูกปล่อย
+	hub.Leave(student)
+	g.Expect(nextRoomMessage(g, advisor).Type).To(Equal(services.RoomMsgUnlock))
+	g.Expect(nextRoomMessage(g, advisor).Type).To(Equal(services.RoomMsgLeave))
+	_, locked = hub.BlockLockedByOther(1, 5, "")
+	g.Expect(locked).To(BeFalse())
+	_, open := <-student.Outbox()
+	g.Expect(open).To(BeFalse())
+
+	hub.BroadcastChanges(1, 7, 20, nil)
+	change := nextRoomMessage(g, advisor)
+	g.Expect(change.Type).To(Equal(services.RoomMsgChange))
+	g.Expect(change.Revision).To(Equal(uint(7)))
+	g.Expect(otherRoom.Outbox()).To(BeEmpty())
+}
+
+// lock หมดอายุเองเมื่อไม่ต่ออายุ และผู้ที่อ่านได้อย่างเดียว lock ไม่ได้
+func TestPortfolioRoomLockRules(t *testing.T) {
+	g := NewWithT(t)
+	hub := services.NewPortfolioRoomHub()
+	hub.LockTTL = -time.Second
+
+	editor := hub.Join(1, 10, "student", true)
+	nextRoomMessage(g, editor)
+	hub.Handle(editor, services.PortfolioRoomMessage{Type: services.RoomMsgLock, BlockID: 3})
+	nextRoomMessage(g, editor)
+	_, locked := hub.BlockLockedByOther(1, 3, "")
+	g.Expect(locked).To(BeFalse())
+
+	viewer := hub.Join(1, 20, "teacher", false)
+	nextRoomMessage(g, viewer)
+	hub.Handle(viewer, services.PortfolioRoomMessage{Type: services.RoomMsgLock, BlockID: 4})
+	g.Expect(nextRoomMessage(g, viewer).Type).To(Equal(services.RoomMsgError))
+}
+
+// เชื่อมต่อผ่าน WebSocket จริง: แก้ block ผ่าน HTTP แล้วห้องได้รับ change, block ที่ถูกคนอื่น lock แก้ไม่ได้ (423)
+func TestPortfolioRoomWebSocket(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createOwnershipFixture(g)
+
+	r := portfolioOwnershipRouter()
+	r.GET("/ws/portfolio/:id", controller.PortfolioRoomHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	token := func(userID uint) string {
+		user := entity.User{}
+		user.ID = userID
+		signed, err := services.NewJWTWrapper().GenerateToken(&user)
+		g.Expect(err).To(BeNil())
+		return signed
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + fmt.Sprintf("/ws/portfolio/%d", f.portfolio)
+	// token ส่งผ่าน Sec-WebSocket-Protocol แบบเดียวกับ new WebSocket(url, ["portfolio-room", token])
+	dial := func(userID uint, origin string) (*websocket.Conn, *http.Response, error) {
+		dialer := websocket.Dialer{Subprotocols: []string{"portfolio-room", token(userID)}}
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		return dialer.Dial(wsURL, header)
+	}
+
+	_, resp, err := dial(f.other, "http://localhost:3000")
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	// Origin ที่ไม่อยู่ใน CORS allow-list ถูกปฏิเสธ แม้ token ถูกต้อง
+	_, resp, err = dial(f.owner, "https://evil.example.com")
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+	// ไม่รับ token จาก query string แล้ว
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL+"?token="+token(f.owner), nil)
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+	conn, resp, err := dial(f.owner, "http://localhost:3000")
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+	// ตอบเฉพาะชื่อ subprotocol ไม่ echo token กลับ
+	g.Expect(conn.Subprotocol()).To(Equal("portfolio-room"))
+	g.Expect(resp.Header.Get("Sec-WebSocket-Protocol")).To(Equal("portfolio-room"))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg services.PortfolioRoomMessage
+	g.Expect(conn.ReadJSON(&msg)).To(Succeed())
+	g.Expect(msg.Type).To(Equal(services.RoomMsgWelcome))
+	sessionID := msg.SessionID
+
+	g.Expect(doTestRequest(r, f.owner, "PATCH", fmt.Sprintf("/portfolio/block/%d", f.block), `{"content":{"text":"live"}}`).Code).To(Equal(http.StatusOK))
+	g.Expect(conn.ReadJSON(&msg)).To(Succeed())
+	g.Expect(msg.Type).To(Equal(services.RoomMsgChange))
+	g.Expect(msg.Revision).NotTo(BeZero())
+
+	g.Expect(conn.WriteJSON(services.PortfolioRoomMessage{Type: services.RoomMsgLock, BlockID: f.block})).To(Succeed())
+	g.Expect(conn.ReadJSON(&msg)).To(Succeed())
+	g.Expect(msg.Type).To(Equal(services.RoomMsgLock))
+
+	// แท็บอื่น (ไม่มี session ที่ถือ lock) แก้ block นี้ไม่ได้
+	blockPath := fmt.Sprintf("/portfolio/block/%d", f.block)
+	g.Expect(doTestRequest(r, f.owner, "PATCH", blockPath, `{"content":{"text":"other tab"}}`).Code).To(Equal(http.StatusLocked))
+	g.Expect(doTestRequest(r, f.owner, "DELETE", blockPath, "").Code).To(Equal(http.StatusLocked))
+
+	req := newTestRequest(f.owner, "PATCH", blockPath, strings.NewReader(`{"content":{"text":"lock holder"}}`))
+	req.Header.Set("X-Room-Session", sessionID)
+	w := serveTestRequest(r, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+}
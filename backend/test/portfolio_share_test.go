@@ -0,0 +1,166 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+)
+
+func shareRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.GET("/portfolio/:id/shares", controller.ListPortfolioShareLinks)
+	r.POST("/portfolio/:id/shares", controller.CreatePortfolioShareLink)
+	r.DELETE("/portfolio/:id/shares/:shareId", controller.RevokePortfolioShareLink)
+	r.GET("/p/:token", controller.GetSharedPortfolio)
+	return r
+}
+
+// sharedViewRequest เปิดลิงก์สาธารณะโดยส่งรหัสผ่านทาง header X-Share-Password
+func sharedViewRequest(r *gin.Engine, path, password string) *httptest.ResponseRecorder {
+	req := newTestRequest(0, "GET", path, nil)
+	req.Header.Set("X-Share-Password", password)
+	return serveTestRequest(r, req)
+}
+
+func createShareLink(g *WithT, r *gin.Engine, f ownershipFixture, body string) entity.PortfolioShareLink {
+	w := doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/shares", f.portfolio), body)
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var resp struct {
+		Data entity.PortfolioShareLink `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	return resp.Data
+}
+
+// ลิงก์สาธารณะเปิดได้โดยไม่ login และไม่เปิดเผยข้อมูลส่วนตัว
+func TestSharedPortfolioPublicView(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := shareRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+	g.Expect(db.Model(&entity.User{}).Where("id = ?", f.owner).Updates(map[string]interface{}{
+		"first_name_th": "สมชาย", "id_number": "1234567890123", "phone": "0812345678",
+	}).Error).To(BeNil())
+
+	// คนอื่นสร้างลิงก์ให้ portfolio ที่ไม่ใช่ของตัวเองไม่ได้
+	w := doTestRequest(r, f.other, "POST", fmt.Sprintf("/portfolio/%d/shares", f.portfolio), `{}`)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+
+	// block แบบอ้างอิงต้องแสดงข้อมูลต้นทางในลิงก์สาธารณะด้วย
+	activity := entity.Activity{ActivityName: fmt.Sprintf("Shared camp %d", time.Now().UnixNano()), UserID: f.owner}
+	g.Expect(db.Omit("ActivityDetail", "User", "Reward").Create(&activity).Error).To(BeNil())
+	activityBlock := createSourceBlock(g, f, fmt.Sprintf(`{"type":"activity","data_id":%d}`, activity.ID))
+	profileBlock := createSourceBlock(g, f, `{"type":"profile"}`)
+
+	link := createShareLink(g, r, f, `{"label":"guidance","expires_in_days":7}`)
+	g.Expect(link.Token).NotTo(BeEmpty())
+	g.Expect(link.HasPassword).To(BeFalse())
+
+	w = doTestRequest(r, 0, "GET", "/p/"+link.Token, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data hydratedPortfolio `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Data.block(activityBlock).Source).NotTo(BeNil())
+	g.Expect(resp.Data.block(activityBlock).Source.Data).To(HaveKeyWithValue("activity_name", activity.ActivityName))
+	profile := resp.Data.block(profileBlock).Source
+	g.Expect(profile).NotTo(BeNil())
+	g.Expect(profile.Data).To(HaveKeyWithValue("first_name_th", "สมชาย"))
+	g.Expect(profile.Data).NotTo(HaveKey("email"))
+	g.Expect(profile.Data).NotTo(HaveKey("phone"))
+	body := w.Body.String()
+	g.Expect(body).To(ContainSubstring("สมชาย"))
+	g.Expect(body).To(ContainSubstring("hello"))
+	g.Expect(body).NotTo(ContainSubstring("1234567890123"))
+	g.Expect(body).NotTo(ContainSubstring("0812345678"))
+	g.Expect(body).NotTo(ContainSubstring("@test.local"))
+
+	doTestRequest(r, 0, "GET", "/p/"+link.Token, "")
+	var stored entity.PortfolioShareLink
+	g.Expect(db.First(&stored, link.ID).Error).To(BeNil())
+	g.Expect(stored.ViewCount).To(Equal(2))
+	g.Expect(stored.LastViewedAt).NotTo(BeNil())
+
+	g.Expect(doTestRequest(r, 0, "GET", "/p/not-a-token", "").Code).To(Equal(http.StatusNotFound))
+}
+
+// รหัสผ่าน, การเพิกถอน และวันหมดอายุ
+func TestSharedPortfolioAccessControl(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := shareRouter()
+	f := createOwnershipFixture(g)
+
+	link := createShareLink(g, r, f, `{"password":"s3cret-pass"}`)
+	g.Expect(link.HasPassword).To(BeTrue())
+	g.Expect(link.ExpiresAt).NotTo(BeNil())
+
+	path := "/p/" + link.Token
+	g.Expect(doTestRequest(r, 0, "GET", path, "").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(sharedViewRequest(r, path, "wrong").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(sharedViewRequest(r, path, "s3cret-pass").Code).To(Equal(http.StatusOK))
+	// ไม่รับรหัสผ่านทาง query string
+	g.Expect(doTestRequest(r, 0, "GET", path+"?password=s3cret-pass", "").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/shares", f.portfolio), `{"password":"short"}`).Code).To(Equal(http.StatusBadRequest))
+
+	w := doTestRequest(r, f.owner, "DELETE", fmt.Sprintf("/portfolio/%d/shares/%d", f.portfolio, link.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(sharedViewRequest(r, path, "s3cret-pass").Code).To(Equal(http.StatusGone))
+
+	expired := createShareLink(g, r, f, `{}`)
+	g.Expect(config.GetDB().Model(&entity.PortfolioShareLink{}).Where("id = ?", expired.ID).
+		Update("expires_at", time.Now().Add(-time.Hour)).Error).To(BeNil())
+	g.Expect(doTestRequest(r, 0, "GET", "/p/"+expired.Token, "").Code).To(Equal(http.StatusGone))
+
+	g.Expect(doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/shares", f.portfolio), `{"expires_in_days":400}`).Code).To(Equal(http.StatusBadRequest))
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/shares", f.portfolio), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).NotTo(ContainSubstring("password_hash"))
+}
+
+// ใส่รหัสผ่านผิดติดกันครบ 5 ครั้งลิงก์ถูกล็อก แม้รหัสถูกก็ตอบ 429 จนพ้นช่วงล็อก
+func TestSharedPortfolioPasswordLockout(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := shareRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	link := createShareLink(g, r, f, `{"password":"s3cret-pass"}`)
+	path := "/p/" + link.Token
+
+	// ใส่ถูกแล้วตัวนับรีเซ็ต
+	for i := 0; i < 4; i++ {
+		g.Expect(sharedViewRequest(r, path, "wrong").Code).To(Equal(http.StatusUnauthorized))
+	}
+	g.Expect(sharedViewRequest(r, path, "s3cret-pass").Code).To(Equal(http.StatusOK))
+	for i := 0; i < 4; i++ {
+		g.Expect(sharedViewRequest(r, path, "wrong").Code).To(Equal(http.StatusUnauthorized))
+	}
+	g.Expect(sharedViewRequest(r, path, "wrong").Code).To(Equal(http.StatusTooManyRequests))
+	g.Expect(sharedViewRequest(r, path, "s3cret-pass").Code).To(Equal(http.StatusTooManyRequests))
+
+	// ลิงก์อื่นไม่โดนล็อกไปด้วย
+	other := createShareLink(g, r, f, `{"password":"s3cret-pass"}`)
+	g.Expect(sharedViewRequest(r, "/p/"+other.Token, "s3cret-pass").Code).To(Equal(http.StatusOK))
+
+	g.Expect(db.Model(&entity.PortfolioShareLink{}).Where("id = ?", link.ID).
+		Update("locked_until", time.Now().Add(-time.Minute)).Error).To(BeNil())
+	g.Expect(sharedViewRequest(r, path, "s3cret-pass").Code).To(Equal(http.StatusOK))
+	var stored entity.PortfolioShareLink
+	g.Expect(db.First(&stored, link.ID).Error).To(BeNil())
+	g.Expect(stored.FailedAttempts).To(Equal(0))
+	g.Expect(stored.LockedUntil).To(BeNil())
+}
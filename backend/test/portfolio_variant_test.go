@@ -0,0 +1,97 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+func variantRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.GET("/portfolio", controller.GetPortfolioByStatusActive)
+	r.POST("/portfolio/:id/clone", controller.ClonePortfolio)
+	r.PUT("/portfolio/:id/curricula/:curriculumId", controller.DesignatePortfolioCurriculum)
+	r.DELETE("/portfolio/:id/curricula/:curriculumId", controller.RemovePortfolioCurriculum)
+	return r
+}
+
+func activePortfolioFor(g *WithT, r *gin.Engine, userID, curriculumID uint) (uint, bool) {
+	w := doTestRequest(r, userID, "GET", fmt.Sprintf("/portfolio?curriculum_id=%d", curriculumID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data       *entity.Portfolio `json:"data"`
+		Designated bool              `json:"designated"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	if resp.Data == nil {
+		return 0, false
+	}
+	return resp.Data.ID, resp.Designated
+}
+
+// clone ได้ variant ใหม่พร้อม section/block และกำหนดให้ใช้กับหลักสูตรได้
+func TestPortfolioCloneAndCurriculumDesignation(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := variantRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	curriculum := entity.Curriculum{Code: "CPE", Name: "Computer Engineering", Link: "https://example.com", Status: "open", FacultyID: 1, ProgramID: 1, ApplicationPeriod: "2026", Quota: 10, PortfolioMaxPages: 10}
+	g.Expect(db.Omit("Faculty", "Program", "User").Create(&curriculum).Error).To(BeNil())
+
+	// ยังไม่ได้กำหนด ใช้ portfolio หลัก
+	id, designated := activePortfolioFor(g, r, f.owner, curriculum.ID)
+	g.Expect(id).To(Equal(f.portfolio))
+	g.Expect(designated).To(BeFalse())
+
+	g.Expect(doTestRequest(r, f.other, "POST", fmt.Sprintf("/portfolio/%d/clone", f.portfolio), `{}`).Code).To(Equal(http.StatusNotFound))
+
+	w := doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/clone", f.portfolio), fmt.Sprintf(`{"portfolio_name":"CPE variant","curriculum_id":%d}`, curriculum.ID))
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var resp struct {
+		Data entity.Portfolio `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	clone := resp.Data
+	g.Expect(clone.ID).NotTo(Equal(f.portfolio))
+	g.Expect(clone.Status).To(Equal("draft"))
+	g.Expect(*clone.ClonedFromID).To(Equal(f.portfolio))
+	g.Expect(clone.PortfolioSections).To(HaveLen(1))
+	g.Expect(clone.PortfolioSections[0].PortfolioBlocks).To(HaveLen(1))
+	g.Expect(string(clone.PortfolioSections[0].PortfolioBlocks[0].Content)).To(Equal(`{"text":"hello"}`))
+
+	// แก้ variant ไม่กระทบต้นฉบับ
+	var original entity.PortfolioBlock
+	g.Expect(db.First(&original, f.block).Error).To(BeNil())
+	g.Expect(original.PortfolioSectionID).To(Equal(f.section))
+
+	id, designated = activePortfolioFor(g, r, f.owner, curriculum.ID)
+	g.Expect(id).To(Equal(clone.ID))
+	g.Expect(designated).To(BeTrue())
+	id, _ = activePortfolioFor(g, r, f.owner, 0)
+	g.Expect(id).To(Equal(f.portfolio))
+
+	// กำหนดใหม่แทนที่ของเดิม, ลบแล้วกลับไปใช้ portfolio หลัก
+	path := fmt.Sprintf("/portfolio/%d/curricula/%d", f.portfolio, curriculum.ID)
+	g.Expect(doTestRequest(r, f.owner, "PUT", path, "").Code).To(Equal(http.StatusOK))
+	portfolioID, designated, err := services.ActivePortfolioIDForCurriculum(db, f.owner, curriculum.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(portfolioID).To(Equal(f.portfolio))
+	g.Expect(designated).To(BeTrue())
+
+	g.Expect(doTestRequest(r, f.owner, "PUT", fmt.Sprintf("/portfolio/%d/curricula/999999", f.portfolio), "").Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.owner, "DELETE", path, "").Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.owner, "DELETE", path, "").Code).To(Equal(http.StatusNotFound))
+
+	_, _, err = services.ActivePortfolioIDForCurriculum(db, f.other, curriculum.ID)
+	g.Expect(err).To(MatchError(services.ErrNoPortfolioForCurriculum))
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/services"
+)
+
+func TestMedianDuration(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(services.MedianDuration(nil)).To(Equal(time.Duration(0)))
+	g.Expect(services.MedianDuration([]time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour})).To(Equal(2 * time.Hour))
+	g.Expect(services.MedianDuration([]time.Duration{4 * time.Hour, time.Hour})).To(Equal(150 * time.Minute))
+}
+
+// คะแนนต้องถูกแปลงเป็นเปอร์เซ็นต์และจำกัดอยู่ในช่วง 0-100
+func TestScorePercent(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(services.ScorePercent(15, 20)).To(Equal(75.0))
+	g.Expect(services.ScorePercent(120, 100)).To(Equal(100.0))
+	g.Expect(services.ScorePercent(-5, 100)).To(Equal(0.0))
+	g.Expect(services.ScorePercent(40, 0)).To(Equal(40.0))
+}
+
+// ช่วงสุดท้ายต้องนับคะแนนเต็ม 100 ด้วย
+func TestScoreHistogram(t *testing.T) {
+	g := NewWithT(t)
+
+	buckets := services.ScoreHistogram([]float64{0, 9.9, 10, 55, 95, 100}, 10)
+	g.Expect(buckets).To(HaveLen(10))
+	g.Expect(buckets[0].Count).To(Equal(2))
+	g.Expect(buckets[1].Count).To(Equal(1))
+	g.Expect(buckets[5].Count).To(Equal(1))
+	g.Expect(buckets[9].Label).To(Equal("90-100"))
+	g.Expect(buckets[9].Count).To(Equal(2))
+}
+
+// คิวแยกตามหลักสูตรนับ submission ให้เฉพาะหลักสูตรของ portfolio ที่ส่ง
+// u1 เลือก A,B แต่ส่งเฉพาะ portfolio ของ A จึงไม่ถูกนับในคิวของ B
+func TestReviewAnalyticsQueueByCurriculum(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	f := createSelectionStatsFixture(g)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	c := controller.ReviewAnalyticsController{DB: config.GetDB()}
+	r.GET("/api/admin/analytics/reviews", c.GetReviewAnalytics)
+
+	w := doTestRequest(r, 0, "GET", "/api/admin/analytics/reviews?from=2025-01-10&to=2025-01-10", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data struct {
+			QueueByCurriculum []struct {
+				CurriculumName string `json:"curriculum_name"`
+				Count          int    `json:"count"`
+			} `json:"queue_by_curriculum"`
+		} `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+
+	counts := map[string]int{}
+	for _, q := range resp.Data.QueueByCurriculum {
+		counts[q.CurriculumName] = q.Count
+	}
+	g.Expect(counts).To(HaveKeyWithValue(f.facultyA, 1))
+	g.Expect(counts).To(HaveKeyWithValue(f.facultyB, 1))
+
+	g.Expect(doTestRequest(r, 0, "GET", "/api/admin/analytics/reviews?from=10-01-2025", "").Code).To(Equal(http.StatusBadRequest))
+}
@@ -0,0 +1,195 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+// แถวที่ถูกต้องต้องถูกแปลงครบทุกฟิลด์
+func TestParseSchoolRowsValid(t *testing.T) {
+	g := NewWithT(t)
+
+	rows := [][]string{
+		{"code", "name", "school_type", "is_project_based"},
+		{"TH-BKK-I001", "Bangkok Patana School", "โรงเรียนนานาชาติ", "false"},
+		{"TH-NMA-0001", "โรงเรียนสุรนารีวิทยา", "1", "ใช่"},
+	}
+
+	items, rowErrors, err := services.ParseSchoolRows(rows)
+	g.Expect(err).To(BeNil())
+	g.Expect(rowErrors).To(BeEmpty())
+	g.Expect(items).To(HaveLen(2))
+	g.Expect(items[0].Row).To(Equal(2))
+	g.Expect(items[0].IsProjectBased).To(BeFalse())
+	g.Expect(items[1].SchoolType).To(Equal("1"))
+	g.Expect(items[1].IsProjectBased).To(BeTrue())
+}
+
+// code ซ้ำในไฟล์เดียวกันต้องถูกรายงาน โดยเก็บแถวแรกไว้
+func TestParseSchoolRowsDuplicateCode(t *testing.T) {
+	g := NewWithT(t)
+
+	rows := [][]string{
+		{"code", "name", "school_type"},
+		{"TH-001", "School A", "รัฐบาล"},
+		{"th-001", "School A (copy)", "รัฐบาล"},
+	}
+
+	items, rowErrors, err := services.ParseSchoolRows(rows)
+	g.Expect(err).To(BeNil())
+	g.Expect(items).To(HaveLen(1))
+	g.Expect(items[0].Code).To(Equal("TH-001"))
+	g.Expect(rowErrors).To(HaveLen(1))
+	g.Expect(rowErrors[0].Row).To(Equal(3))
+	g.Expect(rowErrors[0].Message).To(ContainSubstring("already used in row 2"))
+}
+
+// ไม่กรอกข้อมูลที่จำเป็น หรือค่า boolean ผิดรูปแบบ
+func TestParseSchoolRowsInvalidFields(t *testing.T) {
+	g := NewWithT(t)
+
+	rows := [][]string{
+		{"code", "name", "school_type", "is_project_based"},
+		{"", "No code school", "รัฐบาล", ""},
+		{"TH-002", "", "", "maybe"},
+	}
+
+	items, rowErrors, err := services.ParseSchoolRows(rows)
+	g.Expect(err).To(BeNil())
+	g.Expect(items).To(BeEmpty())
+
+	columns := []string{}
+	for _, e := range rowErrors {
+		columns = append(columns, e.Column)
+	}
+	g.Expect(columns).To(ConsistOf("code", "name", "school_type", "is_project_based"))
+}
+
+// ขาดคอลัมน์ที่จำเป็น ต้อง error ทั้งไฟล์
+func TestParseSchoolRowsMissingColumn(t *testing.T) {
+	g := NewWithT(t)
+
+	rows := [][]string{
+		{"code", "name"},
+		{"TH-001", "School A"},
+	}
+
+	_, _, err := services.ParseSchoolRows(rows)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("school_type"))
+}
+
+func TestImportJobProgress(t *testing.T) {
+	g := NewWithT(t)
+
+	job := entity.ImportJob{TotalRows: 200, Processed: 50, Status: entity.ImportJobRunning}
+	g.Expect(job.Progress()).To(Equal(25))
+
+	empty := entity.ImportJob{Status: entity.ImportJobCompleted}
+	g.Expect(empty.Progress()).To(Equal(100))
+}
+
+// waitImportJob รอให้ job ใน goroutine ทำงานเสร็จ แล้วคืนสถานะล่าสุด
+func waitImportJob(g *WithT, jobID uint) entity.ImportJob {
+	var job entity.ImportJob
+	g.Eventually(func() entity.ImportJobStatus {
+		g.Expect(config.GetDB().First(&job, jobID).Error).To(BeNil())
+		return job.Status
+	}, 5*time.Second, 10*time.Millisecond).Should(BeElementOf(entity.ImportJobCompleted, entity.ImportJobFailed))
+	return job
+}
+
+// upsert ตาม code: สร้างใหม่, อัปเดตของเดิม (รวม code ตัวพิมพ์เล็กที่มีอยู่ก่อน) และข้ามแถวที่ประเภทโรงเรียนไม่ถูกต้อง
+func TestStartSchoolImport(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	suffix := time.Now().UnixNano()
+	code := func(prefix string) string { return fmt.Sprintf("%s-%d", prefix, suffix) }
+
+	schoolType := entity.SchoolType{Name: fmt.Sprintf("ประเภททดสอบ %d", suffix)}
+	g.Expect(db.Create(&schoolType).Error).To(BeNil())
+	legacy := entity.School{Code: fmt.Sprintf("old-%d", suffix), Name: "Old name", SchoolTypeID: schoolType.ID}
+	g.Expect(db.Omit("SchoolType").Create(&legacy).Error).To(BeNil())
+	admin := entity.User{Email: fmt.Sprintf("school-import-%d@test.local", suffix), AccountTypeID: entity.UserTypeAdmin}
+	g.Expect(db.Omit("AccountType", "IDDocType").Create(&admin).Error).To(BeNil())
+
+	rows := [][]string{
+		{"code", "name", "school_type", "is_project_based"},
+		{code("new"), "New school", schoolType.Name, "yes"},
+		{code("OLD"), "Renamed school", fmt.Sprint(schoolType.ID), ""},
+		{code("bad"), "Unknown type", "ไม่มีประเภทนี้", ""},
+		{code("NEW"), "Duplicate", schoolType.Name, ""},
+	}
+	items, parseErrors, err := services.ParseSchoolRows(rows)
+	g.Expect(err).To(BeNil())
+	g.Expect(parseErrors).To(HaveLen(1))
+
+	job, err := services.StartSchoolImport(db, admin.ID, "schools.csv", items, parseErrors)
+	g.Expect(err).To(BeNil())
+	g.Expect(job.Status).To(Equal(entity.ImportJobPending))
+	g.Expect(job.TotalRows).To(Equal(4))
+
+	done := waitImportJob(g, job.ID)
+	g.Expect(done.Status).To(Equal(entity.ImportJobCompleted))
+	g.Expect(done.StartedAt).NotTo(BeNil())
+	g.Expect(done.FinishedAt).NotTo(BeNil())
+	g.Expect(done.Progress()).To(Equal(100))
+	g.Expect([]int{done.Created, done.Updated, done.Skipped}).To(Equal([]int{1, 1, 2}))
+
+	var rowErrors []services.SpreadsheetRowError
+	g.Expect(json.Unmarshal(done.RowErrors, &rowErrors)).To(Succeed())
+	columns := map[int]string{}
+	for _, e := range rowErrors {
+		columns[e.Row] = e.Column
+	}
+	g.Expect(columns).To(Equal(map[int]string{4: "school_type", 5: "code"}))
+
+	var created entity.School
+	g.Expect(db.Where("code = ?", fmt.Sprintf("NEW-%d", suffix)).First(&created).Error).To(BeNil())
+	g.Expect(created.IsProjectBased).To(BeTrue())
+
+	// ไม่มีโรงเรียนซ้ำจาก code ตัวพิมพ์เล็ก/ใหญ่
+	var schools []entity.School
+	g.Expect(db.Where("UPPER(code) = ?", fmt.Sprintf("OLD-%d", suffix)).Find(&schools).Error).To(BeNil())
+	g.Expect(schools).To(HaveLen(1))
+	g.Expect(schools[0].ID).To(Equal(legacy.ID))
+	g.Expect(schools[0].Code).To(Equal(fmt.Sprintf("OLD-%d", suffix)))
+	g.Expect(schools[0].Name).To(Equal("Renamed school"))
+
+	var skipped int64
+	db.Model(&entity.School{}).Where("code = ?", fmt.Sprintf("BAD-%d", suffix)).Count(&skipped)
+	g.Expect(skipped).To(BeZero())
+}
+
+// นักเรียนนำเข้าโรงเรียนและดูสถานะ import job ไม่ได้ (403) แอดมินดูได้
+func TestSchoolImportRequiresAdmin(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	suffix := time.Now().UnixNano()
+
+	admin := entity.User{Email: fmt.Sprintf("school-job-admin-%d@test.local", suffix), AccountTypeID: entity.UserTypeAdmin}
+	student := entity.User{Email: fmt.Sprintf("school-job-student-%d@test.local", suffix), AccountTypeID: entity.UserTypeStudent}
+	g.Expect(db.Omit("AccountType", "IDDocType").Create(&admin).Error).To(BeNil())
+	g.Expect(db.Omit("AccountType", "IDDocType").Create(&student).Error).To(BeNil())
+	job := entity.ImportJob{JobType: services.ImportJobTypeSchools, FileName: "schools.csv", Status: entity.ImportJobCompleted, UserID: admin.ID}
+	g.Expect(db.Create(&job).Error).To(BeNil())
+
+	r := portfolioOwnershipRouter()
+	controller.NewEducationAdminController(db).RegisterRoutes(r.Group(""))
+	jobPath := fmt.Sprintf("/admin/education/import-jobs/%d", job.ID)
+
+	g.Expect(doTestRequest(r, student.ID, http.MethodPost, "/admin/education/schools/import", "").Code).To(Equal(http.StatusForbidden))
+	g.Expect(doTestRequest(r, student.ID, http.MethodGet, jobPath, "").Code).To(Equal(http.StatusForbidden))
+	g.Expect(doTestRequest(r, admin.ID, http.MethodGet, jobPath, "").Code).To(Equal(http.StatusOK))
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+)
+
+// หลักสูตรที่ระบุมาขึ้นก่อน ที่เหลือต่อท้ายตามลำดับเดิม
+func TestReorderSelections(t *testing.T) {
+	g := NewWithT(t)
+
+	order, err := services.ReorderSelections([]uint{1, 2, 3, 4}, []uint{3, 1})
+	g.Expect(err).To(BeNil())
+	g.Expect(order).To(Equal([]uint{3, 1, 2, 4}))
+
+	order, err = services.ReorderSelections([]uint{1, 2, 3}, []uint{3, 2, 1})
+	g.Expect(err).To(BeNil())
+	g.Expect(order).To(Equal([]uint{3, 2, 1}))
+}
+
+func TestReorderSelectionsInvalid(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := services.ReorderSelections([]uint{1, 2}, []uint{5})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("not in your selections"))
+
+	_, err = services.ReorderSelections([]uint{1, 2}, []uint{2, 2})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("more than once"))
+}
+
+func TestSelectionRoundLimitValidation(t *testing.T) {
+	g := NewWithT(t)
+
+	limit := entity.SelectionRoundLimit{AcademicYear: "2568", RoundName: "Portfolio", MaxChoices: 3}
+	ok, err := govalidator.ValidateStruct(limit)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(err).To(BeNil())
+
+	limit.MaxChoices = 0
+	ok, err = govalidator.ValidateStruct(limit)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err.Error()).To(ContainSubstring("Max choices is required"))
+
+	limit.MaxChoices = 99
+	ok, err = govalidator.ValidateStruct(limit)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err.Error()).To(ContainSubstring("between 1 and 50"))
+}
+
+// จัดลำดับได้เฉพาะรายการของผู้ใช้ที่ login (user_id ใน body ไม่มีผล)
+func TestUpdateSelectionPriorities(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	f := createOwnershipFixture(g)
+	round := fmt.Sprintf("Round %d", time.Now().UnixNano())
+
+	var curricula []uint
+	for i := 0; i < 3; i++ {
+		cur := entity.Curriculum{Code: fmt.Sprintf("%s-%d", round, i), Name: "priority", AcademicYear: "2568", RoundName: round, Status: "open"}
+		g.Expect(db.Omit("Faculty", "Program", "User").Create(&cur).Error).To(BeNil())
+		curricula = append(curricula, cur.ID)
+	}
+	for _, userID := range []uint{f.owner, f.other} {
+		for i, id := range curricula {
+			selection := entity.Selection{UserID: userID, CurriculumID: id, Priority: i + 1}
+			g.Expect(db.Omit("User", "Curriculum").Create(&selection).Error).To(BeNil())
+		}
+	}
+	priorities := func(userID uint) []uint {
+		var selections []entity.Selection
+		g.Expect(db.Where("user_id = ? AND curriculum_id IN ?", userID, curricula).Order("priority").Find(&selections).Error).To(BeNil())
+		ids := make([]uint, len(selections))
+		for i, s := range selections {
+			ids[i] = s.CurriculumID
+		}
+		return ids
+	}
+
+	r := portfolioOwnershipRouter()
+	r.PUT("/selections/priority", controller.NewSelectionController().UpdatePriorities)
+	body := fmt.Sprintf(`{"user_id":%d,"curriculum_ids":[%d,%d]}`, f.other, curricula[2], curricula[0])
+
+	g.Expect(doTestRequest(r, 0, http.MethodPut, "/selections/priority", body).Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(doTestRequest(r, f.owner, http.MethodPut, "/selections/priority", body).Code).To(Equal(http.StatusOK))
+	g.Expect(priorities(f.owner)).To(Equal([]uint{curricula[2], curricula[0], curricula[1]}))
+	g.Expect(priorities(f.other)).To(Equal(curricula))
+
+	// หลักสูตรที่ผู้ใช้ไม่ได้เลือกจัดลำดับไม่ได้
+	g.Expect(doTestRequest(r, f.teacher, http.MethodPut, "/selections/priority", body).Code).To(Equal(http.StatusBadRequest))
+}
+
+// เลือก/ดูรายการที่เลือกได้เฉพาะของผู้ใช้ที่ login (user_id ใน body / query ไม่มีผล)
+func TestSelectionsUseAuthUser(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	f := createOwnershipFixture(g)
+
+	cur := entity.Curriculum{Code: fmt.Sprintf("SEL-%d", time.Now().UnixNano()), Name: "auth", AcademicYear: "2568", RoundName: "Portfolio", Status: "open"}
+	g.Expect(db.Omit("Faculty", "Program", "User").Create(&cur).Error).To(BeNil())
+
+	sc := controller.NewSelectionController()
+	r := portfolioOwnershipRouter()
+	r.POST("/selections", sc.ToggleSelection)
+	r.GET("/selections", sc.GetMySelections)
+	body := fmt.Sprintf(`{"user_id":%d,"curriculum_id":%d}`, f.other, cur.ID)
+
+	g.Expect(doTestRequest(r, 0, http.MethodPost, "/selections", body).Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(doTestRequest(r, 0, http.MethodGet, "/selections", "").Code).To(Equal(http.StatusUnauthorized))
+
+	g.Expect(doTestRequest(r, f.owner, http.MethodPost, "/selections", body).Code).To(Equal(http.StatusCreated))
+	var count int64
+	db.Model(&entity.Selection{}).Where("user_id = ? AND curriculum_id = ?", f.owner, cur.ID).Count(&count)
+	g.Expect(count).To(BeEquivalentTo(1))
+	db.Model(&entity.Selection{}).Where("user_id = ? AND curriculum_id = ?", f.other, cur.ID).Count(&count)
+	g.Expect(count).To(BeZero())
+
+	w := doTestRequest(r, f.other, http.MethodGet, fmt.Sprintf("/selections?user_id=%d", f.owner), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).NotTo(ContainSubstring(cur.Code))
+	w = doTestRequest(r, f.owner, http.MethodGet, "/selections", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(ContainSubstring(cur.Code))
+}
+
+// "ส่งแล้ว" คิดรายหลักสูตร: ส่ง portfolio ที่กำหนดให้ A แล้ว ไม่ได้ทำให้ B ขึ้นว่าส่งแล้ว
+func TestMySelectionsSubmittedPerCurriculum(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	f := createOwnershipFixture(g)
+	round := fmt.Sprintf("Submitted %d", time.Now().UnixNano())
+
+	var curA, curB uint
+	for i, id := range []*uint{&curA, &curB} {
+		cur := entity.Curriculum{Code: fmt.Sprintf("%s-%d", round, i), Name: "submitted", AcademicYear: "2568", RoundName: round, Status: "open"}
+		g.Expect(db.Omit("Faculty", "Program", "User").Create(&cur).Error).To(BeNil())
+		selection := entity.Selection{UserID: f.owner, CurriculumID: cur.ID, Priority: i + 1}
+		g.Expect(db.Omit("User", "Curriculum").Create(&selection).Error).To(BeNil())
+		*id = cur.ID
+	}
+
+	forA := entity.Portfolio{PortfolioName: "for A", Status: "draft", UserID: f.owner, ColorsID: 1, FontID: 1}
+	g.Expect(db.Omit("Template", "User", "Colors", "Font").Create(&forA).Error).To(BeNil())
+	_, err := services.DesignatePortfolioForCurriculum(db, f.owner, forA.ID, curA)
+	g.Expect(err).To(BeNil())
+	submission := entity.PortfolioSubmission{PortfolioID: forA.ID, UserID: f.owner, Status: "awaiting", Version: 1, Submission_at: time.Now()}
+	g.Expect(db.Omit("Portfolio", "User").Create(&submission).Error).To(BeNil())
+
+	r := portfolioOwnershipRouter()
+	r.GET("/selections", controller.NewSelectionController().GetMySelections)
+	w := doTestRequest(r, f.owner, http.MethodGet, "/selections", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	var resp struct {
+		Data []struct {
+			CurriculumID uint `json:"curriculum_id"`
+			Submitted    bool `json:"submitted"`
+		} `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	submitted := map[uint]bool{}
+	for _, s := range resp.Data {
+		submitted[s.CurriculumID] = s.Submitted
+	}
+	g.Expect(submitted).To(Equal(map[uint]bool{curA: true, curB: false}))
+}
@@ -0,0 +1,213 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"github.com/xuri/excelize/v2"
+)
+
+// selectionStatsFixture สองสำนักวิชา สามผู้สมัคร ในปีการศึกษาที่ไม่ซ้ำกับเทสต์อื่น
+//
+//	u1 เลือก A,B วันแรก: ส่ง portfolio ที่กำหนดให้ A, portfolio ของ B ยังไม่ส่ง
+//	u2 เลือก B วันที่สอง: ไม่ได้กำหนด portfolio จึงนับ portfolio หลักที่ส่งแล้ว
+//	u3 เลือก A วันที่สาม: ยังไม่ส่ง
+type selectionStatsFixture struct {
+	year               string
+	facultyA, facultyB string
+	schoolType, level  string
+	day                time.Time
+}
+
+func createSelectionStatsFixture(g *WithT) selectionStatsFixture {
+	db := config.GetDB()
+	suffix := time.Now().UnixNano()
+	f := selectionStatsFixture{
+		year:     fmt.Sprintf("Y%d", suffix),
+		facultyA: fmt.Sprintf("Faculty A %d", suffix),
+		facultyB: fmt.Sprintf("Faculty B %d", suffix),
+		day:      time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC),
+	}
+
+	newCurriculum := func(facultyName string) uint {
+		faculty := entity.Faculty{Name: facultyName}
+		g.Expect(db.Create(&faculty).Error).To(BeNil())
+		program := entity.Program{Name: facultyName + " program", FacultyID: faculty.ID}
+		g.Expect(db.Omit("Faculty").Create(&program).Error).To(BeNil())
+		cur := entity.Curriculum{Code: facultyName, Name: facultyName, AcademicYear: f.year, Status: "open", FacultyID: faculty.ID, ProgramID: program.ID}
+		g.Expect(db.Omit("Faculty", "Program", "User").Create(&cur).Error).To(BeNil())
+		return cur.ID
+	}
+	curA, curB := newCurriculum(f.facultyA), newCurriculum(f.facultyB)
+
+	newUser := func(name string) uint {
+		u := entity.User{Email: fmt.Sprintf("%s-%d@test.local", name, suffix), AccountTypeID: entity.UserTypeStudent}
+		g.Expect(db.Omit("AccountType", "IDDocType").Create(&u).Error).To(BeNil())
+		return u.ID
+	}
+	u1, u2, u3 := newUser("stats1"), newUser("stats2"), newUser("stats3")
+
+	selectOn := func(userID, curriculumID uint, day int) {
+		s := entity.Selection{UserID: userID, CurriculumID: curriculumID}
+		s.CreatedAt = f.day.AddDate(0, 0, day)
+		g.Expect(db.Omit("User", "Curriculum").Create(&s).Error).To(BeNil())
+	}
+	selectOn(u1, curA, 0)
+	selectOn(u1, curB, 0)
+	selectOn(u2, curB, 1)
+	selectOn(u3, curA, 2)
+
+	newPortfolio := func(userID uint, status string) uint {
+		p := entity.Portfolio{PortfolioName: "stats", Status: status, UserID: userID, ColorsID: 1, FontID: 1}
+		g.Expect(db.Omit("Template", "User", "Colors", "Font").Create(&p).Error).To(BeNil())
+		return p.ID
+	}
+	submit := func(userID, portfolioID uint) {
+		s := entity.PortfolioSubmission{PortfolioID: portfolioID, UserID: userID, Status: "awaiting", Version: 1, Submission_at: f.day}
+		g.Expect(db.Omit("Portfolio", "User").Create(&s).Error).To(BeNil())
+	}
+
+	forA, forB := newPortfolio(u1, "active"), newPortfolio(u1, "draft")
+	_, err := services.DesignatePortfolioForCurriculum(db, u1, forA, curA)
+	g.Expect(err).To(BeNil())
+	_, err = services.DesignatePortfolioForCurriculum(db, u1, forB, curB)
+	g.Expect(err).To(BeNil())
+	submit(u1, forA)
+	submit(u2, newPortfolio(u2, "active"))
+
+	schoolType := entity.SchoolType{Name: fmt.Sprintf("stats type %d", suffix)}
+	g.Expect(db.Create(&schoolType).Error).To(BeNil())
+	level := entity.EducationLevel{Name: fmt.Sprintf("stats level %d", suffix)}
+	g.Expect(db.Create(&level).Error).To(BeNil())
+	education := entity.Education{UserID: u1, EducationLevelID: level.ID, SchoolTypeID: &schoolType.ID, Status: "current"}
+	g.Expect(db.Omit("User", "EducationLevel", "School", "SchoolType", "CurriculumType").Create(&education).Error).To(BeNil())
+	f.schoolType, f.level = schoolType.Name, level.Name
+
+	return f
+}
+
+func selectionStatsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	cc := controller.NewCurriculumController()
+	r.GET("/admin/curricula/stats", cc.GetSelectionStats)
+	r.GET("/admin/curricula/stats/export", cc.ExportSelectionStats)
+	return r
+}
+
+func TestSelectionStats(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := selectionStatsRouter()
+	f := createSelectionStatsFixture(g)
+
+	w := doTestRequest(r, 0, "GET", "/admin/curricula/stats?academic_year="+f.year, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var stats controller.SelectionStats
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &stats)).To(Succeed())
+
+	g.Expect(stats.FacultyStats).To(ConsistOf(
+		controller.StatResult{Name: f.facultyA, Value: 2},
+		controller.StatResult{Name: f.facultyB, Value: 2},
+	))
+	g.Expect(stats.ProgramStats).To(ContainElement(controller.StatResult{Name: f.facultyA + " program", Value: 2, GroupName: f.facultyA}))
+
+	// time series เรียงตามวัน
+	g.Expect(stats.DailyStats).To(Equal([]controller.DailyStatResult{
+		{Date: "2025-01-10", Value: 2},
+		{Date: "2025-01-11", Value: 1},
+		{Date: "2025-01-12", Value: 1},
+	}))
+
+	// u1 (สองการเลือก) มีข้อมูลการศึกษา ที่เหลือเป็น "ไม่ระบุ"
+	g.Expect(stats.SchoolTypeStats).To(ConsistOf(
+		controller.StatResult{Name: f.schoolType, Value: 2},
+		controller.StatResult{Name: "ไม่ระบุ", Value: 2},
+	))
+	g.Expect(stats.EducationLevelStats).To(ConsistOf(
+		controller.StatResult{Name: f.level, Value: 2},
+		controller.StatResult{Name: "ไม่ระบุ", Value: 2},
+	))
+
+	// การส่ง portfolio ของ A ต้องไม่ถูกนับเป็น conversion ของ B
+	g.Expect(stats.Conversion).To(Equal(controller.ConversionStatResult{Name: "all", SelectedUsers: 3, SubmittedUsers: 2, ConversionRate: 66.67}))
+	g.Expect(stats.FacultyConversion).To(ConsistOf(
+		controller.ConversionStatResult{Name: f.facultyA, SelectedUsers: 2, SubmittedUsers: 1, ConversionRate: 50},
+		controller.ConversionStatResult{Name: f.facultyB, SelectedUsers: 2, SubmittedUsers: 1, ConversionRate: 50},
+	))
+
+	// ตัวกรองช่วงวันที่ใช้กับ time series ด้วย
+	w = doTestRequest(r, 0, "GET", "/admin/curricula/stats?academic_year="+f.year+"&from=2025-01-11&to=2025-01-11", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var filtered controller.SelectionStats
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &filtered)).To(Succeed())
+	g.Expect(filtered.DailyStats).To(Equal([]controller.DailyStatResult{{Date: "2025-01-11", Value: 1}}))
+
+	w = doTestRequest(r, 0, "GET", "/admin/curricula/stats?from=10-01-2025", "")
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+}
+
+func TestSelectionStatsExport(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := selectionStatsRouter()
+	f := createSelectionStatsFixture(g)
+	base := "/admin/curricula/stats/export?academic_year=" + f.year
+
+	w := doTestRequest(r, 0, "GET", base+"&chart=daily&format=csv", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(w.Header().Get("Content-Disposition")).To(ContainSubstring("selection-stats-daily.csv"))
+	rows, err := services.ReadSpreadsheet(w.Body, services.SpreadsheetFormatCSV)
+	g.Expect(err).To(BeNil())
+	g.Expect(rows).To(Equal([][]string{
+		{"date", "new_selections"},
+		{"2025-01-10", "2"},
+		{"2025-01-11", "1"},
+		{"2025-01-12", "1"},
+	}))
+
+	w = doTestRequest(r, 0, "GET", base+"&chart=conversion", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	rows, err = services.ReadSpreadsheet(w.Body, services.SpreadsheetFormatCSV)
+	g.Expect(err).To(BeNil())
+	g.Expect(rows[0]).To(Equal([]string{"faculty", "selected_users", "submitted_users", "conversion_rate"}))
+	g.Expect(rows).To(ContainElement([]string{"all", "3", "2", "66.67"}))
+	g.Expect(rows).To(ContainElement([]string{f.facultyB, "2", "1", "50.00"}))
+
+	// XLSX ไม่ระบุ chart ได้ทุกกราฟแยก sheet
+	w = doTestRequest(r, 0, "GET", base+"&format=xlsx", "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	book, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	g.Expect(err).To(BeNil())
+	defer book.Close()
+	g.Expect(book.GetSheetList()).To(Equal([]string{"faculty", "program", "daily", "school_type", "education_level", "conversion"}))
+	schoolRows, err := book.GetRows("school_type")
+	g.Expect(err).To(BeNil())
+	g.Expect(schoolRows).To(ContainElement([]string{f.schoolType, "2"}))
+
+	g.Expect(doTestRequest(r, 0, "GET", base+"&format=csv", "").Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, 0, "GET", base+"&chart=unknown", "").Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, 0, "GET", base+"&chart=daily&format=pdf", "").Code).To(Equal(http.StatusBadRequest))
+}
+
+// export สถิติเป็นของแอดมินเท่านั้น นักเรียนที่ onboard แล้วได้ 403
+func TestSelectionStatsExportRequiresAdmin(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	path := "/admin/curricula/stats/export?chart=daily&format=csv"
+
+	w := doAppRequest(g, createOnboardedUser(g, entity.UserTypeStudent), "GET", path, "")
+	g.Expect(w.Code).To(Equal(http.StatusForbidden), w.Body.String())
+	w = doAppRequest(g, createOnboardedUser(g, entity.UserTypeAdmin), "GET", path, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+}
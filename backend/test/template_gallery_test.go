@@ -0,0 +1,151 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/router"
+	"github.com/sut68/team14/backend/services"
+)
+
+func templateGalleryRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.GET("/templates/gallery", controller.GetTemplateGallery)
+	r.GET("/templates/:id/ratings", controller.GetTemplateRatings)
+	r.GET("/templates/:id/rating", controller.GetMyTemplateRating)
+	r.PUT("/templates/:id/rating", controller.RateTemplate)
+	r.DELETE("/templates/:id/rating", controller.DeleteTemplateRating)
+	return r
+}
+
+type galleryResponse struct {
+	Data  []services.TemplateSummary `json:"data"`
+	Total int64                      `json:"total"`
+}
+
+func getGallery(g *WithT, r *gin.Engine, query string) galleryResponse {
+	w := doTestRequest(r, 0, "GET", "/templates/gallery?"+query, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp galleryResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	return resp
+}
+
+// gallery กรองตามหมวด เรียงตามจำนวนการใช้งานหรือคะแนน และให้คะแนนได้คนละครั้ง
+func TestTemplateGalleryAndRatings(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := templateGalleryRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+
+	category := entity.CategoryTemplate{CategoryName: fmt.Sprintf("Gallery %d", time.Now().UnixNano())}
+	g.Expect(db.Create(&category).Error).To(BeNil())
+	popular := entity.Templates{TemplateName: "Popular minimal", CategoryTemplateID: category.ID}
+	loved := entity.Templates{TemplateName: "Loved classic", Description: "Two columns", CategoryTemplateID: category.ID}
+	for _, tpl := range []*entity.Templates{&popular, &loved} {
+		g.Expect(db.Omit("Category").Create(tpl).Error).To(BeNil())
+	}
+	for _, templateID := range []uint{popular.ID, popular.ID, loved.ID} {
+		id := templateID
+		p := entity.Portfolio{PortfolioName: "uses template", Status: "draft", UserID: f.owner, TemplateID: &id, ColorsID: 1, FontID: 1}
+		g.Expect(db.Omit("Template", "User", "Colors", "Font").Create(&p).Error).To(BeNil())
+	}
+
+	// ให้คะแนน
+	g.Expect(doTestRequest(r, 0, "PUT", fmt.Sprintf("/templates/%d/rating", loved.ID), `{"stars":5}`).Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(doTestRequest(r, f.owner, "PUT", fmt.Sprintf("/templates/%d/rating", loved.ID), `{"stars":6}`).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(doTestRequest(r, f.owner, "PUT", "/templates/999999/rating", `{"stars":5}`).Code).To(Equal(http.StatusNotFound))
+	g.Expect(doTestRequest(r, f.owner, "PUT", fmt.Sprintf("/templates/%d/rating", loved.ID), `{"stars":4}`).Code).To(Equal(http.StatusOK))
+	w := doTestRequest(r, f.owner, "PUT", fmt.Sprintf("/templates/%d/rating", loved.ID), `{"stars":5,"comment":"clean"}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(doTestRequest(r, f.other, "PUT", fmt.Sprintf("/templates/%d/rating", loved.ID), `{"stars":4}`).Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.other, "PUT", fmt.Sprintf("/templates/%d/rating", popular.ID), `{"stars":2}`).Code).To(Equal(http.StatusOK))
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/templates/%d/ratings", loved.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	var ratings struct {
+		Data services.TemplateRatingStats `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &ratings)).To(Succeed())
+	g.Expect(ratings.Data.RatingCount).To(Equal(int64(2)))
+	g.Expect(ratings.Data.RatingAverage).To(Equal(4.5))
+	g.Expect(ratings.Data.Distribution[5]).To(Equal(1))
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/templates/%d/rating", loved.ID), "")
+	var mine struct {
+		Data *entity.TemplateRating `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &mine)).To(Succeed())
+	g.Expect(mine.Data.Stars).To(Equal(5))
+	g.Expect(mine.Data.Comment).To(Equal("clean"))
+
+	byCategory := fmt.Sprintf("category_id=%d", category.ID)
+	resp := getGallery(g, r, byCategory)
+	g.Expect(resp.Total).To(Equal(int64(2)))
+	g.Expect(resp.Data[0].ID).To(Equal(popular.ID))
+	g.Expect(resp.Data[0].UsageCount).To(Equal(int64(2)))
+	g.Expect(resp.Data[0].CategoryName).To(Equal(category.CategoryName))
+
+	resp = getGallery(g, r, byCategory+"&sort=rating")
+	g.Expect(resp.Data[0].ID).To(Equal(loved.ID))
+	g.Expect(resp.Data[0].RatingAverage).To(Equal(4.5))
+	g.Expect(resp.Data[0].RatingCount).To(Equal(int64(2)))
+
+	resp = getGallery(g, r, byCategory+"&limit=1&page=2")
+	g.Expect(resp.Total).To(Equal(int64(2)))
+	g.Expect(resp.Data).To(HaveLen(1))
+	g.Expect(resp.Data[0].ID).To(Equal(loved.ID))
+
+	resp = getGallery(g, r, byCategory+"&q=COLUMNS")
+	g.Expect(resp.Data).To(HaveLen(1))
+	g.Expect(resp.Data[0].ID).To(Equal(loved.ID))
+
+	g.Expect(doTestRequest(r, 0, "GET", "/templates/gallery?sort=random", "").Code).To(Equal(http.StatusBadRequest))
+
+	// ลบแล้วให้ใหม่ได้
+	g.Expect(doTestRequest(r, f.owner, "DELETE", fmt.Sprintf("/templates/%d/rating", loved.ID), "").Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, f.owner, "DELETE", fmt.Sprintf("/templates/%d/rating", loved.ID), "").Code).To(Equal(http.StatusNotFound))
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/templates/%d/rating", loved.ID), "")
+	g.Expect(w.Body.String()).To(MatchJSON(`{"data":null}`))
+	g.Expect(doTestRequest(r, f.owner, "PUT", fmt.Sprintf("/templates/%d/rating", loved.ID), `{"stars":3}`).Code).To(Equal(http.StatusOK))
+	stats, err := services.GetTemplateRatingStats(db, loved.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(stats.RatingAverage).To(Equal(3.5))
+}
+
+// route จริงใช้ JWT: ครูเปิดดูคะแนนได้ แต่ให้คะแนนได้เฉพาะนักเรียน
+func TestRateTemplateRequiresStudent(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	router.TemplateRoutes(r)
+	f := createOwnershipFixture(g)
+
+	category := entity.CategoryTemplate{CategoryName: fmt.Sprintf("Rating %d", time.Now().UnixNano())}
+	g.Expect(config.GetDB().Create(&category).Error).To(BeNil())
+	template := entity.Templates{TemplateName: "Rated", CategoryTemplateID: category.ID}
+	g.Expect(config.GetDB().Omit("Category").Create(&template).Error).To(BeNil())
+
+	rate := func(userID uint) int {
+		user := entity.User{}
+		user.ID = userID
+		token, err := services.NewJWTWrapper().GenerateToken(&user)
+		g.Expect(err).To(BeNil())
+		req := newTestRequest(0, "PUT", fmt.Sprintf("/templates/%d/rating", template.ID), strings.NewReader(`{"stars":3}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return serveTestRequest(r, req).Code
+	}
+	g.Expect(rate(f.teacher)).To(Equal(http.StatusForbidden))
+	g.Expect(rate(f.owner)).To(Equal(http.StatusOK))
+}
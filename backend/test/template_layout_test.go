@@ -0,0 +1,149 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"gorm.io/datatypes"
+)
+
+func templateDesignerRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/sections", controller.CreateSection)
+	r.PUT("/sections/:id", controller.UpdateSection)
+	r.DELETE("/sections/:id", controller.DeleteSection)
+	r.GET("/sections/:id/validate", controller.ValidateSection)
+	r.POST("/sections/:id/section-blocks", controller.AddSectionBlock)
+	r.PUT("/sections/:id/section-blocks/order", controller.ReorderSectionBlocks)
+	r.PATCH("/section-blocks/:id", controller.UpdateSectionBlock)
+	r.DELETE("/section-blocks/:id", controller.DeleteSectionBlock)
+	r.POST("/template-blocks", controller.CreateTemplateBlock)
+	r.PUT("/template-blocks/:id", controller.UpdateTemplateBlock)
+	r.DELETE("/template-blocks/:id", controller.DeleteTemplateBlock)
+	return r
+}
+
+func templateCurrentVersion(g *WithT, id uint) uint {
+	var template entity.Templates
+	g.Expect(config.GetDB().First(&template, id).Error).To(BeNil())
+	return template.CurrentVersion
+}
+
+// แก้ section/block ของ template: ตรวจ layout, ป้องกันการแก้ section ที่ใช้ร่วมกัน และบันทึก version ใหม่
+func TestTemplateSectionAndBlockManagement(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := templateDesignerRouter()
+	db := config.GetDB()
+	template := createPackageTemplate(g, "")
+
+	var links []entity.TemplateSectionLink
+	g.Expect(db.Where("templates_id = ?", template.ID).Order("order_index ASC").Find(&links).Error).To(BeNil())
+	sectionID := links[0].TemplatesSectionID
+	var sectionBlocks []entity.SectionBlock
+	g.Expect(db.Where("templates_section_id = ?", sectionID).Order("order_index ASC").Find(&sectionBlocks).Error).To(BeNil())
+	g.Expect(sectionBlocks).To(HaveLen(2))
+
+	w := doTestRequest(r, 0, "PUT", fmt.Sprintf("/sections/%d", sectionID), `{"section_name":"Profile","layout_type":"profile_header_left"}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(templateCurrentVersion(g, template.ID)).To(Equal(uint(1)))
+
+	// grid_settings ใช้กับ flex ไม่ได้
+	w = doTestRequest(r, 0, "PATCH", fmt.Sprintf("/section-blocks/%d", sectionBlocks[0].ID), `{"layout_type":"flex","grid_settings":{"columns":2}}`)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+	g.Expect(w.Body.String()).To(ContainSubstring("grid_settings is set but layout_type is flex"))
+	w = doTestRequest(r, 0, "PATCH", fmt.Sprintf("/section-blocks/%d", sectionBlocks[0].ID), `{"position":{"marginTop":{"px":4}}}`)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+	w = doTestRequest(r, 0, "PATCH", fmt.Sprintf("/section-blocks/%d", sectionBlocks[0].ID), `{"layout_type":"flex","position":null,"flex_settings":{"width":"calc(100% - 220px)","flexGrow":1}}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(templateCurrentVersion(g, template.ID)).To(Equal(uint(2)))
+
+	// เรียงใหม่ต้องระบุครบทุก block
+	w = doTestRequest(r, 0, "PUT", fmt.Sprintf("/sections/%d/section-blocks/order", sectionID), fmt.Sprintf(`{"section_block_ids":[%d]}`, sectionBlocks[1].ID))
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest), w.Body.String())
+	w = doTestRequest(r, 0, "PUT", fmt.Sprintf("/sections/%d/section-blocks/order", sectionID), fmt.Sprintf(`{"section_block_ids":[%d,%d]}`, sectionBlocks[1].ID, sectionBlocks[0].ID))
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var section entity.TemplatesSection
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &section)).To(Succeed())
+	g.Expect(section.SectionBlocks[0].ID).To(Equal(sectionBlocks[1].ID))
+	g.Expect(section.SectionBlocks[1].ID).To(Equal(sectionBlocks[0].ID))
+
+	// section ที่ template อื่นใช้ด้วย ต้องยืนยันด้วย force
+	other := entity.Templates{TemplateName: "Other", CategoryTemplateID: template.CategoryTemplateID}
+	g.Expect(db.Omit("Category").Create(&other).Error).To(BeNil())
+	g.Expect(db.Omit("Templates", "TemplatesSection").Create(&entity.TemplateSectionLink{TemplatesID: other.ID, TemplatesSectionID: sectionID}).Error).To(BeNil())
+	w = doTestRequest(r, 0, "PUT", fmt.Sprintf("/sections/%d", sectionID), `{"section_name":"About","layout_type":"profile_header_left"}`)
+	g.Expect(w.Code).To(Equal(http.StatusConflict), w.Body.String())
+	g.Expect(w.Body.String()).To(ContainSubstring(fmt.Sprintf(`"template_ids":[%d,%d]`, template.ID, other.ID)))
+	w = doTestRequest(r, 0, "PUT", fmt.Sprintf("/sections/%d?force=true", sectionID), `{"section_name":"About","layout_type":"profile_header_left"}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(templateCurrentVersion(g, other.ID)).To(Equal(uint(1)))
+	g.Expect(doTestRequest(r, 0, "DELETE", fmt.Sprintf("/sections/%d", sectionID), "").Code).To(Equal(http.StatusConflict))
+
+	// block ใหม่ต้องผ่าน schema, เพิ่มเข้า section แล้วลบออกได้
+	g.Expect(doTestRequest(r, 0, "POST", "/template-blocks", `{"block_name":"bad","block_type":"nope"}`).Code).To(Equal(http.StatusBadRequest))
+	w = doTestRequest(r, 0, "POST", "/template-blocks", `{"block_name":"intro","block_type":"text","default_content":{"text":"Hi"}}`)
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	var block entity.TemplatesBlock
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &block)).To(Succeed())
+
+	lastSection := links[1].TemplatesSectionID
+	w = doTestRequest(r, 0, "POST", fmt.Sprintf("/sections/%d/section-blocks", lastSection), fmt.Sprintf(`{"templates_block_id":%d}`, block.ID))
+	g.Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &section)).To(Succeed())
+	g.Expect(section.SectionBlocks).To(HaveLen(2))
+	added := section.SectionBlocks[1]
+	g.Expect(added.TemplatesBlockID).To(Equal(block.ID))
+	g.Expect(added.OrderIndex).To(Equal(1))
+	g.Expect(added.LayoutType).To(Equal("grid"))
+
+	g.Expect(doTestRequest(r, 0, "DELETE", fmt.Sprintf("/template-blocks/%d", block.ID), "").Code).To(Equal(http.StatusConflict))
+	g.Expect(doTestRequest(r, 0, "PUT", fmt.Sprintf("/template-blocks/%d", block.ID), `{"block_name":"intro","block_type":"text","default_content":{"text":"Hello"}}`).Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, 0, "DELETE", fmt.Sprintf("/section-blocks/%d", added.ID), "").Code).To(Equal(http.StatusOK))
+	g.Expect(doTestRequest(r, 0, "DELETE", fmt.Sprintf("/template-blocks/%d", block.ID), "").Code).To(Equal(http.StatusOK))
+
+	// ลบ block สุดท้ายแล้ว section ว่าง render ไม่ได้
+	g.Expect(db.Where("templates_section_id = ?", lastSection).Find(&sectionBlocks).Error).To(BeNil())
+	g.Expect(doTestRequest(r, 0, "DELETE", fmt.Sprintf("/section-blocks/%d", sectionBlocks[0].ID), "").Code).To(Equal(http.StatusBadRequest))
+}
+
+// ตรวจ section ที่บันทึกไว้ก่อนมีการตรวจ layout
+func TestValidateStoredSectionLayout(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := templateDesignerRouter()
+	db := config.GetDB()
+
+	block := entity.TemplatesBlock{BlockName: "legacy", BlockType: "text", DefaultContent: datatypes.JSON(`{"text":"x"}`)}
+	g.Expect(db.Create(&block).Error).To(BeNil())
+	section := entity.TemplatesSection{SectionName: "Legacy", LayoutType: "grid"}
+	g.Expect(db.Create(&section).Error).To(BeNil())
+	for i := 0; i < 2; i++ {
+		sb := entity.SectionBlock{TemplatesSectionID: section.ID, TemplatesBlockID: block.ID, OrderIndex: 0, LayoutType: "table"}
+		g.Expect(db.Omit("TemplatesBlock", "TemplatesSection").Create(&sb).Error).To(BeNil())
+	}
+
+	w := doTestRequest(r, 0, "GET", fmt.Sprintf("/sections/%d/validate", section.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		Data struct {
+			Valid    bool     `json:"valid"`
+			Problems []string `json:"problems"`
+		} `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Data.Valid).To(BeFalse())
+	g.Expect(resp.Data.Problems).To(ContainElement(ContainSubstring("order_index 0 is also used")))
+	g.Expect(resp.Data.Problems).To(ContainElement(ContainSubstring(`layout_type must be grid or flex, got "table"`)))
+
+	// สร้าง section ใหม่ที่ไม่มี block ไม่ได้
+	g.Expect(doTestRequest(r, 0, "POST", "/sections", `{"section_name":"Empty","layout_type":"grid"}`).Code).To(Equal(http.StatusBadRequest))
+}
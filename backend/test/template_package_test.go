@@ -0,0 +1,228 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+)
+
+func createPackageTemplate(g *WithT, thumbnail string) entity.Templates {
+	db := config.GetDB()
+	suffix := time.Now().UnixNano()
+
+	category := entity.CategoryTemplate{CategoryName: fmt.Sprintf("Package %d", suffix)}
+	g.Expect(db.Create(&category).Error).To(BeNil())
+	shared := entity.TemplatesBlock{BlockName: fmt.Sprintf("heading-%d", suffix), BlockType: "header", DefaultContent: datatypes.JSON(`{"text":"Title","level":2}`)}
+	g.Expect(db.Create(&shared).Error).To(BeNil())
+	image := entity.TemplatesBlock{BlockName: fmt.Sprintf("photo-%d", suffix), BlockType: "image", DefaultContent: datatypes.JSON(`{"url":""}`)}
+	g.Expect(db.Create(&image).Error).To(BeNil())
+
+	template := entity.Templates{TemplateName: "Package", CategoryTemplateID: category.ID, Thumbnail: thumbnail}
+	g.Expect(db.Omit("Category").Create(&template).Error).To(BeNil())
+	for i, blocks := range [][]uint{{shared.ID, image.ID}, {shared.ID}} {
+		section := entity.TemplatesSection{SectionName: fmt.Sprintf("section-%d-%d", i, suffix), LayoutType: "grid"}
+		g.Expect(db.Create(&section).Error).To(BeNil())
+		for j, blockID := range blocks {
+			sb := entity.SectionBlock{TemplatesSectionID: section.ID, TemplatesBlockID: blockID, OrderIndex: j, LayoutType: "grid", Position: datatypes.JSON(`{"top": 0}`)}
+			g.Expect(db.Omit("TemplatesBlock", "TemplatesSection").Create(&sb).Error).To(BeNil())
+		}
+		link := entity.TemplateSectionLink{TemplatesID: template.ID, TemplatesSectionID: section.ID, OrderIndex: uint(i)}
+		g.Expect(db.Omit("Templates", "TemplatesSection").Create(&link).Error).To(BeNil())
+	}
+	return template
+}
+
+func readPackageManifest(g *WithT, data []byte) (services.TemplatePackage, map[string][]byte) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	g.Expect(err).To(BeNil())
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		g.Expect(err).To(BeNil())
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	var pkg services.TemplatePackage
+	g.Expect(json.Unmarshal(files["manifest.json"], &pkg)).To(Succeed())
+	return pkg, files
+}
+
+func writePackage(g *WithT, pkg services.TemplatePackage, assets map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range assets {
+		f, err := zw.Create(name)
+		g.Expect(err).To(BeNil())
+		f.Write(data)
+	}
+	f, err := zw.Create("manifest.json")
+	g.Expect(err).To(BeNil())
+	g.Expect(json.NewEncoder(f).Encode(pkg)).To(Succeed())
+	g.Expect(zw.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+// export แล้ว import กลับต้องได้ template ที่โครงสร้างเหมือนเดิม โดยใช้ section/block เดิมซ้ำ
+func TestTemplatePackageRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+
+	uploadDir := t.TempDir()
+	t.Setenv("UPLOAD_DIR", uploadDir)
+	g.Expect(services.InitLocalStorage()).To(Succeed())
+	t.Cleanup(func() { services.LocalStorage, services.Storage = nil, nil })
+	g.Expect(os.WriteFile(filepath.Join(uploadDir, "thumb.png"), []byte("png-bytes"), 0644)).To(Succeed())
+
+	source := createPackageTemplate(g, "/uploads/thumb.png")
+
+	var buf bytes.Buffer
+	g.Expect(services.ExportTemplatePackage(db, source.ID, &buf)).To(Succeed())
+	pkg, files := readPackageManifest(g, buf.Bytes())
+	g.Expect(pkg.Format).To(Equal(services.TemplatePackageFormat))
+	g.Expect(pkg.Template.Sections).To(HaveLen(2))
+	g.Expect(pkg.Blocks).To(HaveLen(2)) // block ที่ใช้ร่วมกันอยู่ใน manifest ครั้งเดียว
+	g.Expect(pkg.Template.Thumbnail).To(Equal("assets/thumbnail.png"))
+	g.Expect(files["assets/thumbnail.png"]).To(Equal([]byte("png-bytes")))
+
+	imported, result, err := services.ImportTemplatePackage(db, buf.Bytes(), 0)
+	g.Expect(err).To(BeNil())
+	g.Expect(imported.ID).NotTo(Equal(source.ID))
+	g.Expect(imported.CategoryTemplateID).To(Equal(source.CategoryTemplateID))
+	g.Expect(*result).To(Equal(services.TemplateImportResult{SectionsReused: 2, BlocksReused: 2}))
+	g.Expect(imported.TemplateSectionLinks).To(HaveLen(2))
+	g.Expect(imported.Thumbnail).To(HavePrefix("/uploads/"))
+	stored, err := os.ReadFile(filepath.Join(uploadDir, filepath.Base(imported.Thumbnail)))
+	g.Expect(err).To(BeNil())
+	g.Expect(stored).To(Equal([]byte("png-bytes")))
+
+	// แก้ content ของ block: ต้องสร้าง block ใหม่และ section ที่ใช้ block นั้นใหม่ทั้งสอง
+	pkg.Blocks[0].DefaultContent = datatypes.JSON(`{"text":"Changed","level":2}`)
+	pkg.Template.Category = fmt.Sprintf("Imported %d", time.Now().UnixNano())
+	imported, result, err = services.ImportTemplatePackage(db, writePackage(g, pkg, map[string][]byte{"assets/thumbnail.png": files["assets/thumbnail.png"]}), 0)
+	g.Expect(err).To(BeNil())
+	g.Expect(*result).To(Equal(services.TemplateImportResult{SectionsCreated: 2, BlocksCreated: 1, BlocksReused: 1, CategoryCreated: true}))
+	first := imported.TemplateSectionLinks[0].TemplatesSection.SectionBlocks[0].TemplatesBlock
+	second := imported.TemplateSectionLinks[1].TemplatesSection.SectionBlocks[0].TemplatesBlock
+	g.Expect(first.ID).To(Equal(second.ID))
+	g.Expect(string(first.DefaultContent)).To(ContainSubstring("Changed"))
+}
+
+func TestTemplatePackageImportRejectsInvalidPackages(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+
+	var templates, before int64
+	db.Model(&entity.Templates{}).Count(&before)
+
+	_, _, err := services.ImportTemplatePackage(db, []byte("not a zip"), 0)
+	g.Expect(err).To(MatchError(services.ErrInvalidTemplatePackage))
+
+	valid := services.TemplatePackage{
+		Format:   services.TemplatePackageFormat,
+		Version:  services.TemplatePackageVersion,
+		Template: services.TemplatePackageTemplate{TemplateName: "Broken", Category: "Broken", Sections: []string{"a", "b"}},
+		Sections: []services.TemplatePackageSection{
+			{Key: "a", SectionName: "A", Blocks: []services.TemplatePackageSectionBlock{{Block: "x"}}},
+			{Key: "b", SectionName: "B"},
+		},
+		Blocks: []services.TemplatePackageBlock{{Key: "x", BlockName: "x", BlockType: "text", DefaultContent: datatypes.JSON(`{"text":"ok"}`)}},
+	}
+
+	unknownBlock := valid
+	unknownBlock.Sections = []services.TemplatePackageSection{{Key: "a", Blocks: []services.TemplatePackageSectionBlock{{Block: "missing"}}}, {Key: "b"}}
+	_, _, err = services.ImportTemplatePackage(db, writePackage(g, unknownBlock, nil), 0)
+	g.Expect(err).To(MatchError(services.ErrInvalidTemplatePackage))
+
+	badSchema := valid
+	badSchema.Blocks = []services.TemplatePackageBlock{{Key: "x", BlockName: "x", BlockType: "video"}}
+	_, _, err = services.ImportTemplatePackage(db, writePackage(g, badSchema, nil), 0)
+	var validationErr *services.BlockValidationError
+	g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+	g.Expect(validationErr.Field).To(Equal("block_type"))
+
+	missingAsset := valid
+	missingAsset.Template.Thumbnail = "assets/missing.png"
+	_, _, err = services.ImportTemplatePackage(db, writePackage(g, missingAsset, nil), 0)
+	g.Expect(err).To(MatchError(services.ErrInvalidTemplatePackage))
+
+	db.Model(&entity.Templates{}).Count(&templates)
+	g.Expect(templates).To(Equal(before))
+}
+
+func templateImportRequest(g *WithT, category string, pkg []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	g.Expect(mw.WriteField("category_template_id", category)).To(Succeed())
+	part, err := mw.CreateFormFile("file", "template.zip")
+	g.Expect(err).To(BeNil())
+	part.Write(pkg)
+	g.Expect(mw.Close()).To(Succeed())
+	req := newTestRequest(0, "POST", "/templates/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// ไฟล์เกิน 20MB ต้องถูกตัดตั้งแต่ตอน parse form (รวม category_template_id)
+func TestImportTemplatePackageUploadLimit(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := gin.New()
+	r.POST("/templates/import", controller.ImportTemplatePackage)
+
+	category := entity.CategoryTemplate{CategoryName: fmt.Sprintf("Import %d", time.Now().UnixNano())}
+	g.Expect(config.GetDB().Create(&category).Error).To(BeNil())
+
+	w := serveTestRequest(r, templateImportRequest(g, fmt.Sprint(category.ID), bytes.Repeat([]byte("x"), 21<<20)))
+	g.Expect(w.Code).To(Equal(http.StatusRequestEntityTooLarge), w.Body.String())
+
+	var buf bytes.Buffer
+	g.Expect(services.ExportTemplatePackage(config.GetDB(), createPackageTemplate(g, "").ID, &buf)).To(Succeed())
+	w = serveTestRequest(r, templateImportRequest(g, fmt.Sprint(category.ID), buf.Bytes()))
+	g.Expect(w.Code).To(BeNumerically("<", 300), w.Body.String())
+	g.Expect(w.Body.String()).To(ContainSubstring(fmt.Sprintf(`"category_template_id":%d`, category.ID)))
+
+	w = serveTestRequest(r, templateImportRequest(g, "abc", buf.Bytes()))
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+}
+
+// thumbnail ที่อยู่ใน S3 ต้องถูกแนบใน package ด้วย (อ่านผ่าน Storage ไม่ใช่โฟลเดอร์ในเครื่อง)
+func TestTemplatePackageExportThumbnailFromS3(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+
+	_, server := newFakeS3(t, "portfolio")
+	s3, err := services.NewS3Storage(services.S3Config{
+		Endpoint: server.URL, Bucket: "portfolio", Region: "ap-southeast-1",
+		AccessKey: "minio", SecretKey: "minio-secret", Prefix: "media", PathStyle: true,
+	})
+	g.Expect(err).To(BeNil())
+	services.LocalStorage, services.Storage = nil, s3
+	t.Cleanup(func() { services.LocalStorage, services.Storage = nil, nil })
+	url, err := s3.SaveFile(bytes.NewReader([]byte("s3-png-bytes")), "thumb.png")
+	g.Expect(err).To(BeNil())
+
+	var buf bytes.Buffer
+	g.Expect(services.ExportTemplatePackage(config.GetDB(), createPackageTemplate(g, url).ID, &buf)).To(Succeed())
+	pkg, files := readPackageManifest(g, buf.Bytes())
+	g.Expect(pkg.Template.Thumbnail).To(Equal("assets/thumbnail.png"))
+	g.Expect(files["assets/thumbnail.png"]).To(Equal([]byte("s3-png-bytes")))
+}
@@ -0,0 +1,254 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/controller"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func templateRebaseRouter() *gin.Engine {
+	r := portfolioOwnershipRouter()
+	r.POST("/portfolio/use-template/:id", controller.UseTemplate)
+	r.GET("/portfolio/:id/template-rebase", controller.PreviewTemplateRebase)
+	r.POST("/portfolio/:id/template-rebase", controller.ApplyTemplateRebase)
+	r.POST("/portfolio/:id/undo", controller.UndoPortfolioChange)
+	return r
+}
+
+func rebaseActions(plan services.TemplateRebasePlan) map[string]int {
+	actions := map[string]int{}
+	for _, change := range plan.Changes {
+		actions[change.Action]++
+	}
+	return actions
+}
+
+// template ที่ถูกแก้ภายหลัง: portfolio ได้ section ใหม่และ style ใหม่ โดย content และ style ที่นักเรียนแก้เองยังอยู่
+func TestTemplateRebasePreviewAndApply(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := templateRebaseRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+	template := createPackageTemplate(g, "")
+
+	w := doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/use-template/%d", template.ID), `{"portfolio_name":"From template"}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var created struct {
+		Data entity.Portfolio `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+	portfolio := created.Data
+	g.Expect(portfolio.TemplateVersion).To(Equal(uint(1)))
+	g.Expect(portfolio.PortfolioSections).To(HaveLen(2))
+
+	// นักเรียนแก้ content ของรูป และ style ของหัวข้อใน section แรก
+	var image, heading entity.PortfolioBlock
+	g.Expect(db.Joins("JOIN portfolio_sections ON portfolio_sections.id = portfolio_blocks.portfolio_section_id").
+		Where("portfolio_sections.portfolio_id = ? AND block_port_type = ?", portfolio.ID, "image").First(&image).Error).To(BeNil())
+	g.Expect(db.Model(&image).Update("content", datatypes.JSON(`{"url":"https://example.com/me.png"}`)).Error).To(BeNil())
+	g.Expect(db.Where("portfolio_section_id = ? AND block_port_type = ?", image.PortfolioSectionID, "header").First(&heading).Error).To(BeNil())
+	g.Expect(db.Model(&heading).Update("block_style", datatypes.JSON(`{"color":"red"}`)).Error).To(BeNil())
+
+	// ยังไม่มีอะไรเปลี่ยน
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/template-rebase", portfolio.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var preview struct {
+		Data services.TemplateRebasePlan `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &preview)).To(Succeed())
+	g.Expect(preview.Data.UpToDate).To(BeTrue())
+
+	// admin แก้ template: เปลี่ยน style ของทุก block และเพิ่ม section ใหม่
+	g.Expect(db.Model(&entity.TemplatesBlock{}).Where("id IN ?", []uint{*image.TemplateBlockID, *heading.TemplateBlockID}).
+		Update("default_style", datatypes.JSON(`{"padding":"8px"}`)).Error).To(BeNil())
+	extra := entity.TemplatesSection{SectionName: "Awards " + strconv.Itoa(int(template.ID)), LayoutType: "grid"}
+	g.Expect(db.Create(&extra).Error).To(BeNil())
+	sb := entity.SectionBlock{TemplatesSectionID: extra.ID, TemplatesBlockID: *heading.TemplateBlockID, LayoutType: "grid"}
+	g.Expect(db.Omit("TemplatesBlock", "TemplatesSection").Create(&sb).Error).To(BeNil())
+	link := entity.TemplateSectionLink{TemplatesID: template.ID, TemplatesSectionID: extra.ID, OrderIndex: 2}
+	g.Expect(db.Omit("Templates", "TemplatesSection").Create(&link).Error).To(BeNil())
+
+	g.Expect(doTestRequest(r, f.other, "GET", fmt.Sprintf("/portfolio/%d/template-rebase", portfolio.ID), "").Code).To(Equal(http.StatusNotFound))
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/template-rebase", portfolio.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &preview)).To(Succeed())
+	plan := preview.Data
+	g.Expect(plan.FromVersion).To(Equal(uint(1)))
+	g.Expect(plan.ToVersion).To(Equal(uint(2)))
+	g.Expect(plan.UpToDate).To(BeFalse())
+	g.Expect(rebaseActions(plan)).To(Equal(map[string]int{
+		services.RebaseAddSection:       1,
+		services.RebaseUpdateBlockStyle: 2, // รูป และหัวข้อใน section ที่สอง
+		services.RebaseKeepCustomStyle:  1,
+	}))
+
+	// preview เก่ากว่า template ปัจจุบัน
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/template-rebase", portfolio.ID), `{"to_version":1}`)
+	g.Expect(w.Code).To(Equal(http.StatusConflict), w.Body.String())
+
+	w = doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/template-rebase", portfolio.ID), `{"to_version":2}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var applied struct {
+		Data    entity.Portfolio `json:"data"`
+		Applied int              `json:"applied"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &applied)).To(Succeed())
+	g.Expect(applied.Applied).To(Equal(3))
+	g.Expect(applied.Data.TemplateVersion).To(Equal(uint(2)))
+	g.Expect(applied.Data.PortfolioSections).To(HaveLen(3))
+
+	g.Expect(db.First(&image, image.ID).Error).To(BeNil())
+	g.Expect(string(image.Content)).To(Equal(`{"url":"https://example.com/me.png"}`))
+	g.Expect(string(image.BlockStyle)).To(MatchJSON(`{"padding":"8px"}`))
+	g.Expect(db.First(&heading, heading.ID).Error).To(BeNil())
+	g.Expect(string(heading.BlockStyle)).To(MatchJSON(`{"color":"red"}`))
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/template-rebase", portfolio.ID), "")
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &preview)).To(Succeed())
+	g.Expect(preview.Data.UpToDate).To(BeTrue())
+
+	// rebase ทั้งชุด undo ได้ในครั้งเดียว
+	g.Expect(doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/%d/undo", portfolio.ID), "").Code).To(Equal(http.StatusOK))
+	var sections int64
+	db.Model(&entity.PortfolioSection{}).Where("portfolio_id = ?", portfolio.ID).Count(&sections)
+	g.Expect(sections).To(Equal(int64(2)))
+	g.Expect(db.First(&image, image.ID).Error).To(BeNil())
+	g.Expect(canonicalStyle(image.BlockStyle)).To(BeEmpty())
+
+	// portfolio ที่ไม่ได้สร้างจาก template
+	g.Expect(doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/template-rebase", f.portfolio), "").Code).To(Equal(http.StatusBadRequest))
+}
+
+// แก้ template แล้วบันทึกซ้ำโดยไม่มีอะไรเปลี่ยนต้องไม่สร้าง version ใหม่
+func TestEnsureTemplateVersionSkipsUnchanged(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	db := config.GetDB()
+	template := createPackageTemplate(g, "")
+
+	first, err := services.EnsureTemplateVersion(db, template.ID)
+	g.Expect(err).To(BeNil())
+	again, err := services.EnsureTemplateVersion(db, template.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(again.Version).To(Equal(first.Version))
+
+	g.Expect(db.Model(&entity.Templates{}).Where("id = ?", template.ID).Update("description", "changed").Error).To(BeNil())
+	next, err := services.EnsureTemplateVersion(db, template.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(next.Version).To(Equal(first.Version + 1))
+
+	snapshot, err := services.LoadTemplateVersion(db, template.ID, first.Version)
+	g.Expect(err).To(BeNil())
+	g.Expect(snapshot.Template.Description).To(BeEmpty())
+	_, err = services.LoadTemplateVersion(db, template.ID, 99)
+	g.Expect(err).To(MatchError(services.ErrTemplateVersionNotFound))
+}
+
+func canonicalStyle(style datatypes.JSON) string {
+	s := strings.TrimSpace(string(style))
+	if s == "null" || s == "{}" {
+		return ""
+	}
+	return s
+}
+
+// ใช้ template พร้อมกันสองคนแล้วชน unique index ของ version: ต้องอ่านใหม่แล้วใช้ version ที่มีอยู่ ไม่ตอบ 500
+func TestUseTemplateRetriesVersionConflict(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := templateRebaseRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+	template := createPackageTemplate(g, "")
+
+	// จำลอง request อื่นบันทึก version 1 ไปก่อน: insert ครั้งแรกได้ error แบบเดียวกับที่ sqlite ตอบ
+	const callback = "test:template_version_conflict"
+	conflicts := 0
+	g.Expect(db.Callback().Create().Before("gorm:create").Register(callback, func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*entity.TemplateVersion); ok && conflicts == 0 {
+			conflicts++
+			tx.AddError(errors.New("UNIQUE constraint failed: template_versions.templates_id, template_versions.version"))
+		}
+	})).To(Succeed())
+	defer db.Callback().Create().Remove(callback)
+
+	w := doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/use-template/%d", template.ID), `{}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(conflicts).To(Equal(1))
+	var created struct {
+		Data entity.Portfolio `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+	g.Expect(created.Data.TemplateVersion).To(Equal(uint(1)))
+
+	var versions int64
+	g.Expect(db.Model(&entity.TemplateVersion{}).Where("templates_id = ?", template.ID).Count(&versions).Error).To(BeNil())
+	g.Expect(versions).To(Equal(int64(1)))
+}
+
+func TestTemplateRebasePreviewRetriesVersionConflict(t *testing.T) {
+	g := NewWithT(t)
+	setupSQLiteTestDB()
+	r := templateRebaseRouter()
+	f := createOwnershipFixture(g)
+	db := config.GetDB()
+	template := createPackageTemplate(g, "")
+
+	w := doTestRequest(r, f.owner, "POST", fmt.Sprintf("/portfolio/use-template/%d", template.ID), `{}`)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var created struct {
+		Data entity.Portfolio `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+
+	g.Expect(db.Model(&entity.TemplatesBlock{}).Where("id IN (?)",
+		db.Model(&entity.SectionBlock{}).Select("templates_block_id")).
+		Update("default_style", datatypes.JSON(`{"padding":"8px"}`)).Error).To(BeNil())
+
+	countVersions := func() int64 {
+		var versions int64
+		g.Expect(db.Model(&entity.TemplateVersion{}).Where("templates_id = ?", template.ID).Count(&versions).Error).To(BeNil())
+		return versions
+	}
+
+	// PlanTemplateRebase อ่านอย่างเดียว: เทียบกับ version ที่บันทึกไว้ ไม่สร้าง version ใหม่
+	plan, err := services.PlanTemplateRebase(db, created.Data.ID)
+	g.Expect(err).To(BeNil())
+	g.Expect(plan.ToVersion).To(Equal(uint(1)))
+	g.Expect(countVersions()).To(Equal(int64(1)))
+
+	// request อื่นบันทึก version 2 ไปก่อน: preview ต้องลองใหม่แทนที่จะตอบ 500
+	const callback = "test:template_rebase_version_conflict"
+	conflicts := 0
+	g.Expect(db.Callback().Create().Before("gorm:create").Register(callback, func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*entity.TemplateVersion); ok && conflicts == 0 {
+			conflicts++
+			tx.AddError(errors.New("UNIQUE constraint failed: template_versions.templates_id, template_versions.version"))
+		}
+	})).To(Succeed())
+	defer db.Callback().Create().Remove(callback)
+
+	w = doTestRequest(r, f.owner, "GET", fmt.Sprintf("/portfolio/%d/template-rebase", created.Data.ID), "")
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	g.Expect(conflicts).To(Equal(1))
+	var preview struct {
+		Data services.TemplateRebasePlan `json:"data"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &preview)).To(Succeed())
+	g.Expect(preview.Data.ToVersion).To(Equal(uint(2)))
+	g.Expect(countVersions()).To(Equal(int64(2)))
+}
@@ -0,0 +1,254 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sut68/team14/backend/config"
+	"github.com/sut68/team14/backend/entity"
+	"github.com/sut68/team14/backend/services"
+	"gorm.io/datatypes"
+)
+
+type storedUploadResponse struct {
+	URL          string `json:"url"`
+	SHA256       string `json:"sha256"`
+	Deduplicated bool   `json:"deduplicated"`
+}
+
+func uploadDocument(g *WithT, userID uint, fileName string, data []byte) storedUploadResponse {
+	w := uploadImageRequest(g, userID, fileName, data)
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp storedUploadResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	return resp
+}
+
+func deleteUploadRequest(userID uint, fileName string) int {
+	return doTestRequest(uploadRouter(), userID, "DELETE", "/upload/"+fileName, "").Code
+}
+
+func createUploadUser(g *WithT, name string, accountType uint) uint {
+	u := entity.User{Email: fmt.Sprintf("%s-%d@test.local", name, time.Now().UnixNano()), AccountTypeID: accountType}
+	g.Expect(config.GetDB().Omit("AccountType", "IDDocType").Create(&u).Error).To(BeNil())
+	return u.ID
+}
+
+// ไฟล์เนื้อหาเดียวกันเก็บครั้งเดียว ชื่อไฟล์มาจาก SHA-256
+func TestUploadDeduplication(t *testing.T) {
+	g := NewWithT(t)
+	uploadDir, owner := setupImageStorage(g, t)
+	other := createUploadUser(g, "other-uploader", entity.UserTypeStudent)
+	pdf := []byte("%PDF-1.4 transcript " + strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	first := uploadDocument(g, owner, "transcript.PDF", pdf)
+	g.Expect(first.Deduplicated).To(BeFalse())
+	g.Expect(first.SHA256).To(HaveLen(64))
+	g.Expect(path.Base(first.URL)).To(Equal(first.SHA256 + ".pdf"))
+
+	again := uploadDocument(g, owner, "copy.pdf", pdf)
+	g.Expect(again.Deduplicated).To(BeTrue())
+	g.Expect(again.URL).To(Equal(first.URL))
+
+	shared := uploadDocument(g, other, "mine.pdf", pdf)
+	g.Expect(shared.Deduplicated).To(BeTrue())
+	g.Expect(shared.URL).To(Equal(first.URL))
+
+	entries, _ := os.ReadDir(uploadDir)
+	g.Expect(entries).To(HaveLen(1))
+
+	var rows []entity.UploadedFile
+	g.Expect(config.GetDB().Where("sha256 = ?", first.SHA256).Order("id").Find(&rows).Error).To(BeNil())
+	g.Expect(rows).To(HaveLen(2))
+	g.Expect(*rows[0].OwnerID).To(Equal(owner))
+	g.Expect(*rows[1].OwnerID).To(Equal(other))
+	g.Expect(rows[0].Size).To(Equal(int64(len(pdf))))
+	g.Expect(rows[0].MimeType).To(Equal("application/pdf"))
+	g.Expect(rows[0].OriginalName).To(Equal("transcript.PDF"))
+
+	// รูปเดียวกันอัปโหลดซ้ำได้ขนาดภาพเดิมโดยไม่ประมวลผลใหม่
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		img.Set(x, x%200, color.RGBA{uint8(x), 0, 0, 255})
+	}
+	var buf bytes.Buffer
+	g.Expect(jpeg.Encode(&buf, img, nil)).To(Succeed())
+	w := uploadImageRequest(g, owner, "a.jpg", buf.Bytes())
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	w = uploadImageRequest(g, other, "b.jpg", buf.Bytes())
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var resp struct {
+		imageUploadResponse
+		Deduplicated bool `json:"deduplicated"`
+	}
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &resp)).To(Succeed())
+	g.Expect(resp.Deduplicated).To(BeTrue())
+	g.Expect(resp.Width).To(Equal(400))
+	g.Expect(resp.Renditions[services.ImageRenditionThumbnail].Width).To(Equal(320))
+	entries, _ = os.ReadDir(uploadDir)
+	g.Expect(entries).To(HaveLen(4))
+}
+
+// ลบได้เฉพาะไฟล์ของตัวเอง และลบไม่ได้ถ้ายังมีข้อมูลอ้างถึง
+func TestUploadDeleteOwnership(t *testing.T) {
+	g := NewWithT(t)
+	uploadDir, owner := setupImageStorage(g, t)
+	other := createUploadUser(g, "other-uploader", entity.UserTypeStudent)
+	stranger := createUploadUser(g, "stranger", entity.UserTypeStudent)
+	admin := createUploadUser(g, "upload-admin", entity.UserTypeAdmin)
+	db := config.GetDB()
+
+	pdf := []byte("%PDF-1.4 certificate " + strconv.FormatInt(time.Now().UnixNano(), 10))
+	file := uploadDocument(g, owner, "cert.pdf", pdf)
+	uploadDocument(g, other, "cert.pdf", pdf)
+	name := path.Base(file.URL)
+
+	g.Expect(deleteUploadRequest(0, name)).To(Equal(http.StatusUnauthorized))
+	g.Expect(deleteUploadRequest(stranger, name)).To(Equal(http.StatusForbidden))
+	// admin ลบไฟล์ที่มีเจ้าของไม่ได้
+	g.Expect(deleteUploadRequest(admin, name)).To(Equal(http.StatusForbidden))
+	g.Expect(deleteUploadRequest(owner, "missing.pdf")).To(Equal(http.StatusNotFound))
+
+	// ผู้ใช้อื่นยังถือไฟล์อยู่ ลบแค่ของตัวเอง
+	g.Expect(deleteUploadRequest(owner, name)).To(Equal(http.StatusOK))
+	g.Expect(filepath.Join(uploadDir, name)).To(BeAnExistingFile())
+	g.Expect(deleteUploadRequest(owner, name)).To(Equal(http.StatusForbidden))
+
+	// ไฟล์ที่ยังถูกใช้ลบไม่ได้
+	g.Expect(db.Model(&entity.User{}).Where("id = ?", other).Update("profile_image_url", "http://localhost:8080"+file.URL).Error).To(BeNil())
+	g.Expect(deleteUploadRequest(other, name)).To(Equal(http.StatusConflict))
+	g.Expect(db.Model(&entity.User{}).Where("id = ?", other).Update("profile_image_url", "").Error).To(BeNil())
+	g.Expect(deleteUploadRequest(other, name)).To(Equal(http.StatusOK))
+	g.Expect(filepath.Join(uploadDir, name)).NotTo(BeAnExistingFile())
+
+	// ไฟล์เก่าที่ไม่มีเจ้าของ admin เท่านั้นที่ลบได้
+	g.Expect(os.WriteFile(filepath.Join(uploadDir, "1700000000000000000.pdf"), []byte("legacy"), 0644)).To(Succeed())
+	g.Expect(deleteUploadRequest(owner, "1700000000000000000.pdf")).To(Equal(http.StatusForbidden))
+	g.Expect(deleteUploadRequest(admin, "1700000000000000000.pdf")).To(Equal(http.StatusOK))
+	g.Expect(filepath.Join(uploadDir, "1700000000000000000.pdf")).NotTo(BeAnExistingFile())
+}
+
+// GC นับการอ้างถึงใหม่และลบไฟล์ที่ไม่มีใครใช้เกิน grace period
+func TestUploadGarbageCollection(t *testing.T) {
+	g := NewWithT(t)
+	uploadDir, owner := setupImageStorage(g, t)
+	db := config.GetDB()
+	f := createOwnershipFixture(g)
+	stamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	used := uploadDocument(g, owner, "used.pdf", []byte("%PDF used "+stamp))
+	stale := uploadDocument(g, owner, "stale.pdf", []byte("%PDF stale "+stamp))
+	fresh := uploadDocument(g, owner, "fresh.pdf", []byte("%PDF fresh "+stamp))
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 600))
+	var buf bytes.Buffer
+	g.Expect(jpeg.Encode(&buf, img, nil)).To(Succeed())
+	w := uploadImageRequest(g, owner, "photo.jpg", buf.Bytes())
+	g.Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	var photo imageUploadResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &photo)).To(Succeed())
+
+	// block อ้างถึงเอกสาร และอ้างถึงรูปผ่านภาพย่ออย่างเดียว
+	content := fmt.Sprintf(`{"url":%q,"images":[{"url":%q}]}`, used.URL, photo.Renditions[services.ImageRenditionThumbnail].URL)
+	g.Expect(db.Model(&entity.PortfolioBlock{}).Where("id = ?", f.block).Update("content", datatypes.JSON(content)).Error).To(BeNil())
+
+	old := time.Now().Add(-48 * time.Hour)
+	g.Expect(db.Model(&entity.UploadedFile{}).Where("sha256 IN ?", []string{stale.SHA256, used.SHA256}).Update("unreferenced_since", old).Error).To(BeNil())
+
+	opts := services.UploadGCOptions{GracePeriod: 24 * time.Hour, DryRun: true}
+	result, err := services.CollectUnreferencedUploads(db, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Removed).To(ContainElement(path.Base(stale.URL)))
+	g.Expect(result.Removed).NotTo(ContainElement(path.Base(used.URL)))
+	g.Expect(filepath.Join(uploadDir, path.Base(stale.URL))).To(BeAnExistingFile())
+
+	opts.DryRun = false
+	result, err = services.CollectUnreferencedUploads(db, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Removed).To(ConsistOf(path.Base(stale.URL)))
+	g.Expect(filepath.Join(uploadDir, path.Base(stale.URL))).NotTo(BeAnExistingFile())
+	g.Expect(filepath.Join(uploadDir, path.Base(fresh.URL))).To(BeAnExistingFile())
+	g.Expect(filepath.Join(uploadDir, path.Base(photo.URL))).To(BeAnExistingFile())
+
+	var row entity.UploadedFile
+	g.Expect(db.Where("sha256 = ?", used.SHA256).First(&row).Error).To(BeNil())
+	g.Expect(row.ReferenceCount).To(Equal(1))
+	g.Expect(row.UnreferencedSince).To(BeNil())
+	g.Expect(db.Where("sha256 = ?", stale.SHA256).First(&entity.UploadedFile{}).Error).NotTo(BeNil())
+
+	// block ถูกลบ (soft delete) แล้ว รูปเริ่มนับ grace period ใหม่ตั้งแต่รอบนี้
+	g.Expect(db.Delete(&entity.PortfolioBlock{}, f.block).Error).To(BeNil())
+	result, err = services.CollectUnreferencedUploads(db, opts)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Removed).To(BeEmpty())
+	row = entity.UploadedFile{}
+	g.Expect(db.Where("stored_name = ?", path.Base(photo.URL)).First(&row).Error).To(BeNil())
+	g.Expect(row.UnreferencedSince).NotTo(BeNil())
+
+	result, err = services.CollectUnreferencedUploads(db, services.UploadGCOptions{GracePeriod: 0})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Removed).To(ContainElements(path.Base(photo.URL), path.Base(used.URL), path.Base(fresh.URL)))
+	entries, _ := os.ReadDir(uploadDir)
+	g.Expect(entries).To(BeEmpty())
+
+	// ไฟล์เก่าที่ไม่มีแถวถูกรับเข้ามาเมื่อเปิด AdoptUntracked (ภาพย่อไม่นับแยก)
+	for _, name := range []string{"1700000000000000000.png", "1700000000000000000_thumb.png"} {
+		g.Expect(os.WriteFile(filepath.Join(uploadDir, name), []byte("legacy"), 0644)).To(Succeed())
+	}
+	result, err = services.CollectUnreferencedUploads(db, services.UploadGCOptions{GracePeriod: 24 * time.Hour, AdoptUntracked: true})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Adopted).To(Equal(1))
+	g.Expect(db.Where("stored_name = ? AND owner_id IS NULL", "1700000000000000000.png").First(&entity.UploadedFile{}).Error).To(BeNil())
+	entries, _ = os.ReadDir(uploadDir)
+	g.Expect(entries).To(HaveLen(2))
+}
+
+// bucket ที่ตั้ง prefix เป็นชื่ออื่น (หรือไม่มี prefix) URL ไม่มี /uploads/ แต่ไฟล์ที่ถูกอ้างถึงต้องไม่ถูกลบ
+func TestUploadGarbageCollectionWithCustomS3Prefix(t *testing.T) {
+	for _, prefix := range []string{"media", ""} {
+		t.Run("prefix="+prefix, func(t *testing.T) {
+			g := NewWithT(t)
+			_, owner := setupImageStorage(g, t)
+			db := config.GetDB()
+			f := createOwnershipFixture(g)
+
+			fake, server := newFakeS3(t, "portfolio")
+			s3, err := services.NewS3Storage(services.S3Config{
+				Endpoint: server.URL, Bucket: "portfolio", Region: "ap-southeast-1",
+				AccessKey: "minio", SecretKey: "minio-secret", Prefix: prefix, PathStyle: true,
+			})
+			g.Expect(err).To(BeNil())
+			services.LocalStorage, services.Storage = nil, s3
+
+			stamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+			used := uploadDocument(g, owner, "used.pdf", []byte("%PDF used "+stamp))
+			stale := uploadDocument(g, owner, "stale.pdf", []byte("%PDF stale "+stamp))
+			g.Expect(used.URL).NotTo(ContainSubstring("/uploads/"))
+
+			content := fmt.Sprintf(`{"url":%q}`, used.URL)
+			g.Expect(db.Model(&entity.PortfolioBlock{}).Where("id = ?", f.block).Update("content", datatypes.JSON(content)).Error).To(BeNil())
+
+			g.Expect(deleteUploadRequest(owner, path.Base(used.URL))).To(Equal(http.StatusConflict))
+
+			result, err := services.CollectUnreferencedUploads(db, services.UploadGCOptions{GracePeriod: 0})
+			g.Expect(err).To(BeNil())
+			g.Expect(result.Removed).To(ContainElement(path.Base(stale.URL)))
+			g.Expect(result.Removed).NotTo(ContainElement(path.Base(used.URL)))
+			g.Expect(fake.objects).To(HaveKey(strings.TrimPrefix(path.Join(prefix, path.Base(used.URL)), "/")))
+			g.Expect(fake.objects).To(HaveLen(1))
+		})
+	}
+}
@@ -50,7 +50,7 @@ export default function StudentCalendarPage() {
   async function loadData(uid: number) {
     try {
       setLoading(true);
-      const data = await fetchMySelections(uid);
+      const data = await fetchMySelections();
 
       if (!Array.isArray(data)) {
         setSelectedCurricula([]);
@@ -81,7 +81,7 @@ export default function StudentCalendarPage() {
   const handleRemove = async (id: number) => {
     if(!confirm("ต้องการลบออกจากปฏิทินใช่หรือไม่?")) return;
     try {
-      await toggleSelectionAPI(userId, id);
+      await toggleSelectionAPI(id);
       setActiveMenuId(null);
       await loadData(userId);
     } catch (err) {
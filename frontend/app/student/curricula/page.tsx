@@ -95,7 +95,7 @@ export default function StudentCurriculaSearchPage() {
   // โหลดรายการที่เลือกจาก Server
   async function loadSelections(uid: number) {
     try {
-      const selections = await fetchMySelections(uid);
+      const selections = await fetchMySelections();
       
       // แปลง Selection เป็น CurriculumDTO แล้วเก็บลง myList
       const myCurricula = selections.map((s: any) => s.curriculum ? s.curriculum : s);
@@ -137,7 +137,7 @@ export default function StudentCurriculaSearchPage() {
   // Action: Toggle Select
   const toggleSelect = async (curriculumId: number) => {
     try {
-      await toggleSelectionAPI(userId, curriculumId);
+      await toggleSelectionAPI(curriculumId);
       // โหลด Selections ใหม่ -> myList จะเปลี่ยน -> useEffect จะทำงาน -> อัปเดตหน้าจออัตโนมัติ
       await loadSelections(userId); 
     } catch (err) { 
@@ -358,11 +358,13 @@ const PortfolioReview = () => {
       const blocks = section.portfolio_blocks || [];
       blocks.forEach((block: any) => {
         const content = typeof block.content === 'string' ? JSON.parse(block.content) : block.content;
-        if (content?.data) {
-          if (content.type === 'activity') {
-            activities.push(content.data);
-          } else if (content.type === 'working') {
-            workings.push(content.data);
+        // block แบบอ้างอิงเก็บแค่ data_id ข้อมูลล่าสุดอยู่ใน block.source (content.data เป็นสำเนาแบบเก่า)
+        const data = block.source?.status === 'ok' ? block.source.data : content?.data;
+        if (data) {
+          if (content?.type === 'activity') {
+            activities.push(data);
+          } else if (content?.type === 'working') {
+            workings.push(data);
           }
         }
       });
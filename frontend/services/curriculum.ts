@@ -253,18 +253,19 @@ export async function fetchCurriculumSummary() {
 
 // --------------------- Selection / Calendar ---------------------
 
-export async function toggleSelectionAPI(userId: number, curriculumId: number) {
+export async function toggleSelectionAPI(curriculumId: number) {
   const res = await fetch(`${API_URL}/selections`, {
     method: "POST",
-    headers: { "Content-Type": "application/json" }, 
-    body: JSON.stringify({ user_id: userId, curriculum_id: curriculumId }),
+    headers: authHeaders(),
+    body: JSON.stringify({ curriculum_id: curriculumId }),
   });
   if (!res.ok) throw new Error("Failed to toggle selection");
   return await res.json();
 }
 
-export async function fetchMySelections(userId: number): Promise<CurriculumDTO[]> {
-  const res = await fetch(`${API_URL}/selections?user_id=${userId}`, {
+export async function fetchMySelections(): Promise<CurriculumDTO[]> {
+  const res = await fetch(`${API_URL}/selections`, {
+    headers: authHeaders(),
     cache: "no-store",
   });
   